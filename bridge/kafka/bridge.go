@@ -0,0 +1,265 @@
+// Package kafka consumes a Kafka topic into the pending segment of a queue and
+// acknowledges the consumed offsets only when the elements become visible.
+//
+// The Bridge is a Bank for the commit orchestrator. It buffers the consumed
+// elements itself, and its prepare phase stages exactly those elements with
+// the queue's PrepareAppend, together with the messages that produced them;
+// the publish phase makes the elements visible and then commits the offsets.
+// Staging does not go through the queue's pending segment, so options that
+// stage only part of it, such as WithMaxCommitBatch or priority lanes, never
+// leave consumed elements behind whose offsets are committed. The queue's
+// commit filter still applies; elements it rejects count as handled. An
+// aborted commit keeps both the elements and the offsets uncommitted. After a
+// crash the broker redelivers every message whose element was not published,
+// so delivery into the visible segment is at-least-once.
+//
+// The package does not depend on a Kafka client. Consumer is small enough to
+// be implemented on top of any client, for example segmentio/kafka-go (with
+// this package imported as cqkafka):
+//
+//	type readerConsumer struct{ r *kafka.Reader }
+//
+//	func (c readerConsumer) Fetch(ctx context.Context) (cqkafka.Message, error) {
+//		m, err := c.r.FetchMessage(ctx)
+//		return cqkafka.Message{Topic: m.Topic, Partition: m.Partition, Offset: m.Offset, Key: m.Key, Value: m.Value}, err
+//	}
+//
+//	func (c readerConsumer) Commit(ctx context.Context, msgs []cqkafka.Message) error {
+//		converted := make([]kafka.Message, len(msgs))
+//		for i, m := range msgs {
+//			converted[i] = kafka.Message{Topic: m.Topic, Partition: m.Partition, Offset: m.Offset}
+//		}
+//		return c.r.CommitMessages(ctx, converted...)
+//	}
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/timzifer/committable_queue/codec"
+	"github.com/timzifer/committable_queue/queue"
+)
+
+// Message is a consumed Kafka record.
+type Message struct {
+	Topic     string
+	Partition int
+	Offset    int64
+	Key       []byte
+	Value     []byte
+}
+
+// Consumer fetches messages without committing them and commits offsets on
+// request. Commit receives the last message per topic partition; committing it
+// acknowledges every earlier offset of that partition as well.
+type Consumer interface {
+	Fetch(ctx context.Context) (Message, error)
+	Commit(ctx context.Context, messages []Message) error
+}
+
+const defaultCommitTimeout = 10 * time.Second
+
+// Option configures a Bridge.
+type Option func(*config)
+
+type config struct {
+	name          string
+	commitTimeout time.Duration
+}
+
+// WithName sets the bank name reported to the orchestrator's telemetry. The
+// default is "kafka".
+func WithName(name string) Option {
+	return func(c *config) {
+		c.name = name
+	}
+}
+
+// WithCommitTimeout bounds the offset commit in the publish phase. The default
+// is ten seconds.
+func WithCommitTimeout(timeout time.Duration) Option {
+	return func(c *config) {
+		c.commitTimeout = timeout
+	}
+}
+
+type partitionKey struct {
+	topic     string
+	partition int
+}
+
+// Bridge feeds a queue from a Consumer and couples offset commits to queue
+// commits. Register the Bridge with the orchestrator instead of the queue it
+// feeds.
+type Bridge[T any] struct {
+	consumer Consumer
+	queue    *queue.SegmentedQueue[T]
+	decoder  codec.Decoder[T]
+	config   config
+
+	// mu orders pushes against PrepareCommit so that the staged elements and
+	// the staged offsets always describe the same messages.
+	mu sync.Mutex
+	// pending holds the consumed elements that no commit has staged yet.
+	pending []T
+	// uncommitted holds the newest consumed message per partition whose
+	// element has not been published yet.
+	uncommitted map[partitionKey]Message
+	// unacked holds published offsets whose commit failed; they are retried
+	// with the next publish.
+	unacked map[partitionKey]Message
+	err     error
+}
+
+// New creates a Bridge that decodes message values with dec and pushes them
+// into q.
+func New[T any](consumer Consumer, q *queue.SegmentedQueue[T], dec codec.Decoder[T], options ...Option) *Bridge[T] {
+	cfg := config{name: "kafka", commitTimeout: defaultCommitTimeout}
+	for _, opt := range options {
+		opt(&cfg)
+	}
+	return &Bridge[T]{
+		consumer:    consumer,
+		queue:       q,
+		decoder:     dec,
+		config:      cfg,
+		uncommitted: make(map[partitionKey]Message),
+		unacked:     make(map[partitionKey]Message),
+	}
+}
+
+// Name implements the orchestrator's NamedBank interface.
+func (b *Bridge[T]) Name() string {
+	return b.config.name
+}
+
+// Run fetches messages and buffers their elements for the next commit until
+// ctx is done or the consumer fails. A message that cannot be decoded stops Run; its
+// offset is not committed.
+func (b *Bridge[T]) Run(ctx context.Context) error {
+	for {
+		msg, err := b.consumer.Fetch(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+		value, err := b.decoder.Unmarshal(msg.Value)
+		if err != nil {
+			return fmt.Errorf("kafka: decode %s/%d@%d: %w", msg.Topic, msg.Partition, msg.Offset, err)
+		}
+
+		b.mu.Lock()
+		b.pending = append(b.pending, value)
+		b.uncommitted[partitionKey{msg.Topic, msg.Partition}] = msg
+		b.mu.Unlock()
+	}
+}
+
+// PrepareCommit stages the buffered elements in the queue and the offsets of
+// the messages they were decoded from.
+func (b *Bridge[T]) PrepareCommit(ctx context.Context) (publish func(), abort func(), err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if len(b.pending) == 0 {
+		return nil, nil, nil
+	}
+	values := b.pending
+	queuePublish, queueAbort, err := b.queue.PrepareAppend(ctx, values)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	staged := b.uncommitted
+	b.pending = nil
+	b.uncommitted = make(map[partitionKey]Message)
+
+	// With every element rejected by the queue's filter there is nothing to
+	// publish, but the offsets are still committed.
+	publish = func() {
+		if queuePublish != nil {
+			queuePublish()
+		}
+		b.commit(staged)
+	}
+	abort = func() {
+		if queueAbort != nil {
+			queueAbort()
+		}
+		b.restore(values, staged)
+	}
+	return publish, abort, nil
+}
+
+// commit acknowledges staged offsets together with those of earlier failed
+// commits.
+func (b *Bridge[T]) commit(staged map[partitionKey]Message) {
+	b.mu.Lock()
+	for key, msg := range staged {
+		if prev, ok := b.unacked[key]; !ok || prev.Offset < msg.Offset {
+			b.unacked[key] = msg
+		}
+	}
+	messages := make([]Message, 0, len(b.unacked))
+	for _, msg := range b.unacked {
+		messages = append(messages, msg)
+	}
+	b.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), b.config.commitTimeout)
+	err := b.consumer.Commit(ctx, messages)
+	cancel()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.err = err
+	if err != nil {
+		return
+	}
+	for _, msg := range messages {
+		key := partitionKey{msg.Topic, msg.Partition}
+		if b.unacked[key].Offset == msg.Offset {
+			delete(b.unacked, key)
+		}
+	}
+}
+
+// restore returns aborted elements ahead of those consumed in the meantime,
+// and aborted offsets to the uncommitted set unless newer messages of the
+// same partition were consumed since.
+func (b *Bridge[T]) restore(values []T, staged map[partitionKey]Message) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.pending = append(values, b.pending...)
+	for key, msg := range staged {
+		if _, ok := b.uncommitted[key]; !ok {
+			b.uncommitted[key] = msg
+		}
+	}
+}
+
+// Commit prepares and immediately publishes the consumed elements, for use
+// without an orchestrator. It returns the result of the offset commit.
+func (b *Bridge[T]) Commit() error {
+	publish, _, err := b.PrepareCommit(context.Background())
+	if err != nil {
+		return err
+	}
+	if publish != nil {
+		publish()
+	}
+	return b.Err()
+}
+
+// Err returns the error of the most recent offset commit. Failed offsets are
+// retried with the next publish; until then the broker may redeliver them.
+func (b *Bridge[T]) Err() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.err
+}
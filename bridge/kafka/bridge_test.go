@@ -0,0 +1,178 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/timzifer/committable_queue/codec"
+	"github.com/timzifer/committable_queue/orchestrator"
+	"github.com/timzifer/committable_queue/queue"
+)
+
+var errDrained = errors.New("no more messages")
+
+// fakeConsumer serves queued messages and fails with errDrained once they are
+// exhausted, which ends Run deterministically.
+type fakeConsumer struct {
+	messages []Message
+
+	mu        sync.Mutex
+	committed map[int]int64
+	fail      error
+}
+
+func newFakeConsumer() *fakeConsumer {
+	return &fakeConsumer{committed: make(map[int]int64)}
+}
+
+func (c *fakeConsumer) Fetch(context.Context) (Message, error) {
+	if len(c.messages) == 0 {
+		return Message{}, errDrained
+	}
+	msg := c.messages[0]
+	c.messages = c.messages[1:]
+	return msg, nil
+}
+
+func (c *fakeConsumer) Commit(_ context.Context, messages []Message) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.fail != nil {
+		return c.fail
+	}
+	for _, msg := range messages {
+		c.committed[msg.Partition] = msg.Offset
+	}
+	return nil
+}
+
+func (c *fakeConsumer) offset(partition int) (int64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	offset, ok := c.committed[partition]
+	return offset, ok
+}
+
+type failingBank struct{ err error }
+
+func (b failingBank) PrepareCommit(context.Context) (func(), func(), error) {
+	return nil, nil, b.err
+}
+
+// consume feeds messages through Run and returns once all of them are pending.
+func consume(t *testing.T, b *Bridge[string], c *fakeConsumer, messages ...Message) {
+	t.Helper()
+	c.messages = append(c.messages, messages...)
+	if err := b.Run(context.Background()); !errors.Is(err, errDrained) {
+		t.Fatalf("unexpected Run result: %v", err)
+	}
+}
+
+func TestBridgeCommitsOffsetsOnPublish(t *testing.T) {
+	c := newFakeConsumer()
+	q := queue.NewSegmentedQueue[string]()
+	b := New[string](c, q, codec.JSON[string]{})
+
+	consume(t, b, c,
+		Message{Topic: "telemetry", Partition: 0, Offset: 4, Value: []byte(`"a"`)},
+		Message{Topic: "telemetry", Partition: 0, Offset: 5, Value: []byte(`"b"`)},
+		Message{Topic: "telemetry", Partition: 1, Offset: 9, Value: []byte(`"c"`)},
+	)
+
+	if _, ok := c.offset(0); ok {
+		t.Fatalf("offsets must not be committed before publish")
+	}
+
+	o := orchestrator.NewCommitOrchestrator(orchestrator.WithBanks(b))
+	if err := o.CommitAll(context.Background()); err != nil {
+		t.Fatalf("commit failed: %v", err)
+	}
+	if got := q.LenVisible(); got != 3 {
+		t.Fatalf("expected three visible elements, got %d", got)
+	}
+	if offset, _ := c.offset(0); offset != 5 {
+		t.Fatalf("expected partition 0 committed at 5, got %d", offset)
+	}
+	if offset, _ := c.offset(1); offset != 9 {
+		t.Fatalf("expected partition 1 committed at 9, got %d", offset)
+	}
+	if b.Name() != "kafka" {
+		t.Fatalf("unexpected bank name %q", b.Name())
+	}
+}
+
+func TestBridgeKeepsOffsetsOnAbort(t *testing.T) {
+	c := newFakeConsumer()
+	q := queue.NewSegmentedQueue[string]()
+	b := New[string](c, q, codec.JSON[string]{})
+
+	consume(t, b, c, Message{Partition: 0, Offset: 1, Value: []byte(`"a"`)})
+
+	failing := orchestrator.NewCommitOrchestrator(orchestrator.WithBanks(b, failingBank{err: errors.New("boom")}))
+	if err := failing.CommitAll(context.Background()); err == nil {
+		t.Fatalf("expected commit to fail")
+	}
+	if _, ok := c.offset(0); ok || q.LenVisible() != 0 {
+		t.Fatalf("aborted commit must neither publish nor acknowledge")
+	}
+
+	if err := b.Commit(); err != nil {
+		t.Fatalf("commit failed: %v", err)
+	}
+	if offset, ok := c.offset(0); !ok || offset != 1 {
+		t.Fatalf("expected offset from aborted commit to be acknowledged later, got %d", offset)
+	}
+}
+
+func TestBridgeRetriesFailedOffsetCommit(t *testing.T) {
+	c := newFakeConsumer()
+	q := queue.NewSegmentedQueue[string]()
+	b := New[string](c, q, codec.JSON[string]{}, WithName("orders"))
+
+	consume(t, b, c, Message{Partition: 2, Offset: 7, Value: []byte(`"a"`)})
+	c.fail = errors.New("broker unavailable")
+	b.Commit()
+	if b.Err() == nil || q.LenVisible() != 1 {
+		t.Fatalf("expected published element and recorded commit error")
+	}
+
+	c.mu.Lock()
+	c.fail = nil
+	c.mu.Unlock()
+	consume(t, b, c, Message{Partition: 3, Offset: 1, Value: []byte(`"b"`)})
+	b.Commit()
+	if b.Err() != nil {
+		t.Fatalf("unexpected error: %v", b.Err())
+	}
+	if offset, ok := c.offset(2); !ok || offset != 7 {
+		t.Fatalf("expected failed offset to be retried, got %d", offset)
+	}
+}
+
+func TestBridgeStopsOnUndecodableMessage(t *testing.T) {
+	c := newFakeConsumer()
+	b := New[string](c, queue.NewSegmentedQueue[string](), codec.JSON[string]{})
+	c.messages = []Message{{Value: []byte("{")}}
+	if err := b.Run(context.Background()); err == nil {
+		t.Fatalf("expected decode error")
+	}
+}
+
+func TestBridgeStagesEveryConsumedElement(t *testing.T) {
+	c := newFakeConsumer()
+	q := queue.NewSegmentedQueue(queue.WithMaxCommitBatch[string](1))
+	b := New[string](c, q, codec.JSON[string]{})
+
+	consume(t, b, c,
+		Message{Partition: 0, Offset: 1, Value: []byte(`"a"`)},
+		Message{Partition: 0, Offset: 2, Value: []byte(`"b"`)},
+	)
+	if err := b.Commit(); err != nil {
+		t.Fatalf("commit failed: %v", err)
+	}
+	if offset, _ := c.offset(0); offset != 2 || q.LenVisible() != 2 {
+		t.Fatalf("expected both elements visible with offset 2 committed, got %d visible and offset %d", q.LenVisible(), offset)
+	}
+}
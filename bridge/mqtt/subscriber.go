@@ -0,0 +1,161 @@
+// Package mqtt buffers MQTT messages from field devices as pending queue
+// elements, so consumers only see them at consistent commit points.
+//
+// Subscriber is a Bank for the commit orchestrator. Its message handler
+// buffers every decoded payload, and the prepare phase stages exactly the
+// buffered elements with the queue's PrepareAppend, so options that stage only
+// part of the pending segment, such as WithMaxCommitBatch or priority lanes,
+// never leave an element behind whose message is acknowledged. The publish
+// phase makes the staged elements visible and then acknowledges the messages
+// to the broker; elements the queue's commit filter rejects count as handled
+// and their messages are acknowledged too. For
+// at-least-once delivery across reconnects, subscribe with QoS 1 or 2 and
+// configure the client with SetAutoAckDisabled(true) and
+// SetCleanSession(false); the broker then redelivers every message whose
+// element was not published before the connection dropped.
+package mqtt
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	paho "github.com/eclipse/paho.mqtt.golang"
+
+	"github.com/timzifer/committable_queue/codec"
+	"github.com/timzifer/committable_queue/queue"
+)
+
+// Option configures a Subscriber.
+type Option func(*config)
+
+type config struct {
+	name string
+}
+
+// WithName sets the bank name reported to the orchestrator's telemetry. The
+// default is "mqtt".
+func WithName(name string) Option {
+	return func(c *config) {
+		c.name = name
+	}
+}
+
+// Subscriber feeds a queue from MQTT subscriptions. Register the Subscriber
+// with the orchestrator instead of the queue it feeds.
+type Subscriber[T any] struct {
+	queue   *queue.SegmentedQueue[T]
+	decoder codec.Decoder[T]
+	config  config
+
+	// mu orders pushes against PrepareCommit so that staged elements and
+	// staged messages always match.
+	mu sync.Mutex
+	// pending and uncommitted hold the decoded elements that no commit has
+	// staged yet and the messages they came from, in the same order.
+	pending     []T
+	uncommitted []paho.Message
+	err         error
+}
+
+// New creates a Subscriber that decodes payloads with dec and commits them into
+// q.
+func New[T any](q *queue.SegmentedQueue[T], dec codec.Decoder[T], options ...Option) *Subscriber[T] {
+	cfg := config{name: "mqtt"}
+	for _, opt := range options {
+		opt(&cfg)
+	}
+	return &Subscriber[T]{queue: q, decoder: dec, config: cfg}
+}
+
+// Name implements the orchestrator's NamedBank interface.
+func (s *Subscriber[T]) Name() string {
+	return s.config.name
+}
+
+// Subscribe subscribes client to topic with s.Handle as message handler and
+// waits for the broker to confirm.
+func (s *Subscriber[T]) Subscribe(client paho.Client, topic string, qos byte) error {
+	token := client.Subscribe(topic, qos, s.Handle)
+	token.Wait()
+	return token.Error()
+}
+
+// Handle is a paho.MessageHandler. A payload that cannot be decoded is
+// acknowledged and dropped; the error is reported through Err.
+func (s *Subscriber[T]) Handle(_ paho.Client, msg paho.Message) {
+	value, err := s.decoder.Unmarshal(msg.Payload())
+	if err != nil {
+		msg.Ack()
+		s.mu.Lock()
+		s.err = fmt.Errorf("mqtt: decode message on %s: %w", msg.Topic(), err)
+		s.mu.Unlock()
+		return
+	}
+
+	s.mu.Lock()
+	s.pending = append(s.pending, value)
+	s.uncommitted = append(s.uncommitted, msg)
+	s.mu.Unlock()
+}
+
+// PrepareCommit stages the buffered elements in the queue together with the
+// messages they were decoded from.
+func (s *Subscriber[T]) PrepareCommit(ctx context.Context) (publish func(), abort func(), err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.pending) == 0 {
+		return nil, nil, nil
+	}
+	values := s.pending
+	queuePublish, queueAbort, err := s.queue.PrepareAppend(ctx, values)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	staged := s.uncommitted
+	s.pending = nil
+	s.uncommitted = nil
+
+	// With every element rejected by the queue's filter there is nothing to
+	// publish, but the messages are still acknowledged.
+	publish = func() {
+		if queuePublish != nil {
+			queuePublish()
+		}
+		for _, msg := range staged {
+			msg.Ack()
+		}
+	}
+	abort = func() {
+		if queueAbort != nil {
+			queueAbort()
+		}
+		s.mu.Lock()
+		s.pending = append(values, s.pending...)
+		s.uncommitted = append(staged, s.uncommitted...)
+		s.mu.Unlock()
+	}
+	return publish, abort, nil
+}
+
+// Commit prepares and immediately publishes the buffered elements, for use
+// without an orchestrator.
+func (s *Subscriber[T]) Commit() error {
+	publish, _, err := s.PrepareCommit(context.Background())
+	if err != nil {
+		return err
+	}
+	if publish != nil {
+		publish()
+	}
+	return nil
+}
+
+// Err returns the most recent decode error.
+func (s *Subscriber[T]) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
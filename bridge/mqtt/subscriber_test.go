@@ -0,0 +1,93 @@
+package mqtt
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	paho "github.com/eclipse/paho.mqtt.golang"
+
+	"github.com/timzifer/committable_queue/codec"
+	"github.com/timzifer/committable_queue/orchestrator"
+	"github.com/timzifer/committable_queue/queue"
+)
+
+type fakeMessage struct {
+	paho.Message
+	payload []byte
+	acks    int
+}
+
+func (m *fakeMessage) Payload() []byte { return m.payload }
+func (m *fakeMessage) Topic() string   { return "plant/line-1/temperature" }
+func (m *fakeMessage) Ack()            { m.acks++ }
+
+type failingBank struct{ err error }
+
+func (b failingBank) PrepareCommit(context.Context) (func(), func(), error) {
+	return nil, nil, b.err
+}
+
+func TestSubscriberAcksOnPublish(t *testing.T) {
+	q := queue.NewSegmentedQueue[float64]()
+	s := New[float64](q, codec.JSON[float64]{})
+
+	first := &fakeMessage{payload: []byte("21.5")}
+	second := &fakeMessage{payload: []byte("22")}
+	s.Handle(nil, first)
+
+	failing := orchestrator.NewCommitOrchestrator(orchestrator.WithBanks(s, failingBank{err: errors.New("boom")}))
+	if err := failing.CommitAll(context.Background()); err == nil {
+		t.Fatalf("expected commit to fail")
+	}
+	if first.acks != 0 || q.LenVisible() != 0 {
+		t.Fatalf("aborted commit must neither publish nor acknowledge")
+	}
+
+	s.Handle(nil, second)
+	o := orchestrator.NewCommitOrchestrator(orchestrator.WithBanks(s))
+	if err := o.CommitAll(context.Background()); err != nil {
+		t.Fatalf("commit failed: %v", err)
+	}
+	if first.acks != 1 || second.acks != 1 {
+		t.Fatalf("expected each message to be acknowledged once, got %d and %d", first.acks, second.acks)
+	}
+	for _, want := range []float64{21.5, 22} {
+		if v, ok := q.PopFront(); !ok || v != want {
+			t.Fatalf("expected %v, got %v", want, v)
+		}
+	}
+}
+
+func TestSubscriberDropsUndecodablePayload(t *testing.T) {
+	q := queue.NewSegmentedQueue[float64]()
+	s := New[float64](q, codec.JSON[float64]{}, WithName("line-1"))
+
+	bad := &fakeMessage{payload: []byte("not a number")}
+	s.Handle(nil, bad)
+	if bad.acks != 1 || s.Err() == nil {
+		t.Fatalf("expected undecodable message to be acknowledged and reported")
+	}
+	if err := s.Commit(); err != nil || q.LenVisible() != 0 {
+		t.Fatalf("undecodable message must not be queued")
+	}
+	if s.Name() != "line-1" {
+		t.Fatalf("unexpected bank name %q", s.Name())
+	}
+}
+
+func TestSubscriberStagesEveryBufferedElement(t *testing.T) {
+	q := queue.NewSegmentedQueue(queue.WithMaxCommitBatch[float64](1))
+	s := New[float64](q, codec.JSON[float64]{})
+
+	first := &fakeMessage{payload: []byte("21.5")}
+	second := &fakeMessage{payload: []byte("22")}
+	s.Handle(nil, first)
+	s.Handle(nil, second)
+	if err := s.Commit(); err != nil {
+		t.Fatalf("commit failed: %v", err)
+	}
+	if first.acks != 1 || second.acks != 1 || q.LenVisible() != 2 {
+		t.Fatalf("expected both elements visible and acknowledged, got %d visible", q.LenVisible())
+	}
+}
@@ -0,0 +1,79 @@
+package nats
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go/jetstream"
+
+	"github.com/timzifer/committable_queue/codec"
+	"github.com/timzifer/committable_queue/queue"
+)
+
+// Publisher is the subset of jetstream.JetStream used by Sink.
+type Publisher interface {
+	Publish(ctx context.Context, subject string, payload []byte, opts ...jetstream.PublishOpt) (*jetstream.PubAck, error)
+}
+
+// Sink publishes the visible elements of a queue to a subject. An element whose
+// publish fails is kept by the Sink and retried first on the next Flush, so it
+// is neither lost nor reordered. An element that cannot be encoded is dropped
+// and the encoding error is returned.
+type Sink[T any] struct {
+	publisher Publisher
+	subject   string
+	queue     *queue.SegmentedQueue[T]
+	encoder   codec.Encoder[T]
+
+	mu       sync.Mutex
+	inflight []byte
+}
+
+// NewSink creates a Sink that encodes elements of q with enc and publishes
+// them to subject.
+func NewSink[T any](publisher Publisher, subject string, q *queue.SegmentedQueue[T], enc codec.Encoder[T]) *Sink[T] {
+	return &Sink[T]{publisher: publisher, subject: subject, queue: q, encoder: enc}
+}
+
+// Flush publishes visible elements until the visible segment is empty or a
+// publish fails. It returns the number of published elements.
+func (s *Sink[T]) Flush(ctx context.Context) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	published := 0
+	for {
+		if s.inflight == nil {
+			value, ok := s.queue.PopFront()
+			if !ok {
+				return published, nil
+			}
+			payload, err := s.encoder.Marshal(value)
+			if err != nil {
+				return published, err
+			}
+			s.inflight = payload
+		}
+		if _, err := s.publisher.Publish(ctx, s.subject, s.inflight); err != nil {
+			return published, err
+		}
+		s.inflight = nil
+		published++
+	}
+}
+
+// Run flushes the queue every interval until ctx is done. Publish errors are
+// retried on the next tick.
+func (s *Sink[T]) Run(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		s.Flush(ctx)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
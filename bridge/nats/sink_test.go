@@ -0,0 +1,48 @@
+package nats
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/nats-io/nats.go/jetstream"
+
+	"github.com/timzifer/committable_queue/codec"
+	"github.com/timzifer/committable_queue/queue"
+)
+
+type fakePublisher struct {
+	published []string
+	fail      error
+}
+
+func (p *fakePublisher) Publish(_ context.Context, subject string, payload []byte, _ ...jetstream.PublishOpt) (*jetstream.PubAck, error) {
+	if p.fail != nil {
+		return nil, p.fail
+	}
+	p.published = append(p.published, subject+":"+string(payload))
+	return &jetstream.PubAck{}, nil
+}
+
+func TestSinkPublishesOnlyCommittedElements(t *testing.T) {
+	p := &fakePublisher{}
+	q := queue.NewSegmentedQueue[int](queue.WithInitialVisible(1, 2), queue.WithInitialPending(3))
+	sink := NewSink[int](p, "out", q, codec.JSON[int]{})
+
+	p.fail = errors.New("no responders")
+	if n, err := sink.Flush(context.Background()); err == nil || n != 0 {
+		t.Fatalf("expected failed flush, got n=%d err=%v", n, err)
+	}
+
+	p.fail = nil
+	n, err := sink.Flush(context.Background())
+	if err != nil || n != 2 {
+		t.Fatalf("expected two published elements, got n=%d err=%v", n, err)
+	}
+	if len(p.published) != 2 || p.published[0] != "out:1" || p.published[1] != "out:2" {
+		t.Fatalf("unexpected publishes: %v", p.published)
+	}
+	if q.LenVisible() != 0 {
+		t.Fatalf("published elements must leave the queue")
+	}
+}
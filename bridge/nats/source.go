@@ -0,0 +1,229 @@
+// Package nats connects queues to NATS JetStream.
+//
+// Source consumes a stream into a queue and is a Bank for the commit
+// orchestrator. It buffers the consumed elements itself, and its prepare
+// phase stages exactly those elements with the queue's PrepareAppend, so
+// options that stage only part of the pending segment, such as
+// WithMaxCommitBatch or priority lanes, never leave an element behind whose
+// message is acknowledged. Messages are acknowledged in the publish phase,
+// after their elements became visible, and stay unacknowledged when the commit
+// aborts. Elements the queue's commit filter rejects count as handled and
+// their messages are acknowledged too. The consumer's AckWait must exceed the
+// longest time an element can stay buffered, otherwise the server redelivers
+// it and the element is queued twice. The publish phase acknowledges up to
+// WithAckConcurrency messages at once, so a large batch costs a few server
+// round trips rather than one per message.
+//
+// Sink publishes visible, that is committed, elements of a queue to a subject.
+package nats
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go/jetstream"
+
+	"github.com/timzifer/committable_queue/codec"
+	"github.com/timzifer/committable_queue/queue"
+)
+
+const (
+	defaultAckTimeout     = 5 * time.Second
+	defaultAckConcurrency = 32
+)
+
+// SourceOption configures a Source.
+type SourceOption func(*sourceConfig)
+
+type sourceConfig struct {
+	name           string
+	ackTimeout     time.Duration
+	ackConcurrency int
+}
+
+// WithName sets the bank name reported to the orchestrator's telemetry. The
+// default is "jetstream".
+func WithName(name string) SourceOption {
+	return func(c *sourceConfig) {
+		c.name = name
+	}
+}
+
+// WithAckTimeout bounds each acknowledgement in the publish phase. The default
+// is five seconds.
+func WithAckTimeout(timeout time.Duration) SourceOption {
+	return func(c *sourceConfig) {
+		c.ackTimeout = timeout
+	}
+}
+
+// WithAckConcurrency bounds how many acknowledgements the publish phase has
+// in flight at once. The default is 32; values below one are treated as one.
+func WithAckConcurrency(n int) SourceOption {
+	return func(c *sourceConfig) {
+		c.ackConcurrency = max(n, 1)
+	}
+}
+
+// Source feeds a queue from a JetStream consumer. Register the Source with
+// the orchestrator instead of the queue it feeds.
+type Source[T any] struct {
+	messages jetstream.MessagesContext
+	queue    *queue.SegmentedQueue[T]
+	decoder  codec.Decoder[T]
+	config   sourceConfig
+
+	// mu orders pushes against PrepareCommit so that staged elements and
+	// staged messages always match.
+	mu sync.Mutex
+	// pending and uncommitted hold the consumed elements that no commit has
+	// staged yet and the messages they were decoded from, in the same order.
+	pending     []T
+	uncommitted []jetstream.Msg
+	err         error
+}
+
+// NewSource creates a Source that reads from messages, typically obtained via
+// jetstream.Consumer.Messages, decodes the payload with dec and pushes it
+// into q.
+func NewSource[T any](messages jetstream.MessagesContext, q *queue.SegmentedQueue[T], dec codec.Decoder[T], options ...SourceOption) *Source[T] {
+	cfg := sourceConfig{name: "jetstream", ackTimeout: defaultAckTimeout, ackConcurrency: defaultAckConcurrency}
+	for _, opt := range options {
+		opt(&cfg)
+	}
+	return &Source[T]{messages: messages, queue: q, decoder: dec, config: cfg}
+}
+
+// Name implements the orchestrator's NamedBank interface.
+func (s *Source[T]) Name() string {
+	return s.config.name
+}
+
+// Run buffers the elements of incoming messages for the next commit until ctx
+// is done or the iterator fails. A message that cannot be decoded is terminated, so the
+// server does not redeliver it, and Run returns the decode error.
+func (s *Source[T]) Run(ctx context.Context) error {
+	for {
+		msg, err := s.messages.Next(jetstream.NextContext(ctx))
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+		value, err := s.decoder.Unmarshal(msg.Data())
+		if err != nil {
+			msg.Term()
+			return fmt.Errorf("nats: decode message on %s: %w", msg.Subject(), err)
+		}
+
+		s.mu.Lock()
+		s.pending = append(s.pending, value)
+		s.uncommitted = append(s.uncommitted, msg)
+		s.mu.Unlock()
+	}
+}
+
+// PrepareCommit stages the buffered elements in the queue together with the
+// messages they were decoded from.
+func (s *Source[T]) PrepareCommit(ctx context.Context) (publish func(), abort func(), err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.pending) == 0 {
+		return nil, nil, nil
+	}
+	values := s.pending
+	queuePublish, queueAbort, err := s.queue.PrepareAppend(ctx, values)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	staged := s.uncommitted
+	s.pending = nil
+	s.uncommitted = nil
+
+	// With every element rejected by the queue's filter there is nothing to
+	// publish, but the messages are still acknowledged.
+	publish = func() {
+		if queuePublish != nil {
+			queuePublish()
+		}
+		s.ack(staged)
+	}
+	abort = func() {
+		if queueAbort != nil {
+			queueAbort()
+		}
+		s.mu.Lock()
+		s.pending = append(values, s.pending...)
+		s.uncommitted = append(staged, s.uncommitted...)
+		s.mu.Unlock()
+	}
+	return publish, abort, nil
+}
+
+// ack acknowledges the published messages with up to ackConcurrency workers
+// and waits for the server to confirm each acknowledgement. The elements are
+// visible already, so a failed acknowledgement cannot be undone: the server
+// redelivers the message and its element is queued twice. ack records how
+// many acknowledgements failed in the Source's error.
+func (s *Source[T]) ack(staged []jetstream.Msg) {
+	errs := make([]error, len(staged))
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for range min(s.config.ackConcurrency, len(staged)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				ctx, cancel := context.WithTimeout(context.Background(), s.config.ackTimeout)
+				errs[i] = staged[i].DoubleAck(ctx)
+				cancel()
+			}
+		}()
+	}
+	for i := range staged {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+
+	var err error
+	if joined := errors.Join(errs...); joined != nil {
+		failed := 0
+		for _, e := range errs {
+			if e != nil {
+				failed++
+			}
+		}
+		err = fmt.Errorf("nats: %d of %d acknowledgements failed: %w", failed, len(staged), joined)
+	}
+
+	s.mu.Lock()
+	s.err = err
+	s.mu.Unlock()
+}
+
+// Commit prepares and immediately publishes the consumed elements, for use
+// without an orchestrator. It returns the acknowledgement errors.
+func (s *Source[T]) Commit() error {
+	publish, _, err := s.PrepareCommit(context.Background())
+	if err != nil {
+		return err
+	}
+	if publish != nil {
+		publish()
+	}
+	return s.Err()
+}
+
+// Err returns the acknowledgement errors of the most recent publish.
+func (s *Source[T]) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
@@ -0,0 +1,211 @@
+package nats
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go/jetstream"
+
+	"github.com/timzifer/committable_queue/codec"
+	"github.com/timzifer/committable_queue/orchestrator"
+	"github.com/timzifer/committable_queue/queue"
+)
+
+var errDrained = errors.New("no more messages")
+
+type fakeMsg struct {
+	jetstream.Msg
+	data       []byte
+	acked      bool
+	terminated bool
+	ackErr     error
+}
+
+func (m *fakeMsg) Data() []byte    { return m.data }
+func (m *fakeMsg) Subject() string { return "telemetry" }
+func (m *fakeMsg) Term() error {
+	m.terminated = true
+	return nil
+}
+
+func (m *fakeMsg) DoubleAck(context.Context) error {
+	if m.ackErr != nil {
+		return m.ackErr
+	}
+	m.acked = true
+	return nil
+}
+
+// fakeMessages serves queued messages and fails with errDrained once they are
+// exhausted, which ends Run deterministically.
+type fakeMessages struct {
+	jetstream.MessagesContext
+	queued []jetstream.Msg
+}
+
+func (m *fakeMessages) Next(...jetstream.NextOpt) (jetstream.Msg, error) {
+	if len(m.queued) == 0 {
+		return nil, errDrained
+	}
+	msg := m.queued[0]
+	m.queued = m.queued[1:]
+	return msg, nil
+}
+
+type failingBank struct{ err error }
+
+func (b failingBank) PrepareCommit(context.Context) (func(), func(), error) {
+	return nil, nil, b.err
+}
+
+func runSource(t *testing.T, s *Source[string], messages *fakeMessages, queued ...jetstream.Msg) {
+	t.Helper()
+	messages.queued = append(messages.queued, queued...)
+	if err := s.Run(context.Background()); !errors.Is(err, errDrained) {
+		t.Fatalf("unexpected Run result: %v", err)
+	}
+}
+
+func TestSourceAcksOnPublish(t *testing.T) {
+	messages := &fakeMessages{}
+	q := queue.NewSegmentedQueue[string]()
+	s := NewSource[string](messages, q, codec.JSON[string]{})
+
+	first := &fakeMsg{data: []byte(`"a"`)}
+	second := &fakeMsg{data: []byte(`"b"`)}
+	runSource(t, s, messages, first)
+
+	failing := orchestrator.NewCommitOrchestrator(orchestrator.WithBanks(s, failingBank{err: errors.New("boom")}))
+	if err := failing.CommitAll(context.Background()); err == nil {
+		t.Fatalf("expected commit to fail")
+	}
+	if first.acked || q.LenVisible() != 0 {
+		t.Fatalf("aborted commit must neither publish nor acknowledge")
+	}
+
+	runSource(t, s, messages, second)
+	o := orchestrator.NewCommitOrchestrator(orchestrator.WithBanks(s))
+	if err := o.CommitAll(context.Background()); err != nil {
+		t.Fatalf("commit failed: %v", err)
+	}
+	if !first.acked || !second.acked {
+		t.Fatalf("expected both messages to be acknowledged")
+	}
+	for _, want := range []string{"a", "b"} {
+		if v, ok := q.PopFront(); !ok || v != want {
+			t.Fatalf("expected %q, got %q", want, v)
+		}
+	}
+}
+
+func TestSourceReportsAckErrors(t *testing.T) {
+	messages := &fakeMessages{}
+	s := NewSource[string](messages, queue.NewSegmentedQueue[string](), codec.JSON[string]{}, WithName("orders"))
+	runSource(t, s, messages, &fakeMsg{data: []byte(`"a"`), ackErr: errors.New("timeout")})
+
+	if err := s.Commit(); err == nil {
+		t.Fatalf("expected acknowledgement error")
+	}
+	if s.Name() != "orders" {
+		t.Fatalf("unexpected bank name %q", s.Name())
+	}
+}
+
+// slowMsg acknowledges after a delay and tracks how many acknowledgements
+// are in flight at once.
+type slowMsg struct {
+	fakeMsg
+	inFlight, peak *atomic.Int32
+}
+
+func (m *slowMsg) DoubleAck(ctx context.Context) error {
+	n := m.inFlight.Add(1)
+	defer m.inFlight.Add(-1)
+	for {
+		peak := m.peak.Load()
+		if n <= peak || m.peak.CompareAndSwap(peak, n) {
+			break
+		}
+	}
+	time.Sleep(10 * time.Millisecond)
+	return m.fakeMsg.DoubleAck(ctx)
+}
+
+func TestSourceAcksConcurrently(t *testing.T) {
+	messages := &fakeMessages{}
+	s := NewSource[string](messages, queue.NewSegmentedQueue[string](), codec.JSON[string]{}, WithAckConcurrency(4))
+
+	var inFlight, peak atomic.Int32
+	var queued []jetstream.Msg
+	var slow []*slowMsg
+	for i := range 12 {
+		msg := &slowMsg{fakeMsg: fakeMsg{data: []byte(fmt.Sprintf("%q", fmt.Sprint(i)))}, inFlight: &inFlight, peak: &peak}
+		if i%5 == 0 {
+			msg.ackErr = errors.New("timeout")
+		}
+		slow = append(slow, msg)
+		queued = append(queued, msg)
+	}
+	runSource(t, s, messages, queued...)
+
+	err := s.Commit()
+	if err == nil || !strings.Contains(err.Error(), "3 of 12") {
+		t.Fatalf("expected three failed acknowledgements to be reported, got %v", err)
+	}
+	if p := peak.Load(); p < 2 || p > 4 {
+		t.Fatalf("expected between 2 and 4 acknowledgements in flight, got %d", p)
+	}
+	for i, msg := range slow {
+		if msg.acked != (msg.ackErr == nil) {
+			t.Fatalf("message %d: acked=%v despite ackErr=%v", i, msg.acked, msg.ackErr)
+		}
+	}
+}
+
+func TestSourceStagesEveryConsumedElement(t *testing.T) {
+	messages := &fakeMessages{}
+	q := queue.NewSegmentedQueue(queue.WithMaxCommitBatch[string](1))
+	s := NewSource[string](messages, q, codec.JSON[string]{})
+
+	first := &fakeMsg{data: []byte(`"a"`)}
+	second := &fakeMsg{data: []byte(`"b"`)}
+	runSource(t, s, messages, first, second)
+	if err := s.Commit(); err != nil {
+		t.Fatalf("commit failed: %v", err)
+	}
+	if !first.acked || !second.acked || q.LenVisible() != 2 {
+		t.Fatalf("expected both elements visible and acknowledged, got %d visible", q.LenVisible())
+	}
+}
+
+func TestSourceAcksFilteredMessages(t *testing.T) {
+	messages := &fakeMessages{}
+	q := queue.NewSegmentedQueue(queue.WithCommitFilter(func(string) error { return errors.New("rejected") }))
+	s := NewSource[string](messages, q, codec.JSON[string]{})
+
+	msg := &fakeMsg{data: []byte(`"a"`)}
+	runSource(t, s, messages, msg)
+	if err := s.Commit(); err != nil {
+		t.Fatalf("commit failed: %v", err)
+	}
+	if !msg.acked || q.LenVisible() != 0 {
+		t.Fatalf("expected the rejected element's message to be acknowledged")
+	}
+}
+
+func TestSourceTerminatesUndecodableMessage(t *testing.T) {
+	bad := &fakeMsg{data: []byte("{")}
+	messages := &fakeMessages{queued: []jetstream.Msg{bad}}
+	s := NewSource[string](messages, queue.NewSegmentedQueue[string](), codec.JSON[string]{})
+	if err := s.Run(context.Background()); err == nil || errors.Is(err, errDrained) {
+		t.Fatalf("expected decode error, got %v", err)
+	}
+	if !bad.terminated {
+		t.Fatalf("undecodable message must be terminated")
+	}
+}
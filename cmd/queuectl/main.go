@@ -0,0 +1,202 @@
+// Command queuectl inspects persisted queues without knowing their element
+// type.
+//
+//	queuectl wal [-key hex] [-elements] DIR
+//	queuectl snapshot [-key hex] [-elements] FILE
+//	queuectl verify [-key hex] PATH
+//
+// wal replays the log of a DurableQueue and prints its segments, the number
+// of visible, pending and staged elements on either side of the commit
+// boundary and, with -elements, the size and leading bytes of every element.
+// snapshot does the same for a file written by WriteSnapshot, removing
+// encryption and compression layers first. verify checks a log directory or
+// snapshot file and exits with status 1 when it is damaged.
+package main
+
+import (
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/timzifer/committable_queue/persist"
+	"github.com/timzifer/committable_queue/queue"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+const usage = `usage:
+  queuectl wal [-key hex] [-elements] DIR
+  queuectl snapshot [-key hex] [-elements] FILE
+  queuectl verify [-key hex] PATH
+`
+
+// run executes the command in args and returns the exit status: 0 on success,
+// 1 when the inspected data is damaged or unreadable and 2 on usage errors.
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+
+	flags := flag.NewFlagSet("queuectl "+args[0], flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	keyHex := flags.String("key", "", "hex-encoded encryption key")
+	elements := flags.Bool("elements", false, "print every element")
+	if err := flags.Parse(args[1:]); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+	var key []byte
+	if *keyHex != "" {
+		var err error
+		if key, err = hex.DecodeString(*keyHex); err != nil {
+			fmt.Fprintf(stderr, "queuectl: invalid key: %v\n", err)
+			return 2
+		}
+	}
+	path := flags.Arg(0)
+
+	var err error
+	switch args[0] {
+	case "wal":
+		err = printWAL(stdout, path, key, *elements)
+	case "snapshot":
+		err = printSnapshot(stdout, path, key, *elements)
+	case "verify":
+		err = verify(stdout, path, key)
+	default:
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+	if errors.Is(err, persist.ErrDecrypt) && key == nil {
+		err = fmt.Errorf("%w (pass -key)", err)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "queuectl: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+func inspectWAL(dir string, key []byte) (*persist.WALInfo, error) {
+	var options []persist.DurableOption
+	if key != nil {
+		options = append(options, persist.WithEncryption(key))
+	}
+	return persist.InspectWAL(dir, options...)
+}
+
+func printWAL(w io.Writer, dir string, key []byte, elements bool) error {
+	info, err := inspectWAL(dir, key)
+	if err != nil {
+		return err
+	}
+	for _, segment := range info.Segments {
+		fmt.Fprintf(w, "segment %s: %d bytes, %d valid, %d records", segment.Name, segment.Size, segment.Valid, segment.Records)
+		if segment.Superseded {
+			fmt.Fprint(w, ", superseded by a checkpoint")
+		}
+		if segment.Err != nil {
+			fmt.Fprintf(w, ", error: %v", segment.Err)
+		}
+		fmt.Fprintln(w)
+	}
+	staged := 0
+	for _, commit := range info.Staged {
+		staged += len(commit.Elements)
+	}
+	fmt.Fprintf(w, "visible: %d\npending: %d\nstaged: %d in %d commits\n", len(info.Visible), len(info.Pending), staged, len(info.Staged))
+	if elements {
+		printElements(w, "visible", info.Visible)
+		for _, commit := range info.Staged {
+			printElements(w, fmt.Sprintf("staged/%d", commit.ID), commit.Elements)
+		}
+		printElements(w, "pending", info.Pending)
+	}
+	return info.Err()
+}
+
+func inspectSnapshot(path string, key []byte) (*queue.SnapshotInfo, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	r, err := persist.UnwrapSnapshot(f, key)
+	if err != nil {
+		return nil, err
+	}
+	info, err := queue.InspectSnapshot(r)
+	if err != nil {
+		return nil, err
+	}
+	if n, err := io.Copy(io.Discard, r); err != nil {
+		return nil, err
+	} else if n > 0 {
+		return nil, fmt.Errorf("%w: %d trailing bytes", queue.ErrInvalidSnapshot, n)
+	}
+	return info, nil
+}
+
+func printSnapshot(w io.Writer, path string, key []byte, elements bool) error {
+	info, err := inspectSnapshot(path, key)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "visible: %d\npending: %d\ndelayed: %d\n", len(info.Visible), len(info.Pending), len(info.Delayed))
+	if elements {
+		printElements(w, "visible", info.Visible)
+		printElements(w, "pending", info.Pending)
+		for i, d := range info.Delayed {
+			fmt.Fprintf(w, "delayed[%d] due %s %s\n", i, d.Due.UTC().Format("2006-01-02T15:04:05.000Z"), describe(d.Data))
+		}
+	}
+	return nil
+}
+
+func verify(w io.Writer, path string, key []byte) error {
+	stat, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if stat.IsDir() {
+		info, err := inspectWAL(path, key)
+		if err != nil {
+			return err
+		}
+		if err := info.Err(); err != nil {
+			return err
+		}
+		if last := len(info.Segments) - 1; last >= 0 && info.Segments[last].Err != nil {
+			fmt.Fprintf(w, "ok, torn tail of %d bytes will be truncated on open\n", info.Segments[last].Size-info.Segments[last].Valid)
+			return nil
+		}
+	} else if _, err := inspectSnapshot(path, key); err != nil {
+		return err
+	}
+	fmt.Fprintln(w, "ok")
+	return nil
+}
+
+func printElements(w io.Writer, segment string, values [][]byte) {
+	for i, data := range values {
+		fmt.Fprintf(w, "%s[%d] %s\n", segment, i, describe(data))
+	}
+}
+
+// describe prints the size and up to 32 leading bytes of an element.
+func describe(data []byte) string {
+	const prefix = 32
+	if len(data) <= prefix {
+		return fmt.Sprintf("%d bytes %x", len(data), data)
+	}
+	return fmt.Sprintf("%d bytes %x...", len(data), data[:prefix])
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/timzifer/committable_queue/codec"
+	"github.com/timzifer/committable_queue/persist"
+	"github.com/timzifer/committable_queue/queue"
+)
+
+func TestWALCommand(t *testing.T) {
+	dir := t.TempDir()
+	q, err := persist.NewDurableQueue[int](dir, codec.Gob[int]{})
+	if err != nil {
+		t.Fatalf("open failed: %v", err)
+	}
+	q.PushBackPending(1)
+	q.Commit()
+	q.PushBackPending(2)
+	q.Close()
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"wal", "-elements", dir}, &stdout, &stderr); code != 0 {
+		t.Fatalf("exit %d: %s", code, stderr.String())
+	}
+	for _, want := range []string{"visible: 1\n", "pending: 1\n", "staged: 0 in 0 commits\n", "visible[0] ", "pending[0] "} {
+		if !strings.Contains(stdout.String(), want) {
+			t.Fatalf("expected %q in output:\n%s", want, stdout.String())
+		}
+	}
+}
+
+func TestVerifySnapshot(t *testing.T) {
+	sq := queue.NewSegmentedQueue[int]()
+	sq.PushBackPending(1)
+	sq.Commit()
+
+	var buf bytes.Buffer
+	if err := sq.WriteSnapshot(&buf, nil); err != nil {
+		t.Fatalf("write snapshot: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "queue.snap")
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"verify", path}, &stdout, &stderr); code != 0 || stdout.String() != "ok\n" {
+		t.Fatalf("expected ok, got exit %d: %s%s", code, stdout.String(), stderr.String())
+	}
+
+	if err := os.WriteFile(path, buf.Bytes()[:buf.Len()-1], 0o644); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	stdout.Reset()
+	if code := run([]string{"verify", path}, &stdout, &stderr); code != 1 || !strings.Contains(stderr.String(), "invalid snapshot") {
+		t.Fatalf("expected exit 1 for truncated snapshot, got %d: %s", code, stderr.String())
+	}
+}
+
+func TestUsage(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"unknown", "x"}, &stdout, &stderr); code != 2 {
+		t.Fatalf("expected exit 2, got %d", code)
+	}
+}
@@ -0,0 +1,56 @@
+// Package codec converts queue elements to and from bytes for the persistence
+// features (durable queues, snapshots).
+//
+// A codec encodes exactly one element per call. Containers that store several
+// elements (WAL segments, snapshots) frame each encoded element themselves with
+// a uvarint length prefix, the same framing protobuf uses for delimited
+// streams. Snapshots written with the codec of package codec/protobuf can
+// therefore be read by any protobuf library once the snapshot header is
+// skipped.
+//
+// LogEntry describes a single queue mutation: a push, a commit mark, a drop
+// or a pop. MarshalLogEntry encodes it as an op byte followed by the element
+// or a uvarint; LogWriter and LogReader stream entries with the same length
+// prefix, so a queue's changes can be shipped to a replica or recorded for
+// replay.
+package codec
+
+import (
+	"bytes"
+	"encoding/gob"
+)
+
+// Encoder encodes single elements of type T.
+type Encoder[T any] interface {
+	Marshal(value T) ([]byte, error)
+}
+
+// Decoder decodes single elements of type T.
+type Decoder[T any] interface {
+	Unmarshal(data []byte) (T, error)
+}
+
+// Codec encodes and decodes single elements of type T.
+type Codec[T any] interface {
+	Encoder[T]
+	Decoder[T]
+}
+
+// Gob encodes elements with encoding/gob. Every element is encoded with a
+// fresh encoder, so each record is self-describing and can be decoded on its
+// own.
+type Gob[T any] struct{}
+
+func (Gob[T]) Marshal(value T) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(&value); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (Gob[T]) Unmarshal(data []byte) (T, error) {
+	var value T
+	err := gob.NewDecoder(bytes.NewReader(data)).Decode(&value)
+	return value, err
+}
@@ -0,0 +1,39 @@
+package codec
+
+import "testing"
+
+type reading struct {
+	Register uint16
+	Value    float64
+}
+
+func TestGobRoundTrip(t *testing.T) {
+	var c Codec[reading] = Gob[reading]{}
+
+	data, err := c.Marshal(reading{Register: 40001, Value: 21.5})
+	if err != nil {
+		t.Fatalf("marshal failed: %v", err)
+	}
+	got, err := c.Unmarshal(data)
+	if err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+	if got != (reading{Register: 40001, Value: 21.5}) {
+		t.Fatalf("unexpected round trip result: %+v", got)
+	}
+
+	if _, err := c.Unmarshal([]byte{0xff}); err == nil {
+		t.Fatalf("expected error for corrupt input")
+	}
+}
+
+func TestGobZeroValue(t *testing.T) {
+	c := Gob[int]{}
+	data, err := c.Marshal(0)
+	if err != nil {
+		t.Fatalf("marshal failed: %v", err)
+	}
+	if got, err := c.Unmarshal(data); err != nil || got != 0 {
+		t.Fatalf("unexpected zero value round trip: %v, %v", got, err)
+	}
+}
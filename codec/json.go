@@ -0,0 +1,16 @@
+package codec
+
+import "encoding/json"
+
+// JSON encodes elements with encoding/json.
+type JSON[T any] struct{}
+
+func (JSON[T]) Marshal(value T) ([]byte, error) {
+	return json.Marshal(value)
+}
+
+func (JSON[T]) Unmarshal(data []byte) (T, error) {
+	var value T
+	err := json.Unmarshal(data, &value)
+	return value, err
+}
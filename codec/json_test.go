@@ -0,0 +1,23 @@
+package codec
+
+import "testing"
+
+func TestJSONRoundTrip(t *testing.T) {
+	var c Codec[reading] = JSON[reading]{}
+
+	data, err := c.Marshal(reading{Register: 40001, Value: 21.5})
+	if err != nil {
+		t.Fatalf("marshal failed: %v", err)
+	}
+	if string(data) != `{"Register":40001,"Value":21.5}` {
+		t.Fatalf("unexpected encoding: %s", data)
+	}
+	got, err := c.Unmarshal(data)
+	if err != nil || got != (reading{Register: 40001, Value: 21.5}) {
+		t.Fatalf("unexpected round trip result: %+v, %v", got, err)
+	}
+
+	if _, err := c.Unmarshal([]byte("{")); err == nil {
+		t.Fatalf("expected error for corrupt input")
+	}
+}
@@ -0,0 +1,163 @@
+package codec
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrCorruptLogEntry is returned when a log entry cannot be decoded.
+var ErrCorruptLogEntry = errors.New("codec: corrupt log entry")
+
+// LogOp is the kind of queue mutation a LogEntry describes.
+type LogOp byte
+
+const (
+	// LogPush appends Value to the pending segment.
+	LogPush LogOp = iota + 1
+	// LogCommit publishes the pending segment as commit Version.
+	LogCommit
+	// LogDrop discards Value from the visible segment on overflow or expiry.
+	LogDrop
+	// LogPop removes Count elements from the front of the visible segment.
+	LogPop
+)
+
+func (op LogOp) String() string {
+	switch op {
+	case LogPush:
+		return "push"
+	case LogCommit:
+		return "commit"
+	case LogDrop:
+		return "drop"
+	case LogPop:
+		return "pop"
+	}
+	return fmt.Sprintf("LogOp(%d)", byte(op))
+}
+
+// LogEntry is one queue mutation, for streaming a queue's changes to another
+// process or recording them for replay. Only the fields of its Op are
+// encoded: Value for LogPush and LogDrop, Version for LogCommit and Count for
+// LogPop.
+type LogEntry[T any] struct {
+	Op      LogOp
+	Value   T
+	Version uint64
+	Count   uint64
+}
+
+// MarshalLogEntry encodes e as its op byte followed by the element encoded
+// with enc, or by Version or Count as a uvarint. The result is not framed;
+// LogWriter adds the length prefix for streams.
+func MarshalLogEntry[T any](enc Encoder[T], e LogEntry[T]) ([]byte, error) {
+	buf := []byte{byte(e.Op)}
+	switch e.Op {
+	case LogPush, LogDrop:
+		data, err := enc.Marshal(e.Value)
+		if err != nil {
+			return nil, err
+		}
+		return append(buf, data...), nil
+	case LogCommit:
+		return binary.AppendUvarint(buf, e.Version), nil
+	case LogPop:
+		return binary.AppendUvarint(buf, e.Count), nil
+	}
+	return nil, fmt.Errorf("codec: cannot marshal %v", e.Op)
+}
+
+// UnmarshalLogEntry decodes an entry written by MarshalLogEntry.
+func UnmarshalLogEntry[T any](dec Decoder[T], data []byte) (LogEntry[T], error) {
+	if len(data) == 0 {
+		return LogEntry[T]{}, ErrCorruptLogEntry
+	}
+	e := LogEntry[T]{Op: LogOp(data[0])}
+	switch e.Op {
+	case LogPush, LogDrop:
+		value, err := dec.Unmarshal(data[1:])
+		if err != nil {
+			return LogEntry[T]{}, fmt.Errorf("%w: %w", ErrCorruptLogEntry, err)
+		}
+		e.Value = value
+	case LogCommit, LogPop:
+		n, size := binary.Uvarint(data[1:])
+		if size <= 0 || size != len(data)-1 {
+			return LogEntry[T]{}, ErrCorruptLogEntry
+		}
+		if e.Op == LogCommit {
+			e.Version = n
+		} else {
+			e.Count = n
+		}
+	default:
+		return LogEntry[T]{}, ErrCorruptLogEntry
+	}
+	return e, nil
+}
+
+// maxLogEntry bounds the size of a framed entry a LogReader accepts.
+const maxLogEntry = 1 << 30
+
+// LogWriter writes log entries to a stream, each framed with a uvarint length
+// prefix like the other containers of this module.
+type LogWriter[T any] struct {
+	w   io.Writer
+	enc Encoder[T]
+	buf []byte
+}
+
+// NewLogWriter returns a writer that encodes elements with enc. Entries are
+// written to w one Write call each, so w should be buffered.
+func NewLogWriter[T any](w io.Writer, enc Encoder[T]) *LogWriter[T] {
+	return &LogWriter[T]{w: w, enc: enc}
+}
+
+// Write encodes e and writes it as one frame.
+func (lw *LogWriter[T]) Write(e LogEntry[T]) error {
+	body, err := MarshalLogEntry(lw.enc, e)
+	if err != nil {
+		return err
+	}
+	lw.buf = binary.AppendUvarint(lw.buf[:0], uint64(len(body)))
+	lw.buf = append(lw.buf, body...)
+	_, err = lw.w.Write(lw.buf)
+	return err
+}
+
+// LogReader reads the entries written by a LogWriter.
+type LogReader[T any] struct {
+	r   *bufio.Reader
+	dec Decoder[T]
+}
+
+// NewLogReader returns a reader that decodes elements with dec.
+func NewLogReader[T any](r io.Reader, dec Decoder[T]) *LogReader[T] {
+	return &LogReader[T]{r: bufio.NewReader(r), dec: dec}
+}
+
+// Read returns the next entry. It returns io.EOF at the end of the stream,
+// io.ErrUnexpectedEOF if the stream ends within an entry and
+// ErrCorruptLogEntry for an entry it cannot decode. Errors of the underlying
+// reader are passed through.
+func (lr *LogReader[T]) Read() (LogEntry[T], error) {
+	length, err := binary.ReadUvarint(lr.r)
+	if err != nil {
+		return LogEntry[T]{}, err
+	}
+	if length > maxLogEntry {
+		return LogEntry[T]{}, ErrCorruptLogEntry
+	}
+	// Every entry gets its own buffer, as decoders may keep referencing it.
+	body := make([]byte, length)
+	if _, err := io.ReadFull(lr.r, body); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return LogEntry[T]{}, err
+	}
+	return UnmarshalLogEntry(lr.dec, body)
+}
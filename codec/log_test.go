@@ -0,0 +1,83 @@
+package codec
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"slices"
+	"testing"
+)
+
+func TestLogEntryRoundTrip(t *testing.T) {
+	c := JSON[reading]{}
+	for _, e := range []LogEntry[reading]{
+		{Op: LogPush, Value: reading{Register: 40001, Value: 21.5}},
+		{Op: LogCommit, Version: 42},
+		{Op: LogDrop, Value: reading{Register: 40002}},
+		{Op: LogPop, Count: 3},
+	} {
+		data, err := MarshalLogEntry(c, e)
+		if err != nil {
+			t.Fatalf("marshal %v failed: %v", e.Op, err)
+		}
+		got, err := UnmarshalLogEntry(c, data)
+		if err != nil {
+			t.Fatalf("unmarshal %v failed: %v", e.Op, err)
+		}
+		if got != e {
+			t.Fatalf("expected %+v, got %+v", e, got)
+		}
+	}
+
+	for _, data := range [][]byte{nil, {0}, {byte(LogCommit)}, {byte(LogPop), 1, 2}, {byte(LogPush), '{'}} {
+		if _, err := UnmarshalLogEntry(c, data); !errors.Is(err, ErrCorruptLogEntry) {
+			t.Fatalf("expected ErrCorruptLogEntry for %v, got %v", data, err)
+		}
+	}
+	if _, err := MarshalLogEntry(c, LogEntry[reading]{}); err == nil {
+		t.Fatal("expected an entry without op to be rejected")
+	}
+}
+
+func TestLogStreamRoundTrip(t *testing.T) {
+	entries := []LogEntry[int]{
+		{Op: LogPush, Value: 1},
+		{Op: LogPush, Value: 2},
+		{Op: LogCommit, Version: 1},
+		{Op: LogPop, Count: 1},
+		{Op: LogDrop, Value: 2},
+	}
+	var buf bytes.Buffer
+	w := NewLogWriter[int](&buf, Gob[int]{})
+	for _, e := range entries {
+		if err := w.Write(e); err != nil {
+			t.Fatalf("write failed: %v", err)
+		}
+	}
+	stream := buf.Bytes()
+
+	r := NewLogReader[int](bytes.NewReader(stream), Gob[int]{})
+	var got []LogEntry[int]
+	for {
+		e, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("read failed: %v", err)
+		}
+		got = append(got, e)
+	}
+	if !slices.Equal(got, entries) {
+		t.Fatalf("expected %v, got %v", entries, got)
+	}
+
+	r = NewLogReader[int](bytes.NewReader(stream[:len(stream)-1]), Gob[int]{})
+	var err error
+	for err == nil {
+		_, err = r.Read()
+	}
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("expected a truncated stream to fail with io.ErrUnexpectedEOF, got %v", err)
+	}
+}
@@ -0,0 +1,23 @@
+// Package protobuf provides a codec for generated protobuf messages. It lives
+// apart from package codec so that queues and persistence do not depend on the
+// protobuf runtime unless an application opts in.
+package protobuf
+
+import "google.golang.org/protobuf/proto"
+
+// Codec encodes generated protobuf messages in the binary wire format. T is
+// the message pointer type, for example Codec[*pb.Reading].
+type Codec[T proto.Message] struct{}
+
+func (Codec[T]) Marshal(value T) ([]byte, error) {
+	return proto.Marshal(value)
+}
+
+func (Codec[T]) Unmarshal(data []byte) (T, error) {
+	var zero T
+	value := zero.ProtoReflect().New().Interface().(T)
+	if err := proto.Unmarshal(data, value); err != nil {
+		return zero, err
+	}
+	return value, nil
+}
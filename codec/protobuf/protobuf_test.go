@@ -0,0 +1,29 @@
+package protobuf
+
+import (
+	"testing"
+
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"github.com/timzifer/committable_queue/codec"
+)
+
+func TestCodecRoundTrip(t *testing.T) {
+	var c codec.Codec[*wrapperspb.StringValue] = Codec[*wrapperspb.StringValue]{}
+
+	data, err := c.Marshal(wrapperspb.String("sensor-7"))
+	if err != nil {
+		t.Fatalf("marshal failed: %v", err)
+	}
+	got, err := c.Unmarshal(data)
+	if err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+	if got.GetValue() != "sensor-7" {
+		t.Fatalf("unexpected round trip result: %v", got)
+	}
+
+	if _, err := c.Unmarshal([]byte{0xff}); err == nil {
+		t.Fatalf("expected error for corrupt input")
+	}
+}
@@ -0,0 +1,49 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+type testBankV2 struct {
+	testBank
+	abortErr error
+	aborted  bool
+}
+
+func (tb *testBankV2) PrepareCommitV2(context.Context) (func(), func() error, error) {
+	return nil, func() error {
+		tb.aborted = true
+		return tb.abortErr
+	}, nil
+}
+
+func TestAbortErrorsAreAggregated(t *testing.T) {
+	failure := errors.New("prepare failed")
+	lost := errors.New("rollback not persisted")
+	first := &testBankV2{abortErr: lost}
+	second := &testBankV2{}
+	failing := &testBank{prepare: func(context.Context) (func(), func(), error) {
+		return nil, nil, failure
+	}}
+	o := NewCommitOrchestrator(WithBanks(first, second, failing))
+
+	report, err := o.CommitAllReport(context.Background())
+	if !errors.Is(err, failure) || !errors.Is(err, lost) {
+		t.Fatalf("expected the prepare and the abort failure, got %v", err)
+	}
+	var abortErr *AbortError
+	if !errors.As(err, &abortErr) || abortErr.Bank != "bank-0" {
+		t.Fatalf("expected an AbortError for bank-0, got %v", err)
+	}
+	if !first.aborted || !second.aborted {
+		t.Fatal("all prepared banks must be aborted")
+	}
+	if report.Banks[0].AbortErr == nil || report.Banks[1].AbortErr != nil {
+		t.Fatalf("unexpected abort errors in report: %+v", report.Banks)
+	}
+	if s := o.Snapshot()[0]; s.Aborts != 1 || s.AbortFailures != 1 {
+		t.Fatalf("expected one failed abort in the metrics, got %+v", s)
+	}
+}
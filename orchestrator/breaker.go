@@ -0,0 +1,94 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/timzifer/committable_queue/telemetry"
+)
+
+// ErrCircuitOpen meldet eine Bank, deren Circuit Breaker nach wiederholt
+// gescheiterten Vorbereitungen ausgelöst hat. Übersprungene Banken erscheinen
+// mit einem UnhealthyBankError, der ErrCircuitOpen enthält.
+var ErrCircuitOpen = errors.New("circuit open")
+
+// WithCircuitBreaker schützt Commits vor dauerhaft ausgefallenen Banken:
+// Scheitert das PrepareCommit einer Bank threshold Mal in Folge, wird sie für
+// coolDown übersprungen, statt jeden Commit scheitern zu lassen. Danach nimmt
+// sie probeweise wieder teil; scheitert sie erneut, wird sie sofort wieder
+// übersprungen, ein Erfolg setzt den Breaker zurück. Abbrüche durch den
+// Commit-Kontext zählen nicht als Fehlschlag.
+//
+// Übersprungene Banken werden wie bei WithSkipUnhealthyBanks in
+// CommitReport.Skipped und als EventBankSkipped vermerkt und behalten ihre
+// pending Elemente.
+func WithCircuitBreaker(threshold int, coolDown time.Duration) Option {
+	return func(o *CommitOrchestrator) {
+		o.breakerThreshold = threshold
+		o.breakerCoolDown = coolDown
+	}
+}
+
+// circuitBreaker zählt die aufeinanderfolgenden Fehlschläge einer Bank.
+type circuitBreaker struct {
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+}
+
+// open meldet, ob die Bank zum Zeitpunkt now übersprungen wird.
+func (b *circuitBreaker) open(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return now.Before(b.openUntil)
+}
+
+// record verbucht das Ergebnis einer Vorbereitung und meldet, ob der Breaker
+// dadurch ausgelöst hat.
+func (b *circuitBreaker) record(err error, now time.Time, threshold int, coolDown time.Duration) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err == nil {
+		b.failures = 0
+		b.openUntil = time.Time{}
+		return false
+	}
+	b.failures++
+	if b.failures < threshold {
+		return false
+	}
+	b.openUntil = now.Add(coolDown)
+	return true
+}
+
+// checkBreakers liefert die Banken, deren Breaker geschlossen ist, und
+// vermerkt die übrigen als übersprungen.
+func (o *CommitOrchestrator) checkBreakers(ctx context.Context, banks []registeredBank, report *CommitReport) []registeredBank {
+	if o.breakerThreshold <= 0 {
+		return banks
+	}
+	now := time.Now()
+	closed := make([]registeredBank, 0, len(banks))
+	for _, entry := range banks {
+		if !entry.breaker.open(now) {
+			closed = append(closed, entry)
+			continue
+		}
+		report.skipped(entry.name)
+		o.logEvent(ctx, telemetry.Event{Kind: telemetry.EventBankSkipped, Version: o.version.Load() + 1, Bank: entry.name, Err: &UnhealthyBankError{Bank: entry.name, Err: ErrCircuitOpen}})
+	}
+	return closed
+}
+
+// recordPrepare verbucht das Ergebnis einer Vorbereitung im Breaker der Bank.
+func (o *CommitOrchestrator) recordPrepare(ctx context.Context, entry registeredBank, err error) {
+	if o.breakerThreshold <= 0 || ctx.Err() != nil {
+		return
+	}
+	if entry.breaker.record(err, time.Now(), o.breakerThreshold, o.breakerCoolDown) {
+		entry.metrics.ObserveTrip()
+		o.logEvent(ctx, telemetry.Event{Kind: telemetry.EventBankTripped, Version: o.version.Load() + 1, Bank: entry.name, Err: err})
+	}
+}
@@ -0,0 +1,63 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreakerSkipsFailingBank(t *testing.T) {
+	down := errors.New("device down")
+	failing := true
+	prepares := 0
+	device := &namedTestBank{name: "device", testBank: testBank{prepare: func(context.Context) (func(), func(), error) {
+		prepares++
+		if failing {
+			return nil, nil, down
+		}
+		return nil, nil, nil
+	}}}
+	other := &testBank{prepare: func(context.Context) (func(), func(), error) {
+		return nil, nil, nil
+	}}
+	o := NewCommitOrchestrator(WithBanks(device, other), WithCircuitBreaker(2, 20*time.Millisecond))
+
+	for i := 0; i < 2; i++ {
+		if err := o.CommitAll(context.Background()); !errors.Is(err, down) {
+			t.Fatalf("commit %d: expected the device failure, got %v", i, err)
+		}
+	}
+	report, err := o.CommitAllReport(context.Background())
+	if err != nil {
+		t.Fatalf("commit with tripped breaker failed: %v", err)
+	}
+	if prepares != 2 || !slices.Equal(report.Skipped, []string{"device"}) || o.Version() != 1 {
+		t.Fatalf("expected the device to be skipped, got %d prepares, report %+v", prepares, report)
+	}
+	if s := o.Snapshot()[0]; s.Trips != 1 {
+		t.Fatalf("expected one trip in the metrics, got %+v", s)
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	if err := o.CommitAll(context.Background()); !errors.Is(err, down) {
+		t.Fatalf("expected a trial prepare after the cool-down, got %v", err)
+	}
+	if err := o.CommitAll(context.Background()); err != nil || prepares != 3 {
+		t.Fatalf("a failed trial must trip the breaker again, got %v after %d prepares", err, prepares)
+	}
+
+	failing = false
+	time.Sleep(30 * time.Millisecond)
+	if err := o.CommitAll(context.Background()); err != nil {
+		t.Fatalf("commit after recovery failed: %v", err)
+	}
+	failing = true
+	if err := o.CommitAll(context.Background()); !errors.Is(err, down) {
+		t.Fatalf("a successful prepare must reset the breaker, got %v", err)
+	}
+	if s := o.Snapshot()[0]; s.Trips != 2 {
+		t.Fatalf("a single failure after a reset must not trip the breaker, got %+v", s)
+	}
+}
@@ -0,0 +1,48 @@
+package orchestrator
+
+import (
+	"context"
+
+	"github.com/timzifer/committable_queue/telemetry"
+)
+
+type commitIDKey struct{}
+
+// CommitIDFromContext liefert die ID des laufenden Commit-Versuchs. Jeder
+// Versuch erhält eine eigene, zufällige ID, auch wenn Middleware ihn
+// wiederholt. Der Kontext von PrepareCommit trägt sie, sodass Logs aus
+// Bank-Callbacks einem Commit zugeordnet werden können; Middleware läuft vor
+// der Vergabe und sieht sie nicht. Außerhalb eines Versuchs ist ok false.
+func CommitIDFromContext(ctx context.Context) (id string, ok bool) {
+	id, ok = ctx.Value(commitIDKey{}).(string)
+	return id, ok
+}
+
+// identify ordnet dem Versuch in ctx eine neue ID zu und meldet sie den
+// Senken und Observern, die telemetry.CommitIDSink implementieren.
+func identify(ctx context.Context, version uint64, sink telemetry.MultiSink, observers observerList) (context.Context, string) {
+	id := newID()
+	sink.CommitIdentified(version, id)
+	for _, observer := range observers {
+		if ids, ok := observer.(telemetry.CommitIDSink); ok {
+			ids.CommitIdentified(version, id)
+		}
+	}
+	return context.WithValue(ctx, commitIDKey{}, id), id
+}
+
+// identifyHooks meldet die ID den Hooks, die telemetry.CommitIDSink
+// implementieren, bevor sie weitere Aufrufe zum Versuch erhalten.
+func identifyHooks(hooks []Hook, version uint64, id string) {
+	for _, hook := range hooks {
+		if ids, ok := hook.(telemetry.CommitIDSink); ok {
+			ids.CommitIdentified(version, id)
+		}
+	}
+}
+
+// logEvent protokolliert event mit der ID des Versuchs in ctx.
+func (o *CommitOrchestrator) logEvent(ctx context.Context, event telemetry.Event) {
+	event.CommitID, _ = CommitIDFromContext(ctx)
+	o.logger.LogEvent(ctx, event)
+}
@@ -0,0 +1,71 @@
+package orchestrator
+
+import (
+	"context"
+	"testing"
+
+	"github.com/timzifer/committable_queue/telemetry"
+)
+
+type idRecorder struct {
+	ids []string
+}
+
+func (r *idRecorder) CommitIdentified(_ uint64, id string) {
+	r.ids = append(r.ids, id)
+}
+
+func TestCommitIDIsPropagated(t *testing.T) {
+	var seen []string
+	bank := &testBank{prepare: func(ctx context.Context) (func(), func(), error) {
+		id, ok := CommitIDFromContext(ctx)
+		if !ok {
+			t.Error("prepare must see a commit ID")
+		}
+		seen = append(seen, id)
+		return nil, nil, nil
+	}}
+	sink, hook, observer := &idRecorder{}, &idRecorder{}, &idRecorder{}
+	logger := &recordingLogger{}
+	o := NewCommitOrchestrator(
+		WithBanks(bank),
+		WithLogger(logger),
+		WithMetricsSink(struct {
+			telemetry.NopSink
+			*idRecorder
+		}{idRecorder: sink}),
+	)
+	o.AddHook(struct {
+		*recordingHook
+		*idRecorder
+	}{&recordingHook{}, hook})
+	ctx := WithCommitObserver(context.Background(), struct {
+		ObserverFunc
+		*idRecorder
+	}{func(error) {}, observer})
+
+	report, err := o.CommitAllReport(ctx)
+	if err != nil {
+		t.Fatalf("commit failed: %v", err)
+	}
+	if err := o.CommitAll(ctx); err != nil {
+		t.Fatalf("commit failed: %v", err)
+	}
+
+	if len(seen) != 2 || seen[0] == "" || seen[0] == seen[1] {
+		t.Fatalf("expected a distinct ID per commit, got %q", seen)
+	}
+	if report.CommitID != seen[0] {
+		t.Fatalf("expected ID %q in the report, got %q", seen[0], report.CommitID)
+	}
+	for name, r := range map[string]*idRecorder{"sink": sink, "hook": hook, "observer": observer} {
+		if len(r.ids) != 2 || r.ids[0] != seen[0] || r.ids[1] != seen[1] {
+			t.Fatalf("%s: expected IDs %q, got %q", name, seen, r.ids)
+		}
+	}
+	for _, event := range logger.events {
+		if event.CommitID != seen[0] && event.CommitID != seen[1] {
+			t.Fatalf("event without commit ID: %+v", event)
+		}
+	}
+}
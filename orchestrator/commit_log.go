@@ -0,0 +1,174 @@
+package orchestrator
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// CommitLogState beschreibt die Art eines Write-Ahead-Log-Eintrags.
+type CommitLogState string
+
+const (
+	// CommitLogPrepared wird geschrieben, nachdem alle Banken vorbereitet wurden
+	// und bevor der erste Publish-Callback läuft. Banks enthält alle Banken.
+	CommitLogPrepared CommitLogState = "prepared"
+	// CommitLogAborted wird vor den Abort-Callbacks geschrieben. Banks enthält
+	// die bereits vorbereiteten Banken. Nach dem Scheitern einer FallibleBank
+	// folgt er erst, wenn alle Veröffentlichungen kompensiert wurden.
+	CommitLogAborted CommitLogState = "aborted"
+	// CommitLogPublished wird nach dem Publish-Callback einer Bank geschrieben.
+	CommitLogPublished CommitLogState = "published"
+	// CommitLogCommitted schließt einen vollständig veröffentlichten Commit ab.
+	CommitLogCommitted CommitLogState = "committed"
+)
+
+// CommitLogEntry ist ein einzelner Eintrag im Write-Ahead-Log. Einträge werden
+// als JSON-Zeilen geschrieben.
+type CommitLogEntry struct {
+	Version uint64         `json:"version"`
+	State   CommitLogState `json:"state"`
+	Banks   []string       `json:"banks,omitempty"`
+}
+
+type commitLog struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func newCommitLog(w io.Writer) *commitLog {
+	return &commitLog{w: w}
+}
+
+// write hängt einen Eintrag an. Implementiert der Writer Sync (z. B. *os.File),
+// wird der Eintrag vor der Rückkehr auf den Datenträger geschrieben.
+//
+// Nur der Prepared-Eintrag ist für CommitAll verbindlich: schlägt er fehl, wird
+// der Commit abgebrochen. Fehler bei späteren Einträgen werden ignoriert, da die
+// Publish-Callbacks nicht rückgängig gemacht werden können; Recover meldet die
+// betroffenen Banken dann als nicht veröffentlicht.
+func (l *commitLog) write(entry CommitLogEntry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, err := l.w.Write(line); err != nil {
+		return fmt.Errorf("commit log: %w", err)
+	}
+	if syncer, ok := l.w.(interface{ Sync() error }); ok {
+		if err := syncer.Sync(); err != nil {
+			return fmt.Errorf("commit log: %w", err)
+		}
+	}
+	return nil
+}
+
+// Recovery beschreibt den Zustand, der aus einem Write-Ahead-Log rekonstruiert wurde.
+type Recovery struct {
+	// Version ist die letzte vollständig veröffentlichte Version.
+	Version uint64
+	// InDoubt ist gesetzt, wenn der letzte Commit entschieden, aber nicht
+	// abgeschlossen wurde (Absturz während der Publish-Phase).
+	InDoubt bool
+	// InDoubtVersion ist die Version des unvollständigen Commits.
+	InDoubtVersion uint64
+	// Published enthält die Banken, die den unvollständigen Commit bereits
+	// veröffentlicht haben.
+	Published []string
+	// Unpublished enthält die Banken, deren Publish nicht bestätigt wurde.
+	Unpublished []string
+}
+
+// ErrCorruptCommitLog wird gemeldet, wenn ein vollständig geschriebener
+// Log-Eintrag nicht gelesen werden kann.
+var ErrCorruptCommitLog = errors.New("corrupt commit log")
+
+// Recover liest ein Write-Ahead-Log und rekonstruiert, welche Banken den
+// zuletzt entschiedenen Commit veröffentlicht haben. Eine unvollständige letzte
+// Zeile (abgebrochener Schreibvorgang) wird ignoriert.
+func Recover(r io.Reader) (Recovery, error) {
+	var (
+		recovery  Recovery
+		prepared  []string
+		published map[string]bool
+	)
+
+	reader := bufio.NewReader(r)
+	for {
+		line, readErr := reader.ReadBytes('\n')
+		if readErr == io.EOF {
+			// Eine Zeile ohne Zeilenende stammt aus einem abgebrochenen Schreibvorgang.
+			break
+		}
+		if readErr != nil {
+			return Recovery{}, readErr
+		}
+
+		var entry CommitLogEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			return Recovery{}, fmt.Errorf("%w: %v", ErrCorruptCommitLog, err)
+		}
+
+		switch entry.State {
+		case CommitLogPrepared:
+			recovery.InDoubt = true
+			recovery.InDoubtVersion = entry.Version
+			prepared = entry.Banks
+			published = make(map[string]bool, len(entry.Banks))
+		case CommitLogPublished:
+			if recovery.InDoubt && entry.Version == recovery.InDoubtVersion {
+				for _, name := range entry.Banks {
+					published[name] = true
+				}
+			}
+		case CommitLogCommitted:
+			recovery.Version = entry.Version
+			recovery.InDoubt = false
+			recovery.InDoubtVersion = 0
+		case CommitLogAborted:
+			// Ein Abbruch nach einem Prepared-Eintrag (z. B. fehlgeschlagenes Sync)
+			// hebt die Entscheidung wieder auf.
+			if recovery.InDoubt && entry.Version == recovery.InDoubtVersion {
+				recovery.InDoubt = false
+				recovery.InDoubtVersion = 0
+			}
+		default:
+			return Recovery{}, fmt.Errorf("%w: unknown state %q", ErrCorruptCommitLog, entry.State)
+		}
+	}
+
+	if recovery.InDoubt {
+		for _, name := range prepared {
+			if published[name] {
+				recovery.Published = append(recovery.Published, name)
+			} else {
+				recovery.Unpublished = append(recovery.Unpublished, name)
+			}
+		}
+	}
+	return recovery, nil
+}
+
+// Recover liest ein Write-Ahead-Log ein und setzt den Versionszähler des
+// Orchestrators auf die letzte vollständig veröffentlichte Version.
+func (o *CommitOrchestrator) Recover(r io.Reader) (Recovery, error) {
+	recovery, err := Recover(r)
+	if err != nil {
+		return Recovery{}, err
+	}
+	if err := o.locker.Acquire(context.Background()); err != nil {
+		return Recovery{}, err
+	}
+	defer o.locker.Release()
+	o.version.Store(recovery.Version)
+	return recovery, nil
+}
@@ -0,0 +1,136 @@
+package orchestrator
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+func TestCommitLogRecordsDecisions(t *testing.T) {
+	var buf bytes.Buffer
+
+	prepareErr := errors.New("prepare failed")
+	fail := false
+	holding := &namedTestBank{name: "holding", testBank: testBank{prepare: func(context.Context) (func(), func(), error) {
+		return func() {}, func() {}, nil
+	}}}
+	input := &namedTestBank{name: "input", testBank: testBank{prepare: func(context.Context) (func(), func(), error) {
+		if fail {
+			return nil, nil, prepareErr
+		}
+		return func() {}, func() {}, nil
+	}}}
+
+	orchestrator := NewCommitOrchestrator(WithBanks(holding, input), WithCommitLog(&buf))
+	if err := orchestrator.CommitAll(context.Background()); err != nil {
+		t.Fatalf("commit failed: %v", err)
+	}
+	fail = true
+	if err := orchestrator.CommitAll(context.Background()); !errors.Is(err, prepareErr) {
+		t.Fatalf("expected prepare error, got %v", err)
+	}
+
+	expected := strings.Join([]string{
+		`{"version":1,"state":"prepared","banks":["holding","input"]}`,
+		`{"version":1,"state":"published","banks":["holding"]}`,
+		`{"version":1,"state":"published","banks":["input"]}`,
+		`{"version":1,"state":"committed"}`,
+		`{"version":2,"state":"aborted","banks":["holding"]}`,
+	}, "\n") + "\n"
+	if buf.String() != expected {
+		t.Fatalf("unexpected commit log:\n%s", buf.String())
+	}
+
+	recovered := NewCommitOrchestrator()
+	recovery, err := recovered.Recover(&buf)
+	if err != nil {
+		t.Fatalf("recover failed: %v", err)
+	}
+	if recovery.InDoubt || recovery.Version != 1 {
+		t.Fatalf("unexpected recovery: %+v", recovery)
+	}
+	if recovered.Version() != 1 {
+		t.Fatalf("recover should restore version 1, got %d", recovered.Version())
+	}
+}
+
+func TestCommitLogWriteFailureAbortsCommit(t *testing.T) {
+	aborted := false
+	bank := &testBank{prepare: func(context.Context) (func(), func(), error) {
+		return func() {
+				t.Fatalf("publish must not run when the decision cannot be logged")
+			}, func() {
+				aborted = true
+			}, nil
+	}}
+
+	orchestrator := NewCommitOrchestrator(WithBanks(bank), WithCommitLog(failingWriter{}))
+	if err := orchestrator.CommitAll(context.Background()); err == nil {
+		t.Fatalf("expected commit log error")
+	}
+	if !aborted {
+		t.Fatalf("abort should run when the commit log cannot be written")
+	}
+	if orchestrator.Version() != 0 {
+		t.Fatalf("version must not change, got %d", orchestrator.Version())
+	}
+}
+
+func TestRecoverInDoubtCommit(t *testing.T) {
+	log := strings.Join([]string{
+		`{"version":1,"state":"prepared","banks":["a","b"]}`,
+		`{"version":1,"state":"published","banks":["a"]}`,
+		`{"version":1,"state":"published","banks":["b"]}`,
+		`{"version":1,"state":"committed"}`,
+		`{"version":2,"state":"prepared","banks":["a","b","c"]}`,
+		`{"version":2,"state":"published","banks":["a"]}`,
+		`{"version":2,"state":"publ`,
+	}, "\n")
+
+	recovery, err := Recover(strings.NewReader(log))
+	if err != nil {
+		t.Fatalf("recover failed: %v", err)
+	}
+	if recovery.Version != 1 || !recovery.InDoubt || recovery.InDoubtVersion != 2 {
+		t.Fatalf("unexpected recovery: %+v", recovery)
+	}
+	if len(recovery.Published) != 1 || recovery.Published[0] != "a" {
+		t.Fatalf("unexpected published banks: %v", recovery.Published)
+	}
+	if len(recovery.Unpublished) != 2 || recovery.Unpublished[0] != "b" || recovery.Unpublished[1] != "c" {
+		t.Fatalf("unexpected unpublished banks: %v", recovery.Unpublished)
+	}
+}
+
+func TestRecoverAbortAfterPrepared(t *testing.T) {
+	log := `{"version":1,"state":"prepared","banks":["a"]}` + "\n" +
+		`{"version":1,"state":"aborted","banks":["a"]}` + "\n"
+
+	recovery, err := Recover(strings.NewReader(log))
+	if err != nil {
+		t.Fatalf("recover failed: %v", err)
+	}
+	if recovery.InDoubt || recovery.Version != 0 {
+		t.Fatalf("aborted decision must not be in doubt: %+v", recovery)
+	}
+}
+
+func TestRecoverCorruptEntry(t *testing.T) {
+	log := "not json\n" + `{"version":1,"state":"committed"}` + "\n"
+	if _, err := Recover(strings.NewReader(log)); !errors.Is(err, ErrCorruptCommitLog) {
+		t.Fatalf("expected corrupt log error, got %v", err)
+	}
+
+	unknown := `{"version":1,"state":"bogus"}` + "\n"
+	if _, err := Recover(strings.NewReader(unknown)); !errors.Is(err, ErrCorruptCommitLog) {
+		t.Fatalf("expected corrupt log error for unknown state, got %v", err)
+	}
+}
@@ -0,0 +1,486 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/timzifer/committable_queue/telemetry"
+)
+
+// Bank beschreibt eine Commit-fähige Partition.
+//
+// PrepareCommit liefert Publish-/Abort-Callbacks. Erst wenn alle Banken
+// erfolgreich vorbereitet wurden, ruft der Orchestrator die Publish-Callbacks
+// auf. Bei Fehlern oder Kontextabbruch werden die Abort-Callbacks in umgekehrter
+// Reihenfolge ausgeführt.
+type Bank interface {
+	PrepareCommit(ctx context.Context) (publish func(), abort func(), err error)
+}
+
+// BankV2 erweitert den Bank-Vertrag um einen Abort-Callback, der einen Fehler
+// liefern darf, etwa wenn die Rückabwicklung nicht dauerhaft gespeichert
+// werden konnte. Der Orchestrator ruft bei solchen Banken PrepareCommitV2
+// statt PrepareCommit auf und fasst fehlgeschlagene Abbrüche als AbortError im
+// Fehler des Commits zusammen, statt sie still zu verwerfen.
+type BankV2 interface {
+	Bank
+	PrepareCommitV2(ctx context.Context) (publish func(), abort func() error, err error)
+}
+
+// prepare bereitet die Bank über den passenden Vertrag vor und ergänzt
+// fehlende Callbacks durch wirkungslose.
+func (b registeredBank) prepare(ctx context.Context) (p preparedBank, err error) {
+	switch bank := b.bank.(type) {
+	case FallibleBank:
+		p.Prepared, err = bank.PrepareFallible(ctx)
+		p.fallible = true
+	case BankV2:
+		var publish func()
+		publish, p.Abort, err = bank.PrepareCommitV2(ctx)
+		p.Publish = infallible(publish)
+	default:
+		var publish, abort func()
+		publish, abort, err = bank.PrepareCommit(ctx)
+		p.Publish, p.Abort = infallible(publish), infallible(abort)
+	}
+	if p.Publish == nil {
+		p.Publish = func() error { return nil }
+	}
+	if p.Abort == nil {
+		p.Abort = func() error { return nil }
+	}
+	return p, err
+}
+
+// infallible passt einen Callback ohne Fehlerrückgabe an; nil bleibt nil.
+func infallible(fn func()) func() error {
+	if fn == nil {
+		return nil
+	}
+	return func() error {
+		fn()
+		return nil
+	}
+}
+
+// NamedBank kann von Banken implementiert werden, um in Telemetrie-Auswertungen
+// unter einem sprechenden Namen statt ihres Index zu erscheinen.
+type NamedBank interface {
+	Bank
+	Name() string
+}
+
+type registeredBank struct {
+	bank    Bank
+	name    string
+	tags    []string
+	metrics *telemetry.BankMetrics
+	breaker *circuitBreaker
+}
+
+func newRegisteredBank(bank Bank, index int, tags ...string) registeredBank {
+	name := fmt.Sprintf("bank-%d", index)
+	if named, ok := bank.(NamedBank); ok {
+		name = named.Name()
+	}
+	return registeredBank{bank: bank, name: name, tags: tags, metrics: &telemetry.BankMetrics{}, breaker: &circuitBreaker{}}
+}
+
+// hasAnyTag meldet, ob die Bank mindestens eines der Tags trägt.
+func (b registeredBank) hasAnyTag(tags []string) bool {
+	for _, tag := range tags {
+		if slices.Contains(b.tags, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// Hook wird einmalig am Orchestrator registriert und bei jedem Commit-Versuch
+// benachrichtigt, bei dem mindestens eine Bank beteiligt ist.
+//
+// BeforePublish läuft nach erfolgreicher Vorbereitung aller Banken, unmittelbar
+// vor den Publish-Callbacks, und erhält die Version, die veröffentlicht wird.
+// AfterPublish läuft am Ende jedes Versuchs und erhält die danach sichtbare
+// Version sowie den Fehler des Versuchs (nil bei Erfolg). Hooks, die
+// zusätzlich telemetry.CommitIDSink implementieren, erhalten vorab die ID des
+// Versuchs.
+type Hook interface {
+	BeforePublish(version uint64)
+	AfterPublish(version uint64, err error)
+}
+
+// CommitOrchestrator serialisiert Commits über alle bekannten Banken.
+//
+// Die Serialisierung erfolgt über einen Locker; mu schützt lediglich die
+// Registrierung von Banken, Hooks und Middleware.
+type CommitOrchestrator struct {
+	locker      Locker
+	mu          sync.Mutex
+	banks       []registeredBank
+	hooks       []Hook
+	middlewares []Middleware
+	log         *commitLog
+	metrics     *telemetry.CommitMetrics
+	sinks       []telemetry.MetricsSink
+	logger      telemetry.Logger
+	version     atomic.Uint64
+
+	// resumed ist während einer Pause gesetzt und wird bei Resume geschlossen.
+	pauseMu          sync.Mutex
+	resumed          chan struct{}
+	blockWhilePaused bool
+	skipUnhealthy    bool
+	prepareTimeout   time.Duration
+	breakerThreshold int
+	breakerCoolDown  time.Duration
+	panicHandler     func(*PanicError)
+}
+
+type commitVersionKey struct{}
+
+// VersionFromContext liefert die Version, die der Orchestrator gerade
+// vorbereitet. CommitAll hinterlegt sie im Kontext, den PrepareCommit erhält,
+// sodass Banken ihre Elemente mit der veröffentlichenden Version markieren
+// können. Außerhalb von CommitAll ist ok false.
+func VersionFromContext(ctx context.Context) (version uint64, ok bool) {
+	version, ok = ctx.Value(commitVersionKey{}).(uint64)
+	return version, ok
+}
+
+// NewCommitOrchestrator erzeugt einen neuen Orchestrator.
+func NewCommitOrchestrator(options ...Option) *CommitOrchestrator {
+	o := &CommitOrchestrator{locker: newMutexLocker(), metrics: telemetry.NewCommitMetrics(), logger: telemetry.NopLogger{}}
+	for _, opt := range options {
+		opt(o)
+	}
+	return o
+}
+
+// CommitAll führt Commit auf allen Banken innerhalb einer globalen kritischen Sektion aus.
+func (o *CommitOrchestrator) CommitAll(ctx context.Context) error {
+	return o.commit(ctx, "CommitAll", nil, nil)
+}
+
+// CommitTagged führt den zweiphasigen Commit nur über die Banken aus, die
+// mindestens eines der Tags tragen (siehe WithTaggedBank). Die globale Sperre
+// und die Versionszählung teilt er mit CommitAll, sodass sich Banken mit
+// unterschiedlicher Taktung unabhängig committen lassen. Ohne passende Bank
+// verhält er sich wie CommitAll ohne Banken.
+func (o *CommitOrchestrator) CommitTagged(ctx context.Context, tags ...string) error {
+	return o.commit(ctx, "CommitTagged", func(banks []registeredBank) []registeredBank {
+		var matched []registeredBank
+		for _, entry := range banks {
+			if entry.hasAnyTag(tags) {
+				matched = append(matched, entry)
+			}
+		}
+		return matched
+	}, nil)
+}
+
+// CommitBanks führt den zweiphasigen Commit über banks statt über die
+// registrierten Banken aus. Sperre, Versionszählung, Middleware, Hooks,
+// Observer und Commit-Log teilt er mit CommitAll, sodass sich ad hoc
+// zusammengestellte Banken wie die einer tx.Tx in die Commits der Anwendung
+// einreihen. Die Banken werden nicht registriert; ihre Metriken erscheinen
+// nicht in Snapshot.
+func (o *CommitOrchestrator) CommitBanks(ctx context.Context, banks ...Bank) error {
+	entries := make([]registeredBank, len(banks))
+	for i, bank := range banks {
+		entries[i] = newRegisteredBank(bank, i)
+	}
+	return o.commit(ctx, "CommitBanks", func([]registeredBank) []registeredBank { return entries }, nil)
+}
+
+// commit führt einen Commit-Versuch über die Banken aus, die choose aus den
+// registrierten auswählt; ein nil-choose wählt alle Banken. Ist report gesetzt, wird er
+// während des Versuchs befüllt. Der Versuch läuft durch die mit Use
+// registrierte Middleware; alle Versuche teilen denselben Idempotenzschlüssel.
+func (o *CommitOrchestrator) commit(ctx context.Context, span string, choose func([]registeredBank) []registeredBank, report *CommitReport) error {
+	return o.chain(func(ctx context.Context) error {
+		return o.attempt(ctx, span, choose, report)
+	})(withIdempotencyKey(ctx))
+}
+
+// attempt ist der eigentliche Commit-Versuch hinter der Middleware.
+func (o *CommitOrchestrator) attempt(ctx context.Context, span string, choose func([]registeredBank) []registeredBank, report *CommitReport) (err error) {
+	report.reset()
+	begin := time.Now()
+	version := o.version.Load() + 1
+	sink := append(telemetry.MultiSink{o.metrics}, o.sinks...)
+	sink.CommitStarted(version)
+	observers := commitObservers(ctx)
+	ctx, id := identify(ctx, version, sink, observers)
+	ctx, endSpan := telemetry.StartSpan(ctx, span, telemetry.Attribute{Key: "commit.id", Value: id})
+	defer func() {
+		sink.CommitFinished(version, time.Since(begin), err)
+		endSpan(err)
+		if report != nil {
+			report.CommitID = id
+			report.IdempotencyKey, _ = IdempotencyKeyFromContext(ctx)
+			report.Duration = time.Since(begin)
+		}
+	}()
+
+	if err = o.acquire(ctx); err != nil {
+		if ctx.Err() != nil && !errors.Is(err, ErrCommitCancelled) {
+			err = cancelled(err)
+		}
+		observers.AbortStart(err)
+		if report != nil {
+			report.Version = o.version.Load()
+		}
+		return err
+	}
+	defer o.locker.Release()
+	// visible ist die nach dem Versuch sichtbare Version. Der Bericht
+	// übernimmt sie, solange die Sperre noch gehalten wird; danach kann ein
+	// nebenläufiger Commit den Zähler bereits weitergesetzt haben.
+	visible := o.version.Load()
+	defer func() {
+		if report != nil {
+			report.Version = visible
+		}
+	}()
+
+	o.mu.Lock()
+	banks, hooks := o.banks, o.hooks
+	o.mu.Unlock()
+	identifyHooks(hooks, o.version.Load()+1, id)
+	if choose != nil {
+		banks = choose(banks)
+	}
+	if banks, err = o.checkHealth(ctx, banks, report); err != nil {
+		o.logEvent(ctx, telemetry.Event{Kind: telemetry.EventCommitFailed, Version: version, Err: err})
+		observers.AbortStart(err)
+		for _, hook := range hooks {
+			hook.AfterPublish(o.version.Load(), err)
+		}
+		return err
+	}
+	banks = o.checkBreakers(ctx, banks, report)
+
+	if len(banks) == 0 {
+		current := o.version.Load()
+		observers.PublishStart(current)
+		observers.PublishEnd(current)
+		return nil
+	}
+
+	started := time.Now()
+	next := o.version.Load() + 1
+	version = next
+	o.logEvent(ctx, telemetry.Event{Kind: telemetry.EventCommitStarted, Version: next})
+
+	prepared := make([]preparedBank, 0, len(banks))
+
+	versionCtx := context.WithValue(ctx, commitVersionKey{}, next)
+	for _, entry := range banks {
+		if err = ctx.Err(); err != nil {
+			err = cancelled(err)
+			break
+		}
+		prepareCtx, endSpan := telemetry.StartSpan(versionCtx, "PrepareCommit", telemetry.Attribute{Key: "commit.bank", Value: entry.name})
+		prepareCtx, elements := countElements(prepareCtx)
+		prepareCtx, cancel := o.prepareContext(prepareCtx)
+		observers.PrepareStart(entry.name)
+		start := time.Now()
+		p, prepareErr := entry.prepare(prepareCtx)
+		elapsed := time.Since(start)
+		cancel()
+		observers.PrepareEnd(entry.name, prepareErr)
+		entry.metrics.ObservePrepare(elapsed, prepareErr)
+		o.recordPrepare(ctx, entry, prepareErr)
+		report.prepared(entry.name, elapsed, elements, prepareErr)
+		endSpan(prepareErr)
+		if prepareErr != nil {
+			err = &PrepareError{Bank: entry.name, Err: prepareErr}
+			break
+		}
+		p.elements = *elements
+		prepared = append(prepared, p)
+	}
+
+	if err == nil && ctx.Err() != nil {
+		err = cancelled(ctx.Err())
+	}
+
+	telemetry.AnnotateSpan(ctx,
+		telemetry.Attribute{Key: "commit.version", Value: next},
+		telemetry.Attribute{Key: "commit.banks", Value: bankNames(banks)},
+	)
+
+	if err == nil && o.log != nil {
+		err = o.log.write(CommitLogEntry{Version: next, State: CommitLogPrepared, Banks: bankNames(banks[:len(prepared)])})
+	}
+
+	if err != nil {
+		observers.AbortStart(err)
+		if o.log != nil {
+			o.log.write(CommitLogEntry{Version: next, State: CommitLogAborted, Banks: bankNames(banks[:len(prepared)])})
+		}
+		failures := append([]error{err}, o.abort(ctx, next, banks, prepared, 0, report)...)
+		if len(failures) > 1 {
+			err = errors.Join(failures...)
+		}
+		o.logEvent(ctx, telemetry.Event{Kind: telemetry.EventCommitFailed, Version: next, Duration: time.Since(started), Err: err})
+		for _, hook := range hooks {
+			hook.AfterPublish(o.version.Load(), err)
+		}
+		return err
+	}
+
+	observers.PublishStart(next)
+
+	for _, hook := range hooks {
+		hook.BeforePublish(next)
+	}
+
+	// Die Entscheidung ist gefallen: Eine Bank, deren Publish in Panik
+	// gerät, hält die übrigen nicht auf; ihr Fehler wird zurückgegeben,
+	// obwohl die Version steigt. Nur das Scheitern einer FallibleBank
+	// widerruft die Entscheidung (siehe rollBack).
+	var failures []error
+	var done []int
+	var partial *PartialPublishError
+	for i, p := range prepared {
+		_, endSpan := telemetry.StartSpan(ctx, "Publish", telemetry.Attribute{Key: "commit.bank", Value: banks[i].name})
+		start := time.Now()
+		publishErr := o.call(banks[i].name, p.Publish)
+		elapsed := time.Since(start)
+		banks[i].metrics.ObservePublish(elapsed)
+		endSpan(publishErr)
+		if publishErr != nil && p.fallible {
+			partial = &PartialPublishError{Bank: banks[i].name, Err: publishErr}
+			failures = append(failures, o.rollBack(ctx, next, banks, prepared, i, done, partial, observers, report))
+			break
+		}
+		if publishErr != nil {
+			failures = append(failures, publishErr)
+			continue
+		}
+		done = append(done, i)
+		banks[i].metrics.ObserveBatch(p.elements)
+		report.published(i, elapsed)
+		if o.log != nil {
+			o.log.write(CommitLogEntry{Version: next, State: CommitLogPublished, Banks: []string{banks[i].name}})
+		}
+	}
+	err = errors.Join(failures...)
+
+	if partial != nil && len(partial.Published) == 0 {
+		if o.log != nil {
+			o.log.write(CommitLogEntry{Version: next, State: CommitLogAborted, Banks: bankNames(banks[:len(prepared)])})
+		}
+		o.logEvent(ctx, telemetry.Event{Kind: telemetry.EventCommitFailed, Version: next, Duration: time.Since(started), Err: err})
+		for _, hook := range hooks {
+			hook.AfterPublish(o.version.Load(), err)
+		}
+		return err
+	}
+
+	o.version.Store(next)
+	visible = next
+	if o.log != nil {
+		o.log.write(CommitLogEntry{Version: next, State: CommitLogCommitted})
+	}
+	observers.PublishEnd(next)
+
+	if err != nil {
+		o.logEvent(ctx, telemetry.Event{Kind: telemetry.EventCommitFailed, Version: next, Duration: time.Since(started), Err: err})
+	} else {
+		o.logEvent(ctx, telemetry.Event{Kind: telemetry.EventCommitSucceeded, Version: next, Duration: time.Since(started)})
+	}
+
+	for _, hook := range hooks {
+		hook.AfterPublish(next, err)
+	}
+	return err
+}
+
+// prepareContext leitet den Kontext einer einzelnen Vorbereitung ab, begrenzt
+// durch WithBankPrepareTimeout.
+func (o *CommitOrchestrator) prepareContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if o.prepareTimeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, o.prepareTimeout)
+}
+
+func bankNames(banks []registeredBank) []string {
+	names := make([]string, 0, len(banks))
+	for _, entry := range banks {
+		names = append(names, entry.name)
+	}
+	return names
+}
+
+// Version gibt den aktuell veröffentlichten Commit-Stand zurück.
+func (o *CommitOrchestrator) Version() uint64 {
+	return o.version.Load()
+}
+
+// Metrics liefert die Commit-Metriken des Orchestrators.
+func (o *CommitOrchestrator) Metrics() *telemetry.CommitMetrics {
+	return o.metrics
+}
+
+// Snapshot liefert die Messwerte je Bank in Registrierungsreihenfolge.
+func (o *CommitOrchestrator) Snapshot() []telemetry.BankSnapshot {
+	o.mu.Lock()
+	banks := append([]registeredBank(nil), o.banks...)
+	o.mu.Unlock()
+
+	snapshots := make([]telemetry.BankSnapshot, 0, len(banks))
+	for _, entry := range banks {
+		s := entry.metrics.Snapshot()
+		s.Name = entry.name
+		snapshots = append(snapshots, s)
+	}
+	return snapshots
+}
+
+// AddHook registriert einen Hook, der bei allen folgenden Commits aufgerufen wird.
+func (o *CommitOrchestrator) AddHook(hook Hook) error {
+	if hook == nil {
+		return errors.New("nil hook")
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.hooks = append(o.hooks, hook)
+	return nil
+}
+
+// RegisterBank hängt zur Laufzeit eine weitere Bank an, optional mit Tags für
+// CommitTagged.
+func (o *CommitOrchestrator) RegisterBank(bank Bank, tags ...string) error {
+	if bank == nil {
+		return errors.New("nil bank")
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.banks = append(o.banks, newRegisteredBank(bank, len(o.banks), tags...))
+	return nil
+}
+
+// UnregisterBank entfernt eine zuvor registrierte Bank. Commits, die bereits
+// laufen, schließen die Bank noch ein; erst folgende Commits lassen sie aus.
+// Das Ergebnis meldet, ob die Bank registriert war.
+func (o *CommitOrchestrator) UnregisterBank(bank Bank) bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	i := slices.IndexFunc(o.banks, func(b registeredBank) bool { return b.bank == bank })
+	if i < 0 {
+		return false
+	}
+	// Laufende Versuche halten eine Kopie des Slice-Headers; das Backing-Array
+	// darf daher nicht verändert werden.
+	o.banks = slices.Delete(slices.Clone(o.banks), i, i+1)
+	return true
+}
@@ -0,0 +1,605 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/timzifer/committable_queue/telemetry"
+)
+
+type testBank struct {
+	prepare func(context.Context) (func(), func(), error)
+}
+
+func (tb *testBank) PrepareCommit(ctx context.Context) (func(), func(), error) {
+	return tb.prepare(ctx)
+}
+
+func TestWithCommitObserver(t *testing.T) {
+	base := context.Background()
+	if WithCommitObserver(base, nil) != base {
+		t.Fatalf("nil observer should return original context")
+	}
+
+	ctx := WithCommitObserver(base, ObserverFunc(func(error) {}))
+	if ctx == nil {
+		t.Fatalf("context must not be nil")
+	}
+	if observers := commitObservers(ctx); len(observers) != 1 {
+		t.Fatalf("observer function not stored in context")
+	}
+}
+
+func TestWithCommitObserverIsAdditive(t *testing.T) {
+	var order []string
+	parent := WithCommitObserver(context.Background(), ObserverFunc(func(error) {
+		order = append(order, "outer")
+	}))
+	ctx := WithCommitObserver(parent, ObserverFunc(func(error) {
+		order = append(order, "inner")
+	}))
+
+	if got := len(commitObservers(parent)); got != 1 {
+		t.Fatalf("parent context must keep a single observer, got %d", got)
+	}
+
+	orchestrator := NewCommitOrchestrator()
+	if err := orchestrator.CommitAll(ctx); err != nil {
+		t.Fatalf("commit failed: %v", err)
+	}
+
+	if len(order) != 2 || order[0] != "outer" || order[1] != "inner" {
+		t.Fatalf("observers should run in registration order, got %v", order)
+	}
+}
+
+func TestCommitOrchestratorNoBanks(t *testing.T) {
+	orchestrator := NewCommitOrchestrator()
+
+	var observed []error
+	ctx := WithCommitObserver(context.Background(), ObserverFunc(func(err error) {
+		observed = append(observed, err)
+	}))
+
+	if err := orchestrator.CommitAll(ctx); err != nil {
+		t.Fatalf("expected no error with zero banks, got %v", err)
+	}
+
+	if orchestrator.Version() != 0 {
+		t.Fatalf("version should remain zero when nothing was committed")
+	}
+
+	if len(observed) != 1 || observed[0] != nil {
+		t.Fatalf("observer should be invoked with nil error, got %v", observed)
+	}
+
+	metrics := orchestrator.Metrics().Snapshot()
+	if metrics.Attempts != 1 || metrics.Failures != 0 {
+		t.Fatalf("metrics mismatch: attempts=%d failures=%d", metrics.Attempts, metrics.Failures)
+	}
+}
+
+func TestCommitOrchestratorSuccess(t *testing.T) {
+
+	var mu sync.Mutex
+	publishes := []string{}
+
+	bank1 := &testBank{prepare: func(context.Context) (func(), func(), error) {
+		return func() {
+				mu.Lock()
+				publishes = append(publishes, "bank1")
+				mu.Unlock()
+			}, func() {
+				t.Fatalf("abort should not be called on success")
+			}, nil
+	}}
+	bank2 := &testBank{prepare: func(context.Context) (func(), func(), error) {
+		return nil, nil, nil
+	}}
+
+	orchestrator := NewCommitOrchestrator(WithBanks(bank1, bank2))
+
+	var observed []error
+	ctx := WithCommitObserver(context.Background(), ObserverFunc(func(err error) {
+		mu.Lock()
+		observed = append(observed, err)
+		mu.Unlock()
+	}))
+
+	if err := orchestrator.CommitAll(ctx); err != nil {
+		t.Fatalf("commit failed: %v", err)
+	}
+
+	if orchestrator.Version() != 1 {
+		t.Fatalf("version should increment on successful commit, got %d", orchestrator.Version())
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(observed) != 1 || observed[0] != nil {
+		t.Fatalf("observer should record nil error, got %v", observed)
+	}
+
+	if len(publishes) != 1 || publishes[0] != "bank1" {
+		t.Fatalf("unexpected publish sequence: %v", publishes)
+	}
+
+	metrics := orchestrator.Metrics().Snapshot()
+	if metrics.Attempts != 1 || metrics.Failures != 0 {
+		t.Fatalf("metrics mismatch: attempts=%d failures=%d", metrics.Attempts, metrics.Failures)
+	}
+}
+
+func TestCommitOrchestratorDefaultsForNilCallbacks(t *testing.T) {
+
+	bank := &testBank{prepare: func(context.Context) (func(), func(), error) {
+		return nil, nil, nil
+	}}
+
+	orchestrator := NewCommitOrchestrator(WithBanks(bank))
+	if err := orchestrator.CommitAll(context.Background()); err != nil {
+		t.Fatalf("commit failed: %v", err)
+	}
+
+	if orchestrator.Version() != 1 {
+		t.Fatalf("expected version increment, got %d", orchestrator.Version())
+	}
+}
+
+func TestCommitOrchestratorPrepareErrorTriggersAbort(t *testing.T) {
+
+	var mu sync.Mutex
+	aborts := []string{}
+
+	prepareErr := errors.New("prepare failed")
+
+	bank1 := &testBank{prepare: func(context.Context) (func(), func(), error) {
+		return func() {}, func() {
+			mu.Lock()
+			aborts = append(aborts, "bank1")
+			mu.Unlock()
+		}, nil
+	}}
+	bank2 := &testBank{prepare: func(context.Context) (func(), func(), error) {
+		return nil, nil, prepareErr
+	}}
+
+	orchestrator := NewCommitOrchestrator(WithBanks(bank1, bank2))
+
+	var observed error
+	ctx := WithCommitObserver(context.Background(), ObserverFunc(func(err error) {
+		observed = err
+	}))
+
+	err := orchestrator.CommitAll(ctx)
+	if err == nil {
+		t.Fatalf("expected error from CommitAll")
+	}
+	if !errors.Is(err, prepareErr) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if observed == nil {
+		t.Fatalf("observer should receive error notification")
+	}
+
+	mu.Lock()
+	if len(aborts) != 1 || aborts[0] != "bank1" {
+		t.Fatalf("expected abort for first bank, got %v", aborts)
+	}
+	mu.Unlock()
+
+	if orchestrator.Version() != 0 {
+		t.Fatalf("version should remain zero on failure, got %d", orchestrator.Version())
+	}
+
+	metrics := orchestrator.Metrics().Snapshot()
+	if metrics.Attempts != 1 || metrics.Failures != 1 {
+		t.Fatalf("metrics mismatch: attempts=%d failures=%d", metrics.Attempts, metrics.Failures)
+	}
+}
+
+func TestCommitOrchestratorContextCancellationBeforePrepare(t *testing.T) {
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	called := false
+	bank := &testBank{prepare: func(context.Context) (func(), func(), error) {
+		called = true
+		return nil, nil, nil
+	}}
+
+	orchestrator := NewCommitOrchestrator(WithBanks(bank))
+	if err := orchestrator.CommitAll(ctx); err == nil {
+		t.Fatalf("expected context error")
+	}
+	if called {
+		t.Fatalf("bank should not be called when context already cancelled")
+	}
+}
+
+func TestCommitOrchestratorContextCancellationAfterPrepare(t *testing.T) {
+
+	baseCtx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var observed error
+	ctx := WithCommitObserver(baseCtx, ObserverFunc(func(err error) {
+		observed = err
+	}))
+
+	aborted := false
+	bank := &testBank{prepare: func(context.Context) (func(), func(), error) {
+		cancel()
+		return func() {
+				t.Fatalf("publish must not be called when context is cancelled")
+			}, func() {
+				aborted = true
+			}, nil
+	}}
+
+	orchestrator := NewCommitOrchestrator(WithBanks(bank))
+	if err := orchestrator.CommitAll(ctx); err == nil {
+		t.Fatalf("expected context cancellation error")
+	}
+	if !aborted {
+		t.Fatalf("abort should run when context cancelled after prepare")
+	}
+	if observed == nil {
+		t.Fatalf("observer should receive cancellation error")
+	}
+}
+
+func TestCommitOrchestratorRegisterBank(t *testing.T) {
+	orchestrator := NewCommitOrchestrator()
+	if err := orchestrator.RegisterBank(nil); err == nil {
+		t.Fatalf("expected error for nil bank")
+	}
+
+	bank := &testBank{prepare: func(context.Context) (func(), func(), error) {
+		return nil, nil, nil
+	}}
+	if err := orchestrator.RegisterBank(bank); err != nil {
+		t.Fatalf("register failed: %v", err)
+	}
+	if len(orchestrator.banks) != 1 {
+		t.Fatalf("expected one registered bank, got %d", len(orchestrator.banks))
+	}
+}
+
+func TestCommitOrchestratorUnregisterBank(t *testing.T) {
+	prepared := map[string]int{}
+	bank := func(name string) *testBank {
+		return &testBank{prepare: func(context.Context) (func(), func(), error) {
+			prepared[name]++
+			return nil, nil, nil
+		}}
+	}
+	first, second := bank("first"), bank("second")
+	orchestrator := NewCommitOrchestrator(WithBanks(first, second))
+
+	if !orchestrator.UnregisterBank(first) {
+		t.Fatal("expected the registered bank to be removed")
+	}
+	if orchestrator.UnregisterBank(first) {
+		t.Fatal("expected a second removal to report false")
+	}
+	if err := orchestrator.CommitAll(context.Background()); err != nil {
+		t.Fatalf("commit failed: %v", err)
+	}
+	if prepared["first"] != 0 || prepared["second"] != 1 {
+		t.Fatalf("expected only the remaining bank to be prepared, got %v", prepared)
+	}
+}
+
+func TestCommitOrchestratorCommitBanks(t *testing.T) {
+	prepared := map[string]int{}
+	bank := func(name string) *testBank {
+		return &testBank{prepare: func(context.Context) (func(), func(), error) {
+			prepared[name]++
+			return func() {}, func() {}, nil
+		}}
+	}
+	orchestrator := NewCommitOrchestrator(WithBanks(bank("registered")))
+
+	if err := orchestrator.CommitBanks(context.Background(), bank("adhoc")); err != nil {
+		t.Fatalf("commit failed: %v", err)
+	}
+	if prepared["registered"] != 0 || prepared["adhoc"] != 1 {
+		t.Fatalf("expected only the given bank to be prepared, got %v", prepared)
+	}
+	if orchestrator.Version() != 1 || len(orchestrator.Snapshot()) != 1 {
+		t.Fatalf("expected version 1 without registering the bank, got %d and %d banks", orchestrator.Version(), len(orchestrator.Snapshot()))
+	}
+}
+
+type namedTestBank struct {
+	testBank
+	name string
+}
+
+func (nb *namedTestBank) Name() string {
+	return nb.name
+}
+
+func TestCommitOrchestratorSnapshotPerBank(t *testing.T) {
+	prepareErr := errors.New("prepare failed")
+	fail := false
+
+	named := &namedTestBank{name: "holding", testBank: testBank{prepare: func(context.Context) (func(), func(), error) {
+		return func() {}, nil, nil
+	}}}
+	flaky := &testBank{prepare: func(context.Context) (func(), func(), error) {
+		if fail {
+			return nil, nil, prepareErr
+		}
+		return nil, nil, nil
+	}}
+
+	orchestrator := NewCommitOrchestrator(WithBanks(named, flaky))
+	if err := orchestrator.CommitAll(context.Background()); err != nil {
+		t.Fatalf("commit failed: %v", err)
+	}
+	fail = true
+	if err := orchestrator.CommitAll(context.Background()); !errors.Is(err, prepareErr) {
+		t.Fatalf("expected prepare error, got %v", err)
+	}
+
+	snapshots := orchestrator.Snapshot()
+	if len(snapshots) != 2 {
+		t.Fatalf("expected two bank snapshots, got %d", len(snapshots))
+	}
+
+	holding := snapshots[0]
+	if holding.Name != "holding" || holding.Prepares != 2 || holding.Publishes != 1 || holding.Failures != 0 {
+		t.Fatalf("unexpected snapshot for named bank: %+v", holding)
+	}
+
+	second := snapshots[1]
+	if second.Name != "bank-1" || second.Prepares != 2 || second.Publishes != 1 || second.Failures != 1 {
+		t.Fatalf("unexpected snapshot for unnamed bank: %+v", second)
+	}
+}
+
+type recordingHook struct {
+	events []string
+}
+
+func (h *recordingHook) BeforePublish(version uint64) {
+	h.events = append(h.events, fmt.Sprintf("before:%d", version))
+}
+
+func (h *recordingHook) AfterPublish(version uint64, err error) {
+	h.events = append(h.events, fmt.Sprintf("after:%d:%v", version, err))
+}
+
+func TestCommitOrchestratorHooks(t *testing.T) {
+	orchestrator := NewCommitOrchestrator()
+	if err := orchestrator.AddHook(nil); err == nil {
+		t.Fatalf("expected error for nil hook")
+	}
+
+	hook := &recordingHook{}
+	if err := orchestrator.AddHook(hook); err != nil {
+		t.Fatalf("add hook failed: %v", err)
+	}
+
+	prepareErr := errors.New("prepare failed")
+	fail := false
+	bank := &testBank{prepare: func(context.Context) (func(), func(), error) {
+		if fail {
+			return nil, nil, prepareErr
+		}
+		return func() {
+			hook.events = append(hook.events, "publish")
+		}, nil, nil
+	}}
+	if err := orchestrator.RegisterBank(bank); err != nil {
+		t.Fatalf("register failed: %v", err)
+	}
+
+	if err := orchestrator.CommitAll(context.Background()); err != nil {
+		t.Fatalf("commit failed: %v", err)
+	}
+	fail = true
+	if err := orchestrator.CommitAll(context.Background()); !errors.Is(err, prepareErr) {
+		t.Fatalf("expected prepare error, got %v", err)
+	}
+
+	expected := []string{"before:1", "publish", "after:1:<nil>", "after:1:prepare of bank bank-0 failed: prepare failed"}
+	if len(hook.events) != len(expected) {
+		t.Fatalf("unexpected hook events: %v", hook.events)
+	}
+	for i, want := range expected {
+		if hook.events[i] != want {
+			t.Fatalf("unexpected hook events: %v", hook.events)
+		}
+	}
+}
+
+func TestCommitOrchestratorMetricsArePerInstance(t *testing.T) {
+	first := NewCommitOrchestrator()
+	second := NewCommitOrchestrator()
+
+	if err := first.CommitAll(context.Background()); err != nil {
+		t.Fatalf("commit failed: %v", err)
+	}
+	if got := second.Metrics().Snapshot().Attempts; got != 0 {
+		t.Fatalf("orchestrators must not share metrics by default, got %d attempts", got)
+	}
+
+	shared := telemetry.NewCommitMetrics()
+	third := NewCommitOrchestrator(WithMetrics(shared))
+	fourth := NewCommitOrchestrator(WithMetrics(shared))
+	if err := third.CommitAll(context.Background()); err != nil {
+		t.Fatalf("commit failed: %v", err)
+	}
+	if err := fourth.CommitAll(context.Background()); err != nil {
+		t.Fatalf("commit failed: %v", err)
+	}
+	if third.Metrics() != shared || shared.Snapshot().Attempts != 2 {
+		t.Fatalf("expected shared metrics to record both commits, got %d", shared.Snapshot().Attempts)
+	}
+}
+
+type recordingLogger struct {
+	events []telemetry.Event
+}
+
+func (l *recordingLogger) LogEvent(_ context.Context, event telemetry.Event) {
+	l.events = append(l.events, event)
+}
+
+func TestCommitOrchestratorLogsEvents(t *testing.T) {
+	prepareErr := errors.New("prepare failed")
+	fail := false
+
+	holding := &namedTestBank{name: "holding", testBank: testBank{prepare: func(context.Context) (func(), func(), error) {
+		return nil, nil, nil
+	}}}
+	input := &namedTestBank{name: "input", testBank: testBank{prepare: func(context.Context) (func(), func(), error) {
+		if fail {
+			return nil, nil, prepareErr
+		}
+		return nil, nil, nil
+	}}}
+
+	logger := &recordingLogger{}
+	orchestrator := NewCommitOrchestrator(WithBanks(holding, input), WithLogger(logger))
+	if err := orchestrator.CommitAll(context.Background()); err != nil {
+		t.Fatalf("commit failed: %v", err)
+	}
+	fail = true
+	if err := orchestrator.CommitAll(context.Background()); !errors.Is(err, prepareErr) {
+		t.Fatalf("expected prepare error, got %v", err)
+	}
+
+	expected := []telemetry.Event{
+		{Kind: telemetry.EventCommitStarted, Version: 1},
+		{Kind: telemetry.EventCommitSucceeded, Version: 1},
+		{Kind: telemetry.EventCommitStarted, Version: 2},
+		{Kind: telemetry.EventBankAborted, Version: 2, Bank: "holding"},
+		{Kind: telemetry.EventCommitFailed, Version: 2, Err: prepareErr},
+	}
+	if len(logger.events) != len(expected) {
+		t.Fatalf("unexpected events: %+v", logger.events)
+	}
+	for i, want := range expected {
+		got := logger.events[i]
+		if got.Kind != want.Kind || got.Version != want.Version || got.Bank != want.Bank || !errors.Is(got.Err, want.Err) {
+			t.Fatalf("event %d: expected %+v, got %+v", i, want, got)
+		}
+	}
+}
+
+type recordingSink struct {
+	telemetry.NopSink
+	mu     sync.Mutex
+	events []string
+}
+
+func (s *recordingSink) CommitStarted(version uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, fmt.Sprintf("started %d", version))
+}
+
+func (s *recordingSink) CommitFinished(version uint64, _ time.Duration, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, fmt.Sprintf("finished %d %v", version, err))
+}
+
+func TestWithMetricsSinkReceivesCommitEvents(t *testing.T) {
+	sink := &recordingSink{}
+	failure := errors.New("prepare failed")
+	fail := false
+	bank := &testBank{prepare: func(context.Context) (func(), func(), error) {
+		if fail {
+			return nil, nil, failure
+		}
+		return func() {}, func() {}, nil
+	}}
+	o := NewCommitOrchestrator(WithBanks(bank), WithMetricsSink(sink))
+
+	if err := o.CommitAll(context.Background()); err != nil {
+		t.Fatalf("commit: %v", err)
+	}
+	fail = true
+	if err := o.CommitAll(context.Background()); !errors.Is(err, failure) {
+		t.Fatalf("expected prepare failure, got %v", err)
+	}
+
+	want := []string{"started 1", "finished 1 <nil>", "started 2", "finished 2 prepare of bank bank-0 failed: prepare failed"}
+	if fmt.Sprint(sink.events) != fmt.Sprint(want) {
+		t.Fatalf("expected %v, got %v", want, sink.events)
+	}
+	if s := o.Metrics().Snapshot(); s.Attempts != 2 || s.Failures != 1 {
+		t.Fatalf("default sink must keep counting: %+v", s)
+	}
+}
+
+func TestVersionFromContextDuringPrepare(t *testing.T) {
+	if _, ok := VersionFromContext(context.Background()); ok {
+		t.Fatal("expected no version outside of CommitAll")
+	}
+
+	var seen []uint64
+	bank := &testBank{prepare: func(ctx context.Context) (func(), func(), error) {
+		version, ok := VersionFromContext(ctx)
+		if !ok {
+			t.Fatal("expected version in prepare context")
+		}
+		seen = append(seen, version)
+		return nil, nil, nil
+	}}
+	o := NewCommitOrchestrator(WithBanks(bank))
+	for i := 0; i < 2; i++ {
+		if err := o.CommitAll(context.Background()); err != nil {
+			t.Fatalf("commit: %v", err)
+		}
+	}
+
+	if fmt.Sprint(seen) != "[1 2]" {
+		t.Fatalf("expected versions [1 2], got %v", seen)
+	}
+}
+
+func TestCommitTaggedOnlyCommitsMatchingBanks(t *testing.T) {
+	var prepared []string
+	bank := func(name string) *testBank {
+		return &testBank{prepare: func(context.Context) (func(), func(), error) {
+			prepared = append(prepared, name)
+			return nil, nil, nil
+		}}
+	}
+	o := NewCommitOrchestrator(
+		WithTaggedBank(bank("fast"), "fast"),
+		WithTaggedBank(bank("slow"), "slow"),
+	)
+	if err := o.RegisterBank(bank("both"), "fast", "slow"); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+
+	if err := o.CommitTagged(context.Background(), "fast"); err != nil {
+		t.Fatalf("commit fast: %v", err)
+	}
+	if err := o.CommitTagged(context.Background(), "unknown"); err != nil {
+		t.Fatalf("commit unknown: %v", err)
+	}
+	if err := o.CommitAll(context.Background()); err != nil {
+		t.Fatalf("commit all: %v", err)
+	}
+
+	want := "[fast both fast slow both]"
+	if fmt.Sprint(prepared) != want {
+		t.Fatalf("expected %s, got %v", want, prepared)
+	}
+	if o.Version() != 2 {
+		t.Fatalf("expected version 2, got %d", o.Version())
+	}
+}
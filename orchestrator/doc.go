@@ -0,0 +1,69 @@
+// Package orchestrator koordiniert Commits über mehrere Banken.
+//
+// Ein CommitOrchestrator bereitet in CommitAll alle registrierten Banken vor
+// und veröffentlicht sie nur, wenn jede Vorbereitung gelungen ist; andernfalls
+// werden die bereits vorbereiteten Banken in umgekehrter Reihenfolge
+// abgebrochen. Jede Queue dieses Moduls implementiert Bank und kann daher
+// zusammen mit eigenen Banken registriert werden:
+//
+//	o := orchestrator.NewCommitOrchestrator(orchestrator.WithBanks(left, right))
+//	if err := o.CommitAll(ctx); err != nil {
+//		// keine Bank wurde veröffentlicht
+//	}
+//
+// Banken lassen sich mit WithTaggedBank oder RegisterBank markieren;
+// CommitTagged committet dann nur die passende Teilmenge, etwa schnell und
+// langsam veränderliche Banken in unterschiedlichem Takt.
+// UnregisterBank nimmt eine Bank wieder heraus; bereits laufende Commits
+// schließen sie noch ein. CommitBanks committet dagegen nicht registrierte
+// Banken unter derselben Sperre und Versionszählung.
+//
+// Banken, die HealthChecker implementieren, werden vor der Vorbereitung
+// geprüft: Eine ungesunde Bank lässt den Commit sofort mit einem
+// UnhealthyBankError scheitern oder wird mit WithSkipUnhealthyBanks
+// übersprungen. WithCircuitBreaker überspringt eine Bank nach wiederholt
+// gescheiterten Vorbereitungen für eine Abkühlzeit, statt jeden Commit an ihr
+// scheitern zu lassen.
+//
+// WithBankPrepareTimeout gibt jeder Bank eine eigene Frist für ihre
+// Vorbereitung, damit eine langsame Bank die folgenden nicht aushungert.
+//
+// CommitAllReport liefert zu jedem Versuch einen CommitReport mit Dauer und
+// Elementzahl je Bank, übersprungenen Banken und der resultierenden Version,
+// etwa für Audit-Logs.
+//
+// CommitAsync startet einen Commit im Hintergrund; das zurückgegebene
+// CommitFuture meldet Abschluss, Fehler und Version.
+//
+// Pause und Resume frieren die Veröffentlichung für Wartungsfenster ein;
+// CommitAll liefert währenddessen ErrOrchestratorPaused oder wartet mit
+// WithBlockWhilePaused auf das Ende der Pause.
+//
+// Fehlgeschlagene Commits lassen sich nach ihrer Ursache unterscheiden: Eine
+// Bank, deren Vorbereitung scheitert, meldet sich als PrepareError, ein
+// fehlgeschlagener Abbruch einer BankV2 als AbortError, ein beendeter
+// Kontext als ErrCommitCancelled und eine Pause als ErrOrchestratorPaused.
+// Paniken in Publish- und Abort-Callbacks fängt der Orchestrator ab, meldet
+// sie als PanicError und an den Handler aus WithPanicHandler und führt die
+// übrigen Banken trotzdem zu Ende.
+//
+// Banken, deren Veröffentlichung scheitern kann, etwa beim Schreiben auf
+// Hardware, implementieren FallibleBank. Scheitert ihr Publish, bricht der
+// Orchestrator die übrigen Banken ab, kompensiert die bereits
+// veröffentlichten in umgekehrter Reihenfolge und meldet einen
+// PartialPublishError.
+//
+// Jeder Versuch erhält eine eigene ID, die CommitIDFromContext in Banken
+// liefert und die in Ereignissen, Spans, Berichten sowie bei
+// Hooks, Observern und Senken erscheint, die telemetry.CommitIDSink
+// implementieren.
+//
+// Jeder Commit trägt einen Idempotenzschlüssel im Kontext (siehe
+// IdempotencyKeyFromContext), den auch von Middleware wiederholte Versuche
+// behalten; Banken mit dauerhaften Seiteneffekten erkennen daran
+// Wiederholungen.
+//
+// Use registriert Middleware, die jeden Commit-Versuch wie HTTP-Middleware
+// umschließt, etwa für Logging, Tracing, Ratenbegrenzung oder
+// Berechtigungsprüfungen.
+package orchestrator
@@ -0,0 +1,93 @@
+package orchestrator
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Fehlerklassen eines Commit-Versuchs. Die Fehler von CommitAll lassen sich
+// mit errors.Is und errors.As nach ihnen unterscheiden; treffen mehrere zu,
+// werden sie mit errors.Join zusammengefasst.
+var (
+	// ErrCommitCancelled meldet, dass der Kontext des Versuchs endete, bevor
+	// alle Banken vorbereitet waren. Der Kontextfehler ist mit eingebunden.
+	ErrCommitCancelled = errors.New("commit cancelled")
+	// ErrOrchestratorPaused wird geliefert, solange der Orchestrator pausiert
+	// ist und WithBlockWhilePaused nicht gesetzt wurde.
+	ErrOrchestratorPaused = errors.New("commits paused")
+)
+
+// PrepareError meldet eine Bank, deren PrepareCommit fehlgeschlagen ist. Die
+// bereits vorbereiteten Banken wurden abgebrochen.
+type PrepareError struct {
+	Bank string
+	Err  error
+}
+
+func (e *PrepareError) Error() string {
+	return fmt.Sprintf("prepare of bank %s failed: %v", e.Bank, e.Err)
+}
+
+func (e *PrepareError) Unwrap() error {
+	return e.Err
+}
+
+// AbortError meldet eine Bank, deren Abort-Callback fehlgeschlagen ist. Ihre
+// vorbereiteten Elemente sind danach womöglich verloren.
+type AbortError struct {
+	Bank string
+	Err  error
+}
+
+func (e *AbortError) Error() string {
+	return fmt.Sprintf("abort of bank %s failed: %v", e.Bank, e.Err)
+}
+
+func (e *AbortError) Unwrap() error {
+	return e.Err
+}
+
+// PartialPublishError meldet eine FallibleBank, deren Publish gescheitert
+// ist. Die noch nicht veröffentlichten Banken wurden abgebrochen, die
+// veröffentlichten soweit möglich kompensiert. Bleibt keine Bank
+// veröffentlicht, steigt die Version nicht.
+type PartialPublishError struct {
+	Bank string
+	Err  error
+	// Compensated nennt die zurückgenommenen, Published die weiterhin
+	// veröffentlichten Banken, jeweils in Registrierungsreihenfolge.
+	Compensated []string
+	Published   []string
+}
+
+func (e *PartialPublishError) Error() string {
+	if len(e.Published) == 0 {
+		return fmt.Sprintf("publish of bank %s failed: %v", e.Bank, e.Err)
+	}
+	return fmt.Sprintf("publish of bank %s failed: %v (banks %s remain published)", e.Bank, e.Err, strings.Join(e.Published, ", "))
+}
+
+func (e *PartialPublishError) Unwrap() error {
+	return e.Err
+}
+
+// CompensationError meldet eine Bank, deren Compensate-Callback
+// fehlgeschlagen ist. Ihre Veröffentlichung bleibt bestehen.
+type CompensationError struct {
+	Bank string
+	Err  error
+}
+
+func (e *CompensationError) Error() string {
+	return fmt.Sprintf("compensation of bank %s failed: %v", e.Bank, e.Err)
+}
+
+func (e *CompensationError) Unwrap() error {
+	return e.Err
+}
+
+// cancelled ordnet einen Kontextfehler der Klasse ErrCommitCancelled zu.
+func cancelled(err error) error {
+	return errors.Join(ErrCommitCancelled, err)
+}
@@ -0,0 +1,36 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestCommitErrorsAreClassified(t *testing.T) {
+	failure := errors.New("disk full")
+	bank := &testBank{prepare: func(context.Context) (func(), func(), error) {
+		return nil, nil, failure
+	}}
+	o := NewCommitOrchestrator(WithBanks(bank))
+
+	err := o.CommitAll(context.Background())
+	var prepareErr *PrepareError
+	if !errors.As(err, &prepareErr) || prepareErr.Bank != "bank-0" || !errors.Is(err, failure) {
+		t.Fatalf("expected a PrepareError for bank-0, got %v", err)
+	}
+	if errors.Is(err, ErrCommitCancelled) {
+		t.Fatalf("a failed prepare is no cancellation: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = o.CommitAll(ctx)
+	if !errors.Is(err, ErrCommitCancelled) || !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected a cancellation, got %v", err)
+	}
+
+	o.Pause()
+	if err := o.CommitAll(context.Background()); !errors.Is(err, ErrOrchestratorPaused) || !errors.Is(err, ErrPaused) {
+		t.Fatalf("expected ErrOrchestratorPaused, got %v", err)
+	}
+}
@@ -0,0 +1,92 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"slices"
+
+	"github.com/timzifer/committable_queue/telemetry"
+)
+
+// FallibleBank beschreibt eine Bank, deren Veröffentlichung scheitern kann,
+// etwa weil sie erst beim Publish auf Hardware oder ein entferntes System
+// schreibt. Der Orchestrator ruft bei solchen Banken PrepareFallible statt
+// PrepareCommit auf.
+//
+// Scheitert Publish, widerruft der Orchestrator den Commit: Die gescheiterte
+// und alle noch nicht veröffentlichten Banken werden in umgekehrter
+// Reihenfolge abgebrochen, die bereits veröffentlichten in umgekehrter
+// Reihenfolge kompensiert. Banken ohne Compensate bleiben veröffentlicht;
+// fallible Banken sollten daher vor den übrigen registriert werden.
+type FallibleBank interface {
+	Bank
+	PrepareFallible(ctx context.Context) (Prepared, error)
+}
+
+// Prepared enthält die Callbacks einer vorbereiteten FallibleBank. Nicht
+// gesetzte Callbacks gelten als wirkungslos; ohne Compensate lässt sich die
+// Veröffentlichung der Bank nicht zurücknehmen.
+type Prepared struct {
+	Publish    func() error
+	Abort      func() error
+	Compensate func() error
+}
+
+// preparedBank ist eine vorbereitete Bank gleich welchen Vertrags. Nur bei
+// FallibleBank widerruft ein gescheitertes Publish den Commit.
+type preparedBank struct {
+	Prepared
+	fallible bool
+	// elements zählt die über ReportElements gemeldeten Elemente.
+	elements int
+}
+
+// abort bricht die vorbereiteten Banken ab Index from in umgekehrter
+// Reihenfolge ab und liefert die fehlgeschlagenen Abbrüche als AbortError.
+func (o *CommitOrchestrator) abort(ctx context.Context, version uint64, banks []registeredBank, prepared []preparedBank, from int, report *CommitReport) []error {
+	var failures []error
+	for i := len(prepared) - 1; i >= from; i-- {
+		abortErr := o.call(banks[i].name, prepared[i].Abort)
+		banks[i].metrics.ObserveAbort(abortErr)
+		if abortErr != nil {
+			abortErr = &AbortError{Bank: banks[i].name, Err: abortErr}
+			failures = append(failures, abortErr)
+		}
+		report.aborted(i, abortErr)
+		o.logEvent(ctx, telemetry.Event{Kind: telemetry.EventBankAborted, Version: version, Bank: banks[i].name, Err: abortErr})
+	}
+	return failures
+}
+
+// rollBack widerruft einen Commit, nachdem das Publish der FallibleBank mit
+// Index failed gescheitert ist. done sind die bereits veröffentlichten Banken.
+// Das Ergebnis fasst partial mit den fehlgeschlagenen Abbrüchen und
+// Kompensationen zusammen; partial.Published nennt danach die Banken, die
+// veröffentlicht bleiben.
+func (o *CommitOrchestrator) rollBack(ctx context.Context, version uint64, banks []registeredBank, prepared []preparedBank, failed int, done []int, partial *PartialPublishError, observers observerList, report *CommitReport) error {
+	observers.AbortStart(partial)
+	failures := append([]error{partial}, o.abort(ctx, version, banks, prepared, failed, report)...)
+	for _, i := range slices.Backward(done) {
+		compensate := prepared[i].Compensate
+		if compensate == nil {
+			partial.Published = append(partial.Published, banks[i].name)
+			continue
+		}
+		compensateErr := o.call(banks[i].name, compensate)
+		if compensateErr != nil {
+			compensateErr = &CompensationError{Bank: banks[i].name, Err: compensateErr}
+			failures = append(failures, compensateErr)
+			partial.Published = append(partial.Published, banks[i].name)
+		} else {
+			partial.Compensated = append(partial.Compensated, banks[i].name)
+			report.compensated(i)
+		}
+		o.logEvent(ctx, telemetry.Event{Kind: telemetry.EventBankCompensated, Version: version, Bank: banks[i].name, Err: compensateErr})
+	}
+	slices.Reverse(partial.Published)
+	slices.Reverse(partial.Compensated)
+	if len(failures) > 1 {
+		return errors.Join(failures...)
+	}
+	return partial
+}
@@ -0,0 +1,96 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+)
+
+type fallibleBank struct {
+	name       string
+	calls      *[]string
+	publishErr error
+	compensate bool
+}
+
+func (b *fallibleBank) Name() string { return b.name }
+
+func (b *fallibleBank) PrepareCommit(context.Context) (func(), func(), error) {
+	panic("PrepareCommit must not be called on a FallibleBank")
+}
+
+func (b *fallibleBank) PrepareFallible(context.Context) (Prepared, error) {
+	p := Prepared{
+		Publish: func() error {
+			*b.calls = append(*b.calls, "publish "+b.name)
+			return b.publishErr
+		},
+		Abort: func() error {
+			*b.calls = append(*b.calls, "abort "+b.name)
+			return nil
+		},
+	}
+	if b.compensate {
+		p.Compensate = func() error {
+			*b.calls = append(*b.calls, "compensate "+b.name)
+			return nil
+		}
+	}
+	return p, nil
+}
+
+func TestFailedPublishIsCompensated(t *testing.T) {
+	var calls []string
+	flush := errors.New("flush failed")
+	o := NewCommitOrchestrator(WithBanks(
+		&fallibleBank{name: "a", calls: &calls, compensate: true},
+		&fallibleBank{name: "b", calls: &calls, compensate: true},
+		&fallibleBank{name: "c", calls: &calls, publishErr: flush},
+		&fallibleBank{name: "d", calls: &calls},
+	))
+
+	report, err := o.CommitAllReport(context.Background())
+	var partial *PartialPublishError
+	if !errors.As(err, &partial) || partial.Bank != "c" || !errors.Is(err, flush) {
+		t.Fatalf("expected a PartialPublishError for bank c, got %v", err)
+	}
+	want := []string{"publish a", "publish b", "publish c", "abort d", "abort c", "compensate b", "compensate a"}
+	if !slices.Equal(calls, want) {
+		t.Fatalf("expected calls %v, got %v", want, calls)
+	}
+	if !slices.Equal(partial.Compensated, []string{"a", "b"}) || len(partial.Published) != 0 {
+		t.Fatalf("unexpected rollback result: %+v", partial)
+	}
+	if o.Version() != 0 {
+		t.Fatalf("a fully compensated commit must not advance the version, got %d", o.Version())
+	}
+	if !report.Banks[0].Compensated || !report.Banks[2].Aborted || !report.Banks[3].Aborted {
+		t.Fatalf("unexpected report: %+v", report.Banks)
+	}
+}
+
+func TestFailedPublishWithoutCompensation(t *testing.T) {
+	var calls []string
+	flush := errors.New("flush failed")
+	infallible := &testBank{prepare: func(context.Context) (func(), func(), error) {
+		return func() { calls = append(calls, "publish queue") }, nil, nil
+	}}
+	o := NewCommitOrchestrator(WithBanks(
+		&fallibleBank{name: "device", calls: &calls, compensate: true},
+		infallible,
+		&fallibleBank{name: "flush", calls: &calls, publishErr: flush},
+	))
+
+	err := o.CommitAll(context.Background())
+	var partial *PartialPublishError
+	if !errors.As(err, &partial) {
+		t.Fatalf("expected a PartialPublishError, got %v", err)
+	}
+	if !slices.Equal(partial.Published, []string{"bank-1"}) || !slices.Equal(partial.Compensated, []string{"device"}) {
+		t.Fatalf("unexpected rollback result: %+v", partial)
+	}
+	if o.Version() != 1 {
+		t.Fatalf("a bank that remains published must advance the version, got %d", o.Version())
+	}
+}
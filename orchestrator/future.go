@@ -0,0 +1,41 @@
+package orchestrator
+
+import "context"
+
+// CommitFuture ist das Ergebnis eines mit CommitAsync gestarteten Commits.
+type CommitFuture struct {
+	done    chan struct{}
+	err     error
+	version uint64
+}
+
+// CommitAsync startet CommitAll in einer eigenen Goroutine und kehrt sofort
+// zurück, sodass etwa eine Regelschleife ihren Takt nicht an langsamen
+// Vorbereitungen ausrichten muss. ctx gilt für den gesamten Commit-Versuch.
+func (o *CommitOrchestrator) CommitAsync(ctx context.Context) *CommitFuture {
+	f := &CommitFuture{done: make(chan struct{})}
+	go func() {
+		defer close(f.done)
+		var report CommitReport
+		f.err = o.commit(ctx, "CommitAll", nil, &report)
+		f.version = report.Version
+	}()
+	return f
+}
+
+// Done wird geschlossen, sobald der Commit abgeschlossen ist.
+func (f *CommitFuture) Done() <-chan struct{} {
+	return f.done
+}
+
+// Err wartet auf den Abschluss und liefert den Fehler von CommitAll.
+func (f *CommitFuture) Err() error {
+	<-f.done
+	return f.err
+}
+
+// Version wartet auf den Abschluss und liefert die danach sichtbare Version.
+func (f *CommitFuture) Version() uint64 {
+	<-f.done
+	return f.version
+}
@@ -0,0 +1,83 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+)
+
+func TestCommitAsync(t *testing.T) {
+	release := make(chan struct{})
+	bank := &testBank{prepare: func(context.Context) (func(), func(), error) {
+		<-release
+		return nil, nil, nil
+	}}
+	o := NewCommitOrchestrator(WithBanks(bank))
+
+	f := o.CommitAsync(context.Background())
+	select {
+	case <-f.Done():
+		t.Fatal("commit finished before its bank was prepared")
+	default:
+	}
+
+	close(release)
+	if err := f.Err(); err != nil {
+		t.Fatalf("commit: %v", err)
+	}
+	if f.Version() != 1 || o.Version() != 1 {
+		t.Fatalf("expected version 1, got future %d orchestrator %d", f.Version(), o.Version())
+	}
+}
+
+func TestCommitAsyncFailure(t *testing.T) {
+	failure := errors.New("prepare failed")
+	bank := &testBank{prepare: func(context.Context) (func(), func(), error) {
+		return nil, nil, failure
+	}}
+	o := NewCommitOrchestrator(WithBanks(bank))
+
+	f := o.CommitAsync(context.Background())
+	<-f.Done()
+	if !errors.Is(f.Err(), failure) || f.Version() != 0 {
+		t.Fatalf("expected failure at version 0, got %v at %d", f.Err(), f.Version())
+	}
+}
+
+type futureIndexKey struct{}
+
+func TestCommitAsyncVersionsUnderConcurrency(t *testing.T) {
+	var mu sync.Mutex
+	prepared := make(map[int]uint64)
+	bank := &testBank{prepare: func(ctx context.Context) (func(), func(), error) {
+		version, _ := VersionFromContext(ctx)
+		mu.Lock()
+		prepared[ctx.Value(futureIndexKey{}).(int)] = version
+		mu.Unlock()
+		return func() {}, func() {}, nil
+	}}
+	o := NewCommitOrchestrator(WithBanks(bank), WithLocker(lingeringLocker{newMutexLocker()}))
+
+	futures := make([]*CommitFuture, 16)
+	for i := range futures {
+		futures[i] = o.CommitAsync(context.WithValue(t.Context(), futureIndexKey{}, i))
+	}
+
+	for i, f := range futures {
+		if err := f.Err(); err != nil {
+			t.Fatalf("commit %d: %v", i, err)
+		}
+	}
+
+	seen := make(map[uint64]bool)
+	for i, f := range futures {
+		if want := prepared[i]; f.Version() != want {
+			t.Fatalf("commit %d prepared version %d but its future reports %d", i, want, f.Version())
+		}
+		if seen[f.Version()] {
+			t.Fatalf("version %d reported twice", f.Version())
+		}
+		seen[f.Version()] = true
+	}
+}
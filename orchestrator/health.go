@@ -0,0 +1,67 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/timzifer/committable_queue/telemetry"
+)
+
+// HealthChecker kann von Banken implementiert werden, deren Vorbereitung von
+// externen Ressourcen abhängt. Der Orchestrator prüft vor jedem Commit alle
+// beteiligten Banken, damit ein Ausfall sofort erkannt wird, statt erst in
+// PrepareCommit in einen Timeout zu laufen.
+type HealthChecker interface {
+	CheckHealth(ctx context.Context) error
+}
+
+// UnhealthyBankError meldet eine Bank, deren Gesundheitsprüfung fehlgeschlagen
+// ist.
+type UnhealthyBankError struct {
+	Bank string
+	Err  error
+}
+
+func (e *UnhealthyBankError) Error() string {
+	return fmt.Sprintf("bank %s unhealthy: %v", e.Bank, e.Err)
+}
+
+func (e *UnhealthyBankError) Unwrap() error {
+	return e.Err
+}
+
+// WithSkipUnhealthyBanks lässt Commits ungesunde Banken überspringen, statt
+// mit einem UnhealthyBankError abzubrechen. Übersprungene Banken behalten ihre
+// pending Elemente bis zum nächsten Commit, bei dem sie gesund sind.
+func WithSkipUnhealthyBanks() Option {
+	return func(o *CommitOrchestrator) {
+		o.skipUnhealthy = true
+	}
+}
+
+// checkHealth prüft alle Banken, die HealthChecker implementieren, und liefert
+// die gesunden. Ohne WithSkipUnhealthyBanks bricht die erste ungesunde Bank
+// die Prüfung mit einem UnhealthyBankError ab; andernfalls werden übersprungene
+// Banken in report vermerkt.
+func (o *CommitOrchestrator) checkHealth(ctx context.Context, banks []registeredBank, report *CommitReport) ([]registeredBank, error) {
+	healthy := make([]registeredBank, 0, len(banks))
+	for _, entry := range banks {
+		checker, ok := entry.bank.(HealthChecker)
+		if !ok {
+			healthy = append(healthy, entry)
+			continue
+		}
+		err := checker.CheckHealth(ctx)
+		if err == nil {
+			healthy = append(healthy, entry)
+			continue
+		}
+		err = &UnhealthyBankError{Bank: entry.name, Err: err}
+		if !o.skipUnhealthy {
+			return nil, err
+		}
+		report.skipped(entry.name)
+		o.logEvent(ctx, telemetry.Event{Kind: telemetry.EventBankSkipped, Version: o.version.Load() + 1, Bank: entry.name, Err: err})
+	}
+	return healthy, nil
+}
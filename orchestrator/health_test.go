@@ -0,0 +1,75 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/timzifer/committable_queue/telemetry"
+)
+
+type healthBank struct {
+	testBank
+	health error
+}
+
+func (b *healthBank) CheckHealth(context.Context) error {
+	return b.health
+}
+
+func TestUnhealthyBankFailsFast(t *testing.T) {
+	prepared := 0
+	prepare := func(context.Context) (func(), func(), error) {
+		prepared++
+		return nil, nil, nil
+	}
+	down := errors.New("connection refused")
+	healthy := &healthBank{testBank: testBank{prepare: prepare}}
+	sick := &healthBank{testBank: testBank{prepare: prepare}, health: down}
+	o := NewCommitOrchestrator(WithBanks(healthy, sick))
+
+	err := o.CommitAll(context.Background())
+	var unhealthy *UnhealthyBankError
+	if !errors.As(err, &unhealthy) || unhealthy.Bank != "bank-1" || !errors.Is(err, down) {
+		t.Fatalf("expected UnhealthyBankError for bank-1, got %v", err)
+	}
+	if prepared != 0 || o.Version() != 0 {
+		t.Fatalf("no bank may be prepared: prepared %d version %d", prepared, o.Version())
+	}
+}
+
+func TestSkipUnhealthyBanks(t *testing.T) {
+	var prepared []string
+	prepare := func(name string) func(context.Context) (func(), func(), error) {
+		return func(context.Context) (func(), func(), error) {
+			prepared = append(prepared, name)
+			return nil, nil, nil
+		}
+	}
+	healthy := &healthBank{testBank: testBank{prepare: prepare("healthy")}}
+	sick := &healthBank{testBank: testBank{prepare: prepare("sick")}, health: errors.New("down")}
+	logger := &recordingLogger{}
+	o := NewCommitOrchestrator(WithBanks(healthy, sick), WithSkipUnhealthyBanks(), WithLogger(logger))
+
+	if err := o.CommitAll(context.Background()); err != nil {
+		t.Fatalf("commit: %v", err)
+	}
+	if len(prepared) != 1 || prepared[0] != "healthy" || o.Version() != 1 {
+		t.Fatalf("expected only the healthy bank to commit: %v version %d", prepared, o.Version())
+	}
+	skipped := false
+	for _, event := range logger.events {
+		skipped = skipped || event.Kind == telemetry.EventBankSkipped && event.Bank == "bank-1"
+	}
+	if !skipped {
+		t.Fatalf("expected a bank_skipped event, got %+v", logger.events)
+	}
+
+	sick.health = nil
+	if err := o.CommitAll(context.Background()); err != nil {
+		t.Fatalf("commit: %v", err)
+	}
+	if len(prepared) != 3 {
+		t.Fatalf("expected the recovered bank to take part again: %v", prepared)
+	}
+}
@@ -0,0 +1,44 @@
+package orchestrator
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+)
+
+type idempotencyKey struct{}
+
+// WithIdempotencyKey hinterlegt key als Idempotenzschlüssel der Commits, die
+// mit dem zurückgegebenen Kontext gestartet werden. Wer einen gescheiterten
+// Commit selbst wiederholt, übergibt bei jeder Wiederholung denselben
+// Schlüssel.
+func WithIdempotencyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, idempotencyKey{}, key)
+}
+
+// IdempotencyKeyFromContext liefert den Idempotenzschlüssel des laufenden
+// Commits. Jeder Aufruf von CommitAll, CommitTagged, CommitAllReport und
+// CommitAsync erhält einen eigenen Schlüssel, sofern der Kontext keinen
+// mitbringt; Versuche, die eine Middleware wiederholt, teilen ihn. Banken, die
+// Seiteneffekte dauerhaft speichern, erkennen daran eine Wiederholung und
+// überspringen die erneute Ausführung. Außerhalb eines Commits ist ok false.
+func IdempotencyKeyFromContext(ctx context.Context) (key string, ok bool) {
+	key, ok = ctx.Value(idempotencyKey{}).(string)
+	return key, ok
+}
+
+// withIdempotencyKey ergänzt ctx um einen neuen Schlüssel, falls er noch
+// keinen enthält.
+func withIdempotencyKey(ctx context.Context) context.Context {
+	if _, ok := IdempotencyKeyFromContext(ctx); ok {
+		return ctx
+	}
+	return WithIdempotencyKey(ctx, newID())
+}
+
+// newID erzeugt 128 zufällige Bits in Hexadezimaldarstellung.
+func newID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
@@ -0,0 +1,57 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestIdempotencyKeySurvivesRetries(t *testing.T) {
+	transient := errors.New("transient")
+	var keys []string
+	bank := &testBank{prepare: func(ctx context.Context) (func(), func(), error) {
+		key, ok := IdempotencyKeyFromContext(ctx)
+		if !ok {
+			t.Error("prepare must see an idempotency key")
+		}
+		keys = append(keys, key)
+		if len(keys) == 1 {
+			return nil, nil, transient
+		}
+		return nil, nil, nil
+	}}
+	o := NewCommitOrchestrator(WithBanks(bank))
+	o.Use(func(next CommitFunc) CommitFunc {
+		return func(ctx context.Context) error {
+			if err := next(ctx); !errors.Is(err, transient) {
+				return err
+			}
+			return next(ctx)
+		}
+	})
+
+	report, err := o.CommitAllReport(context.Background())
+	if err != nil {
+		t.Fatalf("commit failed: %v", err)
+	}
+	if len(keys) != 2 || keys[0] == "" || keys[0] != keys[1] {
+		t.Fatalf("expected the retry to reuse the key, got %q", keys)
+	}
+	if report.IdempotencyKey != keys[0] {
+		t.Fatalf("expected key %q in the report, got %q", keys[0], report.IdempotencyKey)
+	}
+
+	if err := o.CommitAll(context.Background()); err != nil {
+		t.Fatalf("commit failed: %v", err)
+	}
+	if keys[2] == keys[0] {
+		t.Fatal("every CommitAll must get its own key")
+	}
+
+	if err := o.CommitAll(WithIdempotencyKey(context.Background(), "order-42")); err != nil {
+		t.Fatalf("commit failed: %v", err)
+	}
+	if keys[3] != "order-42" {
+		t.Fatalf("expected the key from the context, got %q", keys[3])
+	}
+}
@@ -0,0 +1,34 @@
+package orchestrator
+
+import "context"
+
+// Locker serialisiert Commit-Versuche. Acquire blockiert, bis die Sperre
+// erworben wurde oder ctx endet; im zweiten Fall wird der Kontextfehler
+// zurückgegeben und Release darf nicht aufgerufen werden.
+type Locker interface {
+	Acquire(ctx context.Context) error
+	Release()
+}
+
+// mutexLocker ist der prozessinterne Standard-Locker. Ein Kanal mit Kapazität
+// eins erlaubt im Gegensatz zu sync.Mutex einen abbrechbaren Erwerb.
+type mutexLocker struct {
+	sem chan struct{}
+}
+
+func newMutexLocker() *mutexLocker {
+	return &mutexLocker{sem: make(chan struct{}, 1)}
+}
+
+func (l *mutexLocker) Acquire(ctx context.Context) error {
+	select {
+	case l.sem <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (l *mutexLocker) Release() {
+	<-l.sem
+}
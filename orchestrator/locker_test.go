@@ -0,0 +1,91 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+type countingLocker struct {
+	inner    Locker
+	acquired int
+	released int
+	err      error
+}
+
+func (l *countingLocker) Acquire(ctx context.Context) error {
+	if l.err != nil {
+		return l.err
+	}
+	l.acquired++
+	return l.inner.Acquire(ctx)
+}
+
+func (l *countingLocker) Release() {
+	l.released++
+	l.inner.Release()
+}
+
+func TestMutexLockerAcquireRespectsContext(t *testing.T) {
+	locker := newMutexLocker()
+	if err := locker.Acquire(context.Background()); err != nil {
+		t.Fatalf("acquire failed: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := locker.Acquire(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected cancellation while lock is held, got %v", err)
+	}
+
+	locker.Release()
+	if err := locker.Acquire(context.Background()); err != nil {
+		t.Fatalf("acquire after release failed: %v", err)
+	}
+	locker.Release()
+}
+
+func TestCommitOrchestratorUsesCustomLocker(t *testing.T) {
+	locker := &countingLocker{inner: newMutexLocker()}
+	bank := &testBank{prepare: func(context.Context) (func(), func(), error) {
+		return nil, nil, nil
+	}}
+
+	orchestrator := NewCommitOrchestrator(WithBanks(bank), WithLocker(locker))
+	if err := orchestrator.CommitAll(context.Background()); err != nil {
+		t.Fatalf("commit failed: %v", err)
+	}
+	if locker.acquired != 1 || locker.released != 1 {
+		t.Fatalf("expected one acquire/release, got %d/%d", locker.acquired, locker.released)
+	}
+}
+
+func TestCommitOrchestratorLockerFailure(t *testing.T) {
+	lockErr := errors.New("lease lost")
+	locker := &countingLocker{inner: newMutexLocker(), err: lockErr}
+
+	called := false
+	bank := &testBank{prepare: func(context.Context) (func(), func(), error) {
+		called = true
+		return nil, nil, nil
+	}}
+
+	var observed error
+	ctx := WithCommitObserver(context.Background(), ObserverFunc(func(err error) {
+		observed = err
+	}))
+
+	orchestrator := NewCommitOrchestrator(WithBanks(bank), WithLocker(locker))
+	if err := orchestrator.CommitAll(ctx); !errors.Is(err, lockErr) {
+		t.Fatalf("expected locker error, got %v", err)
+	}
+	if called {
+		t.Fatalf("bank must not be prepared without the lock")
+	}
+	if !errors.Is(observed, lockErr) {
+		t.Fatalf("observer should receive locker error, got %v", observed)
+	}
+	if locker.released != 0 {
+		t.Fatalf("release must not be called after failed acquire")
+	}
+}
@@ -0,0 +1,42 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+)
+
+// CommitFunc führt einen Commit-Versuch aus, wie ihn CommitAll startet.
+type CommitFunc func(ctx context.Context) error
+
+// Middleware umschließt einen Commit-Versuch wie HTTP-Middleware einen
+// Handler: Sie kann vor und nach next eigene Schritte ausführen, den Kontext
+// anreichern, den Fehler umschreiben oder den Versuch verweigern, indem sie
+// next nicht aufruft. Typische Anwendungen sind Logging, Tracing,
+// Ratenbegrenzung und Berechtigungsprüfungen.
+type Middleware func(next CommitFunc) CommitFunc
+
+// Use hängt eine Middleware an, die alle folgenden Commits von CommitAll,
+// CommitTagged, CommitAllReport und CommitAsync umschließt. Die zuerst
+// registrierte Middleware liegt außen. Middleware läuft vor dem Erwerb der
+// globalen Sperre; ein verweigerter Versuch blockiert daher keine anderen
+// Commits.
+func (o *CommitOrchestrator) Use(middleware Middleware) error {
+	if middleware == nil {
+		return errors.New("nil middleware")
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.middlewares = append(o.middlewares, middleware)
+	return nil
+}
+
+// chain umschließt attempt mit der registrierten Middleware.
+func (o *CommitOrchestrator) chain(attempt CommitFunc) CommitFunc {
+	o.mu.Lock()
+	middlewares := o.middlewares
+	o.mu.Unlock()
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		attempt = middlewares[i](attempt)
+	}
+	return attempt
+}
@@ -0,0 +1,74 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestMiddlewareWrapsCommits(t *testing.T) {
+	type key struct{}
+	var calls []string
+	bank := &testBank{prepare: func(ctx context.Context) (func(), func(), error) {
+		calls = append(calls, "prepare:"+ctx.Value(key{}).(string))
+		return nil, nil, nil
+	}}
+	o := NewCommitOrchestrator(WithBanks(bank))
+
+	trace := func(name string) Middleware {
+		return func(next CommitFunc) CommitFunc {
+			return func(ctx context.Context) error {
+				calls = append(calls, "before:"+name)
+				err := next(context.WithValue(ctx, key{}, name))
+				calls = append(calls, "after:"+name)
+				return err
+			}
+		}
+	}
+	if err := o.Use(trace("outer")); err != nil {
+		t.Fatalf("use failed: %v", err)
+	}
+	o.Use(trace("inner"))
+	if err := o.Use(nil); err == nil {
+		t.Fatal("expected an error for a nil middleware")
+	}
+
+	if err := o.CommitAll(context.Background()); err != nil {
+		t.Fatalf("commit failed: %v", err)
+	}
+	want := []string{"before:outer", "before:inner", "prepare:inner", "after:inner", "after:outer"}
+	if !slices.Equal(calls, want) {
+		t.Fatalf("expected %v, got %v", want, calls)
+	}
+
+	calls = nil
+	f := o.CommitAsync(context.Background())
+	<-f.Done()
+	if f.Err() != nil || f.Version() != 2 {
+		t.Fatalf("async commit: err %v version %d", f.Err(), f.Version())
+	}
+	if !slices.Equal(calls, want) {
+		t.Fatalf("expected async commits to pass the middleware, got %v", calls)
+	}
+}
+
+func TestMiddlewareCanRejectCommits(t *testing.T) {
+	errDenied := errors.New("denied")
+	prepared := false
+	bank := &testBank{prepare: func(context.Context) (func(), func(), error) {
+		prepared = true
+		return nil, nil, nil
+	}}
+	o := NewCommitOrchestrator(WithBanks(bank))
+	o.Use(func(CommitFunc) CommitFunc {
+		return func(context.Context) error { return errDenied }
+	})
+
+	if _, err := o.CommitAllReport(context.Background()); !errors.Is(err, errDenied) {
+		t.Fatalf("expected the middleware's error, got %v", err)
+	}
+	if prepared || o.Version() != 0 {
+		t.Fatalf("a rejected commit must not prepare: prepared %v version %d", prepared, o.Version())
+	}
+}
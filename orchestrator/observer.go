@@ -0,0 +1,90 @@
+package orchestrator
+
+import "context"
+
+// CommitObserver follows the stages of a commit attempt, for example to drive
+// progress displays or fine-grained tracing.
+//
+// PrepareStart and PrepareEnd bracket the PrepareCommit call of every bank.
+// When all banks are prepared, PublishStart runs immediately before the
+// publish callbacks and PublishEnd after the new version became visible; an
+// attempt without participating banks reports both with the unchanged
+// version. When the attempt fails, AbortStart runs before the abort callbacks,
+// or right away if the attempt fails before any bank was prepared.
+//
+// Observers that also implement telemetry.CommitIDSink receive the ID of the
+// attempt (see CommitIDFromContext) before any other stage.
+type CommitObserver interface {
+	PrepareStart(bank string)
+	PrepareEnd(bank string, err error)
+	PublishStart(version uint64)
+	PublishEnd(version uint64)
+	AbortStart(err error)
+}
+
+// ObserverFunc adapts a function that is only interested in the outcome of a
+// commit attempt: it is called with nil at PublishStart and with the error at
+// AbortStart.
+type ObserverFunc func(error)
+
+func (f ObserverFunc) PrepareStart(string)      {}
+func (f ObserverFunc) PrepareEnd(string, error) {}
+func (f ObserverFunc) PublishStart(uint64)      { f(nil) }
+func (f ObserverFunc) PublishEnd(uint64)        {}
+func (f ObserverFunc) AbortStart(err error)     { f(err) }
+
+type commitObserverKey struct{}
+
+// WithCommitObserver returns a context that reports the stages of commit
+// attempts run with it to observer.
+//
+// Observers are additive: observers already attached to ctx are kept and all of
+// them are invoked in registration order.
+func WithCommitObserver(ctx context.Context, observer CommitObserver) context.Context {
+	if observer == nil {
+		return ctx
+	}
+	existing := commitObservers(ctx)
+	observers := make(observerList, 0, len(existing)+1)
+	observers = append(observers, existing...)
+	observers = append(observers, observer)
+	return context.WithValue(ctx, commitObserverKey{}, observers)
+}
+
+func commitObservers(ctx context.Context) observerList {
+	observers, _ := ctx.Value(commitObserverKey{}).(observerList)
+	return observers
+}
+
+// observerList forwards every stage to all observers in registration order.
+type observerList []CommitObserver
+
+func (l observerList) PrepareStart(bank string) {
+	for _, observer := range l {
+		observer.PrepareStart(bank)
+	}
+}
+
+func (l observerList) PrepareEnd(bank string, err error) {
+	for _, observer := range l {
+		observer.PrepareEnd(bank, err)
+	}
+}
+
+func (l observerList) PublishStart(version uint64) {
+	for _, observer := range l {
+		observer.PublishStart(version)
+	}
+}
+
+func (l observerList) PublishEnd(version uint64) {
+	for _, observer := range l {
+		observer.PublishEnd(version)
+	}
+}
+
+func (l observerList) AbortStart(err error) {
+	for _, observer := range l {
+		observer.AbortStart(err)
+	}
+}
@@ -0,0 +1,69 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+type stageRecorder struct {
+	stages []string
+}
+
+func (r *stageRecorder) PrepareStart(bank string) {
+	r.stages = append(r.stages, "prepare-start "+bank)
+}
+
+func (r *stageRecorder) PrepareEnd(bank string, err error) {
+	r.stages = append(r.stages, fmt.Sprintf("prepare-end %s %v", bank, err))
+}
+
+func (r *stageRecorder) PublishStart(version uint64) {
+	r.stages = append(r.stages, fmt.Sprintf("publish-start %d", version))
+}
+
+func (r *stageRecorder) PublishEnd(version uint64) {
+	r.stages = append(r.stages, fmt.Sprintf("publish-end %d", version))
+}
+
+func (r *stageRecorder) AbortStart(err error) {
+	r.stages = append(r.stages, fmt.Sprintf("abort-start %v", err))
+}
+
+func TestCommitObserverStages(t *testing.T) {
+	failure := errors.New("boom")
+	fail := false
+	ok := &testBank{prepare: func(context.Context) (func(), func(), error) {
+		return nil, nil, nil
+	}}
+	flaky := &testBank{prepare: func(context.Context) (func(), func(), error) {
+		if fail {
+			return nil, nil, failure
+		}
+		return nil, nil, nil
+	}}
+	o := NewCommitOrchestrator(WithBanks(ok, flaky))
+
+	recorder := &stageRecorder{}
+	ctx := WithCommitObserver(context.Background(), recorder)
+	if err := o.CommitAll(ctx); err != nil {
+		t.Fatalf("commit: %v", err)
+	}
+	fail = true
+	if err := o.CommitAll(ctx); !errors.Is(err, failure) {
+		t.Fatalf("expected failure, got %v", err)
+	}
+
+	want := []string{
+		"prepare-start bank-0", "prepare-end bank-0 <nil>",
+		"prepare-start bank-1", "prepare-end bank-1 <nil>",
+		"publish-start 1", "publish-end 1",
+		"prepare-start bank-0", "prepare-end bank-0 <nil>",
+		"prepare-start bank-1", "prepare-end bank-1 boom",
+		"abort-start prepare of bank bank-1 failed: boom",
+	}
+	if fmt.Sprint(recorder.stages) != fmt.Sprint(want) {
+		t.Fatalf("expected stages\n%v\ngot\n%v", want, recorder.stages)
+	}
+}
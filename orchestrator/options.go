@@ -0,0 +1,94 @@
+package orchestrator
+
+import (
+	"io"
+	"time"
+
+	"github.com/timzifer/committable_queue/telemetry"
+)
+
+// Option konfiguriert einen CommitOrchestrator bei der Erzeugung.
+type Option func(*CommitOrchestrator)
+
+// WithBanks registriert die übergebenen Banken in der angegebenen Reihenfolge.
+func WithBanks(banks ...Bank) Option {
+	return func(o *CommitOrchestrator) {
+		for _, bank := range banks {
+			o.banks = append(o.banks, newRegisteredBank(bank, len(o.banks)))
+		}
+	}
+}
+
+// WithTaggedBank registriert eine Bank mit Tags, über die CommitTagged sie
+// auswählt. CommitAll bezieht sie wie jede andere Bank ein.
+func WithTaggedBank(bank Bank, tags ...string) Option {
+	return func(o *CommitOrchestrator) {
+		o.banks = append(o.banks, newRegisteredBank(bank, len(o.banks), tags...))
+	}
+}
+
+// WithCommitLog aktiviert das Write-Ahead-Log. Der Orchestrator schreibt seine
+// Publish-/Abort-Entscheidungen nach w, bevor er sie ausführt.
+func WithCommitLog(w io.Writer) Option {
+	return func(o *CommitOrchestrator) {
+		if w != nil {
+			o.log = newCommitLog(w)
+		}
+	}
+}
+
+// WithLocker ersetzt den prozessinternen Mutex, über den CommitAll serialisiert
+// wird, etwa durch eine verteilte Sperre (etcd, Redis), wenn sich mehrere
+// Prozesse dieselben Banken teilen.
+func WithLocker(locker Locker) Option {
+	return func(o *CommitOrchestrator) {
+		if locker != nil {
+			o.locker = locker
+		}
+	}
+}
+
+// WithMetrics übergibt die Commit-Metriken, in die der Orchestrator schreibt.
+// Ohne diese Option erhält jeder Orchestrator eine eigene Instanz; mehrere
+// Orchestratoren können sich eine Instanz teilen, um gemeinsam ausgewertet zu
+// werden.
+func WithMetrics(metrics *telemetry.CommitMetrics) Option {
+	return func(o *CommitOrchestrator) {
+		if metrics != nil {
+			o.metrics = metrics
+		}
+	}
+}
+
+// WithLogger übergibt einen Logger für strukturierte Commit-Ereignisse, etwa
+// telemetry.NewSlogLogger. Standard ist telemetry.NopLogger.
+func WithLogger(logger telemetry.Logger) Option {
+	return func(o *CommitOrchestrator) {
+		if logger != nil {
+			o.logger = logger
+		}
+	}
+}
+
+// WithMetricsSink meldet Beginn und Ende jedes Commit-Versuchs zusätzlich an
+// sink. Die eigenen CommitMetrics (siehe Metrics) bleiben die Standard-Senke
+// und werden weiterhin befüllt; mehrfache Angabe fügt weitere Senken hinzu.
+func WithMetricsSink(sink telemetry.MetricsSink) Option {
+	return func(o *CommitOrchestrator) {
+		if sink != nil {
+			o.sinks = append(o.sinks, sink)
+		}
+	}
+}
+
+// WithBankPrepareTimeout begrenzt jeden PrepareCommit-Aufruf auf d. Jede Bank
+// erhält einen eigenen, vom Commit-Kontext abgeleiteten Kontext mit dieser
+// Frist, sodass eine langsame Bank nicht das gesamte Budget des Commits
+// aufbraucht. Überschreitet eine Bank die Frist, scheitert der Commit mit
+// einem PrepareError, der context.DeadlineExceeded enthält. d <= 0 hebt die
+// Begrenzung auf.
+func WithBankPrepareTimeout(d time.Duration) Option {
+	return func(o *CommitOrchestrator) {
+		o.prepareTimeout = max(d, 0)
+	}
+}
@@ -0,0 +1,50 @@
+package orchestrator
+
+import (
+	"fmt"
+	"runtime/debug"
+)
+
+// PanicError meldet eine Panik, die der Orchestrator im Publish- oder
+// Abort-Callback einer Bank abgefangen hat.
+type PanicError struct {
+	Bank  string
+	Value any
+	// Stack ist der Stack der panischen Goroutine zum Zeitpunkt der Panik.
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("bank %s panicked: %v", e.Bank, e.Value)
+}
+
+// Unwrap liefert den Wert der Panik, wenn er ein Fehler ist.
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// WithPanicHandler meldet jede abgefangene Panik zusätzlich an handler, etwa
+// für Alarmierung oder Crash-Reports. handler läuft synchron unter der
+// globalen Sperre.
+func WithPanicHandler(handler func(*PanicError)) Option {
+	return func(o *CommitOrchestrator) {
+		o.panicHandler = handler
+	}
+}
+
+// call führt einen Publish- oder Abort-Callback der Bank aus und wandelt eine
+// Panik in einen PanicError um, damit eine fehlerhafte Bank weder den Prozess
+// beendet noch die übrigen Banken ohne Publish oder Abort zurücklässt.
+func (o *CommitOrchestrator) call(bank string, callback func() error) (err error) {
+	defer func() {
+		if value := recover(); value != nil {
+			panicErr := &PanicError{Bank: bank, Value: value, Stack: debug.Stack()}
+			if o.panicHandler != nil {
+				o.panicHandler(panicErr)
+			}
+			err = panicErr
+		}
+	}()
+	return callback()
+}
@@ -0,0 +1,65 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestPanicInAbortIsContained(t *testing.T) {
+	failure := errors.New("prepare failed")
+	aborted := false
+	healthy := &testBank{prepare: func(context.Context) (func(), func(), error) {
+		return nil, func() { aborted = true }, nil
+	}}
+	broken := &testBank{prepare: func(context.Context) (func(), func(), error) {
+		return nil, func() { panic("abort exploded") }, nil
+	}}
+	failing := &testBank{prepare: func(context.Context) (func(), func(), error) {
+		return nil, nil, failure
+	}}
+	var handled []*PanicError
+	o := NewCommitOrchestrator(
+		WithBanks(healthy, broken, failing),
+		WithPanicHandler(func(p *PanicError) { handled = append(handled, p) }),
+	)
+
+	err := o.CommitAll(context.Background())
+	if !errors.Is(err, failure) {
+		t.Fatalf("expected the prepare failure, got %v", err)
+	}
+	var abortErr *AbortError
+	var panicErr *PanicError
+	if !errors.As(err, &abortErr) || abortErr.Bank != "bank-1" || !errors.As(err, &panicErr) || panicErr.Value != "abort exploded" {
+		t.Fatalf("expected an AbortError with the panic of bank-1, got %v", err)
+	}
+	if !aborted {
+		t.Fatal("the remaining banks must still be aborted")
+	}
+	if len(handled) != 1 || handled[0] != panicErr || len(panicErr.Stack) == 0 {
+		t.Fatalf("expected the panic handler to receive the panic, got %v", handled)
+	}
+}
+
+func TestPanicInPublishIsContained(t *testing.T) {
+	published := false
+	broken := &testBank{prepare: func(context.Context) (func(), func(), error) {
+		return func() { panic(errors.New("publish exploded")) }, nil, nil
+	}}
+	healthy := &testBank{prepare: func(context.Context) (func(), func(), error) {
+		return func() { published = true }, nil, nil
+	}}
+	o := NewCommitOrchestrator(WithBanks(broken, healthy))
+
+	report, err := o.CommitAllReport(context.Background())
+	var panicErr *PanicError
+	if !errors.As(err, &panicErr) || panicErr.Bank != "bank-0" || panicErr.Unwrap() == nil {
+		t.Fatalf("expected a PanicError of bank-0, got %v", err)
+	}
+	if !published || o.Version() != 1 {
+		t.Fatalf("the other banks must still publish: published %v version %d", published, o.Version())
+	}
+	if report.Banks[0].Published || !report.Banks[1].Published {
+		t.Fatalf("unexpected report %+v", report.Banks)
+	}
+}
@@ -0,0 +1,76 @@
+package orchestrator
+
+import "context"
+
+// ErrPaused ist der frühere Name von ErrOrchestratorPaused.
+//
+// Deprecated: Stattdessen ErrOrchestratorPaused verwenden.
+var ErrPaused = ErrOrchestratorPaused
+
+// WithBlockWhilePaused lässt CommitAll während einer Pause warten, bis Resume
+// aufgerufen wird oder der Kontext endet, statt sofort ErrOrchestratorPaused zu
+// liefern.
+func WithBlockWhilePaused() Option {
+	return func(o *CommitOrchestrator) {
+		o.blockWhilePaused = true
+	}
+}
+
+// Pause friert die Veröffentlichung ein, etwa für ein Wartungsfenster.
+// Produzenten können weiter in die Banken schreiben; ihre Elemente bleiben
+// pending, bis nach Resume wieder committet wird. Ein bereits laufender Commit
+// wird noch abgeschlossen.
+func (o *CommitOrchestrator) Pause() {
+	o.pauseMu.Lock()
+	defer o.pauseMu.Unlock()
+	if o.resumed == nil {
+		o.resumed = make(chan struct{})
+	}
+}
+
+// Resume hebt eine Pause auf und weckt wartende CommitAll-Aufrufe.
+func (o *CommitOrchestrator) Resume() {
+	o.pauseMu.Lock()
+	defer o.pauseMu.Unlock()
+	if o.resumed != nil {
+		close(o.resumed)
+		o.resumed = nil
+	}
+}
+
+// Paused meldet, ob der Orchestrator pausiert ist.
+func (o *CommitOrchestrator) Paused() bool {
+	return o.pausedChan() != nil
+}
+
+// pausedChan liefert den Kanal, der bei Resume geschlossen wird, oder nil,
+// wenn keine Pause aktiv ist.
+func (o *CommitOrchestrator) pausedChan() <-chan struct{} {
+	o.pauseMu.Lock()
+	defer o.pauseMu.Unlock()
+	return o.resumed
+}
+
+// acquire erwirbt die globale Sperre außerhalb einer Pause. Die Pause wird erst
+// unter der Sperre geprüft, damit kein Commit mehr beginnt, sobald Pause
+// zurückgekehrt ist.
+func (o *CommitOrchestrator) acquire(ctx context.Context) error {
+	for {
+		if err := o.locker.Acquire(ctx); err != nil {
+			return err
+		}
+		resumed := o.pausedChan()
+		if resumed == nil {
+			return nil
+		}
+		o.locker.Release()
+		if !o.blockWhilePaused {
+			return ErrOrchestratorPaused
+		}
+		select {
+		case <-resumed:
+		case <-ctx.Done():
+			return cancelled(ctx.Err())
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPauseRejectsCommits(t *testing.T) {
+	prepared := 0
+	bank := &testBank{prepare: func(context.Context) (func(), func(), error) {
+		prepared++
+		return nil, nil, nil
+	}}
+	locker := &countingLocker{inner: newMutexLocker()}
+	o := NewCommitOrchestrator(WithBanks(bank), WithLocker(locker))
+
+	o.Pause()
+	if !o.Paused() {
+		t.Fatal("expected orchestrator to be paused")
+	}
+	if err := o.CommitAll(context.Background()); !errors.Is(err, ErrPaused) {
+		t.Fatalf("expected ErrPaused, got %v", err)
+	}
+	if prepared != 0 || o.Version() != 0 {
+		t.Fatalf("paused commit must not prepare: prepared %d version %d", prepared, o.Version())
+	}
+	if locker.acquired != locker.released {
+		t.Fatalf("lock must be released: acquired %d released %d", locker.acquired, locker.released)
+	}
+
+	o.Resume()
+	if err := o.CommitAll(context.Background()); err != nil {
+		t.Fatalf("commit after resume: %v", err)
+	}
+	if prepared != 1 || o.Version() != 1 {
+		t.Fatalf("expected one commit after resume: prepared %d version %d", prepared, o.Version())
+	}
+}
+
+func TestPauseBlocksCommitsUntilResume(t *testing.T) {
+	bank := &testBank{prepare: func(context.Context) (func(), func(), error) {
+		return nil, nil, nil
+	}}
+	o := NewCommitOrchestrator(WithBanks(bank), WithBlockWhilePaused())
+	o.Pause()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := o.CommitAll(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the commit to wait until the deadline, got %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- o.CommitAll(context.Background()) }()
+	select {
+	case err := <-done:
+		t.Fatalf("commit finished while paused: %v", err)
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	o.Resume()
+	if err := <-done; err != nil {
+		t.Fatalf("commit after resume: %v", err)
+	}
+	if o.Version() != 1 {
+		t.Fatalf("expected version 1, got %d", o.Version())
+	}
+}
@@ -0,0 +1,47 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBankPrepareTimeoutIsPerBank(t *testing.T) {
+	var deadlines []time.Time
+	var slowErr error
+	slow := &testBank{prepare: func(ctx context.Context) (func(), func(), error) {
+		deadline, _ := ctx.Deadline()
+		deadlines = append(deadlines, deadline)
+		<-ctx.Done()
+		slowErr = ctx.Err()
+		return nil, nil, ctx.Err()
+	}}
+	fast := &testBank{prepare: func(ctx context.Context) (func(), func(), error) {
+		deadline, _ := ctx.Deadline()
+		deadlines = append(deadlines, deadline)
+		return nil, nil, nil
+	}}
+
+	o := NewCommitOrchestrator(WithBanks(fast, fast), WithBankPrepareTimeout(time.Hour))
+	start := time.Now()
+	if err := o.CommitAll(context.Background()); err != nil {
+		t.Fatalf("commit failed: %v", err)
+	}
+	if len(deadlines) != 2 || deadlines[0].Before(start.Add(time.Hour)) || deadlines[1].Before(deadlines[0]) {
+		t.Fatalf("expected a fresh deadline per bank, got %v", deadlines)
+	}
+
+	o = NewCommitOrchestrator(WithBanks(slow, fast), WithBankPrepareTimeout(10*time.Millisecond))
+	err := o.CommitAll(context.Background())
+	var prepareErr *PrepareError
+	if !errors.As(err, &prepareErr) || prepareErr.Bank != "bank-0" || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected a PrepareError with DeadlineExceeded for bank-0, got %v", err)
+	}
+	if errors.Is(err, ErrCommitCancelled) {
+		t.Fatalf("a bank deadline must not cancel the commit, got %v", err)
+	}
+	if !errors.Is(slowErr, context.DeadlineExceeded) {
+		t.Fatalf("expected the slow bank to see its deadline, got %v", slowErr)
+	}
+}
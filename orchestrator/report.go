@@ -0,0 +1,120 @@
+package orchestrator
+
+import (
+	"context"
+	"time"
+)
+
+// CommitReport beschreibt einen Commit-Versuch für Audit-Logs. Wiederholt
+// eine Middleware den Versuch, beschreibt der Bericht den letzten.
+type CommitReport struct {
+	// Version ist die nach dem Versuch sichtbare Version; sie steigt nur bei
+	// Erfolg.
+	Version uint64
+	// Duration ist die Gesamtdauer des Versuchs einschließlich des Wartens
+	// auf die globale Sperre.
+	Duration time.Duration
+	// CommitID ist die ID des Versuchs (siehe CommitIDFromContext).
+	CommitID string
+	// IdempotencyKey ist der Schlüssel, den die Banken über
+	// IdempotencyKeyFromContext erhalten haben.
+	IdempotencyKey string
+	// Banks enthält die Banken, deren PrepareCommit aufgerufen wurde, in
+	// Aufrufreihenfolge.
+	Banks []BankReport
+	// Skipped nennt die Banken, die WithSkipUnhealthyBanks übersprungen hat.
+	Skipped []string
+}
+
+// BankReport beschreibt die Beteiligung einer Bank an einem Commit-Versuch.
+type BankReport struct {
+	Name    string
+	Prepare time.Duration
+	Publish time.Duration
+	// Elements ist die Zahl der vorbereiteten Elemente, sofern die Bank sie
+	// über ReportElements meldet, wie es die Queues dieses Moduls tun.
+	Elements int
+	// Err ist der Fehler aus PrepareCommit.
+	Err error
+	// Published und Aborted halten fest, welcher Callback ausgeführt wurde.
+	Published bool
+	Aborted   bool
+	// AbortErr ist der AbortError, falls der Abbruch fehlschlug.
+	AbortErr error
+	// Compensated meldet eine veröffentlichte Bank, deren Veröffentlichung
+	// nach dem Scheitern einer FallibleBank zurückgenommen wurde.
+	Compensated bool
+}
+
+type elementsKey struct{}
+
+// ReportElements meldet innerhalb von PrepareCommit, wie viele Elemente die
+// Bank für den laufenden Commit vorbereitet hat. Die Zahl erscheint in
+// BankReport.Elements und, sobald die Bank veröffentlicht, im
+// Batch-Histogramm ihrer BankMetrics; außerhalb eines Commits ist der Aufruf
+// wirkungslos.
+func ReportElements(ctx context.Context, n int) {
+	if elements, ok := ctx.Value(elementsKey{}).(*int); ok {
+		*elements += n
+	}
+}
+
+// CommitAllReport führt CommitAll aus und liefert zusätzlich einen Bericht über
+// den Versuch, auch wenn er fehlschlägt.
+func (o *CommitOrchestrator) CommitAllReport(ctx context.Context) (CommitReport, error) {
+	var report CommitReport
+	err := o.commit(ctx, "CommitAll", nil, &report)
+	return report, err
+}
+
+// countElements ergänzt ctx um den Zähler für ReportElements.
+func countElements(ctx context.Context) (context.Context, *int) {
+	elements := new(int)
+	return context.WithValue(ctx, elementsKey{}, elements), elements
+}
+
+// reset leert den Bericht zu Beginn eines Versuchs, damit eine Wiederholung
+// nicht die Einträge des vorigen Versuchs fortschreibt.
+func (r *CommitReport) reset() {
+	if r == nil {
+		return
+	}
+	*r = CommitReport{}
+}
+
+func (r *CommitReport) prepared(name string, elapsed time.Duration, elements *int, err error) {
+	if r == nil {
+		return
+	}
+	r.Banks = append(r.Banks, BankReport{Name: name, Prepare: elapsed, Elements: *elements, Err: err})
+}
+
+func (r *CommitReport) published(i int, elapsed time.Duration) {
+	if r == nil {
+		return
+	}
+	r.Banks[i].Publish = elapsed
+	r.Banks[i].Published = true
+}
+
+func (r *CommitReport) aborted(i int, err error) {
+	if r == nil {
+		return
+	}
+	r.Banks[i].Aborted = true
+	r.Banks[i].AbortErr = err
+}
+
+func (r *CommitReport) compensated(i int) {
+	if r == nil {
+		return
+	}
+	r.Banks[i].Compensated = true
+}
+
+func (r *CommitReport) skipped(name string) {
+	if r == nil {
+		return
+	}
+	r.Skipped = append(r.Skipped, name)
+}
@@ -0,0 +1,161 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCommitAllReportSuccess(t *testing.T) {
+	queue := &namedTestBank{name: "queue", testBank: testBank{prepare: func(ctx context.Context) (func(), func(), error) {
+		ReportElements(ctx, 3)
+		return func() {}, func() {}, nil
+	}}}
+	sick := &healthBank{testBank: testBank{prepare: func(context.Context) (func(), func(), error) {
+		return nil, nil, nil
+	}}, health: errors.New("down")}
+	o := NewCommitOrchestrator(WithBanks(queue, sick), WithSkipUnhealthyBanks())
+
+	report, err := o.CommitAllReport(context.Background())
+	if err != nil {
+		t.Fatalf("commit: %v", err)
+	}
+	if report.Version != 1 || len(report.Banks) != 1 || len(report.Skipped) != 1 || report.Skipped[0] != "bank-1" {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	bank := report.Banks[0]
+	if bank.Name != "queue" || bank.Elements != 3 || !bank.Published || bank.Aborted || bank.Err != nil {
+		t.Fatalf("unexpected bank report: %+v", bank)
+	}
+}
+
+func TestBankMetricsRecordBatchSizes(t *testing.T) {
+	n := 0
+	queue := &namedTestBank{name: "queue", testBank: testBank{prepare: func(ctx context.Context) (func(), func(), error) {
+		n++
+		ReportElements(ctx, n*100)
+		return func() {}, func() {}, nil
+	}}}
+	o := NewCommitOrchestrator(WithBanks(queue))
+	for range 2 {
+		if err := o.CommitAll(context.Background()); err != nil {
+			t.Fatalf("commit: %v", err)
+		}
+	}
+
+	s := o.Snapshot()[0]
+	if s.Elements != 300 || s.BatchP50 > 100 || s.BatchP99 <= 100 {
+		t.Fatalf("unexpected batch sizes: %+v", s)
+	}
+}
+
+func TestCommitAllReportFailure(t *testing.T) {
+	failure := errors.New("prepare failed")
+	first := &testBank{prepare: func(context.Context) (func(), func(), error) {
+		return func() {}, func() {}, nil
+	}}
+	second := &testBank{prepare: func(context.Context) (func(), func(), error) {
+		return nil, nil, failure
+	}}
+	o := NewCommitOrchestrator(WithBanks(first, second))
+
+	report, err := o.CommitAllReport(context.Background())
+	if !errors.Is(err, failure) {
+		t.Fatalf("expected prepare failure, got %v", err)
+	}
+	if report.Version != 0 || len(report.Banks) != 2 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	if !report.Banks[0].Aborted || report.Banks[0].Published {
+		t.Fatalf("expected the first bank to be aborted: %+v", report.Banks[0])
+	}
+	if !errors.Is(report.Banks[1].Err, failure) || report.Banks[1].Aborted {
+		t.Fatalf("expected the second bank to carry the error: %+v", report.Banks[1])
+	}
+}
+
+func TestCommitAllReportDescribesLastRetry(t *testing.T) {
+	transient := errors.New("transient")
+	a := &namedTestBank{name: "a", testBank: testBank{prepare: func(context.Context) (func(), func(), error) {
+		return func() {}, func() {}, nil
+	}}}
+	failed := false
+	b := &namedTestBank{name: "b", testBank: testBank{prepare: func(context.Context) (func(), func(), error) {
+		if !failed {
+			failed = true
+			return nil, nil, transient
+		}
+		return func() {}, func() {}, nil
+	}}}
+	o := NewCommitOrchestrator(WithBanks(a, b))
+	o.Use(func(next CommitFunc) CommitFunc {
+		return func(ctx context.Context) error {
+			if err := next(ctx); !errors.Is(err, transient) {
+				return err
+			}
+			return next(ctx)
+		}
+	})
+
+	report, err := o.CommitAllReport(context.Background())
+	if err != nil {
+		t.Fatalf("commit: %v", err)
+	}
+	if report.Version != 1 || len(report.Banks) != 2 {
+		t.Fatalf("expected a report of the retry only: %+v", report)
+	}
+	for _, bank := range report.Banks {
+		if bank.Err != nil || !bank.Published || bank.Aborted {
+			t.Fatalf("unexpected bank report: %+v", bank)
+		}
+	}
+}
+
+type lingeringLocker struct{ *mutexLocker }
+
+func (l lingeringLocker) Release() {
+	l.mutexLocker.Release()
+	time.Sleep(time.Millisecond)
+}
+
+func TestCommitAllReportVersionUnderConcurrency(t *testing.T) {
+	var mu sync.Mutex
+	prepared := make(map[string]uint64)
+	bank := &testBank{prepare: func(ctx context.Context) (func(), func(), error) {
+		id, _ := CommitIDFromContext(ctx)
+		version, _ := VersionFromContext(ctx)
+		mu.Lock()
+		prepared[id] = version
+		mu.Unlock()
+		return func() {}, func() {}, nil
+	}}
+	o := NewCommitOrchestrator(WithBanks(bank), WithLocker(lingeringLocker{newMutexLocker()}))
+
+	const commits = 16
+	reports := make([]CommitReport, commits)
+	var wg sync.WaitGroup
+	for i := range reports {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var err error
+			if reports[i], err = o.CommitAllReport(t.Context()); err != nil {
+				t.Errorf("commit failed: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	seen := make(map[uint64]bool)
+	for _, report := range reports {
+		if want := prepared[report.CommitID]; report.Version != want {
+			t.Fatalf("commit %s prepared version %d but reported %d", report.CommitID, want, report.Version)
+		}
+		if seen[report.Version] {
+			t.Fatalf("version %d reported twice", report.Version)
+		}
+		seen[report.Version] = true
+	}
+}
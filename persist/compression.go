@@ -0,0 +1,198 @@
+package persist
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// ErrUnknownCompressor is returned when persisted data names a compressor that
+// is neither built in nor configured.
+var ErrUnknownCompressor = errors.New("persist: unknown compressor")
+
+// ErrCorruptStream is returned by DecompressReader when the stream is not one
+// written by CompressWriter.
+var ErrCorruptStream = errors.New("persist: corrupt compressed stream")
+
+// Compressor compresses blocks of persisted data. Name identifies the format
+// and is stored next to the compressed data, so a reader picks the matching
+// compressor; it must not change between releases.
+type Compressor interface {
+	Name() string
+	Compress(dst, src []byte) ([]byte, error)
+	Decompress(dst, src []byte) ([]byte, error)
+}
+
+// Gzip compresses with compress/gzip at the default level.
+type Gzip struct{}
+
+func (Gzip) Name() string { return "gzip" }
+
+func (Gzip) Compress(dst, src []byte) ([]byte, error) {
+	buf := bytes.NewBuffer(dst)
+	zw := gzip.NewWriter(buf)
+	if _, err := zw.Write(src); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (Gzip) Decompress(dst, src []byte) ([]byte, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(src))
+	if err != nil {
+		return nil, err
+	}
+	buf := bytes.NewBuffer(dst)
+	if _, err := buf.ReadFrom(zr); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Zstd compresses with Zstandard, which is faster than gzip at a similar
+// ratio.
+type Zstd struct{}
+
+var (
+	zstdEncoder = sync.OnceValues(func() (*zstd.Encoder, error) { return zstd.NewWriter(nil) })
+	zstdDecoder = sync.OnceValues(func() (*zstd.Decoder, error) { return zstd.NewReader(nil) })
+)
+
+func (Zstd) Name() string { return "zstd" }
+
+func (Zstd) Compress(dst, src []byte) ([]byte, error) {
+	enc, err := zstdEncoder()
+	if err != nil {
+		return nil, err
+	}
+	return enc.EncodeAll(src, dst), nil
+}
+
+func (Zstd) Decompress(dst, src []byte) ([]byte, error) {
+	dec, err := zstdDecoder()
+	if err != nil {
+		return nil, err
+	}
+	return dec.DecodeAll(src, dst)
+}
+
+// WithCompression compresses the log with c. Records appended together, and
+// the checkpoints written on compaction, share one compressed frame, so the
+// ratio grows with batch size. Logs written with a built-in compressor, or
+// without compression, remain readable whatever compressor is configured.
+func WithCompression(c Compressor) DurableOption {
+	return func(cfg *durableConfig) {
+		cfg.compressor = c
+	}
+}
+
+// lookupCompressor returns c when its name matches, or the built-in
+// compressor called name.
+func lookupCompressor(c Compressor, name string) (Compressor, error) {
+	switch {
+	case c != nil && c.Name() == name:
+		return c, nil
+	case name == Gzip{}.Name():
+		return Gzip{}, nil
+	case name == Zstd{}.Name():
+		return Zstd{}, nil
+	}
+	return nil, fmt.Errorf("%w %q", ErrUnknownCompressor, name)
+}
+
+// compressBody compresses plain as opCompressed | uvarint(len(name)) | name |
+// data.
+func compressBody(c Compressor, plain []byte) ([]byte, error) {
+	name := c.Name()
+	body := []byte{byte(opCompressed)}
+	body = binary.AppendUvarint(body, uint64(len(name)))
+	body = append(body, name...)
+	return c.Compress(body, plain)
+}
+
+func decompressBody(c Compressor, body []byte) ([]byte, error) {
+	size, n := binary.Uvarint(body[1:])
+	if n <= 0 || uint64(len(body)-1-n) < size {
+		return nil, ErrCorruptWAL
+	}
+	name := string(body[1+n : 1+n+int(size)])
+	c, err := lookupCompressor(c, name)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := c.Decompress(nil, body[1+n+int(size):])
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCorruptWAL, err)
+	}
+	return plain, nil
+}
+
+var compressedMagic = [4]byte{'C', 'Q', 'Z', '1'}
+
+const compressedChunk = 1 << 20
+
+// CompressWriter returns a writer that compresses everything written to it
+// with c before passing it on to w. Like EncryptWriter it wraps the writer
+// given to queue.SegmentedQueue.WriteSnapshot, and Close must be called to
+// write the final chunk. To combine both, compress first:
+// CompressWriter(encryptWriter, c).
+//
+// The stream is the magic "CQZ1" and the compressor name as uvarint(len) |
+// name, followed by chunks of at most 1 MiB of input, each as
+// flag | uvarint(len) | compressed.
+func CompressWriter(w io.Writer, c Compressor) (io.WriteCloser, error) {
+	name := c.Name()
+	header := append(compressedMagic[:], binary.AppendUvarint(nil, uint64(len(name)))...)
+	header = append(header, name...)
+	if _, err := w.Write(header); err != nil {
+		return nil, err
+	}
+	return &chunkWriter{w: w, size: compressedChunk, seal: func(dst, chunk []byte, final bool) ([]byte, error) {
+		return c.Compress(dst, chunk)
+	}}, nil
+}
+
+// DecompressReader returns a reader over the data of a stream written by
+// CompressWriter. The stream is decompressed with c when the names match or
+// with the built-in compressor it names otherwise; c may be nil.
+func DecompressReader(r io.Reader, c Compressor) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	var magic [4]byte
+	if _, err := io.ReadFull(br, magic[:]); err != nil || magic != compressedMagic {
+		return nil, ErrCorruptStream
+	}
+	size, err := binary.ReadUvarint(br)
+	if err != nil || size > 255 {
+		return nil, ErrCorruptStream
+	}
+	name := make([]byte, size)
+	if _, err := io.ReadFull(br, name); err != nil {
+		return nil, ErrCorruptStream
+	}
+	if c, err = lookupCompressor(c, string(name)); err != nil {
+		return nil, err
+	}
+
+	return &chunkReader{
+		r:       br,
+		limit:   2 * compressedChunk,
+		corrupt: ErrCorruptStream,
+		open: func(dst, sealed []byte, final bool) ([]byte, error) {
+			plain, err := c.Decompress(dst, sealed)
+			if err != nil || len(plain) > compressedChunk {
+				return nil, ErrCorruptStream
+			}
+			return plain, nil
+		},
+	}, nil
+}
@@ -0,0 +1,167 @@
+package persist
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/timzifer/committable_queue/codec"
+	"github.com/timzifer/committable_queue/queue"
+)
+
+func segmentBytes(t *testing.T, dir string) int64 {
+	t.Helper()
+	seqs, err := listSegments(dir)
+	if err != nil {
+		t.Fatalf("list segments: %v", err)
+	}
+	var total int64
+	for _, seq := range seqs {
+		info, err := os.Stat(filepath.Join(dir, segmentName(seq)))
+		if err != nil {
+			t.Fatalf("stat: %v", err)
+		}
+		total += info.Size()
+	}
+	return total
+}
+
+func TestDurableQueueCompressesCheckpoint(t *testing.T) {
+	for _, c := range []Compressor{Gzip{}, Zstd{}} {
+		t.Run(c.Name(), func(t *testing.T) {
+			plainDir, compressedDir := t.TempDir(), t.TempDir()
+			options := map[string][]DurableOption{
+				plainDir:      {WithSyncWrites(false)},
+				compressedDir: {WithSyncWrites(false), WithCompression(c)},
+			}
+			for _, dir := range []string{plainDir, compressedDir} {
+				q := openTestQueue(t, dir, options[dir]...)
+				for i := range 1000 {
+					if err := q.PushBackPending(i % 10); err != nil {
+						t.Fatalf("push failed: %v", err)
+					}
+				}
+				if err := q.Commit(); err != nil {
+					t.Fatalf("commit failed: %v", err)
+				}
+				q.Close()
+			}
+
+			// Reopening compacts both logs into a single checkpoint.
+			for _, dir := range []string{plainDir, compressedDir} {
+				openTestQueue(t, dir, options[dir]...).Close()
+			}
+			if plain, compressed := segmentBytes(t, plainDir), segmentBytes(t, compressedDir); compressed*10 > plain {
+				t.Fatalf("expected at least 10:1 compression, got %d of %d bytes", compressed, plain)
+			}
+
+			q := openTestQueue(t, compressedDir, WithCompression(c), WithEncryption(testKey))
+			got := drain(t, q)
+			q.Close()
+			if len(got) != 1000 || got[999] != 9 {
+				t.Fatalf("expected 1000 restored elements, got %d", len(got))
+			}
+		})
+	}
+}
+
+type reverseCompressor struct{}
+
+func (reverseCompressor) Name() string { return "reverse" }
+
+func (reverseCompressor) Compress(dst, src []byte) ([]byte, error) {
+	for i := len(src) - 1; i >= 0; i-- {
+		dst = append(dst, src[i])
+	}
+	return dst, nil
+}
+
+func (c reverseCompressor) Decompress(dst, src []byte) ([]byte, error) {
+	return c.Compress(dst, src)
+}
+
+func TestDurableQueueRequiresCustomCompressor(t *testing.T) {
+	dir := t.TempDir()
+	q := openTestQueue(t, dir, WithCompression(reverseCompressor{}))
+	if err := q.PushBackPending(1); err != nil {
+		t.Fatalf("push failed: %v", err)
+	}
+	q.Close()
+
+	if _, err := NewDurableQueue[int](dir, codec.Gob[int]{}); !errors.Is(err, ErrUnknownCompressor) {
+		t.Fatalf("expected unknown compressor error, got %v", err)
+	}
+	q = openTestQueue(t, dir, WithCompression(reverseCompressor{}))
+	defer q.Close()
+	if q.LenPending() != 1 {
+		t.Fatalf("expected one pending element, got %d", q.LenPending())
+	}
+}
+
+func TestCompressedEncryptedSnapshotRoundTrip(t *testing.T) {
+	src := queue.NewSegmentedQueue[int]()
+	for i := range 50000 {
+		src.PushBackPending(i % 7)
+	}
+	src.Commit()
+
+	var buf bytes.Buffer
+	ew, err := EncryptWriter(&buf, testKey)
+	if err != nil {
+		t.Fatalf("encrypt writer: %v", err)
+	}
+	cw, err := CompressWriter(ew, Zstd{})
+	if err != nil {
+		t.Fatalf("compress writer: %v", err)
+	}
+	if err := src.WriteSnapshot(cw, codec.JSON[int]{}); err != nil {
+		t.Fatalf("write snapshot: %v", err)
+	}
+	if err := cw.Close(); err != nil {
+		t.Fatalf("close compressor: %v", err)
+	}
+	if err := ew.Close(); err != nil {
+		t.Fatalf("close encryptor: %v", err)
+	}
+
+	dr, err := DecryptReader(&buf, testKey)
+	if err != nil {
+		t.Fatalf("decrypt reader: %v", err)
+	}
+	zr, err := DecompressReader(dr, nil)
+	if err != nil {
+		t.Fatalf("decompress reader: %v", err)
+	}
+	dst := queue.NewSegmentedQueue[int]()
+	if err := dst.ReadSnapshot(zr, codec.JSON[int]{}); err != nil {
+		t.Fatalf("read snapshot: %v", err)
+	}
+	if dst.LenVisible() != 50000 {
+		t.Fatalf("expected 50000 visible elements, got %d", dst.LenVisible())
+	}
+}
+
+func TestDecompressReaderRejectsUnknownCompressor(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := CompressWriter(&buf, reverseCompressor{})
+	if err != nil {
+		t.Fatalf("compress writer: %v", err)
+	}
+	io.WriteString(w, "payload")
+	w.Close()
+
+	if _, err := DecompressReader(bytes.NewReader(buf.Bytes()), nil); !errors.Is(err, ErrUnknownCompressor) {
+		t.Fatalf("expected unknown compressor error, got %v", err)
+	}
+	r, err := DecompressReader(bytes.NewReader(buf.Bytes()), reverseCompressor{})
+	if err != nil {
+		t.Fatalf("decompress reader: %v", err)
+	}
+	data, err := io.ReadAll(r)
+	if err != nil || string(data) != "payload" {
+		t.Fatalf("expected payload, got %q %v", data, err)
+	}
+}
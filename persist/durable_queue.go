@@ -0,0 +1,443 @@
+// Package persist provides a crash-safe variant of the segmented queue.
+//
+// A DurableQueue appends every mutation (push, pop, prepare, publish, abort)
+// to a write-ahead log in a directory of segment files before applying it in
+// memory. On startup the log is replayed to restore both the committed
+// (visible) and the uncommitted (pending) segment. Commits that were prepared
+// but neither published nor aborted when the process stopped are rolled back
+// into the pending segment, exactly as an abort would have done.
+//
+// When the active segment grows beyond the configured size, the queue writes a
+// checkpoint of its current state into a new segment and deletes the older
+// ones, so the log never grows much beyond the live data.
+//
+// WithEncryption seals every log record with AES-GCM so buffered data is
+// encrypted at rest. EncryptWriter and DecryptReader wrap the streams used for
+// queue snapshots the same way. WithCompression, CompressWriter and
+// DecompressReader compress the log and snapshots with a Compressor, gzip or
+// zstd built in; compression is applied before encryption.
+//
+// On Unix systems MappedQueue stores a FIFO queue in a memory-mapped ring
+// buffer instead, for queues that exceed RAM. Its commit barrier is a single
+// offset in the mapped header.
+package persist
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/timzifer/committable_queue/codec"
+	"github.com/timzifer/committable_queue/queue"
+)
+
+// ErrClosed is returned by operations on a closed DurableQueue.
+var ErrClosed = errors.New("persist: queue closed")
+
+const defaultSegmentSize = 64 << 20
+
+type durableConfig struct {
+	options     queue.Options
+	segmentSize int64
+	sync        bool
+	key         []byte
+	compressor  Compressor
+}
+
+// DurableOption configures a DurableQueue.
+type DurableOption func(*durableConfig)
+
+// WithQueueOptions sets MaxLen and DropPolicy, which are applied when a commit
+// is published, just like on queue.SegmentedQueue.
+func WithQueueOptions(options queue.Options) DurableOption {
+	return func(c *durableConfig) {
+		c.options = options
+	}
+}
+
+// WithSegmentSize sets the size in bytes after which the log is compacted into
+// a new segment. Zero disables compaction.
+func WithSegmentSize(size int64) DurableOption {
+	return func(c *durableConfig) {
+		c.segmentSize = size
+	}
+}
+
+// WithSyncWrites controls whether every record is fsynced before the operation
+// returns. It is enabled by default; disabling it trades durability of the
+// most recent operations for throughput.
+func WithSyncWrites(enabled bool) DurableOption {
+	return func(c *durableConfig) {
+		c.sync = enabled
+	}
+}
+
+type stagedBatch[T any] struct {
+	id     uint64
+	values []T
+}
+
+// DurableQueue is a segmented queue whose state survives process restarts.
+type DurableQueue[T any] struct {
+	mu      sync.Mutex
+	codec   codec.Codec[T]
+	options queue.Options
+	log     *wal
+
+	visible ring[T]
+	pending ring[T]
+	staged  []*stagedBatch[T]
+	nextID  uint64
+
+	err    error
+	closed bool
+}
+
+// NewDurableQueue opens (or creates) the queue stored in dir and replays its
+// log. Elements are encoded with c.
+func NewDurableQueue[T any](dir string, c codec.Codec[T], options ...DurableOption) (*DurableQueue[T], error) {
+	cfg := durableConfig{segmentSize: defaultSegmentSize, sync: true}
+	for _, opt := range options {
+		opt(&cfg)
+	}
+
+	fc := frameCodec{compressor: cfg.compressor}
+	if cfg.key != nil {
+		var err error
+		if fc.aead, err = newAEAD(cfg.key); err != nil {
+			return nil, err
+		}
+	}
+
+	dq := &DurableQueue[T]{codec: c, options: cfg.options, nextID: 1}
+
+	log, err := openWAL(dir, cfg.segmentSize, cfg.sync, fc, dq.replay)
+	if err != nil {
+		return nil, err
+	}
+	dq.log = log
+
+	// Commits that were in flight when the process stopped never published.
+	for i := len(dq.staged) - 1; i >= 0; i-- {
+		dq.applyAbort(dq.staged[i].id)
+	}
+
+	checkpoint, err := dq.checkpoint()
+	if err == nil {
+		err = dq.log.rotate(checkpoint)
+	}
+	if err != nil {
+		dq.log.close()
+		return nil, err
+	}
+	return dq, nil
+}
+
+func (dq *DurableQueue[T]) replay(r record) error {
+	switch r.op {
+	case opPushBack, opPushFront:
+		value, err := dq.codec.Unmarshal(r.payload)
+		if err != nil {
+			return err
+		}
+		if r.op == opPushBack {
+			dq.pending.pushBack(value)
+		} else {
+			dq.pending.pushFront(value)
+		}
+	case opPopFront:
+		dq.visible.popFront()
+	case opPopBack:
+		dq.visible.popBack()
+	case opPrepare:
+		dq.applyPrepare(r.id)
+		if r.id >= dq.nextID {
+			dq.nextID = r.id + 1
+		}
+	case opPublish:
+		dq.applyPublish(r.id)
+	case opAbort:
+		dq.applyAbort(r.id)
+	}
+	return nil
+}
+
+// write appends records to the log. The first failure is remembered and fails
+// all later writes. Callers must hold dq.mu.
+func (dq *DurableQueue[T]) write(records ...record) error {
+	if dq.closed {
+		return ErrClosed
+	}
+	if dq.err != nil {
+		return dq.err
+	}
+	if err := dq.log.append(records...); err != nil {
+		dq.err = err
+		return err
+	}
+	return nil
+}
+
+func (dq *DurableQueue[T]) compactIfFull() {
+	if dq.err != nil || !dq.log.full() {
+		return
+	}
+	checkpoint, err := dq.checkpoint()
+	if err == nil {
+		err = dq.log.rotate(checkpoint)
+	}
+	if err != nil {
+		dq.err = err
+	}
+}
+
+// checkpoint returns records that rebuild the current state from scratch:
+// visible elements are pushed, prepared and published; each in-flight staged
+// batch is pushed and prepared; the remaining pending elements are pushed.
+func (dq *DurableQueue[T]) checkpoint() ([]record, error) {
+	var records []record
+	push := func(values []T) error {
+		for _, v := range values {
+			payload, err := dq.codec.Marshal(v)
+			if err != nil {
+				return err
+			}
+			records = append(records, record{op: opPushBack, payload: payload})
+		}
+		return nil
+	}
+
+	if dq.visible.len() > 0 {
+		if err := push(dq.visible.values()); err != nil {
+			return nil, err
+		}
+		records = append(records, record{op: opPrepare, id: 0}, record{op: opPublish, id: 0})
+	}
+	for _, batch := range dq.staged {
+		if err := push(batch.values); err != nil {
+			return nil, err
+		}
+		records = append(records, record{op: opPrepare, id: batch.id})
+	}
+	if err := push(dq.pending.values()); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+func (dq *DurableQueue[T]) push(op recordOp, value T) error {
+	payload, err := dq.codec.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	dq.mu.Lock()
+	defer dq.mu.Unlock()
+
+	if err := dq.write(record{op: op, payload: payload}); err != nil {
+		return err
+	}
+	if op == opPushBack {
+		dq.pending.pushBack(value)
+	} else {
+		dq.pending.pushFront(value)
+	}
+	dq.compactIfFull()
+	return nil
+}
+
+func (dq *DurableQueue[T]) PushBackPending(value T) error {
+	return dq.push(opPushBack, value)
+}
+
+func (dq *DurableQueue[T]) PushFrontPending(value T) error {
+	return dq.push(opPushFront, value)
+}
+
+func (dq *DurableQueue[T]) pop(op recordOp) (zero T, _ bool, _ error) {
+	dq.mu.Lock()
+	defer dq.mu.Unlock()
+
+	if dq.visible.len() == 0 {
+		if dq.closed {
+			return zero, false, ErrClosed
+		}
+		return zero, false, dq.err
+	}
+	if err := dq.write(record{op: op}); err != nil {
+		return zero, false, err
+	}
+	var value T
+	if op == opPopFront {
+		value, _ = dq.visible.popFront()
+	} else {
+		value, _ = dq.visible.popBack()
+	}
+	dq.compactIfFull()
+	return value, true, nil
+}
+
+func (dq *DurableQueue[T]) PopFront() (T, bool, error) {
+	return dq.pop(opPopFront)
+}
+
+func (dq *DurableQueue[T]) PopBack() (T, bool, error) {
+	return dq.pop(opPopBack)
+}
+
+func (dq *DurableQueue[T]) LenVisible() int {
+	dq.mu.Lock()
+	defer dq.mu.Unlock()
+	return dq.visible.len()
+}
+
+func (dq *DurableQueue[T]) LenPending() int {
+	dq.mu.Lock()
+	defer dq.mu.Unlock()
+	return dq.pending.len()
+}
+
+// PrepareCommit detaches the pending segment and logs the prepare. It
+// implements the orchestrator's Bank contract. Failures to log the publish or
+// abort record are reported through Err; the in-memory state is updated
+// regardless, and a replay re-delivers the affected elements as pending.
+func (dq *DurableQueue[T]) PrepareCommit(ctx context.Context) (publish func(), abort func(), err error) {
+	publish, abortErr, err := dq.PrepareCommitV2(ctx)
+	if abortErr != nil {
+		abort = func() { abortErr() }
+	}
+	return publish, abort, err
+}
+
+// PrepareCommitV2 is PrepareCommit for orchestrator.BankV2: abort also returns
+// the error of logging the abort record, so a broken log surfaces in the
+// orchestrator's commit error instead of only through Err.
+func (dq *DurableQueue[T]) PrepareCommitV2(ctx context.Context) (publish func(), abort func() error, err error) {
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	dq.mu.Lock()
+	defer dq.mu.Unlock()
+
+	if dq.pending.len() == 0 {
+		return nil, nil, nil
+	}
+
+	id := dq.nextID
+	if err := dq.write(record{op: opPrepare, id: id}); err != nil {
+		return nil, nil, err
+	}
+	dq.nextID++
+	dq.applyPrepare(id)
+
+	var once sync.Once
+	publish = func() {
+		once.Do(func() { dq.finish(opPublish, id) })
+	}
+	abort = func() (err error) {
+		once.Do(func() { err = dq.finish(opAbort, id) })
+		return err
+	}
+	return publish, abort, nil
+}
+
+// finish logs and applies the end of a commit. It returns the error that kept
+// the record from being logged, if any.
+func (dq *DurableQueue[T]) finish(op recordOp, id uint64) error {
+	dq.mu.Lock()
+	defer dq.mu.Unlock()
+
+	var err error
+	if !dq.closed {
+		if err = dq.err; err == nil {
+			if err = dq.log.append(record{op: op, id: id}); err != nil {
+				dq.err = err
+			}
+		}
+	}
+	if op == opPublish {
+		dq.applyPublish(id)
+	} else {
+		dq.applyAbort(id)
+	}
+	dq.compactIfFull()
+	return err
+}
+
+// Commit prepares and immediately publishes the pending segment.
+func (dq *DurableQueue[T]) Commit() error {
+	publish, _, err := dq.PrepareCommit(context.Background())
+	if err != nil {
+		return err
+	}
+	if publish != nil {
+		publish()
+	}
+	return dq.Err()
+}
+
+// Err returns the first log write error. Once set, all further mutations fail
+// with it; reopening the queue replays the log up to the last intact record.
+func (dq *DurableQueue[T]) Err() error {
+	dq.mu.Lock()
+	defer dq.mu.Unlock()
+	return dq.err
+}
+
+// Close flushes and closes the log. Staged commits that have not been
+// published are restored as pending when the queue is reopened.
+func (dq *DurableQueue[T]) Close() error {
+	dq.mu.Lock()
+	defer dq.mu.Unlock()
+	if dq.closed {
+		return nil
+	}
+	dq.closed = true
+	return dq.log.close()
+}
+
+func (dq *DurableQueue[T]) applyPrepare(id uint64) {
+	batch := &stagedBatch[T]{id: id, values: dq.pending.values()}
+	dq.pending.clear()
+	dq.staged = append(dq.staged, batch)
+}
+
+func (dq *DurableQueue[T]) takeStaged(id uint64) *stagedBatch[T] {
+	for i, batch := range dq.staged {
+		if batch.id == id {
+			dq.staged = append(dq.staged[:i], dq.staged[i+1:]...)
+			return batch
+		}
+	}
+	return nil
+}
+
+func (dq *DurableQueue[T]) applyPublish(id uint64) {
+	batch := dq.takeStaged(id)
+	if batch == nil {
+		return
+	}
+	for _, v := range batch.values {
+		dq.visible.pushBack(v)
+	}
+	if dq.options.MaxLen > 0 {
+		for dq.visible.len() > dq.options.MaxLen {
+			if dq.options.DropPolicy == queue.DropNewest {
+				dq.visible.popBack()
+			} else {
+				dq.visible.popFront()
+			}
+		}
+	}
+}
+
+func (dq *DurableQueue[T]) applyAbort(id uint64) {
+	batch := dq.takeStaged(id)
+	if batch == nil {
+		return
+	}
+	for i := len(batch.values) - 1; i >= 0; i-- {
+		dq.pending.pushFront(batch.values[i])
+	}
+}
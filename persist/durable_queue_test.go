@@ -0,0 +1,281 @@
+package persist
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/timzifer/committable_queue/codec"
+	"github.com/timzifer/committable_queue/queue"
+)
+
+func openTestQueue(t *testing.T, dir string, options ...DurableOption) *DurableQueue[int] {
+	t.Helper()
+	q, err := NewDurableQueue[int](dir, codec.Gob[int]{}, options...)
+	if err != nil {
+		t.Fatalf("open failed: %v", err)
+	}
+	return q
+}
+
+func drain(t *testing.T, q *DurableQueue[int]) []int {
+	t.Helper()
+	var values []int
+	for {
+		v, ok, err := q.PopFront()
+		if err != nil {
+			t.Fatalf("pop failed: %v", err)
+		}
+		if !ok {
+			return values
+		}
+		values = append(values, v)
+	}
+}
+
+func equalInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestDurableQueueRestoresCommittedAndPending(t *testing.T) {
+	dir := t.TempDir()
+	q := openTestQueue(t, dir)
+
+	for _, v := range []int{1, 2, 3} {
+		if err := q.PushBackPending(v); err != nil {
+			t.Fatalf("push failed: %v", err)
+		}
+	}
+	if err := q.Commit(); err != nil {
+		t.Fatalf("commit failed: %v", err)
+	}
+	if _, _, err := q.PopFront(); err != nil {
+		t.Fatalf("pop failed: %v", err)
+	}
+	if err := q.PushBackPending(4); err != nil {
+		t.Fatalf("push failed: %v", err)
+	}
+	if err := q.PushFrontPending(0); err != nil {
+		t.Fatalf("push failed: %v", err)
+	}
+	if err := q.Close(); err != nil {
+		t.Fatalf("close failed: %v", err)
+	}
+	if err := q.PushBackPending(5); err != ErrClosed {
+		t.Fatalf("expected ErrClosed after close, got %v", err)
+	}
+
+	reopened := openTestQueue(t, dir)
+	defer reopened.Close()
+
+	if got := reopened.LenVisible(); got != 2 {
+		t.Fatalf("expected two visible elements after restart, got %d", got)
+	}
+	if got := reopened.LenPending(); got != 2 {
+		t.Fatalf("expected two pending elements after restart, got %d", got)
+	}
+	if values := drain(t, reopened); !equalInts(values, []int{2, 3}) {
+		t.Fatalf("unexpected visible values after restart: %v", values)
+	}
+	if err := reopened.Commit(); err != nil {
+		t.Fatalf("commit failed: %v", err)
+	}
+	if values := drain(t, reopened); !equalInts(values, []int{0, 4}) {
+		t.Fatalf("unexpected pending values after restart: %v", values)
+	}
+}
+
+func TestDurableQueueRollsBackInFlightCommit(t *testing.T) {
+	dir := t.TempDir()
+	q := openTestQueue(t, dir)
+
+	q.PushBackPending(1)
+	q.PushBackPending(2)
+	publish, _, err := q.PrepareCommit(context.Background())
+	if err != nil || publish == nil {
+		t.Fatalf("prepare failed: %v", err)
+	}
+	q.PushFrontPending(0)
+	q.PushBackPending(3)
+
+	// Simulate a crash between prepare and publish.
+	q.Close()
+
+	reopened := openTestQueue(t, dir)
+	defer reopened.Close()
+
+	if got := reopened.LenVisible(); got != 0 {
+		t.Fatalf("unpublished commit must not become visible, got %d", got)
+	}
+	reopened.Commit()
+	if values := drain(t, reopened); !equalInts(values, []int{1, 2, 0, 3}) {
+		t.Fatalf("unexpected order after rollback: %v", values)
+	}
+}
+
+func TestDurableQueueAbortAndPublishAreLogged(t *testing.T) {
+	dir := t.TempDir()
+	q := openTestQueue(t, dir, WithQueueOptions(queue.Options{MaxLen: 2, DropPolicy: queue.DropOldest}))
+
+	q.PushBackPending(1)
+	_, abort, err := q.PrepareCommit(context.Background())
+	if err != nil {
+		t.Fatalf("prepare failed: %v", err)
+	}
+	abort()
+	abort()
+
+	q.PushBackPending(2)
+	q.PushBackPending(3)
+	publish, _, err := q.PrepareCommit(context.Background())
+	if err != nil {
+		t.Fatalf("prepare failed: %v", err)
+	}
+	publish()
+	q.Close()
+
+	reopened := openTestQueue(t, dir, WithQueueOptions(queue.Options{MaxLen: 2, DropPolicy: queue.DropOldest}))
+	defer reopened.Close()
+	if values := drain(t, reopened); !equalInts(values, []int{2, 3}) {
+		t.Fatalf("unexpected values after replay with MaxLen: %v", values)
+	}
+}
+
+func TestDurableQueueAbortReportsLogFailure(t *testing.T) {
+	q := openTestQueue(t, t.TempDir())
+	defer q.Close()
+
+	q.PushBackPending(1)
+	_, abort, err := q.PrepareCommitV2(context.Background())
+	if err != nil {
+		t.Fatalf("prepare failed: %v", err)
+	}
+	failure := errors.New("disk gone")
+	q.mu.Lock()
+	q.err = failure
+	q.mu.Unlock()
+
+	if err := abort(); !errors.Is(err, failure) {
+		t.Fatalf("expected the log failure from abort, got %v", err)
+	}
+	if err := abort(); err != nil {
+		t.Fatalf("a repeated abort must be a no-op, got %v", err)
+	}
+	if q.LenPending() != 1 {
+		t.Fatalf("expected the element to be pending again, got %d", q.LenPending())
+	}
+}
+
+func TestDurableQueueCompactsSegments(t *testing.T) {
+	dir := t.TempDir()
+	q := openTestQueue(t, dir, WithSegmentSize(256), WithSyncWrites(false))
+
+	for i := 0; i < 200; i++ {
+		if err := q.PushBackPending(i); err != nil {
+			t.Fatalf("push failed: %v", err)
+		}
+		if i%10 == 9 {
+			if err := q.Commit(); err != nil {
+				t.Fatalf("commit failed: %v", err)
+			}
+			for j := 0; j < 9; j++ {
+				q.PopFront()
+			}
+		}
+	}
+	q.Close()
+
+	segments, err := filepath.Glob(filepath.Join(dir, "*"+segmentSuffix))
+	if err != nil {
+		t.Fatalf("glob failed: %v", err)
+	}
+	if len(segments) != 1 {
+		t.Fatalf("expected compaction to leave one segment, got %d", len(segments))
+	}
+
+	reopened := openTestQueue(t, dir)
+	defer reopened.Close()
+	values := drain(t, reopened)
+	if len(values) != 20 {
+		t.Fatalf("expected 20 surviving elements, got %d: %v", len(values), values)
+	}
+	for i, v := range values {
+		if v != 180+i {
+			t.Fatalf("unexpected surviving values: %v", values)
+		}
+	}
+}
+
+func TestDurableQueueTruncatesTornTail(t *testing.T) {
+	dir := t.TempDir()
+	q := openTestQueue(t, dir)
+	q.PushBackPending(1)
+	q.Commit()
+	q.Close()
+
+	segments, _ := filepath.Glob(filepath.Join(dir, "*"+segmentSuffix))
+	f, err := os.OpenFile(segments[len(segments)-1], os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatalf("open segment failed: %v", err)
+	}
+	f.Write([]byte{0x20, byte(opPushBack), 0x01})
+	f.Close()
+
+	reopened := openTestQueue(t, dir)
+	defer reopened.Close()
+	if values := drain(t, reopened); !equalInts(values, []int{1}) {
+		t.Fatalf("unexpected values after torn write: %v", values)
+	}
+}
+
+func TestDurableQueueIgnoresSegmentsBeforeCheckpoint(t *testing.T) {
+	dir := t.TempDir()
+	q := openTestQueue(t, dir)
+	for _, v := range []int{1, 2, 3} {
+		q.PushBackPending(v)
+	}
+	q.Commit()
+	q.Close()
+
+	// Keep the segments the next open rotates away, and put them back as if
+	// the process had stopped before rotate removed them.
+	old, _ := filepath.Glob(filepath.Join(dir, "*"+segmentSuffix))
+	saved := make(map[string][]byte)
+	for _, path := range old {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("read segment failed: %v", err)
+		}
+		saved[path] = data
+	}
+	openTestQueue(t, dir).Close()
+	for path, data := range saved {
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			t.Fatalf("restore segment failed: %v", err)
+		}
+	}
+
+	info, err := InspectWAL(dir)
+	if err != nil {
+		t.Fatalf("inspect failed: %v", err)
+	}
+	if len(info.Visible) != 3 || !info.Segments[0].Superseded || info.Segments[len(info.Segments)-1].Superseded {
+		t.Fatalf("unexpected inspection: %d visible, segments %+v", len(info.Visible), info.Segments)
+	}
+
+	reopened := openTestQueue(t, dir)
+	defer reopened.Close()
+	if values := drain(t, reopened); !equalInts(values, []int{1, 2, 3}) {
+		t.Fatalf("expected each element once, got %v", values)
+	}
+}
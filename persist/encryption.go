@@ -0,0 +1,138 @@
+package persist
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// ErrDecrypt is returned when encrypted data cannot be authenticated, because
+// the key is missing or wrong or because the data was tampered with.
+var ErrDecrypt = errors.New("persist: cannot decrypt: missing or wrong key")
+
+// WithEncryption encrypts every log record with AES-GCM under key, which must
+// be 16, 24 or 32 bytes long. Each record gets its own random nonce, so a key
+// should not seal more than about 2^32 records. A log written without
+// encryption is still replayed and is re-encrypted by the checkpoint written
+// on open; a log that was encrypted cannot be opened without its key.
+func WithEncryption(key []byte) DurableOption {
+	return func(c *durableConfig) {
+		c.key = append([]byte(nil), key...)
+	}
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// sealBody encrypts body as opSealed | nonce | ciphertext. The leading op byte
+// is authenticated as additional data.
+func sealBody(aead cipher.AEAD, body []byte) []byte {
+	sealed := make([]byte, 1+aead.NonceSize(), 1+aead.NonceSize()+len(body)+aead.Overhead())
+	sealed[0] = byte(opSealed)
+	if _, err := rand.Read(sealed[1:]); err != nil {
+		panic("persist: reading random nonce: " + err.Error())
+	}
+	return aead.Seal(sealed, sealed[1:], body, sealed[:1])
+}
+
+func openBody(aead cipher.AEAD, sealed []byte) ([]byte, error) {
+	if aead == nil || len(sealed) < 1+aead.NonceSize() {
+		return nil, ErrDecrypt
+	}
+	nonce := sealed[1 : 1+aead.NonceSize()]
+	body, err := aead.Open(nil, nonce, sealed[1+aead.NonceSize():], sealed[:1])
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	return body, nil
+}
+
+var encryptedMagic = [4]byte{'C', 'Q', 'E', '1'}
+
+const (
+	encryptedChunk       = 64 << 10
+	encryptedNoncePrefix = 8
+)
+
+// EncryptWriter returns a writer that encrypts everything written to it with
+// AES-GCM under key before passing it on to w. It is meant to wrap the writer
+// given to queue.SegmentedQueue.WriteSnapshot; Close must be called to write
+// the final chunk and does not close w.
+//
+// The stream is the magic "CQE1" and a random nonce prefix, followed by chunks
+// of at most 64 KiB, each as flag | uvarint(len) | ciphertext. Chunks are
+// numbered in their nonce and the last one is flagged, so DecryptReader
+// detects reordered, dropped and truncated chunks.
+func EncryptWriter(w io.Writer, key []byte) (io.WriteCloser, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce[:encryptedNoncePrefix]); err != nil {
+		return nil, err
+	}
+	header := append(encryptedMagic[:], nonce[:encryptedNoncePrefix]...)
+	if _, err := w.Write(header); err != nil {
+		return nil, err
+	}
+
+	var seq uint32
+	return &chunkWriter{w: w, size: encryptedChunk, seal: func(dst, chunk []byte, final bool) ([]byte, error) {
+		binary.BigEndian.PutUint32(nonce[encryptedNoncePrefix:], seq)
+		seq++
+		return aead.Seal(dst, nonce, chunk, finalFlag(final)), nil
+	}}, nil
+}
+
+// DecryptReader returns a reader over the plaintext of a stream written by
+// EncryptWriter. Reads fail with ErrDecrypt when the key is wrong or the
+// stream was modified, and with io.ErrUnexpectedEOF when it ends before its
+// final chunk.
+func DecryptReader(r io.Reader, key []byte) (io.Reader, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	br := bufio.NewReader(r)
+	header := make([]byte, len(encryptedMagic)+encryptedNoncePrefix)
+	if _, err := io.ReadFull(br, header); err != nil || [4]byte(header[:4]) != encryptedMagic {
+		return nil, ErrDecrypt
+	}
+	nonce := make([]byte, aead.NonceSize())
+	copy(nonce, header[len(encryptedMagic):])
+
+	var seq uint32
+	return &chunkReader{
+		r:       br,
+		limit:   encryptedChunk + uint64(aead.Overhead()),
+		corrupt: ErrDecrypt,
+		open: func(dst, sealed []byte, final bool) ([]byte, error) {
+			binary.BigEndian.PutUint32(nonce[encryptedNoncePrefix:], seq)
+			seq++
+			plain, err := aead.Open(dst, nonce, sealed, finalFlag(final))
+			if err != nil {
+				return nil, ErrDecrypt
+			}
+			return plain, nil
+		},
+	}, nil
+}
+
+// finalFlag is the additional data that binds a chunk to its position as the
+// final one or not.
+func finalFlag(final bool) []byte {
+	if final {
+		return []byte{1}
+	}
+	return []byte{0}
+}
@@ -0,0 +1,168 @@
+package persist
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/timzifer/committable_queue/codec"
+	"github.com/timzifer/committable_queue/queue"
+)
+
+var testKey = bytes.Repeat([]byte{7}, 32)
+
+func TestDurableQueueEncryptsLog(t *testing.T) {
+	dir := t.TempDir()
+	q := openTestQueue(t, dir, WithEncryption(testKey))
+	if err := q.PushBackPending(0x5eed); err != nil {
+		t.Fatalf("push failed: %v", err)
+	}
+	if err := q.Commit(); err != nil {
+		t.Fatalf("commit failed: %v", err)
+	}
+	if err := q.PushBackPending(2); err != nil {
+		t.Fatalf("push failed: %v", err)
+	}
+	if err := q.Close(); err != nil {
+		t.Fatalf("close failed: %v", err)
+	}
+
+	plain, err := codec.Gob[int]{}.Marshal(0x5eed)
+	if err != nil {
+		t.Fatalf("marshal failed: %v", err)
+	}
+	seqs, err := listSegments(dir)
+	if err != nil || len(seqs) == 0 {
+		t.Fatalf("list segments: %v %v", seqs, err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, segmentName(seqs[len(seqs)-1])))
+	if err != nil {
+		t.Fatalf("read failed: %v", err)
+	}
+	if bytes.Contains(data, plain) {
+		t.Fatal("log contains the plain element")
+	}
+
+	q = openTestQueue(t, dir, WithEncryption(testKey))
+	if got := drain(t, q); !equalInts(got, []int{0x5eed}) {
+		t.Fatalf("expected [%d] visible, got %v", 0x5eed, got)
+	}
+	if q.LenPending() != 1 {
+		t.Fatalf("expected one pending element, got %d", q.LenPending())
+	}
+	q.Close()
+}
+
+func TestDurableQueueRejectsWrongKey(t *testing.T) {
+	dir := t.TempDir()
+	q := openTestQueue(t, dir, WithEncryption(testKey))
+	if err := q.PushBackPending(1); err != nil {
+		t.Fatalf("push failed: %v", err)
+	}
+	q.Close()
+
+	wrong := bytes.Repeat([]byte{8}, 32)
+	if _, err := NewDurableQueue[int](dir, codec.Gob[int]{}, WithEncryption(wrong)); !errors.Is(err, ErrDecrypt) {
+		t.Fatalf("expected decrypt error with wrong key, got %v", err)
+	}
+	if _, err := NewDurableQueue[int](dir, codec.Gob[int]{}); !errors.Is(err, ErrDecrypt) {
+		t.Fatalf("expected decrypt error without key, got %v", err)
+	}
+
+	// The failed opens must not have truncated the log.
+	q = openTestQueue(t, dir, WithEncryption(testKey))
+	if q.LenPending() != 1 {
+		t.Fatalf("expected the pending element to survive, got %d", q.LenPending())
+	}
+	q.Close()
+}
+
+func TestDurableQueueEncryptsPlainLog(t *testing.T) {
+	dir := t.TempDir()
+	q := openTestQueue(t, dir)
+	if err := q.PushBackPending(1); err != nil {
+		t.Fatalf("push failed: %v", err)
+	}
+	q.Close()
+
+	q = openTestQueue(t, dir, WithEncryption(testKey))
+	q.Close()
+	if _, err := NewDurableQueue[int](dir, codec.Gob[int]{}); !errors.Is(err, ErrDecrypt) {
+		t.Fatalf("expected the migrated log to be encrypted, got %v", err)
+	}
+}
+
+func TestEncryptedSnapshotRoundTrip(t *testing.T) {
+	src := queue.NewSegmentedQueue[int]()
+	for i := range 20000 {
+		src.PushBackPending(i)
+	}
+	src.Commit()
+	src.PushBackPending(-1)
+
+	var buf bytes.Buffer
+	w, err := EncryptWriter(&buf, testKey)
+	if err != nil {
+		t.Fatalf("encrypt writer: %v", err)
+	}
+	if err := src.WriteSnapshot(w, nil); err != nil {
+		t.Fatalf("write snapshot: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	r, err := DecryptReader(bytes.NewReader(buf.Bytes()), testKey)
+	if err != nil {
+		t.Fatalf("decrypt reader: %v", err)
+	}
+	dst := queue.NewSegmentedQueue[int]()
+	if err := dst.ReadSnapshot(r, nil); err != nil {
+		t.Fatalf("read snapshot: %v", err)
+	}
+	if dst.LenVisible() != 20000 {
+		t.Fatalf("expected 20000 visible elements, got %d", dst.LenVisible())
+	}
+	dst.Commit()
+	if v, ok := dst.PopBack(); !ok || v != -1 {
+		t.Fatalf("expected the pending element -1 behind the visible ones, got %d %v", v, ok)
+	}
+}
+
+func TestDecryptReaderDetectsTampering(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := EncryptWriter(&buf, testKey)
+	if err != nil {
+		t.Fatalf("encrypt writer: %v", err)
+	}
+	w.Write(bytes.Repeat([]byte("x"), encryptedChunk+10))
+	w.Close()
+	sealed := buf.Bytes()
+
+	read := func(data []byte) error {
+		r, err := DecryptReader(bytes.NewReader(data), testKey)
+		if err != nil {
+			return err
+		}
+		_, err = io.ReadAll(r)
+		return err
+	}
+	if err := read(sealed); err != nil {
+		t.Fatalf("intact stream failed: %v", err)
+	}
+
+	flipped := bytes.Clone(sealed)
+	flipped[len(flipped)-1] ^= 1
+	if err := read(flipped); !errors.Is(err, ErrDecrypt) {
+		t.Fatalf("expected decrypt error for modified stream, got %v", err)
+	}
+
+	// Cutting the stream after the first chunk drops the final one.
+	firstChunk := len(encryptedMagic) + encryptedNoncePrefix + 1 + uvarintLen(encryptedChunk+16) + encryptedChunk + 16
+	if err := read(sealed[:firstChunk]); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("expected unexpected EOF for truncated stream, got %v", err)
+	}
+}
@@ -0,0 +1,145 @@
+package persist
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// WALInfo describes the log of a DurableQueue as it is stored on disk. The
+// elements are kept as the bytes the queue's codec produced.
+type WALInfo struct {
+	Segments []SegmentInfo
+	Visible  [][]byte
+	Pending  [][]byte
+	// Staged lists commits that were prepared but neither published nor
+	// aborted. Opening the queue returns their elements to Pending.
+	Staged []StagedCommit
+}
+
+// SegmentInfo describes one segment file of a log.
+type SegmentInfo struct {
+	Name    string
+	Size    int64
+	Valid   int64
+	Records int
+	// Err is the reason the segment could not be read past Valid bytes.
+	Err error
+	// Superseded marks a segment older than the newest checkpoint, left over
+	// from an interrupted rotation. Opening the queue ignores it.
+	Superseded bool
+}
+
+// StagedCommit is a prepared commit recorded in a log.
+type StagedCommit struct {
+	ID       uint64
+	Elements [][]byte
+}
+
+// Err returns the first segment error that prevents the queue from being
+// opened. Damage at the end of the last segment is not one of them: it is a
+// torn write, and opening the queue truncates it.
+func (info *WALInfo) Err() error {
+	for i, segment := range info.Segments {
+		last := i == len(info.Segments)-1
+		if segment.Err != nil && !segment.Superseded && !(last && errors.Is(segment.Err, ErrCorruptWAL)) {
+			return segment.Err
+		}
+	}
+	return nil
+}
+
+// InspectWAL reads the log in dir without modifying it. options must supply
+// the key and custom compressor the log was written with; other options are
+// ignored. The state is replayed from the newest checkpoint up to the first
+// damaged segment; superseded and later segments are only checked for intact
+// framing.
+func InspectWAL(dir string, options ...DurableOption) (*WALInfo, error) {
+	var cfg durableConfig
+	for _, opt := range options {
+		opt(&cfg)
+	}
+	fc := frameCodec{compressor: cfg.compressor}
+	if cfg.key != nil {
+		var err error
+		if fc.aead, err = newAEAD(cfg.key); err != nil {
+			return nil, err
+		}
+	}
+
+	seqs, err := listSegments(dir)
+	if err != nil {
+		return nil, err
+	}
+	dq := &DurableQueue[[]byte]{codec: rawCodec{}, nextID: 1}
+	info := &WALInfo{}
+	damaged := false
+	start := checkpointStart(dir, seqs, fc)
+	for i, seq := range seqs {
+		segment := SegmentInfo{Name: segmentName(seq), Superseded: i < start}
+		f, err := os.Open(filepath.Join(dir, segment.Name))
+		if err != nil {
+			return nil, err
+		}
+		if stat, err := f.Stat(); err == nil {
+			segment.Size = stat.Size()
+		}
+		segment.Valid, segment.Err = readRecords(f, fc, func(r record) error {
+			segment.Records++
+			if damaged || segment.Superseded {
+				return nil
+			}
+			return dq.replay(r)
+		})
+		f.Close()
+		damaged = damaged || segment.Err != nil && !segment.Superseded
+		info.Segments = append(info.Segments, segment)
+	}
+
+	info.Visible = dq.visible.values()
+	info.Pending = dq.pending.values()
+	for _, batch := range dq.staged {
+		info.Staged = append(info.Staged, StagedCommit{ID: batch.id, Elements: batch.values})
+	}
+	return info, nil
+}
+
+// UnwrapSnapshot returns a reader over the snapshot in r with any layers added
+// by EncryptWriter and CompressWriter removed, so it can be passed to
+// queue.InspectSnapshot or ReadSnapshot. key decrypts encrypted streams;
+// compressed ones must use a built-in compressor.
+func UnwrapSnapshot(r io.Reader, key []byte) (io.Reader, error) {
+	for {
+		br := bufio.NewReader(r)
+		magic, _ := br.Peek(4)
+		var err error
+		switch {
+		case bytes.Equal(magic, encryptedMagic[:]):
+			if key == nil {
+				return nil, ErrDecrypt
+			}
+			r, err = DecryptReader(br, key)
+		case bytes.Equal(magic, compressedMagic[:]):
+			r, err = DecompressReader(br, nil)
+		default:
+			return br, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// rawCodec keeps elements as their encoded bytes.
+type rawCodec struct{}
+
+func (rawCodec) Marshal(value []byte) ([]byte, error) {
+	return value, nil
+}
+
+func (rawCodec) Unmarshal(data []byte) ([]byte, error) {
+	return append([]byte(nil), data...), nil
+}
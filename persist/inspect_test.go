@@ -0,0 +1,117 @@
+package persist
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/timzifer/committable_queue/codec"
+	"github.com/timzifer/committable_queue/queue"
+)
+
+func TestInspectWALReportsCommitBoundary(t *testing.T) {
+	dir := t.TempDir()
+	q := openTestQueue(t, dir, WithEncryption(testKey))
+	for _, v := range []int{1, 2, 3} {
+		if err := q.PushBackPending(v); err != nil {
+			t.Fatalf("push failed: %v", err)
+		}
+	}
+	if err := q.Commit(); err != nil {
+		t.Fatalf("commit failed: %v", err)
+	}
+	q.PushBackPending(4)
+	if _, _, err := q.PrepareCommit(t.Context()); err != nil {
+		t.Fatalf("prepare failed: %v", err)
+	}
+	q.PushBackPending(5)
+	q.Close()
+
+	info, err := InspectWAL(dir, WithEncryption(testKey))
+	if err != nil {
+		t.Fatalf("inspect failed: %v", err)
+	}
+	if err := info.Err(); err != nil {
+		t.Fatalf("unexpected damage: %v", err)
+	}
+	if len(info.Visible) != 3 || len(info.Pending) != 1 || len(info.Staged) != 1 || len(info.Staged[0].Elements) != 1 {
+		t.Fatalf("expected 3 visible, 1 staged and 1 pending, got %+v", info)
+	}
+	v, err := codec.Gob[int]{}.Unmarshal(info.Staged[0].Elements[0])
+	if err != nil || v != 4 {
+		t.Fatalf("expected staged element 4, got %d %v", v, err)
+	}
+
+	// Inspecting must leave the log untouched, so the staged commit is still
+	// rolled back when the queue is opened.
+	q = openTestQueue(t, dir, WithEncryption(testKey))
+	defer q.Close()
+	if q.LenPending() != 2 {
+		t.Fatalf("expected 2 pending elements after open, got %d", q.LenPending())
+	}
+}
+
+func TestInspectWALReportsDamage(t *testing.T) {
+	dir := t.TempDir()
+	q := openTestQueue(t, dir)
+	q.PushBackPending(1)
+	q.Close()
+
+	seqs, _ := listSegments(dir)
+	path := filepath.Join(dir, segmentName(seqs[len(seqs)-1]))
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read failed: %v", err)
+	}
+	if err := os.WriteFile(path, data[:len(data)-1], 0o644); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+
+	info, err := InspectWAL(dir)
+	if err != nil {
+		t.Fatalf("inspect failed: %v", err)
+	}
+	last := info.Segments[len(info.Segments)-1]
+	if !errors.Is(last.Err, ErrCorruptWAL) || info.Err() != nil {
+		t.Fatalf("expected a recoverable torn tail, got %v / %v", last.Err, info.Err())
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, segmentName(seqs[len(seqs)-1]+1)), nil, 0o644); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	if info, err = InspectWAL(dir); err != nil {
+		t.Fatalf("inspect failed: %v", err)
+	}
+	if !errors.Is(info.Err(), ErrCorruptWAL) {
+		t.Fatalf("expected damage in a sealed segment, got %v", info.Err())
+	}
+}
+
+func TestUnwrapSnapshot(t *testing.T) {
+	q := queue.NewSegmentedQueue[int]()
+	q.PushBackPending(1)
+	q.Commit()
+
+	var buf bytes.Buffer
+	ew, _ := EncryptWriter(&buf, testKey)
+	cw, _ := CompressWriter(ew, Gzip{})
+	if err := q.WriteSnapshot(cw, nil); err != nil {
+		t.Fatalf("write snapshot: %v", err)
+	}
+	cw.Close()
+	ew.Close()
+
+	if _, err := UnwrapSnapshot(bytes.NewReader(buf.Bytes()), nil); !errors.Is(err, ErrDecrypt) {
+		t.Fatalf("expected decrypt error without key, got %v", err)
+	}
+	r, err := UnwrapSnapshot(bytes.NewReader(buf.Bytes()), testKey)
+	if err != nil {
+		t.Fatalf("unwrap: %v", err)
+	}
+	info, err := queue.InspectSnapshot(r)
+	if err != nil || len(info.Visible) != 1 {
+		t.Fatalf("expected one visible element, got %+v %v", info, err)
+	}
+}
@@ -0,0 +1,366 @@
+//go:build unix
+
+package persist
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/timzifer/committable_queue/codec"
+	"github.com/timzifer/committable_queue/queue"
+)
+
+var (
+	// ErrFull is returned when a push does not fit into the mapped file.
+	ErrFull = errors.New("persist: mapped queue full")
+	// ErrCommitInProgress is returned by MappedQueue.PrepareCommit while an
+	// earlier prepared commit has been neither published nor aborted.
+	ErrCommitInProgress = errors.New("persist: commit already in progress")
+	// ErrCorruptMapping is returned when an existing file does not carry a
+	// valid mapped queue header.
+	ErrCorruptMapping = errors.New("persist: corrupt mapped queue file")
+)
+
+var mappedMagic = [8]byte{'C', 'Q', 'M', 'M', 'A', 'P', '0', '1'}
+
+// Header layout. Offsets are logical byte positions that only ever grow; the
+// physical position in the data region is the offset modulo the capacity.
+const (
+	hdrMagic    = 0
+	hdrCapacity = 8
+	hdrHead     = 16 // first visible record
+	hdrCommit   = 24 // commit barrier: end of visible, start of pending
+	hdrTail     = 32 // end of pending
+	hdrVisible  = 40 // records in [head, commit)
+	hdrPending  = 48 // records in [commit, tail)
+	headerSize  = 64
+
+	recordHeaderSize = 4
+)
+
+// MappedQueue is a segmented queue stored in a memory-mapped file, for queues
+// that do not fit in RAM. Records live in a ring buffer behind a small header;
+// visible elements occupy [head, commit) and pending elements [commit, tail).
+// Publishing a commit only moves the commit barrier in the header, so the
+// operating system pages records in and out as needed and the queue never
+// holds more than one element in memory.
+//
+// Because both segments are contiguous byte ranges, MappedQueue only offers
+// the FIFO subset of SegmentedQueue: PushBackPending and PopFront. At most one
+// commit can be prepared at a time, and overflow is limited to DropOldest.
+type MappedQueue[T any] struct {
+	mu      sync.Mutex
+	codec   codec.Codec[T]
+	options queue.Options
+	file    *os.File
+	mapping []byte
+	data    []byte
+
+	staged      bool
+	stagedEnd   uint64
+	stagedCount uint64
+	closed      bool
+}
+
+// NewMappedQueue opens the queue stored at path, creating it with room for
+// capacity bytes of records when the file does not exist. Each record takes
+// four bytes plus the encoded element. The capacity of an existing file is
+// read from its header and the argument is ignored.
+//
+// Elements of a commit that was prepared but not published before the process
+// stopped are still pending after reopening.
+func NewMappedQueue[T any](path string, capacity int64, c codec.Codec[T], options queue.Options) (*MappedQueue[T], error) {
+	if options.MaxLen > 0 && options.DropPolicy != queue.DropOldest {
+		return nil, fmt.Errorf("persist: mapped queue does not support drop policy %s", options.DropPolicy)
+	}
+
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	created := info.Size() == 0
+	size := info.Size()
+	if created {
+		if capacity <= recordHeaderSize {
+			file.Close()
+			return nil, fmt.Errorf("persist: mapped queue capacity %d too small", capacity)
+		}
+		size = headerSize + capacity
+		if err := file.Truncate(size); err != nil {
+			file.Close()
+			return nil, err
+		}
+	} else if size < headerSize {
+		file.Close()
+		return nil, ErrCorruptMapping
+	}
+
+	mapping, err := unix.Mmap(int(file.Fd()), 0, int(size), unix.PROT_READ|unix.PROT_WRITE, unix.MAP_SHARED)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	mq := &MappedQueue[T]{codec: c, options: options, file: file, mapping: mapping, data: mapping[headerSize:]}
+	if created {
+		copy(mapping[hdrMagic:], mappedMagic[:])
+		mq.set(hdrCapacity, uint64(capacity))
+		err = mq.sync()
+	} else {
+		err = mq.validate()
+	}
+	if err != nil {
+		mq.unmap()
+		return nil, err
+	}
+	return mq, nil
+}
+
+func (mq *MappedQueue[T]) validate() error {
+	if [8]byte(mq.mapping[hdrMagic:hdrMagic+8]) != mappedMagic {
+		return ErrCorruptMapping
+	}
+	capacity := mq.get(hdrCapacity)
+	head, commit, tail := mq.get(hdrHead), mq.get(hdrCommit), mq.get(hdrTail)
+	if capacity != uint64(len(mq.data)) || head > commit || commit > tail || tail-head > capacity {
+		return ErrCorruptMapping
+	}
+	return nil
+}
+
+func (mq *MappedQueue[T]) get(field int) uint64 {
+	return binary.LittleEndian.Uint64(mq.mapping[field:])
+}
+
+func (mq *MappedQueue[T]) set(field int, v uint64) {
+	binary.LittleEndian.PutUint64(mq.mapping[field:], v)
+}
+
+// copyIn writes src into the ring at logical offset off, wrapping at the end of
+// the data region.
+func (mq *MappedQueue[T]) copyIn(off uint64, src []byte) {
+	pos := off % uint64(len(mq.data))
+	n := copy(mq.data[pos:], src)
+	copy(mq.data, src[n:])
+}
+
+// copyOut reads len(dst) bytes from the ring at logical offset off.
+func (mq *MappedQueue[T]) copyOut(off uint64, dst []byte) {
+	pos := off % uint64(len(mq.data))
+	n := copy(dst, mq.data[pos:])
+	copy(dst[n:], mq.data)
+}
+
+func (mq *MappedQueue[T]) recordLen(off uint64) uint64 {
+	var buf [recordHeaderSize]byte
+	mq.copyOut(off, buf[:])
+	return uint64(binary.LittleEndian.Uint32(buf[:]))
+}
+
+func (mq *MappedQueue[T]) sync() error {
+	return unix.Msync(mq.mapping, unix.MS_SYNC)
+}
+
+func (mq *MappedQueue[T]) unmap() error {
+	err := unix.Munmap(mq.mapping)
+	if cerr := mq.file.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// PushBackPending appends value to the pending segment. It returns ErrFull
+// when the ring has no room for the encoded element.
+func (mq *MappedQueue[T]) PushBackPending(value T) error {
+	payload, err := mq.codec.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	mq.mu.Lock()
+	defer mq.mu.Unlock()
+
+	if mq.closed {
+		return ErrClosed
+	}
+	head, tail := mq.get(hdrHead), mq.get(hdrTail)
+	need := uint64(recordHeaderSize + len(payload))
+	if uint64(len(payload)) > 1<<32-1 || tail-head+need > uint64(len(mq.data)) {
+		return ErrFull
+	}
+
+	var prefix [recordHeaderSize]byte
+	binary.LittleEndian.PutUint32(prefix[:], uint32(len(payload)))
+	mq.copyIn(tail, prefix[:])
+	mq.copyIn(tail+recordHeaderSize, payload)
+
+	// The record is complete before the header points past it.
+	mq.set(hdrPending, mq.get(hdrPending)+1)
+	mq.set(hdrTail, tail+need)
+	return nil
+}
+
+// PopFront removes and returns the oldest visible element. When the element
+// cannot be decoded it is left in place and the error is returned.
+func (mq *MappedQueue[T]) PopFront() (zero T, _ bool, _ error) {
+	mq.mu.Lock()
+	defer mq.mu.Unlock()
+
+	if mq.closed {
+		return zero, false, ErrClosed
+	}
+	if mq.get(hdrVisible) == 0 {
+		return zero, false, nil
+	}
+
+	head := mq.get(hdrHead)
+	length := mq.recordLen(head)
+	payload := make([]byte, length)
+	mq.copyOut(head+recordHeaderSize, payload)
+	value, err := mq.codec.Unmarshal(payload)
+	if err != nil {
+		return zero, false, err
+	}
+
+	mq.set(hdrHead, head+recordHeaderSize+length)
+	mq.set(hdrVisible, mq.get(hdrVisible)-1)
+	return value, true, nil
+}
+
+func (mq *MappedQueue[T]) LenVisible() int {
+	mq.mu.Lock()
+	defer mq.mu.Unlock()
+	if mq.closed {
+		return 0
+	}
+	return int(mq.get(hdrVisible))
+}
+
+func (mq *MappedQueue[T]) LenPending() int {
+	mq.mu.Lock()
+	defer mq.mu.Unlock()
+	if mq.closed {
+		return 0
+	}
+	return int(mq.get(hdrPending) - mq.stagedCount)
+}
+
+// PrepareCommit stages the current pending segment. Pushes made after the
+// prepare stay pending. Publish moves the commit barrier past the staged
+// records and flushes the mapping; abort leaves them pending. It implements
+// the orchestrator's Bank contract.
+func (mq *MappedQueue[T]) PrepareCommit(ctx context.Context) (publish func(), abort func(), err error) {
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	mq.mu.Lock()
+	defer mq.mu.Unlock()
+
+	if mq.closed {
+		return nil, nil, ErrClosed
+	}
+	if mq.staged {
+		return nil, nil, ErrCommitInProgress
+	}
+	count := mq.get(hdrPending)
+	if count == 0 {
+		return nil, nil, nil
+	}
+	mq.staged, mq.stagedEnd, mq.stagedCount = true, mq.get(hdrTail), count
+
+	var once sync.Once
+	publish = func() {
+		once.Do(mq.publish)
+	}
+	abort = func() {
+		once.Do(mq.abort)
+	}
+	return publish, abort, nil
+}
+
+func (mq *MappedQueue[T]) publish() {
+	mq.mu.Lock()
+	defer mq.mu.Unlock()
+
+	if mq.closed {
+		return
+	}
+	mq.set(hdrVisible, mq.get(hdrVisible)+mq.stagedCount)
+	mq.set(hdrPending, mq.get(hdrPending)-mq.stagedCount)
+	mq.set(hdrCommit, mq.stagedEnd)
+
+	if mq.options.MaxLen > 0 {
+		head := mq.get(hdrHead)
+		visible := mq.get(hdrVisible)
+		for visible > uint64(mq.options.MaxLen) {
+			head += recordHeaderSize + mq.recordLen(head)
+			visible--
+		}
+		mq.set(hdrHead, head)
+		mq.set(hdrVisible, visible)
+	}
+	mq.clearStaged()
+	mq.sync()
+}
+
+func (mq *MappedQueue[T]) abort() {
+	mq.mu.Lock()
+	defer mq.mu.Unlock()
+	mq.clearStaged()
+}
+
+func (mq *MappedQueue[T]) clearStaged() {
+	mq.staged, mq.stagedEnd, mq.stagedCount = false, 0, 0
+}
+
+// Commit prepares and immediately publishes the pending segment.
+func (mq *MappedQueue[T]) Commit() error {
+	publish, _, err := mq.PrepareCommit(context.Background())
+	if err != nil {
+		return err
+	}
+	if publish != nil {
+		publish()
+	}
+	return nil
+}
+
+// Sync flushes pushes and pops to the file. Publishing a commit syncs
+// implicitly.
+func (mq *MappedQueue[T]) Sync() error {
+	mq.mu.Lock()
+	defer mq.mu.Unlock()
+	if mq.closed {
+		return ErrClosed
+	}
+	return mq.sync()
+}
+
+// Close flushes and unmaps the file. A staged commit that has not been
+// published remains pending.
+func (mq *MappedQueue[T]) Close() error {
+	mq.mu.Lock()
+	defer mq.mu.Unlock()
+	if mq.closed {
+		return nil
+	}
+	mq.closed = true
+	err := mq.sync()
+	if uerr := mq.unmap(); err == nil {
+		err = uerr
+	}
+	return err
+}
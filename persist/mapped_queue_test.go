@@ -0,0 +1,124 @@
+//go:build unix
+
+package persist
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/timzifer/committable_queue/codec"
+	"github.com/timzifer/committable_queue/queue"
+)
+
+func openMapped(t *testing.T, path string, capacity int64, options queue.Options) *MappedQueue[int] {
+	t.Helper()
+	q, err := NewMappedQueue[int](path, capacity, codec.JSON[int]{}, options)
+	if err != nil {
+		t.Fatalf("open failed: %v", err)
+	}
+	return q
+}
+
+func TestMappedQueueCommitBarrierSurvivesReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "queue.map")
+	q := openMapped(t, path, 1024, queue.Options{})
+
+	q.PushBackPending(1)
+	q.PushBackPending(2)
+	if err := q.Commit(); err != nil {
+		t.Fatalf("commit failed: %v", err)
+	}
+	q.PushBackPending(3)
+
+	// The staged commit is never published, as if the process stopped.
+	if _, _, err := q.PrepareCommit(context.Background()); err != nil {
+		t.Fatalf("prepare failed: %v", err)
+	}
+	if _, _, err := q.PrepareCommit(context.Background()); !errors.Is(err, ErrCommitInProgress) {
+		t.Fatalf("expected ErrCommitInProgress, got %v", err)
+	}
+	q.PushBackPending(4)
+	if got := q.LenPending(); got != 1 {
+		t.Fatalf("expected one pending element besides the staged commit, got %d", got)
+	}
+	if err := q.Close(); err != nil {
+		t.Fatalf("close failed: %v", err)
+	}
+
+	reopened := openMapped(t, path, 0, queue.Options{})
+	defer reopened.Close()
+	if reopened.LenVisible() != 2 || reopened.LenPending() != 2 {
+		t.Fatalf("unexpected lengths after reopen: visible=%d pending=%d", reopened.LenVisible(), reopened.LenPending())
+	}
+	reopened.Commit()
+	for want := 1; want <= 4; want++ {
+		v, ok, err := reopened.PopFront()
+		if err != nil || !ok || v != want {
+			t.Fatalf("expected %d, got %d (ok=%v, err=%v)", want, v, ok, err)
+		}
+	}
+}
+
+func TestMappedQueueAbortKeepsPending(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "queue.map")
+	q := openMapped(t, path, 1024, queue.Options{})
+	defer q.Close()
+
+	q.PushBackPending(1)
+	_, abort, err := q.PrepareCommit(context.Background())
+	if err != nil {
+		t.Fatalf("prepare failed: %v", err)
+	}
+	abort()
+	if q.LenVisible() != 0 || q.LenPending() != 1 {
+		t.Fatalf("abort must keep the element pending")
+	}
+	if err := q.Commit(); err != nil || q.LenVisible() != 1 {
+		t.Fatalf("commit after abort failed: %v", err)
+	}
+}
+
+func TestMappedQueueWrapsAndDropsOldest(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "queue.map")
+	// Every record takes 4+2 bytes, so 30 bytes hold five elements.
+	q := openMapped(t, path, 31, queue.Options{MaxLen: 3, DropPolicy: queue.DropOldest})
+	defer q.Close()
+
+	next := 10
+	for round := 0; round < 10; round++ {
+		for i := 0; i < 2; i++ {
+			if err := q.PushBackPending(next); err != nil {
+				t.Fatalf("push %d failed: %v", next, err)
+			}
+			next++
+		}
+		if err := q.Commit(); err != nil {
+			t.Fatalf("commit failed: %v", err)
+		}
+		if got := q.LenVisible(); got > 3 {
+			t.Fatalf("MaxLen exceeded: %d", got)
+		}
+	}
+	for _, want := range []int{27, 28, 29} {
+		v, ok, err := q.PopFront()
+		if err != nil || !ok || v != want {
+			t.Fatalf("expected %d, got %d (ok=%v, err=%v)", want, v, ok, err)
+		}
+	}
+
+	for i := 0; i < 5; i++ {
+		q.PushBackPending(99)
+	}
+	if err := q.PushBackPending(99); !errors.Is(err, ErrFull) {
+		t.Fatalf("expected ErrFull, got %v", err)
+	}
+}
+
+func TestMappedQueueRejectsDropNewest(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "queue.map")
+	if _, err := NewMappedQueue[int](path, 64, codec.JSON[int]{}, queue.Options{MaxLen: 1, DropPolicy: queue.DropNewest}); err == nil {
+		t.Fatalf("expected DropNewest to be rejected")
+	}
+}
@@ -0,0 +1,79 @@
+package persist
+
+// ring is a growable circular buffer used for the in-memory segments of a
+// DurableQueue.
+type ring[T any] struct {
+	buf   []T
+	head  int
+	count int
+}
+
+func (r *ring[T]) len() int {
+	return r.count
+}
+
+func (r *ring[T]) grow() {
+	size := len(r.buf) * 2
+	if size == 0 {
+		size = 16
+	}
+	buf := make([]T, size)
+	n := copy(buf, r.buf[r.head:])
+	copy(buf[n:], r.buf[:r.head])
+	r.buf = buf
+	r.head = 0
+}
+
+func (r *ring[T]) pushBack(v T) {
+	if r.count == len(r.buf) {
+		r.grow()
+	}
+	r.buf[(r.head+r.count)%len(r.buf)] = v
+	r.count++
+}
+
+func (r *ring[T]) pushFront(v T) {
+	if r.count == len(r.buf) {
+		r.grow()
+	}
+	r.head = (r.head - 1 + len(r.buf)) % len(r.buf)
+	r.buf[r.head] = v
+	r.count++
+}
+
+func (r *ring[T]) popFront() (zero T, _ bool) {
+	if r.count == 0 {
+		return zero, false
+	}
+	v := r.buf[r.head]
+	r.buf[r.head] = zero
+	r.head = (r.head + 1) % len(r.buf)
+	r.count--
+	return v, true
+}
+
+func (r *ring[T]) popBack() (zero T, _ bool) {
+	if r.count == 0 {
+		return zero, false
+	}
+	idx := (r.head + r.count - 1) % len(r.buf)
+	v := r.buf[idx]
+	r.buf[idx] = zero
+	r.count--
+	return v, true
+}
+
+// values returns a copy of the elements in order.
+func (r *ring[T]) values() []T {
+	out := make([]T, r.count)
+	for i := range out {
+		out[i] = r.buf[(r.head+i)%len(r.buf)]
+	}
+	return out
+}
+
+func (r *ring[T]) clear() {
+	r.buf = nil
+	r.head = 0
+	r.count = 0
+}
@@ -0,0 +1,120 @@
+package persist
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+)
+
+// chunkWriter buffers a stream into chunks of size bytes, transforms each one
+// with seal and writes it as flag | uvarint(len) | sealed, where flag is 1 for
+// the final chunk written by Close and 0 otherwise. It does not close w.
+type chunkWriter struct {
+	w      io.Writer
+	size   int
+	seal   func(dst, chunk []byte, final bool) ([]byte, error)
+	buf    []byte
+	out    []byte
+	sealed []byte
+	err    error
+	closed bool
+}
+
+func (cw *chunkWriter) Write(p []byte) (int, error) {
+	if cw.closed {
+		return 0, ErrClosed
+	}
+	written := 0
+	for len(p) > 0 {
+		if cw.err != nil {
+			return written, cw.err
+		}
+		n := min(len(p), cw.size-len(cw.buf))
+		cw.buf = append(cw.buf, p[:n]...)
+		p, written = p[n:], written+n
+		if len(cw.buf) == cw.size {
+			cw.flush(false)
+		}
+	}
+	return written, cw.err
+}
+
+// Close transforms the buffered remainder as the final chunk.
+func (cw *chunkWriter) Close() error {
+	if cw.closed {
+		return cw.err
+	}
+	cw.closed = true
+	if cw.err == nil {
+		cw.flush(true)
+	}
+	return cw.err
+}
+
+func (cw *chunkWriter) flush(final bool) {
+	cw.sealed, cw.err = cw.seal(cw.sealed[:0], cw.buf, final)
+	if cw.err != nil {
+		return
+	}
+	cw.buf = cw.buf[:0]
+	flag := byte(0)
+	if final {
+		flag = 1
+	}
+	cw.out = append(cw.out[:0], flag)
+	cw.out = binary.AppendUvarint(cw.out, uint64(len(cw.sealed)))
+	cw.out = append(cw.out, cw.sealed...)
+	_, cw.err = cw.w.Write(cw.out)
+}
+
+// chunkReader reads the chunks written by a chunkWriter and passes each one to
+// open. Sealed chunks larger than limit and an input that ends before the
+// final chunk fail the read with corrupt and io.ErrUnexpectedEOF.
+type chunkReader struct {
+	r       *bufio.Reader
+	limit   uint64
+	corrupt error
+	open    func(dst, sealed []byte, final bool) ([]byte, error)
+	buf     []byte
+	plain   []byte
+	final   bool
+	err     error
+}
+
+func (cr *chunkReader) Read(p []byte) (int, error) {
+	for len(cr.buf) == 0 {
+		if cr.err != nil {
+			return 0, cr.err
+		}
+		if cr.final {
+			return 0, io.EOF
+		}
+		cr.err = cr.next()
+	}
+	n := copy(p, cr.buf)
+	cr.buf = cr.buf[n:]
+	return n, nil
+}
+
+func (cr *chunkReader) next() error {
+	flag, err := cr.r.ReadByte()
+	if err != nil {
+		return io.ErrUnexpectedEOF
+	}
+	size, err := binary.ReadUvarint(cr.r)
+	if err != nil {
+		return io.ErrUnexpectedEOF
+	}
+	if flag > 1 || size > cr.limit {
+		return cr.corrupt
+	}
+	sealed := make([]byte, size)
+	if _, err := io.ReadFull(cr.r, sealed); err != nil {
+		return io.ErrUnexpectedEOF
+	}
+	if cr.plain, err = cr.open(cr.plain[:0], sealed, flag == 1); err != nil {
+		return err
+	}
+	cr.buf, cr.final = cr.plain, flag == 1
+	return nil
+}
@@ -0,0 +1,443 @@
+package persist
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/cipher"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ErrCorruptWAL is returned when a record in a sealed (non-final) segment
+// cannot be read. Damage at the end of the final segment is treated as a torn
+// write and truncated instead.
+var ErrCorruptWAL = errors.New("persist: corrupt write-ahead log")
+
+const segmentSuffix = ".wal"
+
+type recordOp byte
+
+const (
+	opPushBack recordOp = iota + 1
+	opPushFront
+	opPopFront
+	opPopBack
+	opPrepare
+	opPublish
+	opAbort
+	// opCheckpoint is the first record of a segment written by rotate. The
+	// segment holds the complete state, so older segments are not replayed.
+	opCheckpoint
+
+	// opSealed marks a body that holds another body encrypted with
+	// WithEncryption.
+	opSealed recordOp = 0x80
+	// opCompressed marks a body that holds a batch of framed records
+	// compressed with WithCompression.
+	opCompressed recordOp = 0x81
+)
+
+// record is a single WAL entry. Push records carry the encoded element in
+// payload; prepare/publish/abort records reference a staged commit by id.
+type record struct {
+	op      recordOp
+	id      uint64
+	payload []byte
+}
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// appendRecord frames r as uvarint(len(body)) | body | crc32c(body), where body
+// is the op byte followed by the payload or the uvarint id.
+func appendRecord(buf []byte, r record) []byte {
+	return appendFrame(buf, encodeBody(r))
+}
+
+// frameCodec describes how records are framed: compressed in batches when
+// compressor is set, and encrypted when aead is set. The zero value writes
+// every record in its own plain frame.
+type frameCodec struct {
+	aead       cipher.AEAD
+	compressor Compressor
+}
+
+// compressedBatch is the amount of framed records compressed together.
+const compressedBatch = 1 << 20
+
+// appendRecords frames records as described by fc. Compression is applied
+// before encryption.
+func (fc frameCodec) appendRecords(buf []byte, records []record) ([]byte, error) {
+	if fc.compressor == nil {
+		for _, r := range records {
+			buf = fc.appendFrame(buf, encodeBody(r))
+		}
+		return buf, nil
+	}
+	var plain []byte
+	for len(records) > 0 {
+		plain = plain[:0]
+		for len(records) > 0 && len(plain) < compressedBatch {
+			plain = appendRecord(plain, records[0])
+			records = records[1:]
+		}
+		body, err := compressBody(fc.compressor, plain)
+		if err != nil {
+			return nil, err
+		}
+		buf = fc.appendFrame(buf, body)
+	}
+	return buf, nil
+}
+
+func (fc frameCodec) appendFrame(buf, body []byte) []byte {
+	if fc.aead != nil {
+		body = sealBody(fc.aead, body)
+	}
+	return appendFrame(buf, body)
+}
+
+// decodeFrame unwraps body and returns the records it holds.
+func (fc frameCodec) decodeFrame(body []byte) ([]record, error) {
+	if len(body) > 0 && recordOp(body[0]) == opSealed {
+		var err error
+		if body, err = openBody(fc.aead, body); err != nil {
+			return nil, err
+		}
+	}
+	if len(body) == 0 || recordOp(body[0]) != opCompressed {
+		r, err := decodeBody(body)
+		if err != nil {
+			return nil, err
+		}
+		return []record{r}, nil
+	}
+
+	plain, err := decompressBody(fc.compressor, body)
+	if err != nil {
+		return nil, err
+	}
+	var records []record
+	valid, err := readRecords(bytes.NewReader(plain), frameCodec{}, func(r record) error {
+		records = append(records, r)
+		return nil
+	})
+	if err != nil || valid != int64(len(plain)) {
+		return nil, ErrCorruptWAL
+	}
+	return records, nil
+}
+
+func encodeBody(r record) []byte {
+	body := []byte{byte(r.op)}
+	switch r.op {
+	case opPushBack, opPushFront:
+		body = append(body, r.payload...)
+	case opPrepare, opPublish, opAbort:
+		body = binary.AppendUvarint(body, r.id)
+	}
+	return body
+}
+
+func appendFrame(buf, body []byte) []byte {
+	buf = binary.AppendUvarint(buf, uint64(len(body)))
+	buf = append(buf, body...)
+	return binary.BigEndian.AppendUint32(buf, crc32.Checksum(body, crcTable))
+}
+
+func decodeBody(body []byte) (record, error) {
+	if len(body) == 0 {
+		return record{}, ErrCorruptWAL
+	}
+	r := record{op: recordOp(body[0])}
+	switch r.op {
+	case opPushBack, opPushFront:
+		r.payload = body[1:]
+	case opPopFront, opPopBack, opCheckpoint:
+		if len(body) != 1 {
+			return record{}, ErrCorruptWAL
+		}
+	case opPrepare, opPublish, opAbort:
+		id, n := binary.Uvarint(body[1:])
+		if n <= 0 || n != len(body)-1 {
+			return record{}, ErrCorruptWAL
+		}
+		r.id = id
+	default:
+		return record{}, ErrCorruptWAL
+	}
+	return r, nil
+}
+
+// readRecords calls fn for every intact record in r and returns the number of
+// bytes they occupy. A truncated or corrupt record stops the scan with
+// ErrCorruptWAL; errors returned by fn are passed through unchanged. Frames
+// are unwrapped with fc; without the right key the scan stops with ErrDecrypt.
+func readRecords(r io.Reader, fc frameCodec, fn func(record) error) (int64, error) {
+	reader := bufio.NewReader(r)
+	var valid int64
+	for {
+		length, err := binary.ReadUvarint(reader)
+		if err == io.EOF {
+			return valid, nil
+		}
+		if err != nil {
+			return valid, ErrCorruptWAL
+		}
+		const maxRecord = 1 << 30
+		if length > maxRecord {
+			return valid, ErrCorruptWAL
+		}
+		frame := make([]byte, length+4)
+		if _, err := io.ReadFull(reader, frame); err != nil {
+			return valid, ErrCorruptWAL
+		}
+		body := frame[:length]
+		if binary.BigEndian.Uint32(frame[length:]) != crc32.Checksum(body, crcTable) {
+			return valid, ErrCorruptWAL
+		}
+		records, err := fc.decodeFrame(body)
+		if err != nil {
+			return valid, err
+		}
+		for _, rec := range records {
+			if err := fn(rec); err != nil {
+				return valid, err
+			}
+		}
+		valid += int64(uvarintLen(length)) + int64(length) + 4
+	}
+}
+
+func uvarintLen(v uint64) int {
+	var buf [binary.MaxVarintLen64]byte
+	return binary.PutUvarint(buf[:], v)
+}
+
+// wal is a directory of numbered segment files. Records are only ever appended
+// to the newest segment; rotate starts a new segment from a checkpoint and
+// removes the older ones.
+type wal struct {
+	dir         string
+	seq         uint64
+	file        *os.File
+	size        int64
+	segmentSize int64
+	sync        bool
+	codec       frameCodec
+	buf         []byte
+}
+
+func segmentName(seq uint64) string {
+	return fmt.Sprintf("%020d%s", seq, segmentSuffix)
+}
+
+func listSegments(dir string) ([]uint64, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var seqs []uint64
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, segmentSuffix) {
+			continue
+		}
+		seq, err := strconv.ParseUint(strings.TrimSuffix(name, segmentSuffix), 10, 64)
+		if err != nil {
+			continue
+		}
+		seqs = append(seqs, seq)
+	}
+	sort.Slice(seqs, func(i, j int) bool { return seqs[i] < seqs[j] })
+	return seqs, nil
+}
+
+// errStopScan ends a readRecords scan early.
+var errStopScan = errors.New("persist: stop scan")
+
+// checkpointStart returns the index of the newest segment in seqs that begins
+// with a checkpoint. Segments before it are left over from a rotation that was
+// interrupted before it removed them; replaying them as well would restore
+// their elements twice. Logs without a checkpoint start at 0.
+func checkpointStart(dir string, seqs []uint64, fc frameCodec) int {
+	for i := len(seqs) - 1; i > 0; i-- {
+		f, err := os.Open(filepath.Join(dir, segmentName(seqs[i])))
+		if err != nil {
+			continue
+		}
+		var first recordOp
+		readRecords(f, fc, func(r record) error {
+			first = r.op
+			return errStopScan
+		})
+		f.Close()
+		if first == opCheckpoint {
+			return i
+		}
+	}
+	return 0
+}
+
+// openWAL replays the segments in dir through replay, starting at the newest
+// checkpoint, and opens the newest segment for appending. A torn tail in the
+// newest segment is truncated. New records are framed with fc; frames written
+// with another codec are still replayed as long as fc holds the key to decrypt
+// them. Checkpoint markers are not passed to replay.
+func openWAL(dir string, segmentSize int64, sync bool, fc frameCodec, replay func(record) error) (*wal, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	seqs, err := listSegments(dir)
+	if err != nil {
+		return nil, err
+	}
+	seqs = seqs[checkpointStart(dir, seqs, fc):]
+
+	w := &wal{dir: dir, segmentSize: segmentSize, sync: sync, codec: fc}
+	for i, seq := range seqs {
+		last := i == len(seqs)-1
+		path := filepath.Join(dir, segmentName(seq))
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		valid, err := readRecords(f, fc, func(r record) error {
+			if r.op == opCheckpoint {
+				return nil
+			}
+			return replay(r)
+		})
+		f.Close()
+		if errors.Is(err, ErrCorruptWAL) && last {
+			if err := os.Truncate(path, valid); err != nil {
+				return nil, err
+			}
+		} else if err != nil {
+			return nil, fmt.Errorf("%w: segment %s", err, segmentName(seq))
+		}
+		w.seq = seq
+		w.size = valid
+	}
+
+	if len(seqs) == 0 {
+		w.seq = 1
+	}
+	f, err := os.OpenFile(filepath.Join(dir, segmentName(w.seq)), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	w.file = f
+	return w, nil
+}
+
+func (w *wal) append(records ...record) error {
+	buf, err := w.codec.appendRecords(w.buf[:0], records)
+	if err != nil {
+		return err
+	}
+	w.buf = buf
+	n, err := w.file.Write(w.buf)
+	w.size += int64(n)
+	if err != nil {
+		return err
+	}
+	if w.sync {
+		return w.file.Sync()
+	}
+	return nil
+}
+
+func (w *wal) full() bool {
+	return w.segmentSize > 0 && w.size >= w.segmentSize
+}
+
+// rotate writes checkpoint into a fresh segment, makes it durable and removes
+// all older segments. Replaying the new segment alone reproduces the state the
+// checkpoint was built from. The segment is written under a temporary name
+// and renamed into place, so it is either complete or absent; openWAL ignores
+// the older segments should the process stop before they are removed.
+func (w *wal) rotate(checkpoint []record) error {
+	next := w.seq + 1
+	path := filepath.Join(w.dir, segmentName(next))
+	tmp := path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	fail := func(err error) error {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+
+	records := append([]record{{op: opCheckpoint}}, checkpoint...)
+	buf, err := w.codec.appendRecords(nil, records)
+	if err != nil {
+		return fail(err)
+	}
+	if _, err := f.Write(buf); err != nil {
+		return fail(err)
+	}
+	if err := f.Sync(); err != nil {
+		return fail(err)
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	syncDir(w.dir)
+	if f, err = os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o644); err != nil {
+		return err
+	}
+
+	old := w.file
+	w.file, w.seq, w.size = f, next, int64(len(buf))
+	old.Close()
+
+	seqs, err := listSegments(w.dir)
+	if err != nil {
+		return err
+	}
+	for _, seq := range seqs {
+		if seq < next {
+			if err := os.Remove(filepath.Join(w.dir, segmentName(seq))); err != nil {
+				return err
+			}
+		}
+	}
+	syncDir(w.dir)
+	return nil
+}
+
+func (w *wal) close() error {
+	if w.sync {
+		if err := w.file.Sync(); err != nil {
+			w.file.Close()
+			return err
+		}
+	}
+	return w.file.Close()
+}
+
+// syncDir flushes directory metadata so created and removed segments survive a
+// crash. Platforms that cannot sync directories are ignored.
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	d.Sync()
+	d.Close()
+}
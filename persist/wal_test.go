@@ -0,0 +1,80 @@
+package persist
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRecordRoundTrip(t *testing.T) {
+	records := []record{
+		{op: opPushBack, payload: []byte("a")},
+		{op: opPushFront, payload: []byte{}},
+		{op: opPopFront},
+		{op: opPopBack},
+		{op: opPrepare, id: 300},
+		{op: opPublish, id: 300},
+		{op: opAbort, id: 1},
+	}
+
+	var buf []byte
+	for _, r := range records {
+		buf = appendRecord(buf, r)
+	}
+
+	var got []record
+	valid, err := readRecords(bytes.NewReader(buf), frameCodec{}, func(r record) error {
+		got = append(got, r)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("read failed: %v", err)
+	}
+	if valid != int64(len(buf)) {
+		t.Fatalf("expected %d valid bytes, got %d", len(buf), valid)
+	}
+	if len(got) != len(records) {
+		t.Fatalf("expected %d records, got %d", len(records), len(got))
+	}
+	for i := range records {
+		if got[i].op != records[i].op || got[i].id != records[i].id || !bytes.Equal(got[i].payload, records[i].payload) {
+			t.Fatalf("record %d: expected %+v, got %+v", i, records[i], got[i])
+		}
+	}
+}
+
+func TestReadRecordsDetectsCorruption(t *testing.T) {
+	first := appendRecord(nil, record{op: opPopFront})
+	buf := appendRecord(append([]byte(nil), first...), record{op: opPushBack, payload: []byte("value")})
+	buf[len(buf)-1] ^= 0xff
+
+	count := 0
+	valid, err := readRecords(bytes.NewReader(buf), frameCodec{}, func(record) error {
+		count++
+		return nil
+	})
+	if !errors.Is(err, ErrCorruptWAL) {
+		t.Fatalf("expected corruption error, got %v", err)
+	}
+	if count != 1 || valid != int64(len(first)) {
+		t.Fatalf("expected one valid record of %d bytes, got %d records and %d bytes", len(first), count, valid)
+	}
+}
+
+func TestOpenWALRejectsCorruptSealedSegment(t *testing.T) {
+	dir := t.TempDir()
+	broken := appendRecord(nil, record{op: opPopFront})
+	broken[len(broken)-1] ^= 0xff
+	if err := os.WriteFile(filepath.Join(dir, segmentName(1)), broken, 0o644); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, segmentName(2)), nil, 0o644); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+
+	if _, err := openWAL(dir, 0, false, frameCodec{}, func(record) error { return nil }); !errors.Is(err, ErrCorruptWAL) {
+		t.Fatalf("expected corrupt WAL error, got %v", err)
+	}
+}
@@ -0,0 +1,254 @@
+package queue
+
+import (
+	"cmp"
+	"slices"
+	"time"
+)
+
+// WithVisibilityTimeout returns elements obtained through PopFrontAck to the
+// front of the visible segment when they have not been acknowledged within d.
+// The timeout is checked lazily by pops and LenVisible. Without it, only an
+// explicit Nack redelivers an element. d is also the default for Lease.
+func WithVisibilityTimeout[T any](d time.Duration) SegmentedQueueOption[T] {
+	return func(opts *segmentedQueueOptions[T]) {
+		opts.visibilityTimeout = d
+	}
+}
+
+// WithRedeliveryCount counts how often every element obtained through
+// PopFrontAck or Lease was redelivered, without limiting it like
+// WithMaxRedeliveries does. The count is reported by the handles and
+// PopFrontMeta.
+func WithRedeliveryCount[T any]() SegmentedQueueOption[T] {
+	return func(opts *segmentedQueueOptions[T]) {
+		opts.redeliveryCount = true
+	}
+}
+
+// countsRedeliveries reports whether the chunks carry redelivery counts.
+func (sq *SegmentedQueue[T]) countsRedeliveries() bool {
+	return sq.opts.maxRedelivery > 0 || sq.opts.redeliveryCount
+}
+
+// AckHandle settles an element returned by PopFrontAck. Only the first call to
+// Ack or Nack takes effect, and neither does anything once the visibility
+// timeout has redelivered the element.
+type AckHandle struct {
+	settle       func(requeue bool) bool
+	redeliveries int
+}
+
+// Redeliveries returns how often the element had been redelivered before this
+// delivery, 0 on its first one. It is only counted with WithMaxRedeliveries
+// or WithRedeliveryCount.
+func (h AckHandle) Redeliveries() int {
+	return h.redeliveries
+}
+
+// Ack confirms that the element was processed. It reports false when the
+// element was already settled or redelivered.
+func (h AckHandle) Ack() bool {
+	return h.settle != nil && h.settle(false)
+}
+
+// Nack returns the element to the front of the visible segment immediately.
+// It reports false when the element was already settled or redelivered.
+func (h AckHandle) Nack() bool {
+	return h.settle != nil && h.settle(true)
+}
+
+type ackLease[T any] struct {
+	value        T
+	redeliveries int
+	deadline     int64
+	settled      bool
+	// enqueued and committed are the push and commit times of the element in
+	// nanoseconds, 0 when the queue does not record them. Redelivery keeps
+	// them, so the element's age is not reset.
+	enqueued, committed int64
+}
+
+// newLease returns the lease of an element popped with meta.
+func newLease[T any](v T, meta ElementMeta) *ackLease[T] {
+	lease := &ackLease[T]{value: v, redeliveries: meta.Redeliveries}
+	if !meta.Enqueued.IsZero() {
+		lease.enqueued = meta.Enqueued.UnixNano()
+	}
+	if !meta.Committed.IsZero() {
+		lease.committed = meta.Committed.UnixNano()
+	}
+	return lease
+}
+
+// PopFrontAck removes the oldest visible element like PopFront, but keeps it
+// in flight until the returned handle acknowledges it. A Nack or an expired
+// visibility timeout puts the element back at the front of the visible
+// segment, so a crashed consumer does not lose it. In-flight elements are not
+// part of snapshots.
+func (sq *SegmentedQueue[T]) PopFrontAck() (zero T, _ AckHandle, _ bool) {
+	v, meta, ok := sq.popUnprocessed()
+	if !ok {
+		return zero, AckHandle{}, false
+	}
+
+	lease := newLease(v, meta)
+	sq.ackMu.Lock()
+	sq.inFlight++
+	if timeout := sq.opts.visibilityTimeout; timeout > 0 {
+		lease.deadline = sq.now().Add(timeout).UnixNano()
+		sq.insertLeaseLocked(lease)
+	}
+	sq.ackMu.Unlock()
+
+	settle := func(requeue bool) bool { return sq.settle(lease, requeue) }
+	return v, AckHandle{settle: settle, redeliveries: lease.redeliveries}, true
+}
+
+// LenInFlight returns the number of elements popped with PopFrontAck that are
+// neither acknowledged nor redelivered.
+func (sq *SegmentedQueue[T]) LenInFlight() int {
+	sq.redeliverExpired()
+	sq.ackMu.Lock()
+	defer sq.ackMu.Unlock()
+	return sq.inFlight
+}
+
+func (sq *SegmentedQueue[T]) settle(lease *ackLease[T], requeue bool) bool {
+	if !requeue && !sq.recordProcessed(lease.value) {
+		return false
+	}
+	sq.ackMu.Lock()
+	if lease.settled {
+		sq.ackMu.Unlock()
+		return false
+	}
+	lease.settled = true
+	sq.inFlight--
+	sq.ackMu.Unlock()
+
+	if requeue {
+		sq.requeue([]*ackLease[T]{lease})
+	}
+	return true
+}
+
+// insertLeaseLocked adds lease to sq.leases, keeping them in deadline order
+// and leases with equal deadlines in the order they were handed out. The
+// caller must hold sq.ackMu.
+func (sq *SegmentedQueue[T]) insertLeaseLocked(lease *ackLease[T]) {
+	i := len(sq.leases)
+	for i > 0 && sq.leases[i-1].deadline > lease.deadline {
+		i--
+	}
+	sq.leases = slices.Insert(sq.leases, i, lease)
+}
+
+// redeliverExpired returns every lease whose visibility timeout has passed to
+// the visible segment. Leases are kept in deadline order, so settled leases
+// at the front are discarded on the way.
+func (sq *SegmentedQueue[T]) redeliverExpired() {
+	if sq.opts.visibilityTimeout <= 0 && !sq.leased.Load() {
+		return
+	}
+
+	now := sq.now().UnixNano()
+	var expired []*ackLease[T]
+	sq.ackMu.Lock()
+	for len(sq.leases) > 0 {
+		lease := sq.leases[0]
+		if !lease.settled {
+			if lease.deadline > now {
+				break
+			}
+			lease.settled = true
+			sq.inFlight--
+			expired = append(expired, lease)
+		}
+		sq.leases[0] = nil
+		sq.leases = sq.leases[1:]
+	}
+	sq.ackMu.Unlock()
+
+	if len(expired) > 0 {
+		sq.requeue(expired)
+	}
+}
+
+// requeue redelivers the elements of settled leases, except those that have
+// exhausted WithMaxRedeliveries, which go to the dead-letter queue instead.
+func (sq *SegmentedQueue[T]) requeue(leases []*ackLease[T]) {
+	var exhausted []T
+	if limit := sq.opts.maxRedelivery; limit > 0 {
+		retry := leases[:0:0]
+		for _, lease := range leases {
+			if lease.redeliveries >= limit {
+				exhausted = append(exhausted, lease.value)
+			} else {
+				retry = append(retry, lease)
+			}
+		}
+		leases = retry
+	}
+	if len(leases) > 0 {
+		sq.redeliver(leases)
+	}
+	sq.deadLetter(exhausted)
+}
+
+// redeliver puts the leased values back at the front of the visible segment,
+// keeping their order. They keep their push and commit times, so TTL, the
+// maximum age of DropExpired and the residency histograms see their true age.
+func (sq *SegmentedQueue[T]) redeliver(leases []*ackLease[T]) {
+	// Elements with different redelivery counts or commit times go into
+	// separate chunks, as both are kept per chunk.
+	now := sq.now().UnixNano()
+	var s segment[T]
+	for i := 0; i < len(leases); {
+		d := sq.newDeque()
+		j := i
+		for ; j < len(leases) && leases[j].redeliveries == leases[i].redeliveries && leases[j].committed == leases[i].committed; j++ {
+			d.pushBack(leases[j].value)
+			if tail := d.tail; tail.stamps != nil && leases[j].enqueued != 0 {
+				tail.stamps[tail.hi-1] = leases[j].enqueued
+			}
+		}
+		run := d.detachLocked()
+		committed := cmp.Or(leases[i].committed, now)
+		for c := run.head; c != nil; c = c.next {
+			c.committed = committed
+			if sq.countsRedeliveries() {
+				c.redeliveries = leases[i].redeliveries + 1
+			}
+		}
+		s = s.join(run)
+		i = j
+	}
+	sq.stampVersion(s, sq.version.Load())
+
+	sq.visible.mu.Lock()
+	for _, lease := range leases {
+		sq.rememberLocked(lease.value)
+	}
+	sq.visible.prependSegmentLocked(s)
+	sq.visible.mu.Unlock()
+	sq.notifyReady()
+	sq.reportDepth()
+	sq.checkWatermarks()
+
+	sq.counters.redeliv.Add(uint64(len(leases)))
+}
+
+// nextRedelivery returns how long until the earliest in-flight lease expires.
+// ok is false when no in-flight element has a deadline.
+func (sq *SegmentedQueue[T]) nextRedelivery() (_ time.Duration, ok bool) {
+	now := sq.now().UnixNano()
+	sq.ackMu.Lock()
+	defer sq.ackMu.Unlock()
+	for _, lease := range sq.leases {
+		if !lease.settled {
+			return time.Duration(max(lease.deadline-now, 0)), true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,110 @@
+package queue
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPopFrontAckNackRedeliversAtFront(t *testing.T) {
+	q := NewSegmentedQueue[int](WithInitialVisible(1, 2, 3))
+
+	v, h, ok := q.PopFrontAck()
+	if !ok || v != 1 {
+		t.Fatalf("expected 1, got %d %v", v, ok)
+	}
+	if q.LenInFlight() != 1 || q.LenVisible() != 2 {
+		t.Fatalf("unexpected lengths: in flight %d visible %d", q.LenInFlight(), q.LenVisible())
+	}
+	if !h.Nack() {
+		t.Fatalf("first nack must succeed")
+	}
+	if h.Ack() || h.Nack() {
+		t.Fatalf("settled handle must not settle again")
+	}
+
+	if v, ok := q.PopFront(); !ok || v != 1 {
+		t.Fatalf("expected nacked element at the front, got %d %v", v, ok)
+	}
+	if m := q.Metrics(); m.Redelivered != 1 {
+		t.Fatalf("expected 1 redelivery, got %d", m.Redelivered)
+	}
+}
+
+func TestPopFrontAckVisibilityTimeout(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	q := NewSegmentedQueue[int](
+		WithClock[int](clock.Now),
+		WithVisibilityTimeout[int](time.Second),
+		WithInitialVisible(1, 2, 3),
+	)
+
+	_, first, _ := q.PopFrontAck()
+	_, second, _ := q.PopFrontAck()
+	if !first.Ack() {
+		t.Fatalf("ack before the timeout must succeed")
+	}
+
+	clock.now = clock.now.Add(time.Second)
+	if q.LenInFlight() != 0 || q.LenVisible() != 2 {
+		t.Fatalf("expected element 2 to be redelivered, in flight %d visible %d", q.LenInFlight(), q.LenVisible())
+	}
+	if second.Ack() {
+		t.Fatalf("ack after redelivery must fail")
+	}
+
+	var got []int
+	for {
+		v, ok := q.PopFront()
+		if !ok {
+			break
+		}
+		got = append(got, v)
+	}
+	if len(got) != 2 || got[0] != 2 || got[1] != 3 {
+		t.Fatalf("expected [2 3], got %v", got)
+	}
+}
+
+func TestPopFrontAckEmpty(t *testing.T) {
+	q := NewSegmentedQueue[int]()
+	if _, h, ok := q.PopFrontAck(); ok || h.Ack() {
+		t.Fatalf("empty queue must not hand out a lease")
+	}
+}
+
+func TestRedeliveryKeepsElementAge(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	q := NewSegmentedQueue[int](
+		WithClock[int](clock.Now),
+		WithTTL[int](10*time.Second),
+		WithElementMeta[int](),
+	)
+	pushed := clock.now
+	q.PushBackPending(1)
+	q.PushBackPending(2)
+	clock.now = clock.now.Add(time.Second)
+	committed := clock.now
+	q.Commit()
+
+	_, h, _ := q.PopFrontAck()
+	clock.now = clock.now.Add(5 * time.Second)
+	h.Nack()
+	v, meta, ok := q.PopFrontMeta()
+	if !ok || v != 1 || !meta.Enqueued.Equal(pushed) || !meta.Committed.Equal(committed) {
+		t.Fatalf("expected 1 with its original times, got %d %+v (%v)", v, meta, ok)
+	}
+	// Both deliveries are recorded: after 1s and, counted from the push, 6s.
+	if r := q.Residency(); r.Total.Count != 2 || r.Total.Sum != 7*time.Second {
+		t.Fatalf("expected residencies of 1s and 6s, got %+v", r.Total)
+	}
+
+	_, h, _ = q.PopFrontAck()
+	clock.now = clock.now.Add(5 * time.Second)
+	h.Nack()
+	if v, ok := q.PopFront(); ok {
+		t.Fatalf("expected the redelivered element to expire at its original age, got %d", v)
+	}
+	if m := q.Metrics(); m.Expired != 1 {
+		t.Fatalf("expected 1 expired element, got %d", m.Expired)
+	}
+}
@@ -0,0 +1,64 @@
+package queue_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/timzifer/committable_queue/orchestrator"
+	"github.com/timzifer/committable_queue/queue"
+)
+
+var (
+	_ orchestrator.Bank = (*queue.SegmentedQueue[int])(nil)
+	_ orchestrator.Bank = (*queue.PriorityQueue[int])(nil)
+	_ orchestrator.Bank = (*queue.CoalescingQueue[string, int])(nil)
+)
+
+func TestQueuesCommitTogetherAsBanks(t *testing.T) {
+	segmented := queue.NewSegmentedQueue[int]()
+	priority := queue.NewPriorityQueue[int](queue.Options{})
+	coalescing := queue.NewCoalescingQueue[string, int](queue.Options{})
+
+	segmented.PushBackPending(1)
+	priority.PushPending(5, 2)
+	coalescing.Put("a", 3)
+
+	o := orchestrator.NewCommitOrchestrator(orchestrator.WithBanks(segmented, priority, coalescing))
+	if err := o.CommitAll(context.Background()); err != nil {
+		t.Fatalf("commit: %v", err)
+	}
+
+	if v, ok := segmented.PopFront(); !ok || v != 1 {
+		t.Fatalf("segmented queue: got %d %v", v, ok)
+	}
+	if v, ok := priority.PopFront(); !ok || v != 2 {
+		t.Fatalf("priority queue: got %d %v", v, ok)
+	}
+	if e, ok := coalescing.PopFront(); !ok || e.Value != 3 {
+		t.Fatalf("coalescing queue: got %+v %v", e, ok)
+	}
+}
+
+func TestQueuesReportStagedElements(t *testing.T) {
+	segmented := queue.NewSegmentedQueue[int]()
+	priority := queue.NewPriorityQueue[int](queue.Options{})
+	coalescing := queue.NewCoalescingQueue[string, int](queue.Options{})
+
+	segmented.PushBackPending(1)
+	segmented.PushBackPending(2)
+	priority.PushPending(5, 3)
+	coalescing.Put("a", 4)
+	coalescing.Put("a", 5)
+	coalescing.Put("b", 6)
+
+	o := orchestrator.NewCommitOrchestrator(orchestrator.WithBanks(segmented, priority, coalescing))
+	report, err := o.CommitAllReport(context.Background())
+	if err != nil {
+		t.Fatalf("commit: %v", err)
+	}
+	for i, want := range []int{2, 1, 2} {
+		if got := report.Banks[i].Elements; got != want {
+			t.Fatalf("bank %d: expected %d elements, got %d", i, want, got)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package queue
+
+import (
+	"context"
+	"time"
+)
+
+// PopBatch waits until at least minSize elements are visible or maxWait has
+// elapsed, then removes and returns up to maxSize of the oldest visible
+// elements. It bounds both the size and the latency of batches, for example
+// for bulk inserts downstream. When ctx is done first, PopBatch returns what
+// is visible at that moment. Other consumers may take elements in between,
+// so the batch can be smaller than minSize even before maxWait.
+//
+// maxSize below 1 returns nil, and a minSize above maxSize waits for maxSize
+// elements. maxWait <= 0 does not wait.
+func (sq *SegmentedQueue[T]) PopBatch(ctx context.Context, minSize, maxSize int, maxWait time.Duration) []T {
+	if maxSize < 1 {
+		return nil
+	}
+	minSize = min(max(minSize, 1), maxSize)
+
+	deadline := time.NewTimer(max(maxWait, 0))
+	defer deadline.Stop()
+	for waiting := maxWait > 0; waiting; {
+		ready := sq.Ready()
+		if sq.LenVisible() >= minSize {
+			break
+		}
+		var poll <-chan time.Time
+		if wait, ok := sq.nextRedelivery(); ok {
+			// Redeliveries are only noticed by pops and LenVisible.
+			poll = time.After(wait)
+		}
+		select {
+		case <-ready:
+		case <-poll:
+		case <-deadline.C:
+			waiting = false
+		case <-ctx.Done():
+			waiting = false
+		}
+	}
+
+	var batch []T
+	for len(batch) < maxSize {
+		v, ok := sq.PopFront()
+		if !ok {
+			break
+		}
+		batch = append(batch, v)
+	}
+	return batch
+}
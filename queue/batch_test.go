@@ -0,0 +1,51 @@
+package queue
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestPopBatchWaitsForMinimum(t *testing.T) {
+	q := NewSegmentedQueue[int]()
+	q.PushBackPending(1)
+	q.Commit()
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		for v := 2; v <= 5; v++ {
+			q.PushBackPending(v)
+		}
+		q.Commit()
+	}()
+
+	batch := q.PopBatch(context.Background(), 3, 4, 5*time.Second)
+	if !slices.Equal(batch, []int{1, 2, 3, 4}) {
+		t.Fatalf("expected a batch capped at 4 elements, got %v", batch)
+	}
+	if got := drain(q); !slices.Equal(got, []int{5}) {
+		t.Fatalf("expected the rest to stay visible, got %v", got)
+	}
+}
+
+func TestPopBatchReturnsAfterMaxWait(t *testing.T) {
+	q := NewSegmentedQueue[int]()
+	q.PushBackPending(1)
+	q.Commit()
+
+	start := time.Now()
+	batch := q.PopBatch(context.Background(), 10, 20, 20*time.Millisecond)
+	if !slices.Equal(batch, []int{1}) {
+		t.Fatalf("expected the partial batch, got %v", batch)
+	}
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+		t.Fatalf("expected PopBatch to wait for maxWait, returned after %v", elapsed)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if batch := q.PopBatch(ctx, 1, 5, time.Hour); len(batch) != 0 {
+		t.Fatalf("expected an empty batch after cancellation, got %v", batch)
+	}
+}
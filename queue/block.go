@@ -0,0 +1,76 @@
+package queue
+
+import "context"
+
+// PushCtx appends value to the pending segment like PushBackPending. With the
+// OverflowBlock policy and a MaxLen, it first waits until the queue holds
+// fewer than MaxLen elements, counting visible, pending, delayed and staged
+// elements, so producers are slowed down to the pace of the consumers instead
+// of losing data. It returns ctx.Err() if ctx is done before there is room;
+// value is not pushed in that case.
+//
+// The non-blocking push methods ignore the limit, and published commits never
+// drop elements under OverflowBlock.
+func (sq *SegmentedQueue[T]) PushCtx(ctx context.Context, value T) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if !sq.blocks() {
+		sq.PushBackPending(value)
+		return nil
+	}
+
+	sq.pushMu.Lock()
+	defer sq.pushMu.Unlock()
+	for {
+		space := sq.spaceChan()
+		if sq.occupancy() < sq.options.MaxLen {
+			sq.PushBackPending(value)
+			return nil
+		}
+		select {
+		case <-space:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (sq *SegmentedQueue[T]) blocks() bool {
+	return sq.options.DropPolicy == OverflowBlock && sq.options.MaxLen > 0
+}
+
+// occupancy returns the number of elements that count against MaxLen for
+// blocking pushes.
+func (sq *SegmentedQueue[T]) occupancy() int {
+	n := sq.visible.length() + sq.LenDelayed() + int(sq.staged.Load())
+	sq.mu.Lock()
+	shards := sq.shards
+	sq.mu.Unlock()
+	for _, shard := range shards {
+		n += shard.length()
+	}
+	return n
+}
+
+func (sq *SegmentedQueue[T]) spaceChan() <-chan struct{} {
+	sq.spaceMu.Lock()
+	defer sq.spaceMu.Unlock()
+	if sq.space == nil {
+		sq.space = make(chan struct{})
+	}
+	return sq.space
+}
+
+// notifySpace wakes blocked PushCtx callers after elements left the queue.
+func (sq *SegmentedQueue[T]) notifySpace() {
+	if !sq.blocks() {
+		return
+	}
+	sq.spaceMu.Lock()
+	defer sq.spaceMu.Unlock()
+	if sq.space != nil {
+		close(sq.space)
+		sq.space = nil
+	}
+}
@@ -0,0 +1,81 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPushCtxBlocksUntilConsumerPops(t *testing.T) {
+	q := NewSegmentedQueue[int](WithOptions[int](Options{MaxLen: 2, DropPolicy: OverflowBlock}))
+	ctx := context.Background()
+
+	if err := q.PushCtx(ctx, 1); err != nil {
+		t.Fatalf("push: %v", err)
+	}
+	if err := q.PushCtx(ctx, 2); err != nil {
+		t.Fatalf("push: %v", err)
+	}
+	q.Commit()
+
+	done := make(chan error, 1)
+	go func() { done <- q.PushCtx(ctx, 3) }()
+
+	select {
+	case err := <-done:
+		t.Fatalf("push must block while the queue is full, returned %v", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	if v, ok := q.PopFront(); !ok || v != 1 {
+		t.Fatalf("expected 1, got %d %v", v, ok)
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("push: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("push was not released by the pop")
+	}
+
+	q.Commit()
+	if q.LenVisible() != 2 {
+		t.Fatalf("expected 2 visible, got %d", q.LenVisible())
+	}
+	if m := q.Metrics(); m.Dropped != 0 {
+		t.Fatalf("OverflowBlock must not drop, got %+v", m)
+	}
+}
+
+func TestPushCtxCancelled(t *testing.T) {
+	q := NewSegmentedQueue[int](WithOptions[int](Options{MaxLen: 1, DropPolicy: OverflowBlock}))
+	q.PushBackPending(1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := q.PushCtx(ctx, 2); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+	q.Commit()
+	if q.LenVisible() != 1 {
+		t.Fatalf("cancelled push must not add an element, visible %d", q.LenVisible())
+	}
+}
+
+func TestPushCtxCountsStagedElements(t *testing.T) {
+	q := NewSegmentedQueue[int](WithOptions[int](Options{MaxLen: 1, DropPolicy: OverflowBlock}))
+	q.PushBackPending(1)
+	_, abort, err := q.PrepareCommit(context.Background())
+	if err != nil {
+		t.Fatalf("prepare: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := q.PushCtx(ctx, 2); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("staged elements must count against MaxLen, got %v", err)
+	}
+	abort()
+}
@@ -0,0 +1,80 @@
+package queue
+
+// Clone returns an independent queue with the same options, visible segment,
+// pending shards and delayed elements, for what-if simulations and test
+// fixtures. Each element is duplicated with copier; nil copies elements by
+// assignment. Commits that are prepared but not yet published, elements in
+// flight through PopFrontAck and the counters are not carried over.
+func (sq *SegmentedQueue[T]) Clone(copier func(T) T) *SegmentedQueue[T] {
+	if copier == nil {
+		copier = func(v T) T { return v }
+	}
+
+	clone := &SegmentedQueue[T]{opts: sq.opts, options: sq.options, now: sq.now}
+	clone.initSegments()
+
+	sq.mu.Lock()
+	defer sq.mu.Unlock()
+	for _, p := range sq.namedOrder {
+		clone.addProducerLocked(p.name)
+	}
+	clone.version.Store(sq.version.Load())
+
+	sq.visible.mu.Lock()
+	sq.visible.cloneLocked(clone.visible, copier)
+	sq.visible.mu.Unlock()
+	for c := clone.visible.head; c != nil; c = c.next {
+		for _, v := range c.values[c.lo:c.hi] {
+			clone.rememberLocked(v)
+		}
+	}
+
+	for i, shard := range sq.shards {
+		shard.mu.Lock()
+		shard.cloneLocked(clone.shards[i], copier)
+		shard.mu.Unlock()
+	}
+
+	sq.delayMu.Lock()
+	clone.delayed = make(delayHeap[T], len(sq.delayed))
+	for i, e := range sq.delayed {
+		clone.delayed[i] = delayedElement[T]{at: e.at, seq: e.seq, value: copier(e.value)}
+	}
+	clone.delaySeq = sq.delaySeq
+	sq.delayMu.Unlock()
+
+	return clone
+}
+
+// Clone returns an independent queue with the same options and visible and
+// pending elements, duplicated with copier; nil copies by assignment.
+// Prepared but unpublished commits and the counters are not carried over.
+func (pq *PriorityQueue[T]) Clone(copier func(T) T) *PriorityQueue[T] {
+	if copier == nil {
+		copier = func(v T) T { return v }
+	}
+
+	clone := NewPriorityQueue[T](pq.options)
+
+	pq.mu.Lock()
+	defer pq.mu.Unlock()
+
+	pq.visibleMu.Lock()
+	cloneLanes(&pq.visible, &clone.visible, copier)
+	pq.visibleMu.Unlock()
+
+	pq.pendingMu.Lock()
+	cloneLanes(&pq.pending, &clone.pending, copier)
+	pq.pendingMu.Unlock()
+
+	return clone
+}
+
+func cloneLanes[T any](src, dst *lanes[T], copier func(T) T) {
+	for _, p := range src.priorities {
+		if d := src.deques[p]; d.len > 0 {
+			d.cloneLocked(dst.lane(p), copier)
+		}
+	}
+	dst.len = src.len
+}
@@ -0,0 +1,74 @@
+package queue
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestSegmentedQueueCloneIsIndependent(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	q := NewSegmentedQueue[[]int](
+		WithClock[[]int](clock.Now),
+		WithPendingShards[[]int](2),
+		WithDedup(func(v []int) int { return v[0] }),
+	)
+	for i := 0; i < chunkSize+3; i++ {
+		q.PushBackPending([]int{i})
+	}
+	q.Commit()
+	q.NewProducer().PushBackPending([]int{500})
+	q.NewProducer().PushBackPending([]int{600})
+	q.PushBackPendingAfter([]int{700}, clock.now.Add(time.Second))
+
+	clone := q.Clone(func(v []int) []int { return slices.Clone(v) })
+
+	first, _ := q.PopFront()
+	first[0] = -1
+	q.PushBackPending([]int{800})
+
+	if clone.LenVisible() != chunkSize+3 || clone.LenDelayed() != 1 {
+		t.Fatalf("unexpected clone lengths: visible %d delayed %d", clone.LenVisible(), clone.LenDelayed())
+	}
+	if v, _ := clone.FindFunc(func([]int) bool { return true }); v[0] != 0 {
+		t.Fatalf("clone must hold deep copies, got %v", v)
+	}
+
+	// The clone keeps its own dedup index: key 1 is visible there.
+	clone.PushBackPending([]int{1})
+	clock.now = clock.now.Add(time.Second)
+	clone.Commit()
+
+	var tail []int
+	for {
+		v, ok := clone.PopFront()
+		if !ok {
+			break
+		}
+		tail = append(tail, v[0])
+	}
+	if want := []int{chunkSize + 2, 500, 600, 700}; !slices.Equal(tail[len(tail)-4:], want) {
+		t.Fatalf("expected clone to end with %v, got %v", want, tail[len(tail)-4:])
+	}
+	if m := clone.Metrics(); m.Duplicates != 1 {
+		t.Fatalf("expected the clone to drop the duplicate key, got %+v", m)
+	}
+}
+
+func TestPriorityQueueClone(t *testing.T) {
+	pq := NewPriorityQueue[int](Options{})
+	pq.PushPending(1, 10)
+	pq.Commit()
+	pq.PushPending(5, 50)
+
+	clone := pq.Clone(nil)
+	pq.PopFront()
+
+	if clone.LenVisible() != 1 || clone.LenPending() != 1 {
+		t.Fatalf("unexpected clone lengths: visible %d pending %d", clone.LenVisible(), clone.LenPending())
+	}
+	clone.Commit()
+	if v, ok := clone.PopFront(); !ok || v != 50 {
+		t.Fatalf("expected 50, got %d %v", v, ok)
+	}
+}
@@ -0,0 +1,182 @@
+package queue
+
+import (
+	"context"
+	"sync"
+
+	"github.com/timzifer/committable_queue/orchestrator"
+)
+
+// Entry is a key and the latest value written for it.
+type Entry[K comparable, V any] struct {
+	Key   K
+	Value V
+}
+
+// coalescedBatch holds pending writes collapsed to one value per key. order
+// records the keys in the order of their first write.
+type coalescedBatch[K comparable, V any] struct {
+	order  []K
+	values map[K]V
+}
+
+func newCoalescedBatch[K comparable, V any]() coalescedBatch[K, V] {
+	return coalescedBatch[K, V]{values: make(map[K]V)}
+}
+
+func (b *coalescedBatch[K, V]) put(key K, value V) {
+	if _, ok := b.values[key]; !ok {
+		b.order = append(b.order, key)
+	}
+	b.values[key] = value
+}
+
+// CoalescingQueue is a committable queue for state updates. Pending writes to
+// the same key collapse into one: the latest value wins, while the key keeps
+// the position of its first write since the last commit. Publishing a commit
+// appends one Entry per key to the visible segment in that order.
+//
+// Only pending writes coalesce. A key that is already visible is appended
+// again by the next commit that carries it.
+type CoalescingQueue[K comparable, V any] struct {
+	// mu serialises prepare, publish and abort like SegmentedQueue.mu.
+	mu        sync.Mutex
+	visible   *deque[Entry[K, V]]
+	pendingMu sync.Mutex
+	pending   coalescedBatch[K, V]
+	options   Options
+	counters  queueCounters
+}
+
+func NewCoalescingQueue[K comparable, V any](options Options) *CoalescingQueue[K, V] {
+	return &CoalescingQueue[K, V]{
+		visible: newDeque[Entry[K, V]](),
+		pending: newCoalescedBatch[K, V](),
+		options: options,
+	}
+}
+
+// Put records value as the pending value of key, replacing any pending value
+// written since the last commit.
+func (cq *CoalescingQueue[K, V]) Put(key K, value V) {
+	cq.pendingMu.Lock()
+	cq.pending.put(key, value)
+	cq.pendingMu.Unlock()
+	cq.counters.pushes.Add(1)
+}
+
+// PopFront removes the oldest visible entry.
+func (cq *CoalescingQueue[K, V]) PopFront() (Entry[K, V], bool) {
+	e, ok := cq.visible.popFront()
+	if ok {
+		cq.counters.pops.Add(1)
+	}
+	return e, ok
+}
+
+func (cq *CoalescingQueue[K, V]) LenVisible() int {
+	return cq.visible.length()
+}
+
+// LenPending returns the number of distinct keys awaiting a commit.
+func (cq *CoalescingQueue[K, V]) LenPending() int {
+	cq.pendingMu.Lock()
+	defer cq.pendingMu.Unlock()
+	return len(cq.pending.order)
+}
+
+func (cq *CoalescingQueue[K, V]) Metrics() QueueMetrics {
+	return cq.counters.snapshot()
+}
+
+func (cq *CoalescingQueue[K, V]) Commit() {
+	publish, _, err := cq.PrepareCommit(context.Background())
+	if err != nil {
+		panic(err)
+	}
+	if publish != nil {
+		publish()
+	}
+}
+
+// PrepareCommit detaches the coalesced pending writes. It follows the same
+// contract as SegmentedQueue.PrepareCommit; writes made after the prepare
+// start a new batch and do not change the staged values.
+func (cq *CoalescingQueue[K, V]) PrepareCommit(ctx context.Context) (publish func(), abort func(), err error) {
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	cq.mu.Lock()
+	defer cq.mu.Unlock()
+
+	cq.pendingMu.Lock()
+	staged := cq.pending
+	if len(staged.order) > 0 {
+		cq.pending = newCoalescedBatch[K, V]()
+	}
+	cq.pendingMu.Unlock()
+
+	if len(staged.order) == 0 {
+		return nil, nil, nil
+	}
+	orchestrator.ReportElements(ctx, len(staged.order))
+
+	var once sync.Once
+	publish = func() {
+		once.Do(func() { cq.finalizePublish(staged) })
+	}
+	abort = func() {
+		once.Do(func() { cq.finalizeAbort(staged) })
+	}
+	return publish, abort, nil
+}
+
+func (cq *CoalescingQueue[K, V]) finalizePublish(staged coalescedBatch[K, V]) {
+	entries := newDeque[Entry[K, V]]()
+	for _, key := range staged.order {
+		entries.pushBack(Entry[K, V]{Key: key, Value: staged.values[key]})
+	}
+
+	cq.mu.Lock()
+	defer cq.mu.Unlock()
+
+	cq.visible.mu.Lock()
+	defer cq.visible.mu.Unlock()
+
+	committed := entries.detachLocked()
+	cq.visible.appendSegmentLocked(committed)
+
+	cq.counters.committed(committed.len)
+
+	if cq.options.MaxLen > 0 {
+		dropped := 0
+		for cq.visible.len > cq.options.MaxLen {
+			switch cq.options.DropPolicy {
+			case DropNewest:
+				cq.visible.popBackLocked()
+			default:
+				cq.visible.popFrontLocked()
+			}
+			dropped++
+		}
+		cq.counters.dropped(cq.options.DropPolicy, dropped)
+	}
+}
+
+// finalizeAbort merges the staged writes back in front of the pending ones.
+// Staged keys keep their earlier position; a value written after the prepare
+// still wins over the staged one.
+func (cq *CoalescingQueue[K, V]) finalizeAbort(staged coalescedBatch[K, V]) {
+	cq.mu.Lock()
+	defer cq.mu.Unlock()
+
+	cq.pendingMu.Lock()
+	defer cq.pendingMu.Unlock()
+
+	cq.counters.aborts.Add(1)
+	for _, key := range cq.pending.order {
+		staged.put(key, cq.pending.values[key])
+	}
+	cq.pending = staged
+}
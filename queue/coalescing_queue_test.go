@@ -0,0 +1,78 @@
+package queue
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func drainEntries[K comparable, V any](cq *CoalescingQueue[K, V]) []Entry[K, V] {
+	var out []Entry[K, V]
+	for {
+		e, ok := cq.PopFront()
+		if !ok {
+			return out
+		}
+		out = append(out, e)
+	}
+}
+
+func TestCoalescingQueueLatestValueWins(t *testing.T) {
+	cq := NewCoalescingQueue[string, int](Options{})
+
+	cq.Put("a", 1)
+	cq.Put("b", 2)
+	cq.Put("a", 3)
+	if cq.LenPending() != 2 {
+		t.Fatalf("expected 2 pending keys, got %d", cq.LenPending())
+	}
+	if cq.LenVisible() != 0 {
+		t.Fatalf("pending writes must not be visible")
+	}
+
+	cq.Commit()
+	want := []Entry[string, int]{{"a", 3}, {"b", 2}}
+	if got := drainEntries(cq); !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	if m := cq.Metrics(); m.Pushes != 3 || m.Pops != 2 || m.Commits != 1 {
+		t.Fatalf("unexpected counters: %+v", m)
+	}
+}
+
+func TestCoalescingQueueAbortMergesNewerWrites(t *testing.T) {
+	cq := NewCoalescingQueue[string, int](Options{})
+
+	cq.Put("a", 1)
+	cq.Put("b", 1)
+	_, abort, err := cq.PrepareCommit(context.Background())
+	if err != nil {
+		t.Fatalf("prepare: %v", err)
+	}
+
+	cq.Put("c", 2)
+	cq.Put("a", 2)
+	abort()
+
+	cq.Commit()
+	want := []Entry[string, int]{{"a", 2}, {"b", 1}, {"c", 2}}
+	if got := drainEntries(cq); !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+}
+
+func TestCoalescingQueueMaxLen(t *testing.T) {
+	cq := NewCoalescingQueue[int, int](Options{MaxLen: 2, DropPolicy: DropOldest})
+	for i := 0; i < 4; i++ {
+		cq.Put(i, i)
+	}
+	cq.Commit()
+
+	want := []Entry[int, int]{{2, 2}, {3, 3}}
+	if got := drainEntries(cq); !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	if m := cq.Metrics(); m.Drops[DropOldest] != 2 {
+		t.Fatalf("expected 2 drops, got %+v", m.Drops)
+	}
+}
@@ -0,0 +1,175 @@
+package queue
+
+import (
+	"errors"
+	"slices"
+	"sync"
+)
+
+// ErrMemberExists is returned by ConsumerGroup.Join for a name that is already
+// a member.
+var ErrMemberExists = errors.New("queue: consumer group member already exists")
+
+// ConsumerGroup shares the visible elements of a SegmentedQueue between named
+// members. Elements are spread over a fixed number of partitions, either by
+// key or round-robin, and every partition is owned by exactly one member, so
+// each committed element is delivered to exactly one member. Elements with the
+// same key always land in the same partition and keep their order.
+//
+// Partitions are assigned to the members in name order and reassigned
+// whenever a member joins or leaves. Elements the group has already taken from
+// the queue move with their partition, so a leaving member does not lose any.
+type ConsumerGroup[T any] struct {
+	queue *SegmentedQueue[T]
+	key   func(T) uint64
+
+	mu         sync.Mutex
+	partitions []*deque[T]
+	members    map[string]*Member[T]
+	next       uint64
+}
+
+// NewConsumerGroup creates a group with the given number of partitions on
+// top of q. key selects the partition of an element; nil distributes
+// elements round-robin. The group must be the only consumer of q.
+func NewConsumerGroup[T any](q *SegmentedQueue[T], partitions int, key func(T) uint64) *ConsumerGroup[T] {
+	if partitions < 1 {
+		partitions = 1
+	}
+	g := &ConsumerGroup[T]{
+		queue:      q,
+		key:        key,
+		partitions: make([]*deque[T], partitions),
+		members:    make(map[string]*Member[T]),
+	}
+	for i := range g.partitions {
+		g.partitions[i] = newDeque[T]()
+	}
+	return g
+}
+
+// Member is one consumer of a ConsumerGroup.
+type Member[T any] struct {
+	group      *ConsumerGroup[T]
+	name       string
+	partitions []int
+	left       bool
+}
+
+// Join adds a member and rebalances the partitions.
+func (g *ConsumerGroup[T]) Join(name string) (*Member[T], error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if _, ok := g.members[name]; ok {
+		return nil, ErrMemberExists
+	}
+	m := &Member[T]{group: g, name: name}
+	g.members[name] = m
+	g.rebalanceLocked()
+	return m, nil
+}
+
+// Members returns the names of the current members in assignment order.
+func (g *ConsumerGroup[T]) Members() []string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.namesLocked()
+}
+
+func (g *ConsumerGroup[T]) namesLocked() []string {
+	names := make([]string, 0, len(g.members))
+	for name := range g.members {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// LenBuffered returns the number of elements the group has taken from the
+// queue but not yet delivered.
+func (g *ConsumerGroup[T]) LenBuffered() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	n := 0
+	for _, p := range g.partitions {
+		n += p.length()
+	}
+	return n
+}
+
+func (g *ConsumerGroup[T]) rebalanceLocked() {
+	names := g.namesLocked()
+	for _, name := range names {
+		g.members[name].partitions = g.members[name].partitions[:0]
+	}
+	if len(names) == 0 {
+		return
+	}
+	for i := range g.partitions {
+		m := g.members[names[i%len(names)]]
+		m.partitions = append(m.partitions, i)
+	}
+}
+
+func (g *ConsumerGroup[T]) partitionOf(v T) int {
+	if g.key == nil {
+		g.next++
+		return int((g.next - 1) % uint64(len(g.partitions)))
+	}
+	return int(g.key(v) % uint64(len(g.partitions)))
+}
+
+// Name returns the member's name.
+func (m *Member[T]) Name() string {
+	return m.name
+}
+
+// Partitions returns the partitions currently assigned to the member.
+func (m *Member[T]) Partitions() []int {
+	m.group.mu.Lock()
+	defer m.group.mu.Unlock()
+	return slices.Clone(m.partitions)
+}
+
+// PopFront returns the next element of one of the member's partitions. When
+// they are empty, the group takes visible elements from the queue and routes
+// them to their partitions until one reaches this member or the queue is
+// drained. It returns false after the member has left.
+func (m *Member[T]) PopFront() (zero T, _ bool) {
+	g := m.group
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if m.left {
+		return zero, false
+	}
+	for {
+		for _, p := range m.partitions {
+			if v, ok := g.partitions[p].popFront(); ok {
+				return v, true
+			}
+		}
+		v, ok := g.queue.PopFront()
+		if !ok {
+			return zero, false
+		}
+		g.partitions[g.partitionOf(v)].pushBack(v)
+	}
+}
+
+// Leave removes the member from the group. Its partitions, including elements
+// already routed to them, are reassigned to the remaining members.
+func (m *Member[T]) Leave() {
+	g := m.group
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if m.left {
+		return
+	}
+	m.left = true
+	m.partitions = nil
+	delete(g.members, m.name)
+	g.rebalanceLocked()
+}
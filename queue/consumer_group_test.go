@@ -0,0 +1,92 @@
+package queue
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestConsumerGroupDeliversEachElementOnce(t *testing.T) {
+	q := NewSegmentedQueue[int]()
+	g := NewConsumerGroup(q, 4, func(v int) uint64 { return uint64(v) })
+	a, _ := g.Join("a")
+	b, _ := g.Join("b")
+
+	if got := a.Partitions(); !slices.Equal(got, []int{0, 2}) {
+		t.Fatalf("unexpected assignment for a: %v", got)
+	}
+
+	for i := 0; i < 100; i++ {
+		q.PushBackPending(i)
+	}
+	q.Commit()
+
+	seen := make(map[int]string)
+	for _, m := range []*Member[int]{a, b} {
+		for {
+			v, ok := m.PopFront()
+			if !ok {
+				break
+			}
+			if owner, dup := seen[v]; dup {
+				t.Fatalf("element %d delivered to %s and %s", v, owner, m.Name())
+			}
+			seen[v] = m.Name()
+			if p := v % 4; !slices.Contains(m.Partitions(), p) {
+				t.Fatalf("element %d of partition %d delivered to %s", v, p, m.Name())
+			}
+		}
+	}
+	if len(seen) != 100 {
+		t.Fatalf("expected 100 deliveries, got %d", len(seen))
+	}
+}
+
+func TestConsumerGroupLeaveHandsOverBufferedElements(t *testing.T) {
+	q := NewSegmentedQueue[int](WithInitialVisible(0, 1, 2, 3))
+	g := NewConsumerGroup(q, 2, func(v int) uint64 { return uint64(v) })
+	a, _ := g.Join("a")
+	b, _ := g.Join("b")
+
+	if v, ok := a.PopFront(); !ok || v != 0 {
+		t.Fatalf("expected 0, got %d %v", v, ok)
+	}
+	// Routing the next element for a buffers element 1 in b's partition.
+	if v, ok := a.PopFront(); !ok || v != 2 {
+		t.Fatalf("expected 2, got %d %v", v, ok)
+	}
+	if g.LenBuffered() != 1 {
+		t.Fatalf("expected 1 buffered element, got %d", g.LenBuffered())
+	}
+
+	b.Leave()
+	if _, ok := b.PopFront(); ok {
+		t.Fatalf("a member that left must not receive elements")
+	}
+	if got := g.Members(); !slices.Equal(got, []string{"a"}) {
+		t.Fatalf("unexpected members %v", got)
+	}
+
+	var got []int
+	for {
+		v, ok := a.PopFront()
+		if !ok {
+			break
+		}
+		got = append(got, v)
+	}
+	slices.Sort(got)
+	if !slices.Equal(got, []int{1, 3}) {
+		t.Fatalf("expected the remaining elements, got %v", got)
+	}
+}
+
+func TestConsumerGroupJoinDuplicate(t *testing.T) {
+	g := NewConsumerGroup[int](NewSegmentedQueue[int](), 1, nil)
+	if _, err := g.Join("a"); err != nil {
+		t.Fatalf("join: %v", err)
+	}
+	if _, err := g.Join("a"); !errors.Is(err, ErrMemberExists) {
+		t.Fatalf("expected ErrMemberExists, got %v", err)
+	}
+}
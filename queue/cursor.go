@@ -0,0 +1,137 @@
+package queue
+
+import (
+	"errors"
+	"sync"
+)
+
+// ErrCursorExists is returned by CursorLog.Open for a name that already has a
+// cursor.
+var ErrCursorExists = errors.New("queue: cursor already exists")
+
+// CursorLog lets several named cursors read the same committed elements of a
+// SegmentedQueue. Each cursor keeps its own offset into the stream, so every
+// cursor sees every element in commit order. Elements taken from the queue
+// are retained until all cursors have passed them.
+//
+// Offsets count the elements the log has taken from the queue, starting at 0.
+type CursorLog[T any] struct {
+	queue *SegmentedQueue[T]
+
+	mu       sync.Mutex
+	retained []T
+	base     uint64
+	cursors  map[string]*Cursor[T]
+}
+
+// NewCursorLog creates a log on top of q. The log must be the only consumer
+// of q.
+func NewCursorLog[T any](q *SegmentedQueue[T]) *CursorLog[T] {
+	return &CursorLog[T]{queue: q, cursors: make(map[string]*Cursor[T])}
+}
+
+// Cursor is one reader of a CursorLog.
+type Cursor[T any] struct {
+	log    *CursorLog[T]
+	name   string
+	offset uint64
+	closed bool
+}
+
+// Open adds a cursor that starts at the oldest retained element.
+func (l *CursorLog[T]) Open(name string) (*Cursor[T], error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, ok := l.cursors[name]; ok {
+		return nil, ErrCursorExists
+	}
+	c := &Cursor[T]{log: l, name: name, offset: l.base}
+	l.cursors[name] = c
+	return c, nil
+}
+
+// LenRetained returns the number of elements taken from the queue that some
+// cursor has not read yet.
+func (l *CursorLog[T]) LenRetained() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.retained)
+}
+
+// truncateLocked drops the retained elements every cursor has passed. Without
+// cursors nothing is retained.
+func (l *CursorLog[T]) truncateLocked() {
+	end := l.base + uint64(len(l.retained))
+	for _, c := range l.cursors {
+		end = min(end, c.offset)
+	}
+	n := int(end - l.base)
+	clear(l.retained[:n])
+	l.retained = l.retained[n:]
+	l.base = end
+}
+
+// Name returns the cursor's name.
+func (c *Cursor[T]) Name() string {
+	return c.name
+}
+
+// Offset returns the offset of the next element the cursor reads.
+func (c *Cursor[T]) Offset() uint64 {
+	c.log.mu.Lock()
+	defer c.log.mu.Unlock()
+	return c.offset
+}
+
+// Lag returns how many retained elements the cursor has not read yet.
+// Elements still visible in the queue are not included.
+func (c *Cursor[T]) Lag() int {
+	l := c.log
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if c.closed {
+		return 0
+	}
+	return int(l.base + uint64(len(l.retained)) - c.offset)
+}
+
+// Next returns the element at the cursor's offset and advances it. When the
+// cursor has read every retained element, the log takes the next visible
+// element from the queue. It returns false when the queue has nothing visible
+// or after the cursor was closed.
+func (c *Cursor[T]) Next() (zero T, _ bool) {
+	l := c.log
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if c.closed {
+		return zero, false
+	}
+	i := int(c.offset - l.base)
+	if i == len(l.retained) {
+		v, ok := l.queue.PopFront()
+		if !ok {
+			return zero, false
+		}
+		l.retained = append(l.retained, v)
+	}
+	v := l.retained[i]
+	c.offset++
+	l.truncateLocked()
+	return v, true
+}
+
+// Close removes the cursor. Elements only it still needed are released.
+func (c *Cursor[T]) Close() {
+	l := c.log
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if c.closed {
+		return
+	}
+	c.closed = true
+	delete(l.cursors, c.name)
+	l.truncateLocked()
+}
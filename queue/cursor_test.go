@@ -0,0 +1,90 @@
+package queue
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func readAll(c *Cursor[int]) []int {
+	var values []int
+	for {
+		v, ok := c.Next()
+		if !ok {
+			return values
+		}
+		values = append(values, v)
+	}
+}
+
+func TestCursorsReadTheSameStream(t *testing.T) {
+	q := NewSegmentedQueue[int]()
+	l := NewCursorLog(q)
+	a, _ := l.Open("a")
+	b, _ := l.Open("b")
+	if _, err := l.Open("a"); !errors.Is(err, ErrCursorExists) {
+		t.Fatalf("expected ErrCursorExists, got %v", err)
+	}
+
+	for i := 0; i < 5; i++ {
+		q.PushBackPending(i)
+	}
+	if _, ok := a.Next(); ok {
+		t.Fatal("pending elements must not be readable")
+	}
+	q.Commit()
+
+	if got := readAll(a); !slices.Equal(got, []int{0, 1, 2, 3, 4}) {
+		t.Fatalf("cursor a read %v", got)
+	}
+	if l.LenRetained() != 5 || b.Lag() != 5 || a.Lag() != 0 {
+		t.Fatalf("expected 5 retained elements for b, got retained=%d lag a=%d b=%d", l.LenRetained(), a.Lag(), b.Lag())
+	}
+
+	for _, want := range []int{0, 1} {
+		if v, ok := b.Next(); !ok || v != want {
+			t.Fatalf("cursor b: expected %d, got %d (ok=%v)", want, v, ok)
+		}
+	}
+	if l.LenRetained() != 3 || b.Offset() != 2 {
+		t.Fatalf("expected truncation behind b, retained=%d offset=%d", l.LenRetained(), b.Offset())
+	}
+
+	q.PushBackPending(5)
+	q.Commit()
+	if got := readAll(b); !slices.Equal(got, []int{2, 3, 4, 5}) {
+		t.Fatalf("cursor b read %v", got)
+	}
+	if got := readAll(a); !slices.Equal(got, []int{5}) {
+		t.Fatalf("cursor a read %v", got)
+	}
+	if l.LenRetained() != 0 || q.LenVisible() != 0 {
+		t.Fatalf("expected everything consumed, retained=%d visible=%d", l.LenRetained(), q.LenVisible())
+	}
+}
+
+func TestCursorCloseReleasesElements(t *testing.T) {
+	q := NewSegmentedQueue[int](WithInitialVisible(1, 2, 3))
+	l := NewCursorLog(q)
+	fast, _ := l.Open("fast")
+	slow, _ := l.Open("slow")
+	readAll(fast)
+	if l.LenRetained() != 3 {
+		t.Fatalf("expected the slow cursor to retain 3 elements, got %d", l.LenRetained())
+	}
+
+	slow.Close()
+	if l.LenRetained() != 0 {
+		t.Fatalf("closing the slow cursor must release its elements, got %d", l.LenRetained())
+	}
+	if _, ok := slow.Next(); ok {
+		t.Fatal("closed cursor must not read")
+	}
+
+	late, _ := l.Open("slow")
+	q.PushBackPending(4)
+	q.Commit()
+	if got := readAll(late); !slices.Equal(got, []int{4}) {
+		t.Fatalf("reopened cursor read %v", got)
+	}
+}
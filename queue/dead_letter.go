@@ -0,0 +1,37 @@
+package queue
+
+// WithDeadLetter pushes every element the queue discards into the pending
+// segment of target instead of losing it: overflow drops of every policy,
+// elements whose TTL expired and elements that exhausted
+// WithMaxRedeliveries. Commit target to inspect them. The push happens after
+// the queue's locks have been released, so target may be any other queue,
+// including one that dead-letters back into this one.
+func WithDeadLetter[T any](target *SegmentedQueue[T]) SegmentedQueueOption[T] {
+	return func(opts *segmentedQueueOptions[T]) {
+		opts.deadLetter = target
+	}
+}
+
+// WithMaxRedeliveries limits how often an element obtained through
+// PopFrontAck or Lease is returned to the queue by a Nack or an expired
+// lease. When an element that was already redelivered n times comes back
+// once more, it is passed to the dead-letter queue, or discarded without one.
+// Values below 1 remove the limit.
+func WithMaxRedeliveries[T any](n int) SegmentedQueueOption[T] {
+	return func(opts *segmentedQueueOptions[T]) {
+		opts.maxRedelivery = n
+	}
+}
+
+// deadLetter hands discarded values to the dead-letter queue. The caller must
+// not hold any of the queue's locks.
+func (sq *SegmentedQueue[T]) deadLetter(values []T) {
+	target := sq.opts.deadLetter
+	if target == nil || len(values) == 0 {
+		return
+	}
+	for _, v := range values {
+		target.PushBackPending(v)
+	}
+	sq.counters.deadLet.Add(uint64(len(values)))
+}
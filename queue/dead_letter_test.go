@@ -0,0 +1,73 @@
+package queue
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func drain[T any](q *SegmentedQueue[T]) []T {
+	q.Commit()
+	var values []T
+	for v, ok := q.PopFront(); ok; v, ok = q.PopFront() {
+		values = append(values, v)
+	}
+	return values
+}
+
+func TestDeadLetterReceivesOverflowAndExpiredElements(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	dead := NewSegmentedQueue[int]()
+	q := NewSegmentedQueue[int](
+		WithClock[int](clock.Now),
+		WithOptions[int](Options{MaxLen: 2}),
+		WithTTL[int](time.Minute),
+		WithDeadLetter(dead),
+	)
+
+	for i := 1; i <= 3; i++ {
+		q.PushBackPending(i)
+	}
+	q.Commit()
+	clock.now = clock.now.Add(time.Hour)
+	if _, ok := q.PopFront(); ok {
+		t.Fatal("expected every visible element to be expired")
+	}
+
+	if got := drain(dead); !slices.Equal(got, []int{1, 2, 3}) {
+		t.Fatalf("expected [1 2 3] in the dead-letter queue, got %v", got)
+	}
+	if m := q.Metrics(); m.DeadLettered != 3 {
+		t.Fatalf("expected 3 dead-lettered elements, got %+v", m)
+	}
+}
+
+func TestDeadLetterAfterMaxRedeliveries(t *testing.T) {
+	dead := NewSegmentedQueue[string]()
+	q := NewSegmentedQueue[string](
+		WithInitialVisible("poison", "ok"),
+		WithMaxRedeliveries[string](2),
+		WithDeadLetter(dead),
+	)
+
+	for i := 0; i < 3; i++ {
+		v, h, ok := q.PopFrontAck()
+		if !ok || v != "poison" {
+			t.Fatalf("delivery %d: expected poison, got %q %v", i, v, ok)
+		}
+		if h.Redeliveries() != i {
+			t.Fatalf("delivery %d: expected %d redeliveries, got %d", i, i, h.Redeliveries())
+		}
+		h.Nack()
+	}
+
+	if v, ok := q.PopFront(); !ok || v != "ok" {
+		t.Fatalf("expected the poison element to be gone, got %q %v", v, ok)
+	}
+	if got := drain(dead); !slices.Equal(got, []string{"poison"}) {
+		t.Fatalf("expected [poison] in the dead-letter queue, got %v", got)
+	}
+	if m := q.Metrics(); m.Redelivered != 2 {
+		t.Fatalf("expected two redeliveries, got %+v", m)
+	}
+}
@@ -0,0 +1,143 @@
+package queue
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"unicode/utf8"
+)
+
+// maxSummary bounds the length of an element summary in DebugInfo, so a dump
+// stays readable for large elements.
+const maxSummary = 80
+
+// Debuggable is implemented by queues that can describe their internals; the
+// telemetry/admin handler serves it for registered queues.
+type Debuggable interface {
+	Debug() DebugInfo
+}
+
+// SegmentInfo describes one deque of a queue.
+type SegmentInfo struct {
+	// Name is "visible", "pending", "shard <i>", "lane <i>" or
+	// "producer <name>".
+	Name   string
+	Len    int
+	Chunks int
+	// First and Last summarise the front and back element; both are empty
+	// for an empty deque.
+	First, Last string
+}
+
+// DebugInfo is a point-in-time description of a queue's internals for
+// troubleshooting. The deques are read one after another, so the numbers of
+// a busy queue need not add up exactly.
+type DebugInfo struct {
+	Visible SegmentInfo
+	// Pending lists the pending deques in the order commits take them.
+	Pending []SegmentInfo
+	// Boundary is the position of the commit boundary: the number of
+	// elements ahead of the oldest uncommitted one. Version is the commit
+	// version that moved it last.
+	Boundary int
+	Version  uint64
+	// Staged counts the elements of prepared commits that are neither
+	// published nor aborted yet.
+	Staged   int
+	InFlight int
+	Delayed  int
+}
+
+// WithDebugSummary sets how Debug summarises elements. The default is
+// fmt.Sprint, which uses the String method of elements that implement
+// fmt.Stringer. Summaries are cut to 80 bytes.
+func WithDebugSummary[T any](summary func(T) string) SegmentedQueueOption[T] {
+	return func(opts *segmentedQueueOptions[T]) {
+		opts.debugSummary = summary
+	}
+}
+
+// Debug describes the queue's internals: the length, chunk count and first
+// and last element of every deque, the commit boundary and the staged,
+// in-flight and delayed counts. It is safe to call on a live queue: it holds
+// each lock only while reading from it and modifies nothing, not even by
+// redelivering expired leases as LenInFlight does.
+func (sq *SegmentedQueue[T]) Debug() DebugInfo {
+	sq.mu.Lock()
+	producers, named := sq.producers[:max(sq.opts.pendingShards, 1)], sq.namedOrder
+	sq.mu.Unlock()
+
+	info := DebugInfo{
+		Visible: sq.describe("visible", sq.visible),
+		Version: sq.version.Load(),
+		Staged:  int(sq.staged.Load()),
+	}
+	info.Boundary = info.Visible.Len
+	for i := len(sq.lanes) - 1; i > 0; i-- {
+		info.Pending = append(info.Pending, sq.describe("lane "+strconv.Itoa(i), sq.lanes[i]))
+	}
+	for i, shard := range producers {
+		name := "pending"
+		if i > 0 {
+			name = "shard " + strconv.Itoa(i)
+		}
+		info.Pending = append(info.Pending, sq.describe(name, shard))
+	}
+	for _, p := range named {
+		info.Pending = append(info.Pending, sq.describe("producer "+p.name, p.shard))
+	}
+
+	sq.ackMu.Lock()
+	info.InFlight = sq.inFlight
+	sq.ackMu.Unlock()
+	info.Delayed = sq.LenDelayed()
+	return info
+}
+
+// describe summarises d under its lock.
+func (sq *SegmentedQueue[T]) describe(name string, d *deque[T]) SegmentInfo {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	info := SegmentInfo{Name: name, Len: d.len}
+	for c := d.head; c != nil; c = c.next {
+		info.Chunks++
+	}
+	if d.len > 0 {
+		info.First = sq.summarise(d.head.values[d.head.lo])
+		info.Last = sq.summarise(d.tail.values[d.tail.hi-1])
+	}
+	return info
+}
+
+func (sq *SegmentedQueue[T]) summarise(v T) string {
+	var s string
+	if sq.opts.debugSummary != nil {
+		s = sq.opts.debugSummary(v)
+	} else {
+		s = fmt.Sprint(v)
+	}
+	if len(s) <= maxSummary {
+		return s
+	}
+	cut := maxSummary
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut] + "…"
+}
+
+// String renders the description as a table, one deque per row.
+func (info DebugInfo) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "version %d, boundary at %d, %d staged, %d in flight, %d delayed\n",
+		info.Version, info.Boundary, info.Staged, info.InFlight, info.Delayed)
+	w := tabwriter.NewWriter(&b, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "SEGMENT\tLEN\tCHUNKS\tFIRST\tLAST")
+	for _, s := range append([]SegmentInfo{info.Visible}, info.Pending...) {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%q\t%q\n", s.Name, s.Len, s.Chunks, s.First, s.Last)
+	}
+	w.Flush()
+	return b.String()
+}
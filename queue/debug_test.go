@@ -0,0 +1,74 @@
+package queue
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+type sample struct {
+	sensor string
+	value  int
+}
+
+func (s sample) String() string {
+	return s.sensor
+}
+
+func TestDebug(t *testing.T) {
+	q := NewSegmentedQueue(WithPriorityLanes[sample](2))
+	for i := range 3 {
+		q.PushBackPending(sample{sensor: "visible", value: i})
+	}
+	q.Commit()
+	q.PushBackPending(sample{"p1", 1})
+	q.PushBackPending(sample{"p2", 2})
+	q.PushBackPendingLane(1, sample{"urgent", 3})
+	q.Producer("modbus").PushBackPending(sample{"m1", 4})
+
+	info := q.Debug()
+	if info.Visible.Len != 3 || info.Visible.First != "visible" || info.Boundary != 3 || info.Version != q.Version() {
+		t.Fatalf("unexpected visible segment: %+v", info)
+	}
+	var names []string
+	for _, s := range info.Pending {
+		names = append(names, s.Name)
+	}
+	if got := strings.Join(names, ","); got != "lane 1,pending,producer modbus" {
+		t.Fatalf("unexpected pending deques: %s", got)
+	}
+	if p := info.Pending[1]; p.Len != 2 || p.Chunks != 1 || p.First != "p1" || p.Last != "p2" {
+		t.Fatalf("unexpected pending deque: %+v", p)
+	}
+
+	publish, _, err := q.PrepareCommit(context.Background())
+	if err != nil {
+		t.Fatalf("prepare failed: %v", err)
+	}
+	if info := q.Debug(); info.Staged != 4 || info.Pending[1].Len != 0 || info.Pending[1].First != "" {
+		t.Fatalf("unexpected staged state: %+v", info)
+	}
+	publish()
+	if info := q.Debug(); info.Staged != 0 || info.Boundary != 7 {
+		t.Fatalf("unexpected state after publish: %+v", info)
+	}
+}
+
+func TestDebugSummary(t *testing.T) {
+	q := NewSegmentedQueue(
+		WithDebugSummary(func(v string) string { return strings.ToUpper(v) }),
+		WithInitialVisible(strings.Repeat("ä", 100)),
+		WithInitialPending("b"),
+	)
+
+	info := q.Debug()
+	if first := info.Visible.First; len(first) > maxSummary+len("…") || !strings.HasSuffix(first, "…") || !strings.HasPrefix(first, "Ä") {
+		t.Fatalf("expected a cut summary, got %q", first)
+	}
+	if info.Pending[0].Last != "B" {
+		t.Fatalf("summary function not applied: %+v", info.Pending[0])
+	}
+	if out := info.String(); !strings.Contains(out, "boundary at 1") || !strings.Contains(out, `"B"`) {
+		t.Fatalf("unexpected rendering:\n%s", out)
+	}
+}
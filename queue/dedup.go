@@ -0,0 +1,27 @@
+package queue
+
+// WithDedup discards pending elements on publish whose key, as returned by
+// key, is already present in the visible segment or earlier in the same
+// commit. A key becomes available again once its element leaves the visible
+// segment through a pop, a drop or expiry.
+func WithDedup[T any, K comparable](key func(T) K) SegmentedQueueOption[T] {
+	return func(opts *segmentedQueueOptions[T]) {
+		opts.dedupKey = func(v T) any { return key(v) }
+	}
+}
+
+// dedupLocked removes duplicates from a staged segment, registers the keys of
+// the remaining elements and returns the shortened segment. The caller must
+// hold sq.visible.mu.
+func (sq *SegmentedQueue[T]) dedupLocked(s segment[T]) segment[T] {
+	s, duplicates := s.filter(func(v T) bool {
+		key := sq.opts.dedupKey(v)
+		if sq.visibleKeys[key] > 0 {
+			return false
+		}
+		sq.visibleKeys[key]++
+		return true
+	})
+	sq.counters.dupes.Add(uint64(duplicates))
+	return s
+}
@@ -0,0 +1,84 @@
+package queue
+
+import "testing"
+
+type reading struct {
+	addr  int
+	value int
+}
+
+func TestDedupDropsKeysAlreadyVisible(t *testing.T) {
+	q := NewSegmentedQueue[reading](WithDedup(func(r reading) int { return r.addr }))
+
+	q.PushBackPending(reading{1, 10})
+	q.PushBackPending(reading{2, 20})
+	q.PushBackPending(reading{1, 11})
+	q.Commit()
+
+	q.PushBackPending(reading{2, 21})
+	q.PushBackPending(reading{3, 30})
+	q.Commit()
+
+	var got []reading
+	for {
+		r, ok := q.PopFront()
+		if !ok {
+			break
+		}
+		got = append(got, r)
+	}
+	want := []reading{{1, 10}, {2, 20}, {3, 30}}
+	if len(got) != len(want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("expected %v, got %v", want, got)
+		}
+	}
+	if m := q.Metrics(); m.Duplicates != 2 {
+		t.Fatalf("expected 2 duplicates, got %d", m.Duplicates)
+	}
+}
+
+func TestDedupReleasesKeyAfterPop(t *testing.T) {
+	q := NewSegmentedQueue[int](WithDedup(func(v int) int { return v % 10 }))
+
+	q.PushBackPending(1)
+	q.Commit()
+	if v, ok := q.PopBack(); !ok || v != 1 {
+		t.Fatalf("expected 1, got %d %v", v, ok)
+	}
+
+	q.PushBackPending(11)
+	q.Commit()
+	if v, ok := q.PopFront(); !ok || v != 11 {
+		t.Fatalf("expected key to be free after pop, got %d %v", v, ok)
+	}
+}
+
+func TestDedupAcrossChunksAndDrops(t *testing.T) {
+	q := NewSegmentedQueue[int](
+		WithDedup(func(v int) int { return v % 100 }),
+		WithOptions[int](Options{MaxLen: 50, DropPolicy: DropOldest}),
+	)
+
+	for i := 0; i < 3*chunkSize; i++ {
+		q.PushBackPending(i)
+	}
+	q.Commit()
+	if q.LenVisible() != 50 {
+		t.Fatalf("expected 50 visible, got %d", q.LenVisible())
+	}
+
+	// Keys 0..49 were dropped by MaxLen and are free again; 50..99 are visible.
+	q.PushBackPending(1000)
+	q.PushBackPending(1050)
+	q.Commit()
+	if v, ok := q.PopBack(); !ok || v != 1000 {
+		t.Fatalf("expected 1000 as newest element, got %d %v", v, ok)
+	}
+	if m := q.Metrics(); m.Duplicates != 3*chunkSize-100+1 {
+		t.Fatalf("unexpected duplicate count %d", m.Duplicates)
+	}
+}
@@ -0,0 +1,77 @@
+package queue
+
+import (
+	"container/heap"
+	"time"
+)
+
+type delayedElement[T any] struct {
+	at    time.Time
+	seq   uint64
+	value T
+}
+
+// delayHeap orders delayed elements by due time and, for equal times, by push
+// order.
+type delayHeap[T any] []delayedElement[T]
+
+func (h delayHeap[T]) Len() int { return len(h) }
+
+func (h delayHeap[T]) Less(i, j int) bool {
+	if !h[i].at.Equal(h[j].at) {
+		return h[i].at.Before(h[j].at)
+	}
+	return h[i].seq < h[j].seq
+}
+
+func (h delayHeap[T]) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *delayHeap[T]) Push(x any) { *h = append(*h, x.(delayedElement[T])) }
+
+func (h *delayHeap[T]) Pop() any {
+	old := *h
+	last := old[len(old)-1]
+	old[len(old)-1] = delayedElement[T]{}
+	*h = old[:len(old)-1]
+	return last
+}
+
+// WithClock replaces time.Now as the time source for delayed visibility.
+func WithClock[T any](now func() time.Time) SegmentedQueueOption[T] {
+	return func(opts *segmentedQueueOptions[T]) {
+		opts.now = now
+	}
+}
+
+// PushBackPendingAfter adds value to the pending elements but holds it back
+// from commits until visibleAfter. Commits prepared at or after that time
+// include it behind the regular pending elements, in order of due time.
+func (sq *SegmentedQueue[T]) PushBackPendingAfter(value T, visibleAfter time.Time) {
+	sq.delayMu.Lock()
+	sq.delaySeq++
+	heap.Push(&sq.delayed, delayedElement[T]{at: visibleAfter, seq: sq.delaySeq, value: value})
+	sq.delayMu.Unlock()
+	sq.counters.pushes.Add(1)
+}
+
+// LenDelayed returns the number of pending elements whose delay has not yet
+// been taken into a commit.
+func (sq *SegmentedQueue[T]) LenDelayed() int {
+	sq.delayMu.Lock()
+	defer sq.delayMu.Unlock()
+	return len(sq.delayed)
+}
+
+// takeDue removes up to limit delayed elements that are due at now, or all due
+// elements when limit is negative, and returns them as a segment.
+func (sq *SegmentedQueue[T]) takeDue(now time.Time, limit int) segment[T] {
+	sq.delayMu.Lock()
+	defer sq.delayMu.Unlock()
+
+	due := sq.newDeque()
+	for len(sq.delayed) > 0 && !sq.delayed[0].at.After(now) && limit != 0 {
+		due.pushBack(heap.Pop(&sq.delayed).(delayedElement[T]).value)
+		limit--
+	}
+	return due.detachLocked()
+}
@@ -0,0 +1,71 @@
+package queue
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+type fakeClock struct{ now time.Time }
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func TestDelayedElementsWaitForTheirCommit(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	q := NewSegmentedQueue[string](WithClock[string](clock.Now))
+
+	q.PushBackPendingAfter("retry-2", clock.now.Add(2*time.Second))
+	q.PushBackPendingAfter("retry-1", clock.now.Add(time.Second))
+	q.PushBackPendingAfter("retry-1b", clock.now.Add(time.Second))
+	q.PushBackPending("fresh")
+
+	q.Commit()
+	if v, ok := q.PopFront(); !ok || v != "fresh" {
+		t.Fatalf("expected only the undelayed element, got %q (ok=%v)", v, ok)
+	}
+	if _, ok := q.PopFront(); ok || q.LenDelayed() != 3 {
+		t.Fatalf("delayed elements must stay pending before their time")
+	}
+
+	clock.now = clock.now.Add(time.Second)
+	q.PushBackPending("fresh-2")
+	q.Commit()
+	for _, want := range []string{"fresh-2", "retry-1", "retry-1b"} {
+		if v, ok := q.PopFront(); !ok || v != want {
+			t.Fatalf("expected %q, got %q (ok=%v)", want, v, ok)
+		}
+	}
+
+	clock.now = clock.now.Add(time.Hour)
+	if n := q.CommitUpTo(1); n != 1 {
+		t.Fatalf("expected CommitUpTo to publish the due element, got %d", n)
+	}
+	if v, _ := q.PopFront(); v != "retry-2" || q.LenDelayed() != 0 {
+		t.Fatalf("unexpected element %q", v)
+	}
+}
+
+func TestSnapshotKeepsDelayedElements(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	q := NewSegmentedQueue[int](WithInitialVisible(1), WithClock[int](clock.Now))
+	q.PushBackPendingAfter(3, clock.now.Add(time.Minute))
+	q.PushBackPendingAfter(2, clock.now.Add(time.Second))
+
+	var buf bytes.Buffer
+	if err := q.WriteSnapshot(&buf, nil); err != nil {
+		t.Fatalf("write snapshot failed: %v", err)
+	}
+	restored := NewSegmentedQueue[int](WithClock[int](clock.Now))
+	if err := restored.ReadSnapshot(&buf, nil); err != nil {
+		t.Fatalf("read snapshot failed: %v", err)
+	}
+	if restored.LenVisible() != 1 || restored.LenDelayed() != 2 {
+		t.Fatalf("unexpected lengths: visible=%d delayed=%d", restored.LenVisible(), restored.LenDelayed())
+	}
+
+	clock.now = clock.now.Add(time.Second)
+	restored.Commit()
+	if restored.LenVisible() != 2 || restored.LenDelayed() != 1 {
+		t.Fatalf("expected exactly the due element to be published")
+	}
+}
@@ -0,0 +1,493 @@
+package queue
+
+import "sync"
+
+// chunkSize is the number of elements stored per chunk. Pushes allocate only
+// when they start a new chunk.
+const chunkSize = 64
+
+// chunk is a fixed-size block of a deque. Its elements occupy values[lo:hi];
+// chunks linked into a deque are never empty. Chunks in the middle of a deque
+// may be partially filled after segments have been joined.
+type chunk[T any] struct {
+	values [chunkSize]T
+	lo, hi int
+	prev   *chunk[T]
+	next   *chunk[T]
+
+	// stamps holds per-element push times for deques with a clock.
+	stamps *[chunkSize]int64
+	// committed is the time the chunk was published, for queues with a TTL.
+	committed int64
+	// version is the commit version that published the chunk, for queues
+	// with version stamps.
+	version uint64
+	// redeliveries counts how often the chunk's elements were returned by a
+	// Nack or an expired lease, for queues with WithMaxRedeliveries.
+	redeliveries int
+	// lane is the priority lane a staged chunk was taken from, so an abort
+	// can return it there.
+	lane int
+	// shared marks a chunk referenced by a View. Its values are never
+	// overwritten: pops leave the slots alone, pushes copy the chunk first and
+	// the chunk is not recycled once it empties.
+	shared bool
+}
+
+// segment is a detached run of chunks, as staged by PrepareCommit.
+type segment[T any] struct {
+	head *chunk[T]
+	tail *chunk[T]
+	len  int
+}
+
+// filter removes the elements for which keep returns false by compacting the
+// chunks of s in place and returns the shortened segment and the number of
+// removed elements. keep is called exactly once per element, in order.
+func (s segment[T]) filter(keep func(T) bool) (segment[T], int) {
+	var zero T
+	removed := 0
+	for c := s.head; c != nil; {
+		next := c.next
+		w := c.lo
+		for i := c.lo; i < c.hi; i++ {
+			if !keep(c.values[i]) {
+				removed++
+				continue
+			}
+			c.values[w] = c.values[i]
+			if c.stamps != nil {
+				c.stamps[w] = c.stamps[i]
+			}
+			w++
+		}
+		for i := w; i < c.hi; i++ {
+			c.values[i] = zero
+		}
+		s.len -= c.hi - w
+		c.hi = w
+
+		if c.lo == c.hi {
+			if c.prev != nil {
+				c.prev.next = next
+			} else {
+				s.head = next
+			}
+			if next != nil {
+				next.prev = c.prev
+			} else {
+				s.tail = c.prev
+			}
+			c.prev, c.next = nil, nil
+		}
+		c = next
+	}
+	return s, removed
+}
+
+// setLane records lane on every chunk of s.
+func (s segment[T]) setLane(lane int) segment[T] {
+	for c := s.head; c != nil; c = c.next {
+		c.lane = lane
+	}
+	return s
+}
+
+// splitLanes splits s by the lane recorded on its chunks. Each part keeps
+// the order its chunks had in s.
+func (s segment[T]) splitLanes() map[int]segment[T] {
+	parts := make(map[int]segment[T])
+	for c := s.head; c != nil; {
+		next := c.next
+		c.prev, c.next = nil, nil
+		parts[c.lane] = parts[c.lane].join(segment[T]{head: c, tail: c, len: c.hi - c.lo})
+		c = next
+	}
+	return parts
+}
+
+// join links other behind s and returns the combined segment.
+func (s segment[T]) join(other segment[T]) segment[T] {
+	if other.len == 0 {
+		return s
+	}
+	if s.len == 0 {
+		return other
+	}
+	s.tail.next = other.head
+	other.head.prev = s.tail
+	return segment[T]{head: s.head, tail: other.tail, len: s.len + other.len}
+}
+
+type deque[T any] struct {
+	head *chunk[T]
+	tail *chunk[T]
+	len  int
+	mu   sync.Mutex
+
+	// spare keeps the most recently emptied chunk, so that a deque oscillating
+	// around a chunk boundary does not allocate on every push.
+	spare *chunk[T]
+
+	// now, when set, stamps every pushed element with the current time in
+	// nanoseconds.
+	now func() int64
+}
+
+func newDeque[T any]() *deque[T] {
+	return &deque[T]{}
+}
+
+func (d *deque[T]) newChunk(lo int) *chunk[T] {
+	c := d.spare
+	if c == nil {
+		c = &chunk[T]{}
+	} else {
+		d.spare = nil
+	}
+	c.lo, c.hi = lo, lo
+	c.committed = 0
+	c.version = 0
+	c.redeliveries = 0
+	c.lane = 0
+	c.shared = false
+	if d.now != nil && c.stamps == nil {
+		c.stamps = new([chunkSize]int64)
+	}
+	return c
+}
+
+// release recycles c, which must already be empty and unlinked.
+func (d *deque[T]) release(c *chunk[T]) {
+	c.prev, c.next = nil, nil
+	if !c.shared {
+		d.spare = c
+	}
+}
+
+// unshare replaces the shared chunk c with a private copy and returns it.
+func (d *deque[T]) unshare(c *chunk[T]) *chunk[T] {
+	cp := &chunk[T]{lo: c.lo, hi: c.hi, prev: c.prev, next: c.next, committed: c.committed, version: c.version, redeliveries: c.redeliveries}
+	copy(cp.values[c.lo:c.hi], c.values[c.lo:c.hi])
+	if c.stamps != nil {
+		cp.stamps = new([chunkSize]int64)
+		copy(cp.stamps[c.lo:c.hi], c.stamps[c.lo:c.hi])
+	}
+	if c.prev != nil {
+		c.prev.next = cp
+	} else {
+		d.head = cp
+	}
+	if c.next != nil {
+		c.next.prev = cp
+	} else {
+		d.tail = cp
+	}
+	return cp
+}
+
+func (d *deque[T]) pushBack(value T) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.pushBackLocked(value)
+}
+
+func (d *deque[T]) pushBackLocked(value T) {
+	if d.tail == nil || d.tail.hi == chunkSize {
+		c := d.newChunk(0)
+		if d.tail == nil {
+			d.head = c
+		} else {
+			c.prev = d.tail
+			d.tail.next = c
+		}
+		d.tail = c
+	} else if d.tail.shared {
+		d.unshare(d.tail)
+	}
+	d.tail.values[d.tail.hi] = value
+	if d.now != nil && d.tail.stamps != nil {
+		d.tail.stamps[d.tail.hi] = d.now()
+	}
+	d.tail.hi++
+	d.len++
+}
+
+func (d *deque[T]) pushFront(value T) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.pushFrontLocked(value)
+}
+
+func (d *deque[T]) pushFrontLocked(value T) {
+	if d.head == nil || d.head.lo == 0 {
+		c := d.newChunk(chunkSize)
+		if d.head == nil {
+			d.tail = c
+		} else {
+			c.next = d.head
+			d.head.prev = c
+		}
+		d.head = c
+	} else if d.head.shared {
+		d.unshare(d.head)
+	}
+	d.head.lo--
+	d.head.values[d.head.lo] = value
+	if d.now != nil && d.head.stamps != nil {
+		d.head.stamps[d.head.lo] = d.now()
+	}
+	d.len++
+}
+
+func (d *deque[T]) popFront() (zero T, _ bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.popFrontLocked()
+}
+
+func (d *deque[T]) popFrontLocked() (zero T, _ bool) {
+	if d.len == 0 {
+		return zero, false
+	}
+
+	c := d.head
+	value := c.values[c.lo]
+	if !c.shared {
+		c.values[c.lo] = zero
+	}
+	c.lo++
+	d.len--
+
+	if c.lo == c.hi {
+		d.head = c.next
+		if d.head != nil {
+			d.head.prev = nil
+		} else {
+			d.tail = nil
+		}
+		d.release(c)
+	}
+
+	return value, true
+}
+
+func (d *deque[T]) popBack() (zero T, _ bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.popBackLocked()
+}
+
+func (d *deque[T]) popBackLocked() (zero T, _ bool) {
+	if d.len == 0 {
+		return zero, false
+	}
+
+	c := d.tail
+	c.hi--
+	value := c.values[c.hi]
+	if !c.shared {
+		c.values[c.hi] = zero
+	}
+	d.len--
+
+	if c.lo == c.hi {
+		d.tail = c.prev
+		if d.tail != nil {
+			d.tail.next = nil
+		} else {
+			d.head = nil
+		}
+		d.release(c)
+	}
+
+	return value, true
+}
+
+func (d *deque[T]) length() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.len
+}
+
+func (d *deque[T]) valuesLocked() []T {
+	values := make([]T, 0, d.len)
+	for c := d.head; c != nil; c = c.next {
+		values = append(values, c.values[c.lo:c.hi]...)
+	}
+	return values
+}
+
+// detachLocked removes all elements and returns them as a segment.
+func (d *deque[T]) detachLocked() segment[T] {
+	s := segment[T]{head: d.head, tail: d.tail, len: d.len}
+	d.head, d.tail, d.len = nil, nil, 0
+	return s
+}
+
+// detachFrontLocked removes up to n elements from the front and returns them as
+// a segment. At most one chunk is copied, when n ends inside it.
+func (d *deque[T]) detachFrontLocked(n int) segment[T] {
+	if n <= 0 {
+		return segment[T]{}
+	}
+	if n >= d.len {
+		return d.detachLocked()
+	}
+
+	c := d.head
+	remaining := n
+	for c.hi-c.lo < remaining {
+		remaining -= c.hi - c.lo
+		c = c.next
+	}
+
+	s := segment[T]{head: d.head, len: n}
+	if remaining == c.hi-c.lo {
+		d.head = c.next
+		d.head.prev = nil
+		c.next = nil
+		s.tail = c
+	} else {
+		prefix := &chunk[T]{hi: remaining, committed: c.committed}
+		copy(prefix.values[:remaining], c.values[c.lo:c.lo+remaining])
+		if c.stamps != nil {
+			prefix.stamps = new([chunkSize]int64)
+			copy(prefix.stamps[:remaining], c.stamps[c.lo:c.lo+remaining])
+		}
+		var zero T
+		for i := c.lo; i < c.lo+remaining; i++ {
+			c.values[i] = zero
+		}
+		c.lo += remaining
+
+		prefix.prev = c.prev
+		if c.prev != nil {
+			c.prev.next = prefix
+		} else {
+			s.head = prefix
+		}
+		c.prev = nil
+		d.head = c
+		s.tail = prefix
+	}
+	d.len -= n
+	return s
+}
+
+// replaceLocked discards the current elements and takes over s.
+func (d *deque[T]) replaceLocked(s segment[T]) {
+	d.head, d.tail, d.len = s.head, s.tail, s.len
+}
+
+// appendLocked moves all elements of other behind the current elements.
+func (d *deque[T]) appendLocked(other *deque[T]) {
+	d.appendSegmentLocked(other.detachLocked())
+}
+
+// appendSegmentLocked links s behind the current elements.
+func (d *deque[T]) appendSegmentLocked(s segment[T]) {
+	if s.len == 0 {
+		return
+	}
+	if d.len == 0 {
+		d.replaceLocked(s)
+		return
+	}
+	s.head.prev = d.tail
+	d.tail.next = s.head
+	d.tail = s.tail
+	d.len += s.len
+}
+
+// prependSegmentLocked links s in front of the current elements.
+func (d *deque[T]) prependSegmentLocked(s segment[T]) {
+	if s.len == 0 {
+		return
+	}
+	if d.len == 0 {
+		d.replaceLocked(s)
+		return
+	}
+	s.tail.next = d.head
+	d.head.prev = s.tail
+	d.head = s.head
+	d.len += s.len
+}
+
+// removeLocked removes every element for which remove, given its position
+// counted from the front and its value, returns true, and returns the removed
+// elements in order. remove is called exactly once per element. Chunks are
+// compacted in place; a shared chunk is copied before its first modification.
+func (d *deque[T]) removeLocked(remove func(index int, value T) bool) []T {
+	var zero T
+	var removed []T
+	index := 0
+	for c := d.head; c != nil; {
+		next := c.next
+		w := c.lo
+		for i := c.lo; i < c.hi; i++ {
+			v := c.values[i]
+			index++
+			if remove(index-1, v) {
+				if c.shared {
+					c = d.unshare(c)
+				}
+				removed = append(removed, v)
+				continue
+			}
+			if w != i {
+				c.values[w] = v
+				if c.stamps != nil {
+					c.stamps[w] = c.stamps[i]
+				}
+			}
+			w++
+		}
+		if w == c.hi {
+			c = next
+			continue
+		}
+
+		for i := w; i < c.hi; i++ {
+			c.values[i] = zero
+		}
+		d.len -= c.hi - w
+		c.hi = w
+
+		if c.lo == c.hi {
+			if c.prev != nil {
+				c.prev.next = next
+			} else {
+				d.head = next
+			}
+			if next != nil {
+				next.prev = c.prev
+			} else {
+				d.tail = c.prev
+			}
+			d.release(c)
+		}
+		c = next
+	}
+	return removed
+}
+
+// cloneLocked appends copies of all elements of d to dst, chunk by chunk, so
+// that per-element stamps, publish times and versions are preserved. copy
+// duplicates a single element.
+func (d *deque[T]) cloneLocked(dst *deque[T], copy func(T) T) {
+	for c := d.head; c != nil; c = c.next {
+		cp := &chunk[T]{lo: c.lo, hi: c.hi, committed: c.committed, version: c.version, redeliveries: c.redeliveries}
+		for i := c.lo; i < c.hi; i++ {
+			cp.values[i] = copy(c.values[i])
+		}
+		if c.stamps != nil {
+			cp.stamps = new([chunkSize]int64)
+			*cp.stamps = *c.stamps
+		}
+		dst.appendSegmentLocked(segment[T]{head: cp, tail: cp, len: c.hi - c.lo})
+	}
+}
@@ -0,0 +1,132 @@
+package queue
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestDequeMatchesSliceModel(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	d := newDeque[int]()
+	var model []int
+
+	for i := 0; i < 20000; i++ {
+		switch op := rng.Intn(4); op {
+		case 0:
+			d.pushBack(i)
+			model = append(model, i)
+		case 1:
+			d.pushFront(i)
+			model = append([]int{i}, model...)
+		case 2, 3:
+			var v int
+			var ok bool
+			if op == 2 {
+				v, ok = d.popFront()
+			} else {
+				v, ok = d.popBack()
+			}
+			if ok != (len(model) > 0) {
+				t.Fatalf("step %d: pop ok=%v with model length %d", i, ok, len(model))
+			}
+			if !ok {
+				continue
+			}
+			var want int
+			if op == 2 {
+				want, model = model[0], model[1:]
+			} else {
+				want, model = model[len(model)-1], model[:len(model)-1]
+			}
+			if v != want {
+				t.Fatalf("step %d: expected %d, got %d", i, want, v)
+			}
+		}
+		if d.length() != len(model) {
+			t.Fatalf("step %d: expected length %d, got %d", i, len(model), d.length())
+		}
+	}
+}
+
+func TestDequeJoinsPartialChunks(t *testing.T) {
+	front := newDeque[int]()
+	back := newDeque[int]()
+	for i := 0; i < chunkSize+3; i++ {
+		front.pushBack(i)
+	}
+	for i := chunkSize + 3; i < 2*chunkSize; i++ {
+		back.pushBack(i)
+	}
+
+	front.appendLocked(back)
+	staged := front.detachLocked()
+	front.pushFront(-1)
+	front.appendSegmentLocked(staged)
+	front.pushBack(2 * chunkSize)
+
+	values := front.valuesLocked()
+	if len(values) != 2*chunkSize+2 {
+		t.Fatalf("unexpected length %d", len(values))
+	}
+	for i, v := range values {
+		if v != i-1 {
+			t.Fatalf("unexpected order at %d: %v", i, values)
+		}
+	}
+	for i := range values {
+		if v, ok := front.popBack(); !ok || v != values[len(values)-1-i] {
+			t.Fatalf("unexpected popBack result %d at %d", v, i)
+		}
+	}
+	if front.head != nil || front.tail != nil {
+		t.Fatalf("empty deque must not keep chunks linked")
+	}
+}
+
+func BenchmarkPushBackPending(b *testing.B) {
+	q := NewSegmentedQueue[int]()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		q.PushBackPending(i)
+		if i%1024 == 1023 {
+			q.Commit()
+			for {
+				if _, ok := q.PopFront(); !ok {
+					break
+				}
+			}
+		}
+	}
+}
+
+func TestDequeDetachFront(t *testing.T) {
+	for _, n := range []int{0, 1, chunkSize - 1, chunkSize, chunkSize + 1, 2*chunkSize + 5, 3 * chunkSize} {
+		d := newDeque[int]()
+		for i := 0; i < 2*chunkSize+10; i++ {
+			d.pushBack(i)
+		}
+		d.popFront()
+
+		rest := newDeque[int]()
+		rest.appendSegmentLocked(d.detachFrontLocked(n))
+		taken := rest.valuesLocked()
+		remaining := d.valuesLocked()
+
+		want := n
+		if want > 2*chunkSize+9 {
+			want = 2*chunkSize + 9
+		}
+		if len(taken) != want || rest.length() != want || len(remaining) != 2*chunkSize+9-want || d.length() != len(remaining) {
+			t.Fatalf("n=%d: took %d, left %d", n, len(taken), len(remaining))
+		}
+		for i, v := range append(taken, remaining...) {
+			if v != i+1 {
+				t.Fatalf("n=%d: unexpected order at %d", n, i)
+			}
+		}
+		d.pushFront(0)
+		if v, ok := d.popFront(); !ok || v != 0 {
+			t.Fatalf("n=%d: deque unusable after detach", n)
+		}
+	}
+}
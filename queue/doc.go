@@ -13,6 +13,180 @@
 // visible segment exceeds the configured MaxLen, elements are dropped according
 // to the configured DropPolicy before Publish releases its locks.
 //
+// Every queue counts pushes, pops, published and aborted commits, and the
+// elements discarded by each DropPolicy, as well as a histogram of how many
+// elements each commit published. Metrics returns a snapshot of these
+// counters; the telemetry exporters pick them up for registered queues.
+//
+// WriteSnapshot and ReadSnapshot serialise both segments to an io.Writer and
+// back, keeping the commit boundary, so a long-lived queue can be checkpointed
+// to disk between deploys. Elements are encoded with a codec.Encoder, gob by
+// default.
+//
+// Both segments store elements in linked chunks of fixed-size arrays, so
+// pushes allocate only once per chunk. Prepare and publish still move whole
+// segments in constant time by relinking chunks.
+//
+// CommitUpTo publishes only a bounded prefix of the pending segment. Calling it
+// in a loop commits a very large backlog step by step without holding the
+// segment locks for the whole merge.
+//
+// With WithPendingShards the pending segment is split into several deques.
+// Producers created with NewProducer are spread over the shards and push
+// without contending with each other; PrepareCommit merges the shards in index
+// order, and an aborted commit returns the merged elements to the first shard.
+// WithFairMerge interleaves the shards round-robin instead, so one busy
+// producer cannot crowd the others out of the head of the queue.
+//
+// Producer returns a named ProducerHandle with a pending buffer of its own.
+// ProducerStats reports how much every producer pushed and, with
+// WithProducerLimit, lost, which singles out a producer flooding the queue.
+//
+// WithPriorityLanes adds pending lanes above the regular one. Elements pushed
+// with PushBackPendingLane are published highest lane first, so urgent
+// control messages precede bulk data of the same commit.
+//
+// PushBackPendingAfter holds an element back until a point in time: commits
+// prepared earlier leave it pending, later ones publish it behind the regular
+// pending elements. This suits retries that must wait for their backoff.
+//
+// WithTTL bounds how long an element may stay in the queue, measured from its
+// push or, with WithTTLFromCommit, from its publish. Expired elements are
+// evicted when commits are published and skipped on pop, so they are never
+// delivered; WithExpiryCallback observes them.
+//
+// WithDedup keeps at most one visible element per key. Pending elements whose
+// key is already visible, or appeared earlier in the same commit, are
+// discarded when the commit is published.
+//
+// PriorityQueue follows the same commit protocol but keeps one lane per
+// priority. A published commit merges its elements into the visible lanes, and
+// PopFront serves the highest priority first, so urgent elements overtake
+// routine ones without ever bypassing the commit barrier.
+//
+// Ready returns a channel that is closed when the next commit makes elements
+// visible, so consumers can wait instead of polling PopFront; PopFrontWait
+// wraps that pattern and honours a context deadline.
+//
+// PopBatch collects visible elements into batches bounded by a minimum and
+// maximum size and a maximum wait, for consumers that write in bulk.
+//
+// ShardedQueue routes elements by key to several SegmentedQueues, keeping
+// the order per key, and commits all of them as one orchestrator bank.
+//
+// Router keeps one queue per key and creates it on the first push to that
+// key. The queues are registered with a CommitOrchestrator as they appear and
+// unregistered when removed, so tenants or topics can come and go at runtime.
+//
+// Topic adds publish/subscribe: publishers push into the topic, every
+// subscriber pops from a queue of its own, and one commit publishes the
+// topic's pending elements to all subscribers together.
+//
+// StealingConsumer spreads consumers over a set of queues that share one
+// stream: each consumer pops from its home queue and steals committed
+// elements from the others when its own runs dry.
+//
+// PopFrontAck delivers an element together with an AckHandle. Until it is
+// acknowledged the element is in flight; a Nack or an expired
+// WithVisibilityTimeout returns it to the front of the visible segment, so a
+// consumer that crashes mid-processing does not lose it.
+//
+// Subscribe runs a pool of workers on top of PopFrontAck: every committed
+// element is passed to a handler, and the handler's error decides whether the
+// element is acknowledged, retried or discarded to the dead letter queue.
+//
+// Lease works the same way with a per-call visibility timeout: the element
+// returns to the front unless the LeaseHandle completes it in time.
+//
+// Both handles report how often their element was redelivered before, with
+// WithMaxRedeliveries or WithRedeliveryCount, so a consumer can treat a
+// suspected poison message differently before WithMaxRedeliveries moves it
+// to the dead letter queue.
+//
+// WithProcessedStore records the ID of every acknowledged element in a
+// DedupStore and skips later deliveries of a recorded ID, which makes
+// redeliveries harmless for idempotent consumers.
+//
+// WithDeadLetter keeps everything the queue discards, whether dropped on
+// overflow, expired by its TTL or redelivered more often than
+// WithMaxRedeliveries allows, in a second queue for later inspection.
+//
+// ConsumerGroup lets several named members share one queue. Visible elements
+// are routed to partitions and every partition belongs to exactly one member,
+// so each element is delivered once; partitions are rebalanced as members join
+// and leave.
+//
+// CursorLog is the opposite: every named Cursor reads every committed element
+// at its own pace. Elements stay retained until the slowest cursor has passed
+// them, so several subsystems can share one committed stream.
+//
+// WithHistory and WithCommitHistory retain the last published elements or
+// commits after they were popped; ReplayFrom yields them again from a given
+// commit version, for consumers that need to rebuild state.
+//
+// ReadAt pins the visible segment as of a published version, so multi-pass
+// analytics see a consistent state while commits proceed. A version stays
+// readable until its last reader releases it.
+//
+// SpliceTo moves the visible elements of one queue behind those of another by
+// relinking their chunks under both locks, so the move is atomic and copies
+// nothing. Merge does the same for a whole queue, pending elements included,
+// and SplitFunc moves the visible elements matching a predicate into a new
+// queue, for example to separate urgent traffic during an incident.
+//
+// Options.MaxBytes bounds the visible segment by payload size instead of
+// element count; WithSizer supplies the size of each element.
+//
+// Options.Watermarks reports when the visible depth reaches a high watermark
+// and when it falls back to a low one, so producers can be throttled without
+// polling.
+//
+// WithMaxCommitBatch caps how many pending elements a single commit publishes,
+// so draining a backlog is spread over several commits.
+//
+// With the OverflowBlock policy, PushCtx applies backpressure: producers wait,
+// cancellable through their context, until consumers make room below MaxLen.
+//
+// The DropLowestPriority policy, together with WithDropPriority, evicts the
+// least important visible elements on overflow instead of the oldest or
+// newest ones.
+//
+// The DropExpired policy, configured with WithMaxAge, prefers fresh data: every
+// publish first discards visible elements past their maximum age and only then
+// trims the oldest ones to fit the limits.
+//
+// DropSampled handles overflow by thinning: it drops elements evenly spread
+// over the visible segment, keeping a representative sample of the stream.
+//
+// SnapshotView captures the visible segment without copying its elements. The
+// chunks it references become copy-on-write, so the view can be iterated while
+// commits and pops continue.
+//
+// WithVersionStamps tags elements with the commit version that published them,
+// the CommitOrchestrator's version when one drives the commit, and
+// PopFrontVersioned returns it alongside the element. WithElementMeta adds the
+// enqueue and commit time, which PopFrontMeta reports for latency tracking;
+// Residency aggregates them into distributions of the pending, visible and
+// total time elements spend in the queue.
+//
+// WithCommitFilter validates elements as they are staged for a commit, so
+// invalid ones never become visible: they are dropped and reported, or with
+// WithStrictCommitFilter fail the prepare. WithCommitTransform rewrites
+// elements as their commit is published, for unit conversion or enrichment.
+//
+// Clone duplicates a queue together with its commit boundary, which is handy
+// for what-if simulations and test fixtures.
+//
+// CoalescingQueue is meant for state updates such as register values: pending
+// writes to the same key collapse so that a commit publishes only the latest
+// value per key, in the order the keys were first written.
+//
+// A Registry finds the queues of a process by name: queues join it with
+// WithRegistry or Register, and its Stats list them deepest first, which the
+// telemetry/admin handler and the expvar exporter build on. Debug describes
+// the internals of a live queue for troubleshooting: the length and first and
+// last element of every segment, the commit boundary and the staged commits.
+//
 // The queue is safe for concurrent producers and consumers that interact with
 // different segments. Operations on the visible and pending segments use their
 // own internal locks, while the publish/abort steps serialise mutations via an
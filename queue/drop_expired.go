@@ -0,0 +1,38 @@
+package queue
+
+import "time"
+
+// WithMaxAge sets the age beyond which the DropExpired policy discards visible
+// elements. An element's age is measured from its push. Unlike WithTTL, which
+// hides expired elements from consumers, the age is only checked when a commit
+// is published, and the whole visible segment is scanned, so elements
+// redelivered or returned by an abort out of push order are caught as well.
+func WithMaxAge[T any](age time.Duration) SegmentedQueueOption[T] {
+	return func(opts *segmentedQueueOptions[T]) {
+		opts.maxAge = age
+	}
+}
+
+// dropExpiredLocked removes every visible element older than the maximum age
+// at now and returns them. The caller must hold
+// sq.visible.mu.
+func (sq *SegmentedQueue[T]) dropExpiredLocked(now int64) []T {
+	victims := make([]bool, 0, sq.visible.len)
+	found := false
+	for c := sq.visible.head; c != nil; c = c.next {
+		for i := c.lo; i < c.hi; i++ {
+			old := c.stamps != nil && now-c.stamps[i] > int64(sq.opts.maxAge)
+			victims = append(victims, old)
+			found = found || old
+		}
+	}
+	if !found {
+		return nil
+	}
+
+	removed := sq.visible.removeLocked(func(i int, _ T) bool { return victims[i] })
+	for _, v := range removed {
+		sq.forgetLocked(v)
+	}
+	return removed
+}
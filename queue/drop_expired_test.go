@@ -0,0 +1,66 @@
+package queue
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDropExpiredDiscardsOldElementsBeforeMaxLen(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	q := NewSegmentedQueue[int](
+		WithClock[int](clock.Now),
+		WithOptions[int](Options{MaxLen: 2, DropPolicy: DropExpired}),
+		WithMaxAge[int](time.Minute),
+	)
+
+	q.PushBackPending(1)
+	q.Commit()
+	clock.now = clock.now.Add(2 * time.Minute)
+	q.PushBackPending(2)
+	q.PushBackPending(3)
+	q.PushBackPending(4)
+	q.Commit()
+
+	var got []int
+	for v, ok := q.PopFront(); ok; v, ok = q.PopFront() {
+		got = append(got, v)
+	}
+	if len(got) != 2 || got[0] != 3 || got[1] != 4 {
+		t.Fatalf("expected [3 4], got %v", got)
+	}
+	m := q.Metrics()
+	if m.Drops[DropExpired] != 1 || m.Drops[DropOldest] != 1 {
+		t.Fatalf("unexpected drops: %+v", m.Drops)
+	}
+}
+
+func TestDropExpiredWithoutMaxAgeDropsOldest(t *testing.T) {
+	q := NewSegmentedQueue[int](WithOptions[int](Options{MaxLen: 1, DropPolicy: DropExpired}))
+	q.PushBackPending(1)
+	q.PushBackPending(2)
+	q.Commit()
+
+	if v, ok := q.PopFront(); !ok || v != 2 {
+		t.Fatalf("expected 2, got %d %v", v, ok)
+	}
+}
+
+func TestDropExpiredCatchesRedeliveredElements(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	q := NewSegmentedQueue[int](
+		WithClock[int](clock.Now),
+		WithOptions[int](Options{DropPolicy: DropExpired}),
+		WithMaxAge[int](time.Minute),
+	)
+	q.PushBackPending(1)
+	q.Commit()
+	_, h, _ := q.PopFrontAck()
+	clock.now = clock.now.Add(2 * time.Minute)
+	h.Nack()
+
+	q.PushBackPending(2)
+	q.Commit()
+	if v, ok := q.PopFront(); !ok || v != 2 {
+		t.Fatalf("expected the redelivered element to be dropped by its push age, got %d %v", v, ok)
+	}
+}
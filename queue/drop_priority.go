@@ -0,0 +1,63 @@
+package queue
+
+import (
+	"cmp"
+	"slices"
+)
+
+// WithDropPriority ranks elements for the DropLowestPriority policy. When a
+// published commit overflows MaxLen or MaxBytes, the visible elements with the
+// lowest priority are evicted first, the oldest among equal priorities, so an
+// alarm is never lost merely because it is old. Selecting the victims scans
+// the whole visible segment, so overflow costs O(n log n) per commit.
+func WithDropPriority[T any](priority func(T) int) SegmentedQueueOption[T] {
+	return func(opts *segmentedQueueOptions[T]) {
+		opts.dropPriority = priority
+	}
+}
+
+// dropLowestPriorityLocked evicts the lowest-priority visible elements until
+// the limits hold again and returns them.
+func (sq *SegmentedQueue[T]) dropLowestPriorityLocked() []T {
+	if !sq.exceedsLocked(sq.visible.len, sq.visibleBytes) {
+		return nil
+	}
+
+	type candidate struct {
+		priority int
+		index    int
+		size     int
+	}
+	candidates := make([]candidate, 0, sq.visible.len)
+	for c := sq.visible.head; c != nil; c = c.next {
+		for _, v := range c.values[c.lo:c.hi] {
+			cand := candidate{priority: sq.opts.dropPriority(v), index: len(candidates)}
+			if sq.opts.sizer != nil {
+				cand.size = sq.opts.sizer(v)
+			}
+			candidates = append(candidates, cand)
+		}
+	}
+	// The stable sort keeps older elements ahead of newer ones of the same
+	// priority.
+	slices.SortStableFunc(candidates, func(a, b candidate) int {
+		return cmp.Compare(a.priority, b.priority)
+	})
+
+	victims := make([]bool, len(candidates))
+	n, size := sq.visible.len, sq.visibleBytes
+	for _, cand := range candidates {
+		if !sq.exceedsLocked(n, size) {
+			break
+		}
+		victims[cand.index] = true
+		n--
+		size -= cand.size
+	}
+
+	removed := sq.visible.removeLocked(func(i int, _ T) bool { return victims[i] })
+	for _, v := range removed {
+		sq.forgetLocked(v)
+	}
+	return removed
+}
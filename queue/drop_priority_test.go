@@ -0,0 +1,86 @@
+package queue
+
+import (
+	"slices"
+	"testing"
+)
+
+type event struct {
+	id       int
+	severity int
+}
+
+func TestDropLowestPriorityKeepsAlarms(t *testing.T) {
+	q := NewSegmentedQueue[event](
+		WithDropPriority(func(e event) int { return e.severity }),
+		WithOptions[event](Options{MaxLen: 3, DropPolicy: DropLowestPriority}),
+	)
+
+	q.PushBackPending(event{1, 9}) // alarm, oldest
+	q.PushBackPending(event{2, 1})
+	q.PushBackPending(event{3, 1})
+	q.PushBackPending(event{4, 5})
+	q.PushBackPending(event{5, 1})
+	q.Commit()
+
+	var got []int
+	for {
+		e, ok := q.PopFront()
+		if !ok {
+			break
+		}
+		got = append(got, e.id)
+	}
+	if !slices.Equal(got, []int{1, 4, 5}) {
+		t.Fatalf("expected [1 4 5], got %v", got)
+	}
+	if m := q.Metrics(); m.Drops[DropLowestPriority] != 2 {
+		t.Fatalf("expected 2 lowest-priority drops, got %+v", m.Drops)
+	}
+}
+
+func TestDropLowestPriorityAcrossChunksAndViews(t *testing.T) {
+	q := NewSegmentedQueue[int](
+		WithDropPriority(func(v int) int { return v % 2 }),
+		WithOptions[int](Options{MaxLen: 2 * chunkSize, DropPolicy: DropLowestPriority}),
+	)
+	for i := 0; i < 2*chunkSize; i++ {
+		q.PushBackPending(i)
+	}
+	q.Commit()
+	view := q.SnapshotView()
+
+	for i := 0; i < chunkSize; i++ {
+		q.PushBackPending(1)
+	}
+	q.Commit()
+
+	if q.LenVisible() != 2*chunkSize {
+		t.Fatalf("expected %d visible, got %d", 2*chunkSize, q.LenVisible())
+	}
+	for v := range q.SnapshotView().All() {
+		if v%2 == 0 && v < chunkSize {
+			t.Fatalf("even element %d among the oldest should have been dropped", v)
+		}
+	}
+	i := 0
+	for v := range view.All() {
+		if v != i {
+			t.Fatalf("view changed at %d: %d", i, v)
+		}
+		i++
+	}
+}
+
+func TestDropLowestPriorityWithoutRankFallsBackToOldest(t *testing.T) {
+	q := NewSegmentedQueue[int](WithOptions[int](Options{MaxLen: 1, DropPolicy: DropLowestPriority}))
+	q.PushBackPending(1)
+	q.PushBackPending(2)
+	q.Commit()
+	if v, ok := q.PopFront(); !ok || v != 2 {
+		t.Fatalf("expected 2, got %d %v", v, ok)
+	}
+	if m := q.Metrics(); m.Drops[DropOldest] != 1 {
+		t.Fatalf("expected a DropOldest drop, got %+v", m.Drops)
+	}
+}
@@ -0,0 +1,53 @@
+package queue
+
+import "math/bits"
+
+// dropSampledLocked evicts visible elements spread evenly over the segment
+// until the limits hold again and returns them.
+//
+// Victims are taken in bit-reversed index order, the van der Corput sequence:
+// every prefix of that order is spread evenly over the segment, so dropping k
+// of n elements removes roughly every (n/k)th one, whether the limit is a
+// count or a size in bytes. The sequence continues where the previous publish
+// stopped, so a queue that each commit pushes just over its limit drops
+// elements all over the segment instead of always the oldest one.
+func (sq *SegmentedQueue[T]) dropSampledLocked() []T {
+	if !sq.exceedsLocked(sq.visible.len, sq.visibleBytes) {
+		return nil
+	}
+
+	var sizes []int
+	if sq.opts.sizer != nil {
+		sizes = make([]int, 0, sq.visible.len)
+		for c := sq.visible.head; c != nil; c = c.next {
+			for _, v := range c.values[c.lo:c.hi] {
+				sizes = append(sizes, sq.opts.sizer(v))
+			}
+		}
+	}
+
+	total := sq.visible.len
+	width := bits.Len(uint(total - 1))
+	victims := make([]bool, total)
+	n, size := total, sq.visibleBytes
+	mask := uint(1)<<width - 1
+	j := sq.sampleNext
+	for ; sq.exceedsLocked(n, size); j++ {
+		index := int(bits.Reverse(j&mask) >> (bits.UintSize - width))
+		if index >= total {
+			continue
+		}
+		victims[index] = true
+		n--
+		if sizes != nil {
+			size -= sizes[index]
+		}
+	}
+	sq.sampleNext = j
+
+	removed := sq.visible.removeLocked(func(i int, _ T) bool { return victims[i] })
+	for _, v := range removed {
+		sq.forgetLocked(v)
+	}
+	return removed
+}
@@ -0,0 +1,59 @@
+package queue
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestDropSampledThinsEvenly(t *testing.T) {
+	q := NewSegmentedQueue[int](WithOptions[int](Options{MaxLen: 4, DropPolicy: DropSampled}))
+	for i := 1; i <= 8; i++ {
+		q.PushBackPending(i)
+	}
+	q.Commit()
+
+	if got := q.SnapshotView().Values(); !slices.Equal(got, []int{2, 4, 6, 8}) {
+		t.Fatalf("expected every second element to remain, got %v", got)
+	}
+	if m := q.Metrics(); m.Drops[DropSampled] != 4 {
+		t.Fatalf("unexpected drops: %+v", m.Drops)
+	}
+}
+
+func TestDropSampledByBytes(t *testing.T) {
+	q := NewSegmentedQueue[int](
+		WithOptions[int](Options{MaxBytes: 10, DropPolicy: DropSampled}),
+		WithSizer(func(int) int { return 2 }),
+	)
+	for i := 1; i <= 7; i++ {
+		q.PushBackPending(i)
+	}
+	q.Commit()
+
+	// Dropping two of seven takes the first and the middle element.
+	if got := q.SnapshotView().Values(); !slices.Equal(got, []int{2, 3, 4, 6, 7}) {
+		t.Fatalf("expected [2 3 4 6 7], got %v", got)
+	}
+	if q.SizeVisible() != 10 {
+		t.Fatalf("expected 10 bytes, got %d", q.SizeVisible())
+	}
+}
+
+func TestDropSampledSpreadsAcrossCommits(t *testing.T) {
+	q := NewSegmentedQueue[int](WithOptions[int](Options{MaxLen: 8, DropPolicy: DropSampled}))
+	for i := range 40 {
+		q.PushBackPending(i)
+		q.Commit()
+	}
+
+	got := q.SnapshotView().Values()
+	if len(got) != 8 {
+		t.Fatalf("expected 8 elements, got %v", got)
+	}
+	if got[0] >= 32 {
+		t.Fatalf("expected older elements to survive the sampling, got the newest window %v", got)
+	}
+	if !slices.IsSorted(got) {
+		t.Fatalf("expected the survivors in commit order, got %v", got)
+	}
+}
@@ -0,0 +1,114 @@
+package queue
+
+// WithFairMerge makes commits interleave the producer shards round-robin,
+// one element of each in turn, instead of taking them one after another. A
+// chatty producer then no longer monopolizes the head of the queue after
+// each commit; the order of one producer's elements is kept. With
+// WithMaxCommitBatch the batch is shared out evenly among the shards that
+// have elements. Priority lanes still precede the producer shards.
+func WithFairMerge[T any]() SegmentedQueueOption[T] {
+	return func(opts *segmentedQueueOptions[T]) {
+		opts.fairMerge = true
+	}
+}
+
+// interleave detaches up to n elements from shards, n < 0 meaning all, and
+// merges them round-robin into one segment. Push stamps are carried over.
+func interleave[T any](shards []*deque[T], n int) segment[T] {
+	if len(shards) == 0 || n == 0 {
+		return segment[T]{}
+	}
+	quotas := make([]int, len(shards))
+	if n < 0 {
+		for i := range quotas {
+			quotas[i] = -1
+		}
+	} else {
+		lengths := make([]int, len(shards))
+		for i, shard := range shards {
+			lengths[i] = shard.length()
+		}
+		for progress := true; n > 0 && progress; {
+			progress = false
+			for i := range quotas {
+				if n > 0 && quotas[i] < lengths[i] {
+					quotas[i]++
+					n--
+					progress = true
+				}
+			}
+		}
+	}
+
+	parts := make([]segment[T], 0, len(shards))
+	for i, shard := range shards {
+		shard.mu.Lock()
+		var part segment[T]
+		if quotas[i] < 0 {
+			part = shard.detachLocked()
+		} else {
+			part = shard.detachFrontLocked(quotas[i])
+		}
+		shard.mu.Unlock()
+		if part.len > 0 {
+			parts = append(parts, part)
+		}
+	}
+	if len(parts) == 1 {
+		return parts[0]
+	}
+
+	var out segment[T]
+	type position struct {
+		c *chunk[T]
+		i int
+	}
+	positions := make([]position, len(parts))
+	for i, part := range parts {
+		positions[i] = position{c: part.head, i: part.head.lo}
+	}
+	for remaining := len(parts); remaining > 0; {
+		remaining = 0
+		for i := range positions {
+			p := &positions[i]
+			if p.c == nil {
+				continue
+			}
+			out.appendFrom(p.c, p.i)
+			if p.i++; p.i == p.c.hi {
+				p.c = p.c.next
+				if p.c != nil {
+					p.i = p.c.lo
+				}
+			}
+			if p.c != nil {
+				remaining++
+			}
+		}
+	}
+	return out
+}
+
+// appendFrom copies the element at index i of c, with its push stamp, behind
+// the elements of s.
+func (s *segment[T]) appendFrom(c *chunk[T], i int) {
+	if s.tail == nil || s.tail.hi == chunkSize {
+		next := &chunk[T]{prev: s.tail}
+		if s.tail != nil {
+			s.tail.next = next
+		} else {
+			s.head = next
+		}
+		s.tail = next
+	}
+	t := s.tail
+	t.values[t.hi] = c.values[i]
+	if c.stamps != nil {
+		if t.stamps == nil {
+			t.stamps = new([chunkSize]int64)
+		}
+		t.stamps[t.hi] = c.stamps[i]
+	}
+	t.hi++
+	s.len++
+}
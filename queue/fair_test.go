@@ -0,0 +1,54 @@
+package queue
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestFairMergeInterleavesProducers(t *testing.T) {
+	q := NewSegmentedQueue[int](WithPendingShards[int](2), WithFairMerge[int]())
+	chatty, quiet := q.NewProducer(), q.NewProducer()
+	for v := 1; v <= 4; v++ {
+		chatty.PushBackPending(v)
+	}
+	quiet.PushBackPending(10)
+	quiet.PushBackPending(11)
+
+	if got := drain(q); !slices.Equal(got, []int{1, 10, 2, 11, 3, 4}) {
+		t.Fatalf("expected interleaved producers, got %v", got)
+	}
+}
+
+func TestFairMergeSharesBatchLimit(t *testing.T) {
+	q := NewSegmentedQueue[int](WithPendingShards[int](2), WithFairMerge[int](), WithMaxCommitBatch[int](3))
+	chatty, quiet := q.NewProducer(), q.NewProducer()
+	for v := 1; v <= 4; v++ {
+		chatty.PushBackPending(v)
+	}
+	quiet.PushBackPending(10)
+	quiet.PushBackPending(11)
+
+	if got := drain(q); !slices.Equal(got, []int{1, 10, 2}) {
+		t.Fatalf("unexpected first batch %v", got)
+	}
+	if got := drain(q); !slices.Equal(got, []int{3, 11, 4}) {
+		t.Fatalf("unexpected second batch %v", got)
+	}
+}
+
+func TestFairMergeKeepsPushTimes(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	q := NewSegmentedQueue[int](WithClock[int](clock.Now), WithTTL[int](8*time.Second),
+		WithPendingShards[int](2), WithFairMerge[int]())
+	old, fresh := q.NewProducer(), q.NewProducer()
+	old.PushBackPending(1)
+	old.PushBackPending(2)
+	clock.now = clock.now.Add(5 * time.Second)
+	fresh.PushBackPending(10)
+	clock.now = clock.now.Add(4 * time.Second)
+
+	if got := drain(q); !slices.Equal(got, []int{10}) {
+		t.Fatalf("expected only the fresh element to survive its TTL, got %v", got)
+	}
+}
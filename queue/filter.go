@@ -0,0 +1,99 @@
+package queue
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrRejected is wrapped by the error PrepareCommit returns when a strict
+// commit filter rejects a staged element.
+var ErrRejected = errors.New("queue: element rejected by commit filter")
+
+// WithCommitFilter validates every element when it is staged by PrepareCommit,
+// CommitUpTo or PrepareAppend, so invalid elements never become visible. An
+// element for which filter returns an error is dropped, unless
+// WithStrictCommitFilter makes it fail the prepare instead.
+func WithCommitFilter[T any](filter func(T) error) SegmentedQueueOption[T] {
+	return func(opts *segmentedQueueOptions[T]) {
+		opts.commitFilter = filter
+	}
+}
+
+// WithRejectCallback registers fn to be called with every element the commit
+// filter dropped and the filter's error. It runs after the queue's locks have
+// been released.
+func WithRejectCallback[T any](fn func(T, error)) SegmentedQueueOption[T] {
+	return func(opts *segmentedQueueOptions[T]) {
+		opts.onReject = fn
+	}
+}
+
+// WithStrictCommitFilter fails PrepareCommit with an error wrapping ErrRejected
+// and the filter's error when the commit filter rejects an element. The staged
+// elements return to the front of the pending segment as after an abort, so
+// commits keep failing until the element is removed. Commit and CommitUpTo,
+// which cannot report the error, publish nothing.
+func WithStrictCommitFilter[T any]() SegmentedQueueOption[T] {
+	return func(opts *segmentedQueueOptions[T]) {
+		opts.strictFilter = true
+	}
+}
+
+type rejection[T any] struct {
+	value T
+	err   error
+}
+
+// filterLocked applies the commit filter to staged. It returns the accepted
+// elements and the rejected ones, which the caller hands to reject once it
+// released its locks. In strict mode a rejection returns staged to the
+// pending lanes it was taken from and fails with the filter's error. The
+// caller must hold sq.mu.
+func (sq *SegmentedQueue[T]) filterLocked(staged segment[T]) (segment[T], []rejection[T], error) {
+	accepted, rejected, err := sq.filterSegment(staged)
+	if err != nil {
+		sq.returnToLanes(staged, false)
+	}
+	return accepted, rejected, err
+}
+
+// filterSegment applies the commit filter to s. In strict mode it stops at the
+// first rejection and leaves s unchanged.
+func (sq *SegmentedQueue[T]) filterSegment(s segment[T]) (segment[T], []rejection[T], error) {
+	filter := sq.opts.commitFilter
+	if filter == nil || s.len == 0 {
+		return s, nil, nil
+	}
+	if sq.opts.strictFilter {
+		for c := s.head; c != nil; c = c.next {
+			for _, v := range c.values[c.lo:c.hi] {
+				if err := filter(v); err != nil {
+					return segment[T]{}, nil, fmt.Errorf("%w: %w", ErrRejected, err)
+				}
+			}
+		}
+		return s, nil, nil
+	}
+
+	var rejected []rejection[T]
+	s, _ = s.filter(func(v T) bool {
+		if err := filter(v); err != nil {
+			rejected = append(rejected, rejection[T]{value: v, err: err})
+			return false
+		}
+		return true
+	})
+	sq.counters.reject.Add(uint64(len(rejected)))
+	return s, rejected, nil
+}
+
+// reject reports elements dropped by the commit filter to the reject
+// callback.
+func (sq *SegmentedQueue[T]) reject(rejected []rejection[T]) {
+	if sq.opts.onReject == nil {
+		return
+	}
+	for _, r := range rejected {
+		sq.opts.onReject(r.value, r.err)
+	}
+}
@@ -0,0 +1,81 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+)
+
+var errNegative = errors.New("negative reading")
+
+func rejectNegative(v int) error {
+	if v < 0 {
+		return errNegative
+	}
+	return nil
+}
+
+func TestCommitFilterDropsInvalidElements(t *testing.T) {
+	var rejected []int
+	q := NewSegmentedQueue[int](
+		WithCommitFilter(rejectNegative),
+		WithRejectCallback(func(v int, err error) {
+			if !errors.Is(err, errNegative) {
+				t.Errorf("unexpected reject error %v", err)
+			}
+			rejected = append(rejected, v)
+		}),
+	)
+	for _, v := range []int{1, -2, 3, -4} {
+		q.PushBackPending(v)
+	}
+	if got := drain(q); !slices.Equal(got, []int{1, 3}) {
+		t.Fatalf("expected only valid elements, got %v", got)
+	}
+	if !slices.Equal(rejected, []int{-2, -4}) {
+		t.Fatalf("expected the rejected elements in the callback, got %v", rejected)
+	}
+
+	publish, _, err := q.PrepareAppend(context.Background(), []int{-5, 6})
+	if err != nil {
+		t.Fatalf("prepare append failed: %v", err)
+	}
+	publish()
+	if got := drain(q); !slices.Equal(got, []int{6}) {
+		t.Fatalf("expected the appended valid element, got %v", got)
+	}
+	if m := q.Metrics(); m.Rejected != 3 {
+		t.Fatalf("expected 3 rejected elements, got %d", m.Rejected)
+	}
+}
+
+func TestStrictCommitFilterFailsPrepare(t *testing.T) {
+	q := NewSegmentedQueue[int](WithCommitFilter(rejectNegative), WithStrictCommitFilter[int]())
+	q.PushBackPending(1)
+	q.PushBackPending(-2)
+	q.PushBackPending(3)
+
+	_, _, err := q.PrepareCommit(context.Background())
+	if !errors.Is(err, ErrRejected) || !errors.Is(err, errNegative) {
+		t.Fatalf("expected a rejection, got %v", err)
+	}
+	if n := q.CommitUpTo(10); n != 0 {
+		t.Fatalf("expected CommitUpTo to publish nothing, got %d", n)
+	}
+	q.Commit()
+	if q.LenVisible() != 0 {
+		t.Fatalf("expected nothing visible, got %d elements", q.LenVisible())
+	}
+
+	// The staged elements are pending again, in order.
+	if n := q.CommitUpTo(1); n != 1 {
+		t.Fatalf("expected the valid head to commit, got %d", n)
+	}
+	if v, _ := q.PopFront(); v != 1 {
+		t.Fatalf("expected 1, got %d", v)
+	}
+	if _, _, err := q.PrepareAppend(context.Background(), []int{-1}); !errors.Is(err, ErrRejected) {
+		t.Fatalf("expected PrepareAppend to fail, got %v", err)
+	}
+}
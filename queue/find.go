@@ -0,0 +1,39 @@
+package queue
+
+// FindFunc returns the oldest visible element for which pred returns true.
+// It scans the visible segment under its lock without copying it.
+func (sq *SegmentedQueue[T]) FindFunc(pred func(T) bool) (found T, ok bool) {
+	sq.RangeVisible(func(v T) bool {
+		if pred(v) {
+			found, ok = v, true
+		}
+		return !ok
+	})
+	return found, ok
+}
+
+// ContainsFunc reports whether any visible element satisfies pred.
+func (sq *SegmentedQueue[T]) ContainsFunc(pred func(T) bool) bool {
+	_, ok := sq.FindFunc(pred)
+	return ok
+}
+
+// RangeVisible calls fn for each visible element from the oldest to the
+// newest until fn returns false. It holds the visible lock for the duration
+// and allocates nothing, so fn must be quick and must not call methods of the
+// queue that touch the visible segment. Use SnapshotView to iterate without
+// blocking consumers.
+func (sq *SegmentedQueue[T]) RangeVisible(fn func(T) bool) {
+	sq.redeliverExpired()
+
+	sq.visible.mu.Lock()
+	defer sq.visible.mu.Unlock()
+
+	for c := sq.visible.head; c != nil; c = c.next {
+		for _, v := range c.values[c.lo:c.hi] {
+			if !fn(v) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package queue
+
+import "testing"
+
+func TestFindFuncAndContainsFunc(t *testing.T) {
+	q := NewSegmentedQueue[event](WithInitialVisible(event{1, 1}, event{2, 5}, event{3, 5}))
+	q.PushBackPending(event{4, 9})
+
+	e, ok := q.FindFunc(func(e event) bool { return e.severity == 5 })
+	if !ok || e.id != 2 {
+		t.Fatalf("expected the oldest match 2, got %+v %v", e, ok)
+	}
+	if q.ContainsFunc(func(e event) bool { return e.severity == 9 }) {
+		t.Fatalf("pending elements must not be found")
+	}
+	if !q.ContainsFunc(func(e event) bool { return e.id == 3 }) {
+		t.Fatalf("expected element 3 to be found")
+	}
+	if q.LenVisible() != 3 {
+		t.Fatalf("searching must not remove elements")
+	}
+}
+
+func TestRangeVisibleStopsEarlyWithoutAllocating(t *testing.T) {
+	q := NewSegmentedQueue[int]()
+	for i := 0; i < 2*chunkSize; i++ {
+		q.PushBackPending(i)
+	}
+	q.Commit()
+
+	var seen []int
+	q.RangeVisible(func(v int) bool {
+		seen = append(seen, v)
+		return v < 70
+	})
+	if len(seen) != 71 || seen[70] != 70 {
+		t.Fatalf("expected iteration to stop at 70, got %d elements", len(seen))
+	}
+
+	sum := 0
+	allocs := testing.AllocsPerRun(10, func() {
+		q.RangeVisible(func(v int) bool {
+			sum += v
+			return true
+		})
+	})
+	if allocs != 0 {
+		t.Fatalf("expected no allocations, got %v", allocs)
+	}
+}
@@ -0,0 +1,101 @@
+package queue
+
+import (
+	"errors"
+	"iter"
+)
+
+// ErrHistoryTruncated is returned by ReplayFrom when elements published at or
+// after the requested version are no longer retained.
+var ErrHistoryTruncated = errors.New("queue: history truncated")
+
+// WithHistory retains up to the last n published elements, whether or not
+// they have been popped since, so ReplayFrom can deliver them again. Commits
+// are retained or discarded whole, so a commit of more than n elements is not
+// retained at all.
+func WithHistory[T any](n int) SegmentedQueueOption[T] {
+	return func(opts *segmentedQueueOptions[T]) {
+		opts.historyElements = n
+	}
+}
+
+// WithCommitHistory retains the elements of the last k published commits for
+// ReplayFrom. Combined with WithHistory, both limits apply.
+func WithCommitHistory[T any](k int) SegmentedQueueOption[T] {
+	return func(opts *segmentedQueueOptions[T]) {
+		opts.historyCommits = k
+	}
+}
+
+type historyBatch[T any] struct {
+	version uint64
+	values  []T
+}
+
+// HistoryStart returns the oldest version ReplayFrom accepts: the elements of
+// every commit from this version on are still retained.
+func (sq *SegmentedQueue[T]) HistoryStart() uint64 {
+	sq.mu.Lock()
+	defer sq.mu.Unlock()
+	return sq.historyCut + 1
+}
+
+// ReplayFrom returns the retained elements published by commits with at least
+// the given version, oldest first, together with their commit version. The
+// elements are delivered again regardless of whether they were popped; the
+// queue itself is not changed. The versions are the ones PopFrontVersioned
+// reports with WithVersionStamps. It fails with ErrHistoryTruncated when version is older than
+// HistoryStart, which without WithHistory or WithCommitHistory is the case for
+// every version already published.
+func (sq *SegmentedQueue[T]) ReplayFrom(version uint64) (iter.Seq2[uint64, T], error) {
+	sq.mu.Lock()
+	defer sq.mu.Unlock()
+
+	if version <= sq.historyCut {
+		return nil, ErrHistoryTruncated
+	}
+	var batches []historyBatch[T]
+	for _, batch := range sq.history {
+		if batch.version >= version {
+			batches = append(batches, batch)
+		}
+	}
+	return func(yield func(uint64, T) bool) {
+		for _, batch := range batches {
+			for _, v := range batch.values {
+				if !yield(batch.version, v) {
+					return
+				}
+			}
+		}
+	}, nil
+}
+
+// recordHistoryLocked retains the elements of a commit published with
+// version and discards what exceeds the history limits. The caller must hold
+// sq.mu.
+func (sq *SegmentedQueue[T]) recordHistoryLocked(s segment[T], version uint64) {
+	limit, commits := sq.opts.historyElements, sq.opts.historyCommits
+	if limit <= 0 && commits <= 0 {
+		sq.historyCut = version
+		return
+	}
+	if s.len == 0 {
+		return
+	}
+
+	values := make([]T, 0, s.len)
+	for c := s.head; c != nil; c = c.next {
+		values = append(values, c.values[c.lo:c.hi]...)
+	}
+	sq.history = append(sq.history, historyBatch[T]{version: version, values: values})
+	sq.historyLen += len(values)
+
+	for len(sq.history) > 0 && (commits > 0 && len(sq.history) > commits || limit > 0 && sq.historyLen > limit) {
+		oldest := sq.history[0]
+		sq.historyLen -= len(oldest.values)
+		sq.historyCut = oldest.version
+		sq.history[0] = historyBatch[T]{}
+		sq.history = sq.history[1:]
+	}
+}
@@ -0,0 +1,101 @@
+package queue
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func replay(t *testing.T, q *SegmentedQueue[int], version uint64) ([]uint64, []int) {
+	t.Helper()
+	seq, err := q.ReplayFrom(version)
+	if err != nil {
+		t.Fatalf("replay from %d failed: %v", version, err)
+	}
+	var versions []uint64
+	var values []int
+	for v, value := range seq {
+		versions = append(versions, v)
+		values = append(values, value)
+	}
+	return versions, values
+}
+
+func TestReplayFromRetainedElements(t *testing.T) {
+	q := NewSegmentedQueue[int](WithHistory[int](4))
+
+	for commit := 0; commit < 3; commit++ {
+		q.PushBackPending(commit * 2)
+		q.PushBackPending(commit*2 + 1)
+		q.Commit()
+	}
+	for {
+		if _, ok := q.PopFront(); !ok {
+			break
+		}
+	}
+
+	// Six elements in three commits; the oldest two were discarded.
+	if got := q.HistoryStart(); got != 2 {
+		t.Fatalf("expected history to start at version 2, got %d", got)
+	}
+	versions, values := replay(t, q, 2)
+	if !slices.Equal(values, []int{2, 3, 4, 5}) || !slices.Equal(versions, []uint64{2, 2, 3, 3}) {
+		t.Fatalf("unexpected replay %v at versions %v", values, versions)
+	}
+	if _, values := replay(t, q, 3); !slices.Equal(values, []int{4, 5}) {
+		t.Fatalf("unexpected replay from version 3: %v", values)
+	}
+	if _, err := q.ReplayFrom(1); !errors.Is(err, ErrHistoryTruncated) {
+		t.Fatalf("expected ErrHistoryTruncated, got %v", err)
+	}
+	if q.LenVisible() != 0 {
+		t.Fatal("replay must not change the queue")
+	}
+
+	// Commits are discarded whole, even if that leaves fewer than n elements.
+	q.PushBackPending(6)
+	q.Commit()
+	if got := q.HistoryStart(); got != 3 {
+		t.Fatalf("expected history to start at version 3, got %d", got)
+	}
+	if _, values := replay(t, q, 3); !slices.Equal(values, []int{4, 5, 6}) {
+		t.Fatalf("unexpected replay after trimming: %v", values)
+	}
+}
+
+func TestReplayFromRetainedCommits(t *testing.T) {
+	q := NewSegmentedQueue[int](WithCommitHistory[int](2))
+
+	q.PushBackPending(1)
+	q.Commit()
+	q.Commit() // empty commits are not retained
+	q.PushBackPending(2)
+	q.PushBackPending(3)
+	q.Commit()
+	q.PushBackPending(4)
+	q.Commit()
+
+	if got := q.HistoryStart(); got != 2 {
+		t.Fatalf("expected history to start at version 2, got %d", got)
+	}
+	if _, values := replay(t, q, 2); !slices.Equal(values, []int{2, 3, 4}) {
+		t.Fatalf("unexpected replay %v", values)
+	}
+	if _, values := replay(t, q, 10); len(values) != 0 {
+		t.Fatalf("expected nothing newer than the latest commit, got %v", values)
+	}
+}
+
+func TestReplayFromWithoutHistory(t *testing.T) {
+	q := NewSegmentedQueue[int]()
+	q.PushBackPending(1)
+	q.Commit()
+
+	if _, err := q.ReplayFrom(1); !errors.Is(err, ErrHistoryTruncated) {
+		t.Fatalf("expected ErrHistoryTruncated, got %v", err)
+	}
+	if _, values := replay(t, q, 2); len(values) != 0 {
+		t.Fatalf("expected an empty replay, got %v", values)
+	}
+}
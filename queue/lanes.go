@@ -0,0 +1,43 @@
+package queue
+
+// WithPriorityLanes splits the pending segment into n lanes. Lane 0 is the
+// regular pending segment that PushBackPending and producers use; higher
+// lanes are filled with PushBackPendingLane. PrepareCommit takes the lanes
+// highest first, so urgent elements publish ahead of bulk data of the same
+// commit, and WithMaxCommitBatch limits take them before lower lanes. An
+// aborted commit returns its elements to the lanes they were taken from, so
+// they keep their priority for the retry. Values below 2 keep a single lane.
+func WithPriorityLanes[T any](n int) SegmentedQueueOption[T] {
+	return func(opts *segmentedQueueOptions[T]) {
+		opts.priorityLanes = n
+	}
+}
+
+// PushBackPendingLane appends value to the pending lane with the given
+// priority. Lanes outside the configured range are clamped, so without
+// WithPriorityLanes it behaves like PushBackPending.
+func (sq *SegmentedQueue[T]) PushBackPendingLane(lane int, value T) {
+	sq.lane(lane).pushBack(value)
+	sq.counters.pushes.Add(1)
+}
+
+// lane returns the deque of the given lane, clamped to the configured lanes.
+func (sq *SegmentedQueue[T]) lane(lane int) *deque[T] {
+	return sq.lanes[min(max(lane, 0), len(sq.lanes)-1)]
+}
+
+// returnToLanes puts the elements of staged back into the lanes they were
+// staged from, in front of the elements pushed since or, with back, behind
+// them.
+func (sq *SegmentedQueue[T]) returnToLanes(staged segment[T], back bool) {
+	for lane, run := range staged.splitLanes() {
+		d := sq.lane(lane)
+		d.mu.Lock()
+		if back {
+			d.appendSegmentLocked(run)
+		} else {
+			d.prependSegmentLocked(run)
+		}
+		d.mu.Unlock()
+	}
+}
@@ -0,0 +1,76 @@
+package queue
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestPriorityLanesPublishHighestFirst(t *testing.T) {
+	q := NewSegmentedQueue[string](WithPriorityLanes[string](3))
+	q.PushBackPending("bulk-1")
+	q.PushBackPendingLane(2, "stop")
+	q.PushBackPendingLane(1, "config")
+	q.PushBackPending("bulk-2")
+	q.PushBackPendingLane(2, "reset")
+	q.PushBackPendingLane(9, "clamped")
+
+	q.Commit()
+	want := []string{"stop", "reset", "clamped", "config", "bulk-1", "bulk-2"}
+	if got := drain(q); !slices.Equal(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+}
+
+func TestPriorityLanesWithBatchLimitAndAbort(t *testing.T) {
+	q := NewSegmentedQueue[int](WithPriorityLanes[int](2), WithMaxCommitBatch[int](2))
+	q.PushBackPending(1)
+	q.PushBackPending(2)
+	q.PushBackPendingLane(1, 10)
+	q.Commit()
+	q.PushBackPendingLane(1, 11)
+	if got := drain(q); !slices.Equal(got, []int{10, 1, 11, 2}) {
+		t.Fatalf("expected urgent elements to overtake the remaining bulk, got %v", got)
+	}
+
+	q.PushBackPendingLane(1, 20)
+	q.PushBackPending(3)
+	_, abort, _ := q.PrepareCommit(t.Context())
+	abort()
+	q.PushBackPendingLane(1, 21)
+	if got := drain(q); !slices.Equal(got, []int{20, 21}) {
+		t.Fatalf("expected the aborted urgent element back in its lane, got %v", got)
+	}
+	if got := drain(q); !slices.Equal(got, []int{3}) {
+		t.Fatalf("expected the aborted bulk element last, got %v", got)
+	}
+}
+
+func TestPriorityLanesWithoutOption(t *testing.T) {
+	q := NewSegmentedQueue[int]()
+	q.PushBackPending(1)
+	q.PushBackPendingLane(3, 2)
+	q.Commit()
+	if got := drain(q); !slices.Equal(got, []int{1, 2}) {
+		t.Fatalf("expected a single lane, got %v", got)
+	}
+}
+
+func TestPriorityLanesAbortRestoresLanes(t *testing.T) {
+	q := NewSegmentedQueue[string](WithPriorityLanes[string](3), WithMaxCommitBatch[string](2))
+	q.PushBackPending("bulk")
+	q.PushBackPendingLane(1, "config")
+	q.PushBackPendingLane(2, "stop")
+
+	_, abort, _ := q.PrepareCommit(t.Context())
+	abort()
+	q.PushBackPendingLane(1, "late")
+
+	var got []string
+	for range 2 {
+		q.Commit()
+		got = append(got, drain(q)...)
+	}
+	if want := []string{"stop", "config", "late", "bulk"}; !slices.Equal(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+}
@@ -0,0 +1,62 @@
+package queue
+
+import "time"
+
+// LeaseHandle completes an element obtained through Lease. Only the first call
+// to Complete or Release takes effect, and neither does anything once the
+// lease has expired and the element was returned to the queue.
+type LeaseHandle struct {
+	settle       func(requeue bool) bool
+	redeliveries int
+}
+
+// Redeliveries returns how often the element had been redelivered before this
+// lease, 0 on its first one. It is only counted with WithMaxRedeliveries or
+// WithRedeliveryCount.
+func (h LeaseHandle) Redeliveries() int {
+	return h.redeliveries
+}
+
+// Complete removes the leased element for good. It reports false when the
+// lease was already completed, released or expired.
+func (h LeaseHandle) Complete() bool {
+	return h.settle != nil && h.settle(false)
+}
+
+// Release gives up the lease early and returns the element to the front of the
+// visible segment. It reports false when the lease was already completed,
+// released or expired.
+func (h LeaseHandle) Release() bool {
+	return h.settle != nil && h.settle(true)
+}
+
+// Lease removes the oldest visible element and hides it for d, in the manner
+// of an SQS visibility timeout. Unless the handle completes it in time, the
+// element returns to the front of the visible segment once d has passed.
+// Expired leases are noticed lazily by pops and LenVisible. Leased elements
+// count as in flight (see LenInFlight) and are not part of snapshots.
+//
+// A d of 0 or less uses the queue's default from WithVisibilityTimeout;
+// without one the lease only ends through its handle.
+func (sq *SegmentedQueue[T]) Lease(d time.Duration) (zero T, _ LeaseHandle, _ bool) {
+	if d <= 0 {
+		d = sq.opts.visibilityTimeout
+	}
+	sq.leased.Store(true)
+	v, meta, ok := sq.popUnprocessed()
+	if !ok {
+		return zero, LeaseHandle{}, false
+	}
+
+	lease := newLease(v, meta)
+	sq.ackMu.Lock()
+	sq.inFlight++
+	if d > 0 {
+		lease.deadline = sq.now().Add(d).UnixNano()
+		sq.insertLeaseLocked(lease)
+	}
+	sq.ackMu.Unlock()
+
+	settle := func(requeue bool) bool { return sq.settle(lease, requeue) }
+	return v, LeaseHandle{settle: settle, redeliveries: lease.redeliveries}, true
+}
@@ -0,0 +1,84 @@
+package queue
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLeaseReturnsElementAfterItsOwnDuration(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	q := NewSegmentedQueue[int](WithClock[int](clock.Now), WithInitialVisible(1, 2, 3))
+
+	_, long, _ := q.Lease(time.Minute)
+	v, _, ok := q.Lease(time.Second)
+	if !ok || v != 2 {
+		t.Fatalf("expected 2, got %d %v", v, ok)
+	}
+	if q.LenInFlight() != 2 || q.LenVisible() != 1 {
+		t.Fatalf("unexpected lengths: in flight %d visible %d", q.LenInFlight(), q.LenVisible())
+	}
+
+	// The shorter lease expires first even though it was taken last.
+	clock.now = clock.now.Add(2 * time.Second)
+	if v, ok := q.PopFront(); !ok || v != 2 {
+		t.Fatalf("expected expired lease 2 at the front, got %d %v", v, ok)
+	}
+	if !long.Complete() {
+		t.Fatal("completing a live lease must succeed")
+	}
+	clock.now = clock.now.Add(time.Hour)
+	if q.LenInFlight() != 0 || q.LenVisible() != 1 {
+		t.Fatalf("completed lease must not return: in flight %d visible %d", q.LenInFlight(), q.LenVisible())
+	}
+	if m := q.Metrics(); m.Redelivered != 1 {
+		t.Fatalf("expected one redelivery, got %+v", m)
+	}
+}
+
+func TestLeaseReleaseAndExpiredHandle(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	q := NewSegmentedQueue[int](WithClock[int](clock.Now), WithInitialVisible(1))
+
+	_, h, _ := q.Lease(time.Minute)
+	if !h.Release() || h.Complete() {
+		t.Fatal("only the first settlement may take effect")
+	}
+
+	_, h, _ = q.Lease(time.Second)
+	clock.now = clock.now.Add(time.Second)
+	if q.LenVisible() != 1 {
+		t.Fatalf("expected the expired lease to return")
+	}
+	if h.Complete() {
+		t.Fatal("an expired lease cannot be completed")
+	}
+}
+
+func TestLeaseDefaultsToVisibilityTimeout(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	q := NewSegmentedQueue[int](
+		WithClock[int](clock.Now),
+		WithInitialVisible(1),
+		WithVisibilityTimeout[int](time.Minute),
+		WithRedeliveryCount[int](),
+	)
+
+	_, h, _ := q.Lease(0)
+	if h.Redeliveries() != 0 {
+		t.Fatalf("expected a first delivery, got %d redeliveries", h.Redeliveries())
+	}
+	clock.now = clock.now.Add(30 * time.Second)
+	if q.LenVisible() != 0 {
+		t.Fatal("the lease must last the default visibility timeout")
+	}
+	clock.now = clock.now.Add(30 * time.Second)
+	_, h, ok := q.Lease(0)
+	if !ok || h.Redeliveries() != 1 {
+		t.Fatalf("expected the element back with one redelivery, got %d %v", h.Redeliveries(), ok)
+	}
+
+	_, h, _ = NewSegmentedQueue[int](WithInitialVisible(1)).Lease(0)
+	if !h.Complete() {
+		t.Fatal("a lease without any timeout must stay open until completed")
+	}
+}
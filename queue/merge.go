@@ -0,0 +1,74 @@
+package queue
+
+import (
+	"container/heap"
+	"errors"
+)
+
+// ErrCommitInFlight is returned by Merge when the merged queue has a prepared
+// commit that was neither published nor aborted yet.
+var ErrCommitInFlight = errors.New("queue: commit in flight")
+
+// Merge moves all elements of other into sq in one step, for consolidating
+// per-device queues when devices are merged. The visible elements of other
+// are spliced behind those of sq as with SpliceTo, and its pending and
+// delayed elements join the pending elements of sq, behind them and in their
+// order, so the next commit of sq publishes them. Priority lanes join the
+// lane of sq with the same priority, or its highest lane. No consumer or commit of
+// either queue observes a state in between.
+//
+// Prepared commits would still publish into other, so Merge fails with
+// ErrCommitInFlight while other has one. Run it between the commits of the
+// orchestrator both queues are registered with, for example while it is
+// paused. Elements other has in flight through PopFrontAck stay with other.
+func (sq *SegmentedQueue[T]) Merge(other *SegmentedQueue[T]) error {
+	if other == sq {
+		return nil
+	}
+	var expired, dropped []T
+	defer sq.checkWatermarks()
+	defer func() {
+		sq.expire(expired)
+		sq.deadLetter(dropped)
+	}()
+	defer other.checkWatermarks()
+	defer other.notifySpace()
+
+	unlock := other.lockPair(sq)
+	defer unlock()
+
+	if other.staged.Load() > 0 {
+		return ErrCommitInFlight
+	}
+	_, expired, dropped = other.spliceLocked(sq)
+
+	for i, lane := range other.lanes[1:] {
+		mergeDeque(sq.lane(i+1), lane)
+	}
+	shards := sq.pendingShardsLocked()
+	last := shards[len(shards)-1]
+	for _, shard := range other.producers {
+		mergeDeque(last, shard)
+	}
+
+	other.delayMu.Lock()
+	sq.delayMu.Lock()
+	for len(other.delayed) > 0 {
+		e := heap.Pop(&other.delayed).(delayedElement[T])
+		sq.delaySeq++
+		heap.Push(&sq.delayed, delayedElement[T]{at: e.at, seq: sq.delaySeq, value: e.value})
+	}
+	sq.delayMu.Unlock()
+	other.delayMu.Unlock()
+	return nil
+}
+
+// mergeDeque moves all elements of src behind those of dst.
+func mergeDeque[T any](dst, src *deque[T]) {
+	src.mu.Lock()
+	s := src.detachLocked()
+	src.mu.Unlock()
+	dst.mu.Lock()
+	dst.appendSegmentLocked(s)
+	dst.mu.Unlock()
+}
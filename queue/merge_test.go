@@ -0,0 +1,66 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestMergeCombinesVisibleAndPending(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	q := NewSegmentedQueue[int](WithClock[int](clock.Now))
+	other := NewSegmentedQueue[int](WithClock[int](clock.Now))
+
+	q.PushBackPending(1)
+	q.Commit()
+	q.PushBackPending(2)
+	other.PushBackPending(10)
+	other.Commit()
+	other.PushBackPending(11)
+	other.PushBackPendingAfter(12, clock.now.Add(time.Second))
+
+	if err := q.Merge(other); err != nil {
+		t.Fatalf("merge failed: %v", err)
+	}
+	if other.LenVisible() != 0 || other.LenDelayed() != 0 {
+		t.Fatal("expected the merged queue to be empty")
+	}
+	if q.LenVisible() != 2 {
+		t.Fatalf("expected 2 visible elements, got %d", q.LenVisible())
+	}
+	if got := drain(q); !slices.Equal(got, []int{1, 10, 2, 11}) {
+		t.Fatalf("unexpected elements after merge %v", got)
+	}
+	clock.now = clock.now.Add(time.Second)
+	if got := drain(q); !slices.Equal(got, []int{12}) {
+		t.Fatalf("expected the delayed element once due, got %v", got)
+	}
+	if got := drain(other); len(got) != 0 {
+		t.Fatalf("expected nothing left in the merged queue, got %v", got)
+	}
+}
+
+func TestMergeRefusesCommitInFlight(t *testing.T) {
+	q := NewSegmentedQueue[int]()
+	other := NewSegmentedQueue[int]()
+	other.PushBackPending(1)
+
+	publish, abort, err := other.PrepareCommit(context.Background())
+	if err != nil {
+		t.Fatalf("prepare failed: %v", err)
+	}
+	if err := q.Merge(other); !errors.Is(err, ErrCommitInFlight) {
+		t.Fatalf("expected ErrCommitInFlight, got %v", err)
+	}
+	abort()
+	publish()
+
+	if err := q.Merge(other); err != nil {
+		t.Fatalf("merge after abort failed: %v", err)
+	}
+	if got := drain(q); !slices.Equal(got, []int{1}) {
+		t.Fatalf("expected the aborted element to be merged, got %v", got)
+	}
+}
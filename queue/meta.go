@@ -0,0 +1,94 @@
+package queue
+
+import (
+	"time"
+
+	"github.com/timzifer/committable_queue/telemetry"
+)
+
+// ElementMeta describes when an element passed through the queue.
+type ElementMeta struct {
+	// Enqueued is the time the element was pushed. Delayed elements report
+	// the time they became due. Redelivered elements keep the times of their
+	// first delivery.
+	Enqueued time.Time
+	// Committed is the time the commit that made the element visible was
+	// published.
+	Committed time.Time
+	// Version is the commit version that published the element; see
+	// WithVersionStamps.
+	Version uint64
+	// Redeliveries is how often the element was returned by a Nack or an
+	// expired lease; it is only counted with WithMaxRedeliveries or
+	// WithRedeliveryCount.
+	Redeliveries int
+}
+
+// WithElementMeta records the enqueue and commit time of every element, for
+// PopFrontMeta and Residency. The times come from the queue's clock (see
+// WithClock).
+func WithElementMeta[T any]() SegmentedQueueOption[T] {
+	return func(opts *segmentedQueueOptions[T]) {
+		opts.elementMeta = true
+	}
+}
+
+// PopFrontMeta is PopFront that also returns the element's metadata, for
+// example to measure how long it resided in the queue. Without
+// WithElementMeta the times are zero; without WithVersionStamps so is the
+// version.
+func (sq *SegmentedQueue[T]) PopFrontMeta() (T, ElementMeta, bool) {
+	v, meta, ok := sq.pop(true)
+	if !sq.opts.elementMeta {
+		meta.Enqueued, meta.Committed = time.Time{}, time.Time{}
+	}
+	return v, meta, ok
+}
+
+// metaAt returns the metadata of the element at index i of c. The times are
+// filled in whenever c carries them, so redelivery can keep them; PopFrontMeta
+// only reports them with WithElementMeta.
+func (sq *SegmentedQueue[T]) metaAt(c *chunk[T], i int) ElementMeta {
+	meta := ElementMeta{Version: c.version, Redeliveries: c.redeliveries}
+	if c.committed != 0 {
+		meta.Committed = time.Unix(0, c.committed)
+	}
+	if c.stamps != nil {
+		meta.Enqueued = time.Unix(0, c.stamps[i])
+	}
+	return meta
+}
+
+// Residency returns how long elements stayed in the queue: pending from their
+// push until their commit was published, visible from the publish until they
+// were popped, and in total. It is only recorded with WithElementMeta.
+// Redelivered elements keep their original push and commit times, so the time
+// they spent in flight counts as visible.
+func (sq *SegmentedQueue[T]) Residency() telemetry.ResidencySnapshot {
+	return sq.residency.Snapshot()
+}
+
+// observeCommitted records the pending time of the elements of a published
+// segment.
+func (sq *SegmentedQueue[T]) observeCommitted(s segment[T], now int64) {
+	if !sq.opts.elementMeta {
+		return
+	}
+	for c := s.head; c != nil; c = c.next {
+		if c.stamps == nil {
+			continue
+		}
+		for _, stamp := range c.stamps[c.lo:c.hi] {
+			sq.residency.ObserveCommitted(time.Duration(now - stamp))
+		}
+	}
+}
+
+// observePopped records the visible and total residency of a popped element.
+func (sq *SegmentedQueue[T]) observePopped(meta ElementMeta) {
+	if !sq.opts.elementMeta || meta.Enqueued.IsZero() {
+		return
+	}
+	now := sq.now()
+	sq.residency.ObservePopped(now.Sub(meta.Committed), now.Sub(meta.Enqueued))
+}
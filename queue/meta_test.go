@@ -0,0 +1,80 @@
+package queue
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestPopFrontMetaReportsResidency(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	q := NewSegmentedQueue[string](
+		WithClock[string](clock.Now),
+		WithElementMeta[string](),
+		WithVersionStamps[string](),
+	)
+
+	q.PushBackPending("a")
+	clock.now = clock.now.Add(time.Second)
+	q.PushBackPending("b")
+
+	// An aborted commit keeps the original enqueue times.
+	_, abort, _ := q.PrepareCommit(context.Background())
+	abort()
+	clock.now = clock.now.Add(time.Second)
+	q.Commit()
+	clock.now = clock.now.Add(time.Second)
+
+	want := []struct {
+		value               string
+		enqueued, committed int64
+	}{{"a", 1000, 1002}, {"b", 1001, 1002}}
+	for _, w := range want {
+		v, meta, ok := q.PopFrontMeta()
+		if !ok || v != w.value {
+			t.Fatalf("expected %q, got %q %v", w.value, v, ok)
+		}
+		if !meta.Enqueued.Equal(time.Unix(w.enqueued, 0)) || !meta.Committed.Equal(time.Unix(w.committed, 0)) || meta.Version != 1 {
+			t.Fatalf("unexpected metadata for %q: %+v", v, meta)
+		}
+	}
+}
+
+func TestPopFrontMetaWithoutOption(t *testing.T) {
+	q := NewSegmentedQueue[int](WithInitialVisible(1))
+
+	v, meta, ok := q.PopFrontMeta()
+	if !ok || v != 1 || meta != (ElementMeta{}) {
+		t.Fatalf("expected 1 without metadata, got %d %+v %v", v, meta, ok)
+	}
+}
+
+func TestResidencyDistributions(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	q := NewSegmentedQueue[int](WithClock[int](clock.Now), WithElementMeta[int]())
+
+	q.PushBackPending(1)
+	clock.now = clock.now.Add(2 * time.Second)
+	q.PushBackPending(2)
+	q.Commit()
+	clock.now = clock.now.Add(30 * time.Second)
+	q.PopFront()
+
+	r := q.Residency()
+	if r.Pending.Count != 2 || r.Pending.Sum != 2*time.Second {
+		t.Fatalf("expected pending times of 2s and 0s, got %+v", r.Pending)
+	}
+	if r.Visible.Count != 1 || r.Visible.Sum != 30*time.Second {
+		t.Fatalf("expected one element visible for 30s, got %+v", r.Visible)
+	}
+	if r.Total.Count != 1 || r.Total.Sum != 32*time.Second || r.Total.P99 > time.Minute {
+		t.Fatalf("expected one element resident for 32s, got %+v", r.Total)
+	}
+
+	plain := NewSegmentedQueue[int](WithInitialPending(1))
+	plain.Commit()
+	plain.PopFront()
+	if r := plain.Residency(); r.Pending.Count != 0 || r.Total.Count != 0 {
+		t.Fatalf("expected no residency without WithElementMeta, got %+v", r)
+	}
+}
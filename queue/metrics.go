@@ -0,0 +1,110 @@
+package queue
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/timzifer/committable_queue/telemetry"
+)
+
+// QueueMetrics is a point-in-time snapshot of a queue's counters.
+type QueueMetrics struct {
+	Pushes  uint64
+	Pops    uint64
+	Commits uint64
+	Aborts  uint64
+	// Dropped is the total number of elements discarded by overflow handling;
+	// Drops breaks it down by the policy that discarded them.
+	Dropped uint64
+	Drops   map[DropPolicy]uint64
+	// Expired is the number of elements removed because their TTL passed.
+	Expired uint64
+	// Duplicates is the number of pending elements discarded on publish
+	// because their dedup key was already visible.
+	Duplicates uint64
+	// Redelivered is the number of acknowledged-delivery elements returned to
+	// the visible segment by Nack or the visibility timeout.
+	Redelivered uint64
+	// DeadLettered is the number of discarded elements pushed to the queue
+	// set with WithDeadLetter.
+	DeadLettered uint64
+	// Rejected is the number of staged elements dropped by the commit filter.
+	Rejected uint64
+	// Skipped is the number of elements PopFrontAck and Lease discarded
+	// because WithProcessedStore had already recorded their ID.
+	Skipped uint64
+	// BatchSizes holds the cumulative buckets of a histogram over the
+	// elements each published commit moved to the visible segment, with the
+	// bounds of telemetry.DefaultBatchSizeBuckets. CommittedElements is their
+	// sum.
+	BatchSizes        []telemetry.Bucket
+	CommittedElements uint64
+}
+
+const dropPolicyCount = int(DropSampled) + 1
+
+type queueCounters struct {
+	pushes  atomic.Uint64
+	pops    atomic.Uint64
+	commits atomic.Uint64
+	aborts  atomic.Uint64
+	expired atomic.Uint64
+	dupes   atomic.Uint64
+	redeliv atomic.Uint64
+	deadLet atomic.Uint64
+	reject  atomic.Uint64
+	skipped atomic.Uint64
+	drops   [dropPolicyCount]atomic.Uint64
+
+	batchSizesOnce sync.Once
+	batchSizes     *telemetry.Histogram
+}
+
+// committed counts a published commit that moved n elements.
+func (c *queueCounters) committed(n int) {
+	c.commits.Add(1)
+	c.histogram().Observe(int64(n))
+}
+
+func (c *queueCounters) histogram() *telemetry.Histogram {
+	c.batchSizesOnce.Do(func() {
+		c.batchSizes = telemetry.NewHistogram(telemetry.DefaultBatchSizeBuckets)
+	})
+	return c.batchSizes
+}
+
+func (c *queueCounters) dropped(policy DropPolicy, n int) {
+	if n <= 0 {
+		return
+	}
+	if int(policy) < 0 || int(policy) >= dropPolicyCount {
+		policy = DropOldest
+	}
+	c.drops[policy].Add(uint64(n))
+}
+
+func (c *queueCounters) snapshot() QueueMetrics {
+	m := QueueMetrics{
+		Pushes:       c.pushes.Load(),
+		Pops:         c.pops.Load(),
+		Commits:      c.commits.Load(),
+		Aborts:       c.aborts.Load(),
+		Expired:      c.expired.Load(),
+		Duplicates:   c.dupes.Load(),
+		Redelivered:  c.redeliv.Load(),
+		DeadLettered: c.deadLet.Load(),
+		Rejected:     c.reject.Load(),
+		Skipped:      c.skipped.Load(),
+		Drops:        make(map[DropPolicy]uint64),
+
+		BatchSizes:        c.histogram().Buckets(),
+		CommittedElements: uint64(c.histogram().Sum()),
+	}
+	for i := range c.drops {
+		if n := c.drops[i].Load(); n > 0 {
+			m.Drops[DropPolicy(i)] = n
+			m.Dropped += n
+		}
+	}
+	return m
+}
@@ -0,0 +1,67 @@
+package queue
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSegmentedQueueMetrics(t *testing.T) {
+	q := NewSegmentedQueue[int](WithOptions[int](Options{MaxLen: 2, DropPolicy: DropNewest}))
+
+	q.PushBackPending(1)
+	q.PushBackPending(2)
+	q.PushFrontPending(0)
+	q.Commit()
+
+	_, abort, err := q.PrepareCommit(context.Background())
+	if err != nil || abort != nil {
+		t.Fatalf("empty prepare should return no callbacks, got err=%v", err)
+	}
+
+	q.PushBackPending(3)
+	_, abort, err = q.PrepareCommit(context.Background())
+	if err != nil {
+		t.Fatalf("prepare failed: %v", err)
+	}
+	abort()
+
+	q.PopFront()
+	q.PopBack()
+	q.PopBack()
+
+	m := q.Metrics()
+	if m.Pushes != 4 || m.Pops != 2 || m.Commits != 1 || m.Aborts != 1 {
+		t.Fatalf("unexpected counters: %+v", m)
+	}
+	if m.Dropped != 1 || m.Drops[DropNewest] != 1 || len(m.Drops) != 1 {
+		t.Fatalf("unexpected drop counters: %+v", m)
+	}
+}
+
+func TestSegmentedQueueBatchSizes(t *testing.T) {
+	q := NewSegmentedQueue[int]()
+	for _, n := range []int{3, 40, 0} {
+		for i := range n {
+			q.PushBackPending(i)
+		}
+		q.Commit()
+	}
+
+	m := q.Metrics()
+	if m.CommittedElements != 43 {
+		t.Fatalf("expected 43 committed elements, got %d", m.CommittedElements)
+	}
+	// Buckets are cumulative: 3 fits below 5, 40 below 50.
+	for _, bucket := range m.BatchSizes {
+		want := uint64(0)
+		switch {
+		case bucket.UpperBound >= 50:
+			want = 2
+		case bucket.UpperBound >= 5:
+			want = 1
+		}
+		if bucket.Count != want {
+			t.Fatalf("expected %d commits up to %d elements, got %d", want, bucket.UpperBound, bucket.Count)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package queue
+
+import (
+	"errors"
+	"slices"
+)
+
+var (
+	// ErrVersionNotPublished is returned by ReadAt for a version newer than
+	// the latest publish.
+	ErrVersionNotPublished = errors.New("queue: version not published yet")
+	// ErrVersionCollected is returned by ReadAt for an older version that no
+	// reader pins any more.
+	ErrVersionCollected = errors.New("queue: version no longer retained")
+)
+
+type pinnedVersion[T any] struct {
+	view View[T]
+	refs int
+}
+
+// VersionedView is a View pinned to a published version by ReadAt. It must be
+// released once the reader is done with it.
+type VersionedView[T any] struct {
+	View[T]
+	queue    *SegmentedQueue[T]
+	version  uint64
+	released bool
+}
+
+// Version returns the commit version of the latest publish.
+func (sq *SegmentedQueue[T]) Version() uint64 {
+	return sq.version.Load()
+}
+
+// ReadAt pins the visible segment as of version and returns a view of it.
+// Commits published after version are not part of the view, however long it
+// is held, so several passes over it see the same elements while the queue
+// keeps committing and popping.
+//
+// The latest version can always be pinned; the view holds its elements as
+// they are at the first ReadAt. Older versions stay readable only while some
+// reader pins them: all readers of a version share one view, which is
+// released with the last of them. Other versions fail with
+// ErrVersionCollected, and versions not published yet with
+// ErrVersionNotPublished.
+func (sq *SegmentedQueue[T]) ReadAt(version uint64) (*VersionedView[T], error) {
+	sq.redeliverExpired()
+
+	sq.mu.Lock()
+	defer sq.mu.Unlock()
+
+	pin, ok := sq.pins[version]
+	switch latest := sq.version.Load(); {
+	case ok:
+	case version > latest:
+		return nil, ErrVersionNotPublished
+	case version < latest:
+		return nil, ErrVersionCollected
+	default:
+		sq.visible.mu.Lock()
+		pin = &pinnedVersion[T]{view: sq.viewLocked()}
+		sq.visible.mu.Unlock()
+		if sq.pins == nil {
+			sq.pins = make(map[uint64]*pinnedVersion[T])
+		}
+		sq.pins[version] = pin
+	}
+	pin.refs++
+	return &VersionedView[T]{View: pin.view, queue: sq, version: version}, nil
+}
+
+// PinnedVersions returns the versions readers currently pin, oldest first.
+func (sq *SegmentedQueue[T]) PinnedVersions() []uint64 {
+	sq.mu.Lock()
+	defer sq.mu.Unlock()
+
+	versions := make([]uint64, 0, len(sq.pins))
+	for version := range sq.pins {
+		versions = append(versions, version)
+	}
+	slices.Sort(versions)
+	return versions
+}
+
+// Version returns the version the view is pinned to.
+func (v *VersionedView[T]) Version() uint64 {
+	return v.version
+}
+
+// Release unpins the view's version. Once no reader pins it any more, the
+// queue drops its reference to the elements, so those popped in the meantime
+// can be garbage collected. Release is idempotent and leaves the view
+// empty.
+func (v *VersionedView[T]) Release() {
+	sq := v.queue
+	sq.mu.Lock()
+	defer sq.mu.Unlock()
+
+	if v.released {
+		return
+	}
+	v.released = true
+	v.View = View[T]{}
+	if pin := sq.pins[v.version]; pin != nil {
+		if pin.refs--; pin.refs == 0 {
+			delete(sq.pins, v.version)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package queue
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestReadAtPinsVersion(t *testing.T) {
+	q := NewSegmentedQueue[int]()
+	for i := 0; i < 3; i++ {
+		q.PushBackPending(i)
+	}
+	q.Commit()
+	version := q.Version()
+
+	first, err := q.ReadAt(version)
+	if err != nil {
+		t.Fatalf("ReadAt failed: %v", err)
+	}
+
+	q.PopFront()
+	q.PushBackPending(3)
+	q.Commit()
+
+	if got := first.Values(); !slices.Equal(got, []int{0, 1, 2}) {
+		t.Fatalf("expected the pinned state, got %v", got)
+	}
+
+	// A second reader of the pinned version shares the first one's view.
+	second, err := q.ReadAt(version)
+	if err != nil {
+		t.Fatalf("ReadAt of a pinned version failed: %v", err)
+	}
+	if got := second.Values(); !slices.Equal(got, []int{0, 1, 2}) {
+		t.Fatalf("expected the pinned state, got %v", got)
+	}
+	latest, err := q.ReadAt(q.Version())
+	if err != nil {
+		t.Fatalf("ReadAt of the latest version failed: %v", err)
+	}
+	if got := latest.Values(); !slices.Equal(got, []int{1, 2, 3}) {
+		t.Fatalf("expected the latest state, got %v", got)
+	}
+	if got := q.PinnedVersions(); !slices.Equal(got, []uint64{version, version + 1}) {
+		t.Fatalf("unexpected pinned versions %v", got)
+	}
+
+	first.Release()
+	first.Release()
+	if got := q.PinnedVersions(); len(got) != 2 {
+		t.Fatalf("version must stay pinned by the second reader, got %v", got)
+	}
+	second.Release()
+	latest.Release()
+	if got := q.PinnedVersions(); len(got) != 0 {
+		t.Fatalf("expected no pinned versions, got %v", got)
+	}
+	if _, err := q.ReadAt(version); !errors.Is(err, ErrVersionCollected) {
+		t.Fatalf("expected ErrVersionCollected, got %v", err)
+	}
+}
+
+func TestReadAtUnpublishedVersion(t *testing.T) {
+	q := NewSegmentedQueue[int]()
+	if _, err := q.ReadAt(1); !errors.Is(err, ErrVersionNotPublished) {
+		t.Fatalf("expected ErrVersionNotPublished, got %v", err)
+	}
+	view, err := q.ReadAt(0)
+	if err != nil {
+		t.Fatalf("ReadAt of the initial version failed: %v", err)
+	}
+	if view.Len() != 0 {
+		t.Fatalf("expected an empty view, got %d elements", view.Len())
+	}
+	view.Release()
+}
@@ -0,0 +1,80 @@
+package queue
+
+import (
+	"context"
+	"time"
+)
+
+// Ready returns a channel that is closed the next time elements become
+// visible, through a published commit or a redelivery. Every call after that
+// returns a fresh channel. To avoid missing a publish, obtain the channel
+// before checking the queue:
+//
+//	for {
+//		ready := q.Ready()
+//		if v, ok := q.PopFront(); ok {
+//			handle(v)
+//			continue
+//		}
+//		<-ready
+//	}
+func (sq *SegmentedQueue[T]) Ready() <-chan struct{} {
+	sq.readyMu.Lock()
+	defer sq.readyMu.Unlock()
+	if sq.ready == nil {
+		sq.ready = make(chan struct{})
+	}
+	return sq.ready
+}
+
+// notifyReady wakes everyone waiting on Ready. The channel is created lazily,
+// so publishing costs nothing while nobody waits.
+func (sq *SegmentedQueue[T]) notifyReady() {
+	sq.readyMu.Lock()
+	defer sq.readyMu.Unlock()
+	if sq.ready != nil {
+		close(sq.ready)
+		sq.ready = nil
+	}
+}
+
+// PopFrontWait removes the oldest visible element, waiting for a commit to
+// publish one when the visible segment is empty. It returns ctx.Err() when ctx
+// is done first.
+func (sq *SegmentedQueue[T]) PopFrontWait(ctx context.Context) (v T, err error) {
+	err = sq.wait(ctx, func() (ok bool) {
+		v, ok = sq.PopFront()
+		return ok
+	})
+	return v, err
+}
+
+// wait calls pop until it reports success, waiting for a publish or
+// redelivery in between. It returns ctx.Err() when ctx is done first.
+func (sq *SegmentedQueue[T]) wait(ctx context.Context, pop func() bool) error {
+	for {
+		ready := sq.Ready()
+		if pop() {
+			return nil
+		}
+		if wait, ok := sq.nextRedelivery(); ok {
+			// Redeliveries are only noticed by pops, so poll until the
+			// earliest lease can expire.
+			timer := time.NewTimer(wait)
+			select {
+			case <-ready:
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+				return ctx.Err()
+			}
+			timer.Stop()
+			continue
+		}
+		select {
+		case <-ready:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestReadyClosedByPublish(t *testing.T) {
+	q := NewSegmentedQueue[int]()
+	ready := q.Ready()
+
+	q.PushBackPending(1)
+	select {
+	case <-ready:
+		t.Fatalf("push alone must not signal readiness")
+	default:
+	}
+
+	q.Commit()
+	select {
+	case <-ready:
+	default:
+		t.Fatalf("expected publish to close the ready channel")
+	}
+
+	if next := q.Ready(); next == ready {
+		t.Fatalf("expected a fresh channel after the signal")
+	}
+}
+
+func TestReadyWakesWaitingConsumer(t *testing.T) {
+	q := NewSegmentedQueue[int]()
+	const total = 200
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	got := make([]int, 0, total)
+	go func() {
+		defer wg.Done()
+		for len(got) < total {
+			ready := q.Ready()
+			if v, ok := q.PopFront(); ok {
+				got = append(got, v)
+				continue
+			}
+			select {
+			case <-ready:
+			case <-time.After(5 * time.Second):
+				t.Errorf("consumer was not woken up")
+				return
+			}
+		}
+	}()
+
+	for i := 0; i < total; i++ {
+		q.PushBackPending(i)
+		if i%10 == 9 {
+			q.Commit()
+		}
+	}
+	wg.Wait()
+
+	for i, v := range got {
+		if v != i {
+			t.Fatalf("expected %d at position %d, got %d", i, i, v)
+		}
+	}
+}
+
+func TestPopFrontWait(t *testing.T) {
+	q := NewSegmentedQueue[int]()
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		q.PushBackPending(7)
+		q.Commit()
+	}()
+	v, err := q.PopFrontWait(context.Background())
+	if err != nil || v != 7 {
+		t.Fatalf("expected 7, got %d %v", v, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := q.PopFrontWait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+}
+
+func TestPopFrontWaitSeesRedelivery(t *testing.T) {
+	q := NewSegmentedQueue[int](
+		WithVisibilityTimeout[int](20*time.Millisecond),
+		WithInitialVisible(1),
+	)
+	if _, _, ok := q.PopFrontAck(); !ok {
+		t.Fatalf("expected an element")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	v, err := q.PopFrontWait(ctx)
+	if err != nil || v != 1 {
+		t.Fatalf("expected the redelivered element, got %d %v", v, err)
+	}
+}
@@ -0,0 +1,86 @@
+package queue
+
+import "sync"
+
+// DedupStore records the IDs of elements a consumer has processed. It backs
+// WithProcessedStore; implementations may keep the IDs in memory or in a
+// database shared by several consumers.
+type DedupStore interface {
+	// Processed reports whether id has been recorded.
+	Processed(id string) (bool, error)
+	// Record marks id as processed.
+	Record(id string) error
+}
+
+// WithProcessedStore gives PopFrontAck and Lease effectively-once semantics
+// for idempotent consumers. Acknowledging an element with Ack or Complete
+// first records its ID, as returned by id, in store; a later delivery of an
+// element whose ID is already recorded, typically a redelivery after the
+// consumer crashed between recording and acknowledging, is skipped and
+// counted in QueueMetrics.Skipped.
+//
+// If Record fails, the acknowledgement reports false and the element stays in
+// flight, so it is redelivered rather than lost. If Processed fails, the
+// element is delivered.
+func WithProcessedStore[T any](store DedupStore, id func(T) string) SegmentedQueueOption[T] {
+	return func(opts *segmentedQueueOptions[T]) {
+		opts.processedStore = store
+		opts.processedID = id
+	}
+}
+
+// popUnprocessed pops like pop(true) but skips elements WithProcessedStore
+// has already recorded.
+func (sq *SegmentedQueue[T]) popUnprocessed() (v T, meta ElementMeta, ok bool) {
+	for {
+		v, meta, ok = sq.pop(true)
+		if !ok || sq.opts.processedStore == nil {
+			return v, meta, ok
+		}
+		if done, err := sq.opts.processedStore.Processed(sq.opts.processedID(v)); err != nil || !done {
+			return v, meta, ok
+		}
+		sq.counters.skipped.Add(1)
+	}
+}
+
+// recordProcessed records the ID of v before it is acknowledged. It reports
+// false when the store failed.
+func (sq *SegmentedQueue[T]) recordProcessed(v T) bool {
+	if sq.opts.processedStore == nil {
+		return true
+	}
+	return sq.opts.processedStore.Record(sq.opts.processedID(v)) == nil
+}
+
+// MemoryDedupStore is a DedupStore that keeps the IDs in memory. It never
+// forgets an ID, so it suits bounded streams and tests rather than
+// long-running consumers. The zero value is ready to use.
+type MemoryDedupStore struct {
+	mu  sync.Mutex
+	ids map[string]struct{}
+}
+
+func (s *MemoryDedupStore) Processed(id string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.ids[id]
+	return ok, nil
+}
+
+func (s *MemoryDedupStore) Record(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ids == nil {
+		s.ids = make(map[string]struct{})
+	}
+	s.ids[id] = struct{}{}
+	return nil
+}
+
+// Len returns the number of recorded IDs.
+func (s *MemoryDedupStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.ids)
+}
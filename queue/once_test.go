@@ -0,0 +1,60 @@
+package queue
+
+import (
+	"errors"
+	"testing"
+)
+
+type failingDedupStore struct{ MemoryDedupStore }
+
+func (s *failingDedupStore) Record(string) error {
+	return errors.New("store unavailable")
+}
+
+func TestProcessedStoreSkipsRecordedElements(t *testing.T) {
+	store := &MemoryDedupStore{}
+	q := NewSegmentedQueue[string](
+		WithInitialVisible("a", "b", "a", "c"),
+		WithProcessedStore[string](store, func(v string) string { return v }),
+	)
+
+	v, h, _ := q.PopFrontAck()
+	if v != "a" || !h.Ack() {
+		t.Fatalf("expected to acknowledge a, got %q", v)
+	}
+
+	// The consumer recorded b but crashed before acknowledging it.
+	v, h, _ = q.PopFrontAck()
+	if err := store.Record(v); err != nil {
+		t.Fatal(err)
+	}
+	h.Nack()
+
+	if v, _, ok := q.PopFrontAck(); !ok || v != "c" {
+		t.Fatalf("expected the recorded elements to be skipped, got %q %v", v, ok)
+	}
+	if m := q.Metrics(); m.Skipped != 2 {
+		t.Fatalf("expected two skipped elements, got %+v", m)
+	}
+	if store.Len() != 2 {
+		t.Fatalf("expected two recorded IDs, got %d", store.Len())
+	}
+}
+
+func TestProcessedStoreFailureKeepsElementInFlight(t *testing.T) {
+	q := NewSegmentedQueue[string](
+		WithInitialVisible("a"),
+		WithProcessedStore[string](&failingDedupStore{}, func(v string) string { return v }),
+	)
+
+	_, h, _ := q.PopFrontAck()
+	if h.Ack() {
+		t.Fatal("an acknowledgement whose ID was not recorded must fail")
+	}
+	if q.LenInFlight() != 1 {
+		t.Fatal("expected the element to stay in flight")
+	}
+	if !h.Nack() || q.LenVisible() != 1 {
+		t.Fatal("expected the element to be redelivered")
+	}
+}
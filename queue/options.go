@@ -5,11 +5,78 @@ type DropPolicy int
 const (
 	DropOldest DropPolicy = iota
 	DropNewest
+	// DropLowestPriority evicts the visible elements with the lowest priority,
+	// oldest first among equals. SegmentedQueue needs WithDropPriority to rank
+	// its elements and falls back to DropOldest without it.
+	DropLowestPriority
+	// OverflowBlock never drops. Instead PushCtx waits until the queue holds
+	// fewer than MaxLen elements. Only SegmentedQueue supports it; other queues
+	// treat it as DropOldest.
+	OverflowBlock
+	// DropExpired discards every visible element older than the age set with
+	// WithMaxAge whenever a commit is published, before MaxLen and MaxBytes
+	// are enforced by dropping the oldest of the remaining elements. Only
+	// SegmentedQueue supports it; other queues, and a SegmentedQueue without
+	// WithMaxAge, treat it as DropOldest.
+	DropExpired
+	// DropSampled thins out the visible segment instead of truncating one end
+	// of it: the elements it drops are spread evenly over the whole segment,
+	// so the remaining stream stays representative. Only SegmentedQueue
+	// supports it; other queues treat it as DropOldest.
+	DropSampled
 )
 
+func (p DropPolicy) String() string {
+	switch p {
+	case DropOldest:
+		return "oldest"
+	case DropNewest:
+		return "newest"
+	case DropLowestPriority:
+		return "lowest-priority"
+	case OverflowBlock:
+		return "block"
+	case DropExpired:
+		return "expired"
+	case DropSampled:
+		return "sampled"
+	default:
+		return "unknown"
+	}
+}
+
+// AbortOrder decides where an aborted commit returns its staged elements.
+type AbortOrder int
+
+const (
+	// AbortPrepend puts staged elements back in front of elements pushed after
+	// the prepare, restoring the original push order. It is the default.
+	AbortPrepend AbortOrder = iota
+	// AbortAppend puts staged elements behind elements pushed after the
+	// prepare, so newer elements are published first by the next commit.
+	AbortAppend
+)
+
+func (o AbortOrder) String() string {
+	switch o {
+	case AbortPrepend:
+		return "prepend"
+	case AbortAppend:
+		return "append"
+	default:
+		return "unknown"
+	}
+}
+
 type Options struct {
-	MaxLen     int
+	MaxLen int
+	// MaxBytes caps the total size of the visible segment as measured by the
+	// function passed to WithSizer. It is ignored without a sizer.
+	MaxBytes   int
 	DropPolicy DropPolicy
+	// Watermarks notifies about the visible depth crossing thresholds. Only
+	// SegmentedQueue and PriorityQueue support it.
+	Watermarks Watermarks
 }
 
 func defaultOptions() Options {
@@ -0,0 +1,228 @@
+package queue
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/timzifer/committable_queue/orchestrator"
+)
+
+// lanes keeps one deque per priority, ordered from the highest priority to the
+// lowest. Callers serialise access with their own mutex.
+type lanes[T any] struct {
+	priorities []int
+	deques     map[int]*deque[T]
+	len        int
+}
+
+func newLanes[T any]() lanes[T] {
+	return lanes[T]{deques: make(map[int]*deque[T])}
+}
+
+func (l *lanes[T]) lane(priority int) *deque[T] {
+	if d, ok := l.deques[priority]; ok {
+		return d
+	}
+	d := newDeque[T]()
+	l.deques[priority] = d
+	i := sort.Search(len(l.priorities), func(i int) bool { return l.priorities[i] < priority })
+	l.priorities = append(l.priorities, 0)
+	copy(l.priorities[i+1:], l.priorities[i:])
+	l.priorities[i] = priority
+	return d
+}
+
+// highest returns the non-empty lane with the highest priority, or nil.
+func (l *lanes[T]) highest() *deque[T] {
+	for _, p := range l.priorities {
+		if d := l.deques[p]; d.len > 0 {
+			return d
+		}
+	}
+	return nil
+}
+
+// lowest returns the non-empty lane with the lowest priority, or nil.
+func (l *lanes[T]) lowest() *deque[T] {
+	for i := len(l.priorities) - 1; i >= 0; i-- {
+		if d := l.deques[l.priorities[i]]; d.len > 0 {
+			return d
+		}
+	}
+	return nil
+}
+
+type prioritySegment[T any] struct {
+	priority int
+	segment  segment[T]
+}
+
+// PriorityQueue is a committable queue whose elements carry a priority.
+// Publishing a commit merges the staged elements into the visible segment by
+// priority: PopFront returns the oldest element of the highest priority.
+// Within a priority, elements keep the order in which they were pushed, and
+// nothing becomes visible before its commit is published.
+//
+// When MaxLen is exceeded on publish, elements of the lowest priority are
+// dropped first; DropPolicy selects the oldest or the newest of them.
+type PriorityQueue[T any] struct {
+	// mu serialises prepare, publish and abort like SegmentedQueue.mu.
+	mu        sync.Mutex
+	visibleMu sync.Mutex
+	visible   lanes[T]
+	pendingMu sync.Mutex
+	pending   lanes[T]
+	options   Options
+	counters  queueCounters
+
+	watermarks watermarkState
+}
+
+func NewPriorityQueue[T any](options Options) *PriorityQueue[T] {
+	return &PriorityQueue[T]{
+		visible: newLanes[T](),
+		pending: newLanes[T](),
+		options: options,
+	}
+}
+
+// PushPending appends value to the pending elements of the given priority.
+// Higher values are consumed first.
+func (pq *PriorityQueue[T]) PushPending(priority int, value T) {
+	pq.pendingMu.Lock()
+	pq.pending.lane(priority).pushBack(value)
+	pq.pending.len++
+	pq.pendingMu.Unlock()
+	pq.counters.pushes.Add(1)
+}
+
+// PopFront removes the oldest visible element of the highest priority.
+func (pq *PriorityQueue[T]) PopFront() (zero T, _ bool) {
+	pq.visibleMu.Lock()
+	d := pq.visible.highest()
+	if d == nil {
+		pq.visibleMu.Unlock()
+		return zero, false
+	}
+	v, _ := d.popFront()
+	pq.visible.len--
+	pq.visibleMu.Unlock()
+
+	pq.counters.pops.Add(1)
+	pq.checkWatermarks()
+	return v, true
+}
+
+func (pq *PriorityQueue[T]) LenVisible() int {
+	pq.visibleMu.Lock()
+	defer pq.visibleMu.Unlock()
+	return pq.visible.len
+}
+
+func (pq *PriorityQueue[T]) LenPending() int {
+	pq.pendingMu.Lock()
+	defer pq.pendingMu.Unlock()
+	return pq.pending.len
+}
+
+func (pq *PriorityQueue[T]) Metrics() QueueMetrics {
+	return pq.counters.snapshot()
+}
+
+func (pq *PriorityQueue[T]) Commit() {
+	publish, _, err := pq.PrepareCommit(context.Background())
+	if err != nil {
+		panic(err)
+	}
+	if publish != nil {
+		publish()
+	}
+}
+
+// PrepareCommit detaches the pending elements of every priority. It follows
+// the same contract as SegmentedQueue.PrepareCommit.
+func (pq *PriorityQueue[T]) PrepareCommit(ctx context.Context) (publish func(), abort func(), err error) {
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	pq.mu.Lock()
+	defer pq.mu.Unlock()
+
+	pq.pendingMu.Lock()
+	var staged []prioritySegment[T]
+	for _, p := range pq.pending.priorities {
+		if d := pq.pending.deques[p]; d.len > 0 {
+			staged = append(staged, prioritySegment[T]{priority: p, segment: d.detachLocked()})
+		}
+	}
+	pq.pending.len = 0
+	pq.pendingMu.Unlock()
+
+	if len(staged) == 0 {
+		return nil, nil, nil
+	}
+	elements := 0
+	for _, s := range staged {
+		elements += s.segment.len
+	}
+	orchestrator.ReportElements(ctx, elements)
+
+	var once sync.Once
+	publish = func() {
+		once.Do(func() { pq.finalizePublish(staged) })
+	}
+	abort = func() {
+		once.Do(func() { pq.finalizeAbort(staged) })
+	}
+	return publish, abort, nil
+}
+
+func (pq *PriorityQueue[T]) finalizePublish(staged []prioritySegment[T]) {
+	defer pq.checkWatermarks()
+	pq.mu.Lock()
+	defer pq.mu.Unlock()
+
+	pq.visibleMu.Lock()
+	defer pq.visibleMu.Unlock()
+
+	elements := 0
+	for _, s := range staged {
+		pq.visible.lane(s.priority).appendSegmentLocked(s.segment)
+		pq.visible.len += s.segment.len
+		elements += s.segment.len
+	}
+
+	pq.counters.committed(elements)
+
+	if pq.options.MaxLen > 0 {
+		dropped := 0
+		for pq.visible.len > pq.options.MaxLen {
+			d := pq.visible.lowest()
+			switch pq.options.DropPolicy {
+			case DropNewest:
+				d.popBackLocked()
+			default:
+				d.popFrontLocked()
+			}
+			pq.visible.len--
+			dropped++
+		}
+		pq.counters.dropped(pq.options.DropPolicy, dropped)
+	}
+}
+
+func (pq *PriorityQueue[T]) finalizeAbort(staged []prioritySegment[T]) {
+	pq.mu.Lock()
+	defer pq.mu.Unlock()
+
+	pq.pendingMu.Lock()
+	defer pq.pendingMu.Unlock()
+
+	pq.counters.aborts.Add(1)
+	for _, s := range staged {
+		pq.pending.lane(s.priority).prependSegmentLocked(s.segment)
+		pq.pending.len += s.segment.len
+	}
+}
@@ -0,0 +1,85 @@
+package queue
+
+import (
+	"context"
+	"testing"
+)
+
+const (
+	routine = 0
+	alarm   = 10
+)
+
+func TestPriorityQueueOrdersByPriorityAfterCommit(t *testing.T) {
+	q := NewPriorityQueue[string](Options{})
+
+	q.PushPending(routine, "temp-1")
+	q.PushPending(routine, "temp-2")
+	q.Commit()
+
+	q.PushPending(routine, "temp-3")
+	q.PushPending(alarm, "overheat")
+	q.PushPending(-1, "debug")
+	q.PushPending(alarm, "pressure")
+
+	if v, _ := q.PopFront(); v != "temp-1" {
+		t.Fatalf("uncommitted alarms must not overtake visible elements, got %q", v)
+	}
+
+	q.Commit()
+	expected := []string{"overheat", "pressure", "temp-2", "temp-3", "debug"}
+	for _, want := range expected {
+		if v, ok := q.PopFront(); !ok || v != want {
+			t.Fatalf("expected %q, got %q (ok=%v)", want, v, ok)
+		}
+	}
+	if _, ok := q.PopFront(); ok {
+		t.Fatalf("queue should be empty")
+	}
+}
+
+func TestPriorityQueueAbortRestoresPending(t *testing.T) {
+	q := NewPriorityQueue[int](Options{})
+	q.PushPending(alarm, 1)
+	q.PushPending(routine, 2)
+
+	_, abort, err := q.PrepareCommit(context.Background())
+	if err != nil {
+		t.Fatalf("prepare failed: %v", err)
+	}
+	q.PushPending(alarm, 3)
+	abort()
+	abort()
+
+	if q.LenVisible() != 0 || q.LenPending() != 3 {
+		t.Fatalf("unexpected lengths after abort: visible=%d pending=%d", q.LenVisible(), q.LenPending())
+	}
+	q.Commit()
+	for _, want := range []int{1, 3, 2} {
+		if v, _ := q.PopFront(); v != want {
+			t.Fatalf("expected %d, got %d", want, v)
+		}
+	}
+	if m := q.Metrics(); m.Pushes != 3 || m.Pops != 3 || m.Commits != 1 || m.Aborts != 1 {
+		t.Fatalf("unexpected counters: %+v", m)
+	}
+}
+
+func TestPriorityQueueDropsLowestPriorityFirst(t *testing.T) {
+	q := NewPriorityQueue[int](Options{MaxLen: 3, DropPolicy: DropOldest})
+	q.PushPending(routine, 1)
+	q.PushPending(routine, 2)
+	q.PushPending(alarm, 3)
+	q.PushPending(alarm, 4)
+	q.PushPending(routine, 5)
+	q.Commit()
+
+	for _, want := range []int{3, 4, 5} {
+		if v, _ := q.PopFront(); v != want {
+			t.Fatalf("expected %d, got %d", want, v)
+		}
+	}
+	if m := q.Metrics(); m.Drops[DropOldest] != 2 {
+		t.Fatalf("expected two dropped routine elements, got %+v", m)
+	}
+}
@@ -0,0 +1,139 @@
+package queue
+
+import "sync/atomic"
+
+// WithProducerLimit caps the pending buffer of every ProducerHandle at n
+// elements. A push into a full buffer drops one element of that producer:
+// the pushed one under DropNewest, the oldest pending one otherwise. The
+// drop is counted for the producer and the queue and goes to the dead
+// letter queue, so a flooding producer loses its own data instead of
+// crowding out the others. Values below 1 remove the limit.
+func WithProducerLimit[T any](n int) SegmentedQueueOption[T] {
+	return func(opts *segmentedQueueOptions[T]) {
+		opts.producerLimit = n
+	}
+}
+
+// ProducerHandle pushes into a pending buffer of its own and counts what its
+// producer pushed and lost. Commits take the buffers of named producers after
+// the shards of WithPendingShards, in the order the producers were created,
+// or round-robin with WithFairMerge.
+type ProducerHandle[T any] struct {
+	queue *SegmentedQueue[T]
+	name  string
+	shard *deque[T]
+
+	pushes atomic.Uint64
+	drops  atomic.Uint64
+}
+
+// ProducerStats describes one producer of a queue.
+type ProducerStats struct {
+	Name string
+	// Pushes counts the elements the producer pushed, including dropped ones.
+	Pushes uint64
+	// Dropped counts the elements lost to WithProducerLimit.
+	Dropped uint64
+	// Pending is the number of elements in the producer's buffer that no
+	// commit has taken yet.
+	Pending int
+}
+
+// Producer returns the handle of the named producer, creating it and its
+// pending buffer on first use.
+func (sq *SegmentedQueue[T]) Producer(name string) *ProducerHandle[T] {
+	sq.mu.Lock()
+	defer sq.mu.Unlock()
+	if p, ok := sq.named[name]; ok {
+		return p
+	}
+	return sq.addProducerLocked(name)
+}
+
+// addProducerLocked creates the handle of a new named producer. The caller
+// must hold sq.mu or own the queue exclusively.
+func (sq *SegmentedQueue[T]) addProducerLocked(name string) *ProducerHandle[T] {
+	p := &ProducerHandle[T]{queue: sq, name: name, shard: sq.newDeque()}
+	if sq.named == nil {
+		sq.named = make(map[string]*ProducerHandle[T])
+	}
+	sq.named[name] = p
+	sq.namedOrder = append(sq.namedOrder, p)
+	sq.producers = append(sq.producers, p.shard)
+	sq.shards = append(sq.shards, p.shard)
+	return p
+}
+
+// ProducerStats returns the statistics of all named producers in the order
+// they were created.
+func (sq *SegmentedQueue[T]) ProducerStats() []ProducerStats {
+	sq.mu.Lock()
+	producers := sq.namedOrder
+	sq.mu.Unlock()
+
+	stats := make([]ProducerStats, 0, len(producers))
+	for _, p := range producers {
+		stats = append(stats, p.Stats())
+	}
+	return stats
+}
+
+// Name returns the producer's name.
+func (p *ProducerHandle[T]) Name() string {
+	return p.name
+}
+
+// Stats returns the producer's statistics.
+func (p *ProducerHandle[T]) Stats() ProducerStats {
+	return ProducerStats{
+		Name:    p.name,
+		Pushes:  p.pushes.Load(),
+		Dropped: p.drops.Load(),
+		Pending: p.shard.length(),
+	}
+}
+
+func (p *ProducerHandle[T]) PushBackPending(value T) {
+	p.push(value, false)
+}
+
+func (p *ProducerHandle[T]) PushFrontPending(value T) {
+	p.push(value, true)
+}
+
+func (p *ProducerHandle[T]) push(value T, front bool) {
+	sq := p.queue
+	p.pushes.Add(1)
+	sq.counters.pushes.Add(1)
+
+	policy := DropOldest
+	if sq.options.DropPolicy == DropNewest {
+		policy = DropNewest
+	}
+	var dropped T
+	full := false
+
+	p.shard.mu.Lock()
+	if limit := sq.opts.producerLimit; limit > 0 && p.shard.len >= limit {
+		full = true
+		if policy == DropNewest {
+			dropped = value
+		} else {
+			dropped, _ = p.shard.popFrontLocked()
+		}
+	}
+	if !full || policy != DropNewest {
+		if front {
+			p.shard.pushFrontLocked(value)
+		} else {
+			p.shard.pushBackLocked(value)
+		}
+	}
+	p.shard.mu.Unlock()
+
+	if full {
+		p.drops.Add(1)
+		sq.reportDropped(policy, 1)
+		sq.deadLetter([]T{dropped})
+	}
+}
@@ -0,0 +1,79 @@
+package queue
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestProducerHandlesCountPushes(t *testing.T) {
+	q := NewSegmentedQueue[int]()
+	a, b := q.Producer("poller-a"), q.Producer("poller-b")
+	if q.Producer("poller-a") != a {
+		t.Fatal("expected the same handle for the same name")
+	}
+	q.PushBackPending(1)
+	a.PushBackPending(10)
+	a.PushBackPending(11)
+	b.PushBackPending(20)
+	a.PushFrontPending(9)
+
+	stats := q.ProducerStats()
+	want := []ProducerStats{{Name: "poller-a", Pushes: 3, Pending: 3}, {Name: "poller-b", Pushes: 1, Pending: 1}}
+	if !slices.Equal(stats, want) {
+		t.Fatalf("expected %+v, got %+v", want, stats)
+	}
+	if got := drain(q); !slices.Equal(got, []int{1, 9, 10, 11, 20}) {
+		t.Fatalf("unexpected commit order %v", got)
+	}
+	if s := a.Stats(); s.Pending != 0 || s.Pushes != 3 {
+		t.Fatalf("expected an empty buffer after the commit, got %+v", s)
+	}
+	if m := q.Metrics(); m.Pushes != 5 {
+		t.Fatalf("expected the queue to count producer pushes, got %d", m.Pushes)
+	}
+}
+
+func TestProducerLimitDropsFloodingProducer(t *testing.T) {
+	dead := NewSegmentedQueue[int]()
+	q := NewSegmentedQueue[int](WithProducerLimit[int](2), WithDeadLetter(dead))
+	flood, quiet := q.Producer("flood"), q.Producer("quiet")
+	for v := 1; v <= 5; v++ {
+		flood.PushBackPending(v)
+	}
+	quiet.PushBackPending(100)
+
+	if s := flood.Stats(); s.Dropped != 3 || s.Pending != 2 {
+		t.Fatalf("expected the flooding producer to lose 3 elements, got %+v", s)
+	}
+	if s := quiet.Stats(); s.Dropped != 0 {
+		t.Fatalf("expected the quiet producer to lose nothing, got %+v", s)
+	}
+	if got := drain(q); !slices.Equal(got, []int{4, 5, 100}) {
+		t.Fatalf("expected the newest flood elements to survive, got %v", got)
+	}
+	if got := drain(dead); !slices.Equal(got, []int{1, 2, 3}) {
+		t.Fatalf("expected the dropped elements in the dead letter queue, got %v", got)
+	}
+
+	q = NewSegmentedQueue[int](WithProducerLimit[int](2), WithOptions[int](Options{DropPolicy: DropNewest}))
+	flood = q.Producer("flood")
+	for v := 1; v <= 5; v++ {
+		flood.PushBackPending(v)
+	}
+	if got := drain(q); !slices.Equal(got, []int{1, 2}) {
+		t.Fatalf("expected DropNewest to keep the oldest elements, got %v", got)
+	}
+}
+
+func TestCloneKeepsNamedProducers(t *testing.T) {
+	q := NewSegmentedQueue[int]()
+	q.Producer("a").PushBackPending(1)
+	clone := q.Clone(nil)
+	clone.Producer("a").PushBackPending(2)
+	if got := drain(clone); !slices.Equal(got, []int{1, 2}) {
+		t.Fatalf("expected the clone to share the producer buffer layout, got %v", got)
+	}
+	if got := drain(q); !slices.Equal(got, []int{1}) {
+		t.Fatalf("expected the original to stay unchanged, got %v", got)
+	}
+}
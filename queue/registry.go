@@ -0,0 +1,139 @@
+package queue
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+)
+
+// ErrRegistered is returned by Registry.Register for a name that is taken.
+var ErrRegistered = errors.New("queue: name already registered")
+
+// Monitored is what a Registry needs from a queue. SegmentedQueue,
+// PriorityQueue and CoalescingQueue implement it.
+type Monitored interface {
+	LenVisible() int
+	Metrics() QueueMetrics
+}
+
+// Registry keeps queues under unique names, so tooling such as the admin
+// handler and the expvar exporter can find every queue of a process without
+// being wired to each one. Registration is opt-in: queues appear only after
+// Register or WithRegistry.
+type Registry struct {
+	mu     sync.Mutex
+	queues map[string]Monitored
+}
+
+// NewRegistry creates an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{queues: make(map[string]Monitored)}
+}
+
+// DefaultRegistry is the process-wide registry.
+var DefaultRegistry = NewRegistry()
+
+// WithRegistry registers the queue with r under name when it is created. A
+// name that is already taken panics, as it indicates two queues configured
+// alike by mistake; for the same reason constructors that create several
+// queues from one set of options, such as NewShardedQueue, must not be given
+// WithRegistry. Clones and splits are not registered.
+func WithRegistry[T any](r *Registry, name string) SegmentedQueueOption[T] {
+	return func(opts *segmentedQueueOptions[T]) {
+		opts.registry, opts.registryName = r, name
+	}
+}
+
+// register adds sq to the registry of WithRegistry.
+func (sq *SegmentedQueue[T]) register() {
+	r := sq.opts.registry
+	if r == nil {
+		return
+	}
+	if err := r.Register(sq.opts.registryName, sq); err != nil {
+		panic(fmt.Errorf("%w: %q", err, sq.opts.registryName))
+	}
+}
+
+// Register adds q under name.
+func (r *Registry) Register(name string, q Monitored) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.queues[name]; ok {
+		return ErrRegistered
+	}
+	r.queues[name] = q
+	return nil
+}
+
+// Unregister removes the queue registered under name and reports whether there
+// was one.
+func (r *Registry) Unregister(name string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.queues[name]; !ok {
+		return false
+	}
+	delete(r.queues, name)
+	return true
+}
+
+// List returns the registered names in ascending order.
+func (r *Registry) List() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Sorted(maps.Keys(r.queues))
+}
+
+// Get returns the queue registered under name.
+func (r *Registry) Get(name string) (Monitored, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	q, ok := r.queues[name]
+	return q, ok
+}
+
+// QueueStats summarises one registered queue.
+type QueueStats struct {
+	Name    string
+	Visible int
+	Metrics QueueMetrics
+}
+
+// RegistryStats summarises all registered queues. Queues lists them deepest
+// first, so a backlog shows at the top; Visible, Pushes, Pops and Dropped are
+// totals over all of them.
+type RegistryStats struct {
+	Queues  []QueueStats
+	Visible int
+	Pushes  uint64
+	Pops    uint64
+	Dropped uint64
+}
+
+// Stats collects the depth and counters of every registered queue. The
+// queues are read one after another, not as an atomic snapshot.
+func (r *Registry) Stats() RegistryStats {
+	r.mu.Lock()
+	queues := maps.Clone(r.queues)
+	r.mu.Unlock()
+
+	var stats RegistryStats
+	for name, q := range queues {
+		s := QueueStats{Name: name, Visible: q.LenVisible(), Metrics: q.Metrics()}
+		stats.Queues = append(stats.Queues, s)
+		stats.Visible += s.Visible
+		stats.Pushes += s.Metrics.Pushes
+		stats.Pops += s.Metrics.Pops
+		stats.Dropped += s.Metrics.Dropped
+	}
+	slices.SortFunc(stats.Queues, func(a, b QueueStats) int {
+		return cmp.Or(cmp.Compare(b.Visible, a.Visible), cmp.Compare(a.Name, b.Name))
+	})
+	return stats
+}
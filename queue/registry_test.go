@@ -0,0 +1,60 @@
+package queue
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestRegistry(t *testing.T) {
+	r := NewRegistry()
+	orders := NewSegmentedQueue(WithRegistry[int](r, "orders"))
+	events := NewSegmentedQueue[string]()
+	if err := r.Register("events", events); err != nil {
+		t.Fatalf("register failed: %v", err)
+	}
+	if err := r.Register("events", events); !errors.Is(err, ErrRegistered) {
+		t.Fatalf("expected ErrRegistered for a taken name, got %v", err)
+	}
+
+	if got := r.List(); !slices.Equal(got, []string{"events", "orders"}) {
+		t.Fatalf("unexpected names: %v", got)
+	}
+	if q, ok := r.Get("orders"); !ok || q != Monitored(orders) {
+		t.Fatalf("Get returned %v, %v", q, ok)
+	}
+
+	orders.PushBackPending(1)
+	orders.Commit()
+	for _, v := range []string{"a", "b", "c"} {
+		events.PushBackPending(v)
+	}
+	events.Commit()
+	events.PopFront()
+
+	stats := r.Stats()
+	if stats.Visible != 3 || stats.Pushes != 4 || stats.Pops != 1 {
+		t.Fatalf("unexpected totals: %+v", stats)
+	}
+	if len(stats.Queues) != 2 || stats.Queues[0].Name != "events" || stats.Queues[0].Visible != 2 {
+		t.Fatalf("expected the deepest queue first: %+v", stats.Queues)
+	}
+
+	if !r.Unregister("orders") || r.Unregister("orders") {
+		t.Fatalf("Unregister should report whether the name was registered")
+	}
+	if _, ok := r.Get("orders"); ok {
+		t.Fatalf("unregistered queue is still found")
+	}
+}
+
+func TestWithRegistryPanicsOnTakenName(t *testing.T) {
+	r := NewRegistry()
+	NewSegmentedQueue(WithRegistry[int](r, "orders"))
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("expected a panic for a taken name")
+		}
+	}()
+	NewSegmentedQueue(WithRegistry[int](r, "orders"))
+}
@@ -0,0 +1,26 @@
+package queue
+
+// RemoveFunc removes every visible element for which pred returns true and
+// returns how many were removed. It runs under the visible lock, so consumers
+// never observe a partially filtered segment; pending elements are not
+// affected. Elements handed out by PopFrontAck are not visible and therefore
+// not considered.
+func (sq *SegmentedQueue[T]) RemoveFunc(pred func(T) bool) int {
+	sq.redeliverExpired()
+
+	sq.visible.mu.Lock()
+	removed := sq.visible.removeLocked(func(_ int, v T) bool { return pred(v) })
+	for _, v := range removed {
+		sq.forgetLocked(v)
+	}
+	if len(removed) > 0 {
+		sq.reportDepthLocked()
+	}
+	sq.visible.mu.Unlock()
+
+	if len(removed) > 0 {
+		sq.notifySpace()
+		sq.checkWatermarks()
+	}
+	return len(removed)
+}
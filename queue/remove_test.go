@@ -0,0 +1,46 @@
+package queue
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestRemoveFunc(t *testing.T) {
+	q := NewSegmentedQueue[int]()
+	for i := 0; i < 3*chunkSize; i++ {
+		q.PushBackPending(i)
+	}
+	q.Commit()
+	q.PushBackPending(1000)
+	view := q.SnapshotView()
+
+	calls := 0
+	n := q.RemoveFunc(func(v int) bool {
+		calls++
+		return v%3 != 0 || v >= chunkSize && v < 2*chunkSize
+	})
+	if calls != 3*chunkSize {
+		t.Fatalf("predicate called %d times, want %d", calls, 3*chunkSize)
+	}
+
+	var want []int
+	for i := 0; i < 3*chunkSize; i += 3 {
+		if i < chunkSize || i >= 2*chunkSize {
+			want = append(want, i)
+		}
+	}
+	if n != 3*chunkSize-len(want) {
+		t.Fatalf("expected %d removals, got %d", 3*chunkSize-len(want), n)
+	}
+	if got := q.SnapshotView().Values(); !slices.Equal(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	if view.Len() != 3*chunkSize || view.Values()[1] != 1 {
+		t.Fatalf("existing views must not change")
+	}
+
+	q.Commit()
+	if v, ok := q.PopBack(); !ok || v != 1000 {
+		t.Fatalf("pending elements must survive, got %d %v", v, ok)
+	}
+}
@@ -0,0 +1,106 @@
+package queue
+
+import (
+	"maps"
+	"slices"
+	"sync"
+
+	"github.com/timzifer/committable_queue/orchestrator"
+)
+
+// Router owns a set of SegmentedQueues addressed by key. Push routes an
+// element to the queue of its key and creates that queue on first use; every
+// queue the router creates is registered with its orchestrator as a bank named
+// after the key, so one CommitAll publishes all of them together.
+type Router[T any] struct {
+	orchestrator *orchestrator.CommitOrchestrator
+	options      []SegmentedQueueOption[T]
+
+	mu     sync.Mutex
+	queues map[string]*routedBank[T]
+}
+
+// routedBank names a routed queue after its key in the orchestrator's
+// telemetry.
+type routedBank[T any] struct {
+	*SegmentedQueue[T]
+	key string
+}
+
+func (b *routedBank[T]) Name() string {
+	return b.key
+}
+
+// NewRouter creates an empty router whose queues are registered with o and
+// configured with options. A nil o leaves registration to the caller.
+func NewRouter[T any](o *orchestrator.CommitOrchestrator, options ...SegmentedQueueOption[T]) *Router[T] {
+	return &Router[T]{orchestrator: o, options: options, queues: make(map[string]*routedBank[T])}
+}
+
+// Push appends value to the pending segment of the queue for key, creating
+// and registering that queue if it does not exist yet.
+func (r *Router[T]) Push(key string, value T) {
+	r.queueFor(key).PushBackPending(value)
+}
+
+// queueFor returns the queue for key, creating it on demand.
+func (r *Router[T]) queueFor(key string) *SegmentedQueue[T] {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if bank, ok := r.queues[key]; ok {
+		return bank.SegmentedQueue
+	}
+	bank := &routedBank[T]{SegmentedQueue: NewSegmentedQueue(r.options...), key: key}
+	if r.orchestrator != nil {
+		// RegisterBank only fails for a nil bank.
+		_ = r.orchestrator.RegisterBank(bank)
+	}
+	r.queues[key] = bank
+	return bank.SegmentedQueue
+}
+
+// Queue returns the queue for key, if the router has one.
+func (r *Router[T]) Queue(key string) (*SegmentedQueue[T], bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	bank, ok := r.queues[key]
+	if !ok {
+		return nil, false
+	}
+	return bank.SegmentedQueue, true
+}
+
+// Keys returns the keys of all queues in ascending order.
+func (r *Router[T]) Keys() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Sorted(maps.Keys(r.queues))
+}
+
+// Len returns the number of queues.
+func (r *Router[T]) Len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.queues)
+}
+
+// Remove takes the queue for key out of the router and unregisters it from
+// the orchestrator. Commits already running still include it. The queue is
+// returned with whatever elements it still holds, so the caller can drain it;
+// a later Push with the same key starts a new queue.
+func (r *Router[T]) Remove(key string) (*SegmentedQueue[T], bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	bank, ok := r.queues[key]
+	if !ok {
+		return nil, false
+	}
+	delete(r.queues, key)
+	if r.orchestrator != nil {
+		r.orchestrator.UnregisterBank(bank)
+	}
+	return bank.SegmentedQueue, true
+}
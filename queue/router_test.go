@@ -0,0 +1,64 @@
+package queue
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/timzifer/committable_queue/orchestrator"
+)
+
+func TestRouterCreatesAndRegistersQueues(t *testing.T) {
+	o := orchestrator.NewCommitOrchestrator()
+	r := NewRouter[int](o)
+	r.Push("b", 1)
+	r.Push("a", 2)
+	r.Push("b", 3)
+
+	if keys := r.Keys(); !slices.Equal(keys, []string{"a", "b"}) {
+		t.Fatalf("expected keys [a b], got %v", keys)
+	}
+	if err := o.CommitAll(context.Background()); err != nil {
+		t.Fatalf("commit failed: %v", err)
+	}
+	b, ok := r.Queue("b")
+	if !ok || b.LenVisible() != 2 {
+		t.Fatal("expected the commit to publish queue b")
+	}
+	var names []string
+	for _, s := range o.Snapshot() {
+		names = append(names, s.Name)
+	}
+	if !slices.Equal(names, []string{"b", "a"}) {
+		t.Fatalf("expected banks named after their keys, got %v", names)
+	}
+}
+
+func TestRouterRemoveUnregisters(t *testing.T) {
+	o := orchestrator.NewCommitOrchestrator()
+	r := NewRouter[int](o)
+	r.Push("a", 1)
+	r.Push("b", 2)
+
+	removed, ok := r.Remove("a")
+	if !ok {
+		t.Fatal("expected queue a to be removed")
+	}
+	if _, ok := r.Remove("a"); ok {
+		t.Fatal("expected a second removal to fail")
+	}
+	if err := o.CommitAll(context.Background()); err != nil {
+		t.Fatalf("commit failed: %v", err)
+	}
+	if removed.LenVisible() != 0 {
+		t.Fatal("a removed queue must not take part in later commits")
+	}
+	if len(o.Snapshot()) != 1 || r.Len() != 1 {
+		t.Fatal("expected one remaining queue")
+	}
+
+	r.Push("a", 3)
+	if q, _ := r.Queue("a"); q == removed {
+		t.Fatal("expected a new queue after removal")
+	}
+}
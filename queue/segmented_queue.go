@@ -2,146 +2,55 @@ package queue
 
 import (
 	"context"
+	"errors"
+	"slices"
 	"sync"
-)
-
-type node[T any] struct {
-	value T
-	prev  *node[T]
-	next  *node[T]
-}
-
-type deque[T any] struct {
-	head *node[T]
-	tail *node[T]
-	len  int
-	mu   sync.Mutex
-}
-
-func newDeque[T any]() *deque[T] {
-	return &deque[T]{}
-}
-
-func (d *deque[T]) pushBack(value T) {
-	d.mu.Lock()
-	defer d.mu.Unlock()
-
-	n := &node[T]{value: value}
-	if d.len == 0 {
-		d.head = n
-		d.tail = n
-	} else {
-		n.prev = d.tail
-		d.tail.next = n
-		d.tail = n
-	}
-	d.len++
-}
-
-func (d *deque[T]) pushFront(value T) {
-	d.mu.Lock()
-	defer d.mu.Unlock()
-
-	n := &node[T]{value: value}
-	if d.len == 0 {
-		d.head = n
-		d.tail = n
-	} else {
-		n.next = d.head
-		d.head.prev = n
-		d.head = n
-	}
-	d.len++
-}
-
-func (d *deque[T]) popFront() (zero T, _ bool) {
-	d.mu.Lock()
-	defer d.mu.Unlock()
-
-	return d.popFrontLocked()
-}
-
-func (d *deque[T]) popFrontLocked() (zero T, _ bool) {
-	if d.len == 0 {
-		return zero, false
-	}
+	"sync/atomic"
+	"time"
 
-	current := d.head
-	next := current.next
-	if next != nil {
-		next.prev = nil
-	} else {
-		d.tail = nil
-	}
-	d.head = next
-	d.len--
-
-	current.next = nil
-	current.prev = nil
-
-	return current.value, true
-}
-
-func (d *deque[T]) popBack() (zero T, _ bool) {
-	d.mu.Lock()
-	defer d.mu.Unlock()
-
-	return d.popBackLocked()
-}
-
-func (d *deque[T]) popBackLocked() (zero T, _ bool) {
-	if d.len == 0 {
-		return zero, false
-	}
-
-	current := d.tail
-	prev := current.prev
-	if prev != nil {
-		prev.next = nil
-	} else {
-		d.head = nil
-	}
-	d.tail = prev
-	d.len--
-
-	current.next = nil
-	current.prev = nil
-
-	return current.value, true
-}
-
-func (d *deque[T]) length() int {
-	d.mu.Lock()
-	defer d.mu.Unlock()
-	return d.len
-}
-
-func (d *deque[T]) appendLocked(other *deque[T]) {
-	if other.len == 0 {
-		return
-	}
-
-	if d.len == 0 {
-		d.head = other.head
-		d.tail = other.tail
-		d.len = other.len
-	} else {
-		other.head.prev = d.tail
-		d.tail.next = other.head
-		d.tail = other.tail
-		d.len += other.len
-	}
-
-	other.head = nil
-	other.tail = nil
-	other.len = 0
-}
+	"github.com/timzifer/committable_queue/orchestrator"
+	"github.com/timzifer/committable_queue/telemetry"
+)
 
 type segmentedQueueOptions[T any] struct {
-	initialVisible []T
-	initialPending []T
-	options        Options
-	hasOptions     bool
+	initialVisible  []T
+	initialPending  []T
+	options         Options
+	hasOptions      bool
+	pendingShards   int
+	priorityLanes   int
+	fairMerge       bool
+	producerLimit   int
+	now             func() time.Time
+	ttl             time.Duration
+	ttlFromCommit   bool
+	onExpire        func(T)
+	dedupKey        func(T) any
+	sizer           func(T) int
+	dropPriority    func(T) int
+	maxAge          time.Duration
+	maxCommitBatch  int
+	abortOrder      AbortOrder
+	versionStamps   bool
+	deadLetter      *SegmentedQueue[T]
+	maxRedelivery   int
+	redeliveryCount bool
+	elementMeta     bool
+	historyElements int
+	historyCommits  int
+	commitFilter    func(T) error
+	onReject        func(T, error)
+	strictFilter    bool
+	commitTransform func(T) T
+	sinkName        string
+	sink            telemetry.MetricsSink
+
+	visibilityTimeout time.Duration
+	processedStore    DedupStore
+	processedID       func(T) string
+	registry          *Registry
+	registryName      string
+	debugSummary      func(T) string
 }
 
 type SegmentedQueueOption[T any] func(*segmentedQueueOptions[T])
@@ -165,18 +74,108 @@ func WithOptions[T any](options Options) SegmentedQueueOption[T] {
 	}
 }
 
+// WithPendingShards splits the pending segment into n independent deques.
+// Producers obtained from NewProducer are spread over the shards, so
+// concurrent producers do not contend on a single lock. PrepareCommit merges
+// the shards in index order; the queue's own push methods use shard 0. Values
+// below 2 keep a single pending deque.
+func WithPendingShards[T any](n int) SegmentedQueueOption[T] {
+	return func(opts *segmentedQueueOptions[T]) {
+		opts.pendingShards = n
+	}
+}
+
+// WithMaxCommitBatch limits PrepareCommit, and therefore Commit, to the n
+// oldest pending elements. The rest stays pending for later commits, which
+// bounds the work of a single publish after a backlog has built up. Values
+// below 1 remove the limit.
+func WithMaxCommitBatch[T any](n int) SegmentedQueueOption[T] {
+	return func(opts *segmentedQueueOptions[T]) {
+		opts.maxCommitBatch = n
+	}
+}
+
+// WithAbortOrder selects where aborted commits return their elements in the
+// pending segment. The default is AbortPrepend.
+func WithAbortOrder[T any](order AbortOrder) SegmentedQueueOption[T] {
+	return func(opts *segmentedQueueOptions[T]) {
+		opts.abortOrder = order
+	}
+}
+
 type SegmentedQueue[T any] struct {
-	visible *deque[T]
-	pending *deque[T]
-	mu      sync.Mutex
-	opts    segmentedQueueOptions[T]
-	options Options
+	visible  *deque[T]
+	pending  *deque[T]
+	shards   []*deque[T]
+	mu       sync.Mutex
+	opts     segmentedQueueOptions[T]
+	options  Options
+	counters queueCounters
+	// residency measures how long elements stay, with WithElementMeta.
+	residency telemetry.ResidencyMetrics
+
+	// lanes holds the priority lanes and producers the shards NewProducer
+	// hands out; lanes[0] and producers[0] are pending. shards lists every
+	// pending deque in the order commits take them: the lanes above 0
+	// highest first, then the producer shards.
+	lanes     []*deque[T]
+	producers []*deque[T]
+
+	// named and namedOrder hold the handles returned by Producer. They,
+	// producers and shards are guarded by mu once Producer has been called.
+	named      map[string]*ProducerHandle[T]
+	namedOrder []*ProducerHandle[T]
+
+	nextShard  atomic.Uint64
+	watermarks watermarkState
+
+	// version is the commit version of the latest publish.
+	version atomic.Uint64
+
+	// history holds the elements of recent commits for ReplayFrom, oldest
+	// first, and historyLen counts them. historyCut is the newest version
+	// whose elements were discarded. All three are guarded by mu.
+	history    []historyBatch[T]
+	historyLen int
+	historyCut uint64
+
+	// pins holds the views of versions pinned by ReadAt, guarded by mu.
+	pins map[uint64]*pinnedVersion[T]
+
+	// visibleKeys counts the dedup keys of the visible segment and
+	// visibleBytes sums the sizes of its elements. Both are guarded by
+	// visible.mu.
+	visibleKeys  map[any]int
+	visibleBytes int
+	// sampleNext is the position in the victim sequence of DropSampled,
+	// guarded by visible.mu.
+	sampleNext uint
+
+	readyMu sync.Mutex
+	ready   chan struct{}
+
+	// Blocking pushes: staged counts elements of prepared commits, pushMu
+	// serialises PushCtx callers and space is closed when room frees up.
+	staged  atomic.Int64
+	pushMu  sync.Mutex
+	spaceMu sync.Mutex
+	space   chan struct{}
+
+	// Acknowledged delivery: leases is ordered by deadline and leased is set
+	// once Lease hands out a deadline of its own.
+	ackMu    sync.Mutex
+	leases   []*ackLease[T]
+	inFlight int
+	leased   atomic.Bool
+
+	delayMu  sync.Mutex
+	delayed  delayHeap[T]
+	delaySeq uint64
+	now      func() time.Time
 }
 
 func NewSegmentedQueue[T any](options ...SegmentedQueueOption[T]) *SegmentedQueue[T] {
 	sq := &SegmentedQueue[T]{
-		visible: newDeque[T](),
-		pending: newDeque[T](),
 		options: defaultOptions(),
 	}
 
@@ -188,38 +187,178 @@ func NewSegmentedQueue[T any](options ...SegmentedQueueOption[T]) *SegmentedQueu
 		sq.options = sq.opts.options
 	}
 
+	sq.now = time.Now
+	if sq.opts.now != nil {
+		sq.now = sq.opts.now
+	}
+
+	sq.initSegments()
+
 	for _, v := range sq.opts.initialVisible {
 		sq.visible.pushBack(v)
+		sq.rememberLocked(v)
 	}
+	sq.markCommitted(segment[T]{head: sq.visible.head}, sq.now().UnixNano())
 	for _, v := range sq.opts.initialPending {
 		sq.pending.pushBack(v)
 	}
 
+	sq.register()
+
 	return sq
 }
 
+// initSegments creates the empty visible and pending deques and the
+// bookkeeping that depends on the configured options.
+func (sq *SegmentedQueue[T]) initSegments() {
+	sq.visible = sq.newDeque()
+	sq.pending = sq.newDeque()
+	sq.producers = []*deque[T]{sq.pending}
+	for len(sq.producers) < sq.opts.pendingShards {
+		sq.producers = append(sq.producers, sq.newDeque())
+	}
+	sq.lanes = []*deque[T]{sq.pending}
+	for len(sq.lanes) < sq.opts.priorityLanes {
+		sq.lanes = append(sq.lanes, sq.newDeque())
+	}
+	sq.shards = nil
+	for _, lane := range slices.Backward(sq.lanes[1:]) {
+		sq.shards = append(sq.shards, lane)
+	}
+	sq.shards = append(sq.shards, sq.producers...)
+
+	if sq.opts.dedupKey != nil {
+		sq.visibleKeys = make(map[any]int)
+	}
+}
+
+// tracksVisible reports whether pops must go through popLive to keep TTL,
+// dedup or size bookkeeping up to date or to report element metadata, push
+// times and redelivery counts.
+func (sq *SegmentedQueue[T]) tracksVisible() bool {
+	return sq.opts.ttl > 0 || sq.visibleKeys != nil || sq.opts.sizer != nil || sq.opts.maxAge > 0 ||
+		sq.opts.versionStamps || sq.opts.elementMeta || sq.countsRedeliveries()
+}
+
+// rememberLocked accounts for an element entering the visible segment. The
+// caller must hold sq.visible.mu or own the queue exclusively.
+func (sq *SegmentedQueue[T]) rememberLocked(v T) {
+	if sq.visibleKeys != nil {
+		sq.visibleKeys[sq.opts.dedupKey(v)]++
+	}
+	if sq.opts.sizer != nil {
+		sq.visibleBytes += sq.opts.sizer(v)
+	}
+}
+
+// forgetLocked undoes rememberLocked for an element that left the visible
+// segment.
+func (sq *SegmentedQueue[T]) forgetLocked(v T) {
+	if sq.visibleKeys != nil {
+		key := sq.opts.dedupKey(v)
+		if n := sq.visibleKeys[key]; n > 1 {
+			sq.visibleKeys[key] = n - 1
+		} else {
+			delete(sq.visibleKeys, key)
+		}
+	}
+	if sq.opts.sizer != nil {
+		sq.visibleBytes -= sq.opts.sizer(v)
+	}
+}
+
 func (sq *SegmentedQueue[T]) PopFront() (T, bool) {
-	return sq.visible.popFront()
+	v, _, ok := sq.pop(true)
+	return v, ok
 }
 
 func (sq *SegmentedQueue[T]) PopBack() (T, bool) {
-	return sq.visible.popBack()
+	v, _, ok := sq.pop(false)
+	return v, ok
+}
+
+// pop removes an element from the front or back of the visible segment. The
+// returned metadata is only filled in with WithVersionStamps or
+// WithElementMeta.
+func (sq *SegmentedQueue[T]) pop(front bool) (v T, meta ElementMeta, ok bool) {
+	sq.redeliverExpired()
+	switch {
+	case sq.tracksVisible():
+		v, meta, ok = sq.popLive(front)
+	case front:
+		v, ok = sq.visible.popFront()
+	default:
+		v, ok = sq.visible.popBack()
+	}
+	if ok {
+		sq.counters.pops.Add(1)
+		sq.observePopped(meta)
+		sq.reportDepth()
+		sq.notifySpace()
+		sq.checkWatermarks()
+	}
+	return v, meta, ok
 }
 
 func (sq *SegmentedQueue[T]) LenVisible() int {
+	sq.redeliverExpired()
 	return sq.visible.length()
 }
 
 func (sq *SegmentedQueue[T]) PushBackPending(value T) {
 	sq.pending.pushBack(value)
+	sq.counters.pushes.Add(1)
 }
 
 func (sq *SegmentedQueue[T]) PushFrontPending(value T) {
 	sq.pending.pushFront(value)
+	sq.counters.pushes.Add(1)
+}
+
+// Producer pushes into one pending shard of a SegmentedQueue. Elements pushed
+// through the same Producer keep their order; the order between producers is
+// decided by their shard index at commit time.
+type Producer[T any] struct {
+	queue *SegmentedQueue[T]
+	shard *deque[T]
+}
+
+// NewProducer returns a Producer bound to the next pending shard in
+// round-robin order. Without WithPendingShards every producer uses the single
+// pending deque.
+func (sq *SegmentedQueue[T]) NewProducer() *Producer[T] {
+	sq.mu.Lock()
+	defer sq.mu.Unlock()
+	shards := sq.pendingShardsLocked()
+	index := (sq.nextShard.Add(1) - 1) % uint64(len(shards))
+	return &Producer[T]{queue: sq, shard: shards[index]}
+}
+
+// pendingShardsLocked returns the shards of WithPendingShards, without the
+// buffers of named producers. The caller must hold sq.mu.
+func (sq *SegmentedQueue[T]) pendingShardsLocked() []*deque[T] {
+	return sq.producers[:max(sq.opts.pendingShards, 1)]
+}
+
+func (p *Producer[T]) PushBackPending(value T) {
+	p.shard.pushBack(value)
+	p.queue.counters.pushes.Add(1)
+}
+
+func (p *Producer[T]) PushFrontPending(value T) {
+	p.shard.pushFront(value)
+	p.queue.counters.pushes.Add(1)
+}
+
+func (sq *SegmentedQueue[T]) Metrics() QueueMetrics {
+	return sq.counters.snapshot()
 }
 
 func (sq *SegmentedQueue[T]) commitWithContext(ctx context.Context) {
 	publish, _, err := sq.PrepareCommit(ctx)
+	if errors.Is(err, ErrRejected) {
+		return
+	}
 	if err != nil {
 		panic(err)
 	}
@@ -238,144 +377,259 @@ func (sq *SegmentedQueue[T]) PrepareCommit(ctx context.Context) (publish func(),
 	}
 
 	sq.mu.Lock()
-	defer sq.mu.Unlock()
+	staged, rejected, err := sq.filterLocked(sq.stageLocked(sq.opts.maxCommitBatch))
+	sq.staged.Add(int64(staged.len))
+	sq.mu.Unlock()
+
+	sq.reject(rejected)
+	if err != nil || staged.len == 0 {
+		return nil, nil, err
+	}
+	orchestrator.ReportElements(ctx, staged.len)
 
-	sq.pending.mu.Lock()
-	stagedHead := sq.pending.head
-	stagedTail := sq.pending.tail
-	stagedLen := sq.pending.len
+	commit := &stagedCommit[T]{queue: sq, segment: staged, version: commitVersion(ctx)}
+	return commit.Publish, commit.Abort, nil
+}
+
+// CommitUpTo publishes at most n of the oldest pending elements and returns
+// how many became visible. Calling it repeatedly until it returns 0 commits a
+// large pending segment in bounded steps; locks are released between steps, so
+// producers and consumers are never blocked for more than one step of work.
+// With pending shards, elements are taken from the shards in index order.
+func (sq *SegmentedQueue[T]) CommitUpTo(n int) int {
+	if n <= 0 {
+		return 0
+	}
+	sq.mu.Lock()
+	staged, rejected, err := sq.filterLocked(sq.stageLocked(n))
+	sq.staged.Add(int64(staged.len))
+	sq.mu.Unlock()
 
-	if stagedLen == 0 {
-		sq.pending.mu.Unlock()
+	sq.reject(rejected)
+	if err != nil || staged.len == 0 {
+		return 0
+	}
+	sq.finalizePublish(staged, 0)
+	return staged.len
+}
+
+// PrepareAppend stages values as a commit of their own, bypassing the pending
+// segment: publish appends them to the visible segment exactly like a
+// published commit, abort discards them. It lets a caller that already holds
+// a batch take part in an orchestrated commit without mixing its elements
+// with those of other producers.
+func (sq *SegmentedQueue[T]) PrepareAppend(ctx context.Context, values []T) (publish func(), abort func(), err error) {
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+	if len(values) == 0 {
 		return nil, nil, nil
 	}
 
-	sq.pending.head = nil
-	sq.pending.tail = nil
-	sq.pending.len = 0
+	d := sq.newDeque()
+	for _, v := range values {
+		d.pushBack(v)
+	}
+	sq.counters.pushes.Add(uint64(len(values)))
+	staged, rejected, err := sq.filterSegment(d.detachLocked())
+	sq.reject(rejected)
+	if err != nil || staged.len == 0 {
+		return nil, nil, err
+	}
+	orchestrator.ReportElements(ctx, staged.len)
 
-	if stagedHead != nil {
-		stagedHead.prev = nil
+	commit := &stagedCommit[T]{queue: sq, segment: staged, version: commitVersion(ctx)}
+	sq.staged.Add(int64(staged.len))
+	abort = func() {
+		if staged, ok := commit.take(); ok {
+			sq.staged.Add(-int64(staged.len))
+		}
 	}
-	if stagedTail != nil {
-		stagedTail.next = nil
+	return commit.Publish, abort, nil
+}
+
+// stageLocked detaches up to n of the oldest pending elements, taking the
+// priority lanes highest first, then the shards in index order, followed by
+// due delayed elements. With WithFairMerge the producer shards are
+// interleaved instead. n <= 0 detaches everything. The caller must hold sq.mu.
+func (sq *SegmentedQueue[T]) stageLocked(n int) segment[T] {
+	shards, producers := sq.shards, []*deque[T](nil)
+	if sq.opts.fairMerge {
+		shards, producers = sq.shards[:len(sq.shards)-len(sq.producers)], sq.producers
 	}
 
-	sq.pending.mu.Unlock()
+	// The lanes above 0 lead shards, highest first; everything else is
+	// taken from lane 0.
+	lane := func(i int) int {
+		return max(len(sq.lanes)-1-i, 0)
+	}
 
-	staged := &stagedCommit[T]{
-		queue: sq,
-		head:  stagedHead,
-		tail:  stagedTail,
-		len:   stagedLen,
+	var staged segment[T]
+	if n <= 0 {
+		for i, shard := range shards {
+			shard.mu.Lock()
+			staged = staged.join(shard.detachLocked().setLane(lane(i)))
+			shard.mu.Unlock()
+		}
+		staged = staged.join(interleave(producers, -1).setLane(0))
+		return staged.join(sq.takeDue(sq.now(), -1).setLane(0))
 	}
 
-	return staged.Publish, staged.Abort, nil
+	for i, shard := range shards {
+		if staged.len >= n {
+			break
+		}
+		shard.mu.Lock()
+		staged = staged.join(shard.detachFrontLocked(n - staged.len).setLane(lane(i)))
+		shard.mu.Unlock()
+	}
+	if staged.len < n {
+		staged = staged.join(interleave(producers, n-staged.len).setLane(0))
+	}
+	if staged.len < n {
+		staged = staged.join(sq.takeDue(sq.now(), n-staged.len).setLane(0))
+	}
+	return staged
 }
 
 type stagedCommit[T any] struct {
-	queue *SegmentedQueue[T]
-	head  *node[T]
-	tail  *node[T]
-	len   int
+	queue   *SegmentedQueue[T]
+	segment segment[T]
+	version uint64
 
 	mu   sync.Mutex
 	done bool
 }
 
-func (sc *stagedCommit[T]) Publish() {
+// take marks the commit as finished and hands out the staged segment exactly
+// once.
+func (sc *stagedCommit[T]) take() (segment[T], bool) {
 	sc.mu.Lock()
+	defer sc.mu.Unlock()
 	if sc.done {
-		sc.mu.Unlock()
-		return
+		return segment[T]{}, false
 	}
 	sc.done = true
-	head, tail, length := sc.head, sc.tail, sc.len
-	sc.mu.Unlock()
+	staged := sc.segment
+	sc.segment = segment[T]{}
+	return staged, true
+}
 
-	if length == 0 {
-		return
+func (sc *stagedCommit[T]) Publish() {
+	if staged, ok := sc.take(); ok {
+		sc.queue.finalizePublish(staged, sc.version)
 	}
-
-	sc.queue.finalizePublish(head, tail, length)
-
-	sc.mu.Lock()
-	sc.head = nil
-	sc.tail = nil
-	sc.len = 0
-	sc.mu.Unlock()
 }
 
 func (sc *stagedCommit[T]) Abort() {
-	sc.mu.Lock()
-	if sc.done {
-		sc.mu.Unlock()
-		return
-	}
-	sc.done = true
-	head, tail, length := sc.head, sc.tail, sc.len
-	sc.mu.Unlock()
-
-	if length == 0 {
-		return
+	if staged, ok := sc.take(); ok {
+		sc.queue.finalizeAbort(staged)
 	}
-
-	sc.queue.finalizeAbort(head, tail, length)
-
-	sc.mu.Lock()
-	sc.head = nil
-	sc.tail = nil
-	sc.len = 0
-	sc.mu.Unlock()
 }
 
-func (sq *SegmentedQueue[T]) finalizePublish(head, tail *node[T], length int) {
+// finalizePublish appends staged to the visible segment. version is the
+// commit version to stamp, or 0 to use the one following the latest publish.
+func (sq *SegmentedQueue[T]) finalizePublish(staged segment[T], version uint64) {
+	var expired, dropped []T
+	defer sq.checkWatermarks()
+	defer func() {
+		sq.expire(expired)
+		sq.deadLetter(dropped)
+	}()
+	defer sq.notifySpace()
+	sq.transform(staged)
+
 	sq.mu.Lock()
 	defer sq.mu.Unlock()
+	sq.staged.Add(-int64(staged.len))
 
 	sq.visible.mu.Lock()
 	defer sq.visible.mu.Unlock()
 
-	if sq.visible.len == 0 {
-		sq.visible.head = head
-		sq.visible.tail = tail
-		sq.visible.len = length
-	} else {
-		head.prev = sq.visible.tail
-		sq.visible.tail.next = head
-		sq.visible.tail = tail
-		sq.visible.len += length
-	}
-
-	if sq.options.MaxLen > 0 {
-		for sq.visible.len > sq.options.MaxLen {
-			switch sq.options.DropPolicy {
-			case DropNewest:
-				sq.visible.popBackLocked()
-			default:
-				sq.visible.popFrontLocked()
+	expired, dropped = sq.publishLocked(staged, version)
+}
+
+// publishLocked appends staged to the visible segment, applies the TTL and the
+// drop policy and returns the elements that expired or were dropped. The
+// caller must hold sq.mu and sq.visible.mu and hand the returned elements to
+// expire and deadLetter once it released them.
+func (sq *SegmentedQueue[T]) publishLocked(staged segment[T], version uint64) (expired, dropped []T) {
+	now := sq.now().UnixNano()
+	if sq.visibleKeys != nil {
+		staged = sq.dedupLocked(staged)
+	}
+	if sq.opts.sizer != nil {
+		for c := staged.head; c != nil; c = c.next {
+			for _, v := range c.values[c.lo:c.hi] {
+				sq.visibleBytes += sq.opts.sizer(v)
 			}
 		}
 	}
-}
+	sq.markCommitted(staged, now)
+	sq.observeCommitted(staged, now)
+	version = sq.markVersion(staged, version)
+	sq.recordHistoryLocked(staged, version)
+	sq.visible.appendSegmentLocked(staged)
+	if staged.len > 0 {
+		defer sq.notifyReady()
+	}
+	defer sq.reportDepthLocked()
+	if sq.opts.ttl > 0 {
+		expired = sq.evictExpiredLocked(now)
+	}
 
-func (sq *SegmentedQueue[T]) finalizeAbort(head, tail *node[T], length int) {
-	sq.mu.Lock()
-	defer sq.mu.Unlock()
+	sq.counters.committed(staged.len)
 
-	sq.pending.mu.Lock()
-	defer sq.pending.mu.Unlock()
+	if sq.options.DropPolicy == DropExpired && sq.opts.maxAge > 0 {
+		dropped = sq.dropExpiredLocked(now)
+		sq.reportDropped(DropExpired, len(dropped))
+	}
 
-	if sq.pending.len == 0 {
-		sq.pending.head = head
-		sq.pending.tail = tail
-		sq.pending.len = length
-		return
+	if sq.options.MaxLen > 0 || sq.options.MaxBytes > 0 {
+		policy := sq.options.DropPolicy
+		if policy == DropLowestPriority && sq.opts.dropPriority == nil || policy == DropExpired {
+			policy = DropOldest
+		}
+		var overflow []T
+		switch policy {
+		case DropLowestPriority:
+			overflow = sq.dropLowestPriorityLocked()
+		case DropSampled:
+			overflow = sq.dropSampledLocked()
+		case OverflowBlock:
+		default:
+			for sq.exceedsLocked(sq.visible.len, sq.visibleBytes) {
+				var v T
+				switch policy {
+				case DropNewest:
+					v, _ = sq.visible.popBackLocked()
+				default:
+					v, _ = sq.visible.popFrontLocked()
+				}
+				sq.forgetLocked(v)
+				overflow = append(overflow, v)
+			}
+		}
+		sq.reportDropped(policy, len(overflow))
+		dropped = append(dropped, overflow...)
+	}
+	return expired, dropped
+}
+
+// exceedsLocked reports whether a visible segment of n elements and size bytes
+// exceeds MaxLen or, with a sizer, MaxBytes.
+func (sq *SegmentedQueue[T]) exceedsLocked(n, size int) bool {
+	if sq.options.MaxLen > 0 && n > sq.options.MaxLen {
+		return true
 	}
+	return sq.options.MaxBytes > 0 && sq.opts.sizer != nil && size > sq.options.MaxBytes && n > 0
+}
+
+func (sq *SegmentedQueue[T]) finalizeAbort(staged segment[T]) {
+	sq.mu.Lock()
+	defer sq.mu.Unlock()
+	sq.staged.Add(-int64(staged.len))
+	sq.counters.aborts.Add(1)
 
-	existingHead := sq.pending.head
-	existingHead.prev = tail
-	tail.next = existingHead
-	sq.pending.head = head
-	sq.pending.len += length
+	sq.returnToLanes(staged, sq.opts.abortOrder == AbortAppend)
 }
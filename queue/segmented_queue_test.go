@@ -3,6 +3,7 @@ package queue
 import (
 	"context"
 	"runtime"
+	"slices"
 	"sync"
 	"sync/atomic"
 	"testing"
@@ -446,3 +447,174 @@ func TestDequeAppendLocked(t *testing.T) {
 		}
 	}
 }
+
+func TestShardedPendingMergesInShardOrder(t *testing.T) {
+	q := NewSegmentedQueue[int](WithPendingShards[int](3))
+	producers := []*Producer[int]{q.NewProducer(), q.NewProducer(), q.NewProducer(), q.NewProducer()}
+
+	// Producers 0 and 3 share shard 0; producer 3 pushes after the queue itself.
+	producers[2].PushBackPending(20)
+	producers[1].PushBackPending(10)
+	q.PushBackPending(0)
+	producers[3].PushBackPending(1)
+	producers[1].PushFrontPending(9)
+
+	_, abort, err := q.PrepareCommit(context.Background())
+	if err != nil {
+		t.Fatalf("prepare failed: %v", err)
+	}
+	abort()
+	q.Commit()
+
+	expected := []int{0, 1, 9, 10, 20}
+	for _, want := range expected {
+		if v, ok := q.PopFront(); !ok || v != want {
+			t.Fatalf("expected %d, got %d (ok=%v)", want, v, ok)
+		}
+	}
+	if m := q.Metrics(); m.Pushes != 5 {
+		t.Fatalf("expected five counted pushes, got %d", m.Pushes)
+	}
+}
+
+func TestShardedPendingConcurrentProducers(t *testing.T) {
+	const producers, perProducer = 8, 1000
+	q := NewSegmentedQueue[[2]int](WithPendingShards[[2]int](4))
+
+	var wg sync.WaitGroup
+	for p := 0; p < producers; p++ {
+		producer := q.NewProducer()
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			for i := 0; i < perProducer; i++ {
+				producer.PushBackPending([2]int{id, i})
+				if i%100 == 0 {
+					q.Commit()
+				}
+			}
+		}(p)
+	}
+	wg.Wait()
+	q.Commit()
+
+	next := make([]int, producers)
+	for {
+		v, ok := q.PopFront()
+		if !ok {
+			break
+		}
+		if v[1] != next[v[0]] {
+			t.Fatalf("producer %d out of order: expected %d, got %d", v[0], next[v[0]], v[1])
+		}
+		next[v[0]]++
+	}
+	for id, n := range next {
+		if n != perProducer {
+			t.Fatalf("producer %d: expected %d elements, got %d", id, perProducer, n)
+		}
+	}
+}
+
+func TestCommitUpToPublishesInBoundedSteps(t *testing.T) {
+	q := NewSegmentedQueue[int](WithPendingShards[int](2))
+	q.NewProducer() // shard 0
+	second := q.NewProducer()
+	for i := 0; i < 150; i++ {
+		q.PushBackPending(i)
+	}
+	second.PushBackPending(150)
+
+	steps := 0
+	for {
+		n := q.CommitUpTo(64)
+		if n == 0 {
+			break
+		}
+		if n > 64 {
+			t.Fatalf("step published %d elements", n)
+		}
+		steps++
+	}
+	if steps != 3 || q.LenVisible() != 151 {
+		t.Fatalf("expected 3 steps and 151 visible elements, got %d and %d", steps, q.LenVisible())
+	}
+	for i := 0; i <= 150; i++ {
+		if v, ok := q.PopFront(); !ok || v != i {
+			t.Fatalf("expected %d, got %d", i, v)
+		}
+	}
+	if m := q.Metrics(); m.Commits != 3 {
+		t.Fatalf("expected each step to count as a commit, got %d", m.Commits)
+	}
+}
+
+func TestMaxCommitBatchLimitsPrepareCommit(t *testing.T) {
+	q := NewSegmentedQueue[int](WithMaxCommitBatch[int](3), WithPendingShards[int](2))
+	a, b := q.NewProducer(), q.NewProducer()
+	a.PushBackPending(1)
+	a.PushBackPending(2)
+	b.PushBackPending(3)
+	b.PushBackPending(4)
+
+	publish, abort, err := q.PrepareCommit(context.Background())
+	if err != nil || publish == nil {
+		t.Fatalf("prepare: %v", err)
+	}
+	abort()
+	q.Commit()
+	if q.LenVisible() != 3 {
+		t.Fatalf("expected 3 visible after one commit, got %d", q.LenVisible())
+	}
+
+	q.Commit()
+	var got []int
+	for {
+		v, ok := q.PopFront()
+		if !ok {
+			break
+		}
+		got = append(got, v)
+	}
+	if !slices.Equal(got, []int{1, 2, 3, 4}) {
+		t.Fatalf("expected [1 2 3 4], got %v", got)
+	}
+	if m := q.Metrics(); m.Commits != 2 {
+		t.Fatalf("expected 2 commits, got %d", m.Commits)
+	}
+}
+
+func TestAbortOrder(t *testing.T) {
+	for _, tc := range []struct {
+		order AbortOrder
+		want  []int
+	}{
+		{AbortPrepend, []int{1, 2, 3}},
+		{AbortAppend, []int{3, 1, 2}},
+	} {
+		t.Run(tc.order.String(), func(t *testing.T) {
+			q := NewSegmentedQueue[int](WithAbortOrder[int](tc.order))
+			q.PushBackPending(1)
+			q.PushBackPending(2)
+			_, abort, err := q.PrepareCommit(context.Background())
+			if err != nil {
+				t.Fatalf("prepare: %v", err)
+			}
+			q.PushBackPending(3)
+			abort()
+			q.Commit()
+
+			var got []int
+			for {
+				v, ok := q.PopFront()
+				if !ok {
+					break
+				}
+				got = append(got, v)
+			}
+			if !slices.Equal(got, tc.want) {
+				t.Fatalf("expected %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
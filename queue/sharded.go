@@ -0,0 +1,118 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+)
+
+// ShardedQueue spreads its elements over several SegmentedQueues by key, so
+// producers and consumers of different keys do not contend on the same
+// segments. Elements with the same key always go to the same shard and keep
+// their order. The shards commit together: ShardedQueue is a single
+// orchestrator bank whose PrepareCommit prepares every shard, and a failing
+// shard aborts the others.
+type ShardedQueue[T any] struct {
+	shards []*SegmentedQueue[T]
+	key    func(T) uint64
+	next   atomic.Uint64
+}
+
+// NewShardedQueue creates a queue of n shards, each configured with options.
+// key selects the shard of an element; nil distributes elements round-robin,
+// which gives up the per-key order.
+func NewShardedQueue[T any](n int, key func(T) uint64, options ...SegmentedQueueOption[T]) *ShardedQueue[T] {
+	shards := make([]*SegmentedQueue[T], max(n, 1))
+	for i := range shards {
+		shards[i] = NewSegmentedQueue(options...)
+	}
+	return &ShardedQueue[T]{shards: shards, key: key}
+}
+
+// Shards returns the number of shards.
+func (q *ShardedQueue[T]) Shards() int {
+	return len(q.shards)
+}
+
+// Shard returns the queue of shard i.
+func (q *ShardedQueue[T]) Shard(i int) *SegmentedQueue[T] {
+	return q.shards[i]
+}
+
+// ShardOf returns the shard an element with the value of v is routed to.
+// Without a key function it picks the next shard round-robin.
+func (q *ShardedQueue[T]) ShardOf(v T) int {
+	if q.key == nil {
+		return int((q.next.Add(1) - 1) % uint64(len(q.shards)))
+	}
+	return int(q.key(v) % uint64(len(q.shards)))
+}
+
+func (q *ShardedQueue[T]) PushBackPending(value T) {
+	q.shards[q.ShardOf(value)].PushBackPending(value)
+}
+
+// PrepareCommit prepares every shard. publish and abort act on all of them;
+// if a shard fails to prepare, the shards prepared so far are aborted.
+func (q *ShardedQueue[T]) PrepareCommit(ctx context.Context) (publish func(), abort func(), err error) {
+	publishes := make([]func(), 0, len(q.shards))
+	aborts := make([]func(), 0, len(q.shards))
+	for _, shard := range q.shards {
+		p, a, err := shard.PrepareCommit(ctx)
+		if err != nil {
+			for i := len(aborts) - 1; i >= 0; i-- {
+				aborts[i]()
+			}
+			return nil, nil, err
+		}
+		if p != nil {
+			publishes = append(publishes, p)
+			aborts = append(aborts, a)
+		}
+	}
+	if len(publishes) == 0 {
+		return nil, nil, nil
+	}
+	publish = func() {
+		for _, p := range publishes {
+			p()
+		}
+	}
+	abort = func() {
+		for i := len(aborts) - 1; i >= 0; i-- {
+			aborts[i]()
+		}
+	}
+	return publish, abort, nil
+}
+
+// Commit publishes the pending elements of all shards as one commit.
+func (q *ShardedQueue[T]) Commit() {
+	publish, _, err := q.PrepareCommit(context.Background())
+	if errors.Is(err, ErrRejected) {
+		return
+	}
+	if err != nil {
+		panic(err)
+	}
+	if publish != nil {
+		publish()
+	}
+}
+
+// LenVisible returns the number of visible elements over all shards.
+func (q *ShardedQueue[T]) LenVisible() int {
+	n := 0
+	for _, shard := range q.shards {
+		n += shard.LenVisible()
+	}
+	return n
+}
+
+// Consumer returns a consumer whose home is shard i and that steals from the
+// other shards when its own is empty; see StealingConsumer. With one consumer
+// per shard, the elements of a key are consumed in order as long as no other
+// consumer steals them.
+func (q *ShardedQueue[T]) Consumer(i int) *StealingConsumer[T] {
+	return NewStealingConsumer(i, q.shards...)
+}
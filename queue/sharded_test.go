@@ -0,0 +1,91 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/timzifer/committable_queue/orchestrator"
+)
+
+type keyed struct {
+	key   uint64
+	value int
+}
+
+func TestShardedQueueKeepsPerKeyOrder(t *testing.T) {
+	q := NewShardedQueue(4, func(v keyed) uint64 { return v.key })
+	for i := 0; i < 20; i++ {
+		q.PushBackPending(keyed{key: uint64(i % 5), value: i})
+	}
+	if q.LenVisible() != 0 {
+		t.Fatal("pending elements must not be visible")
+	}
+	o := orchestrator.NewCommitOrchestrator(orchestrator.WithBanks(q))
+	if err := o.CommitAll(context.Background()); err != nil {
+		t.Fatalf("commit failed: %v", err)
+	}
+	if q.LenVisible() != 20 {
+		t.Fatalf("expected 20 visible elements, got %d", q.LenVisible())
+	}
+
+	perKey := map[uint64][]int{}
+	for i := range q.Shards() {
+		for _, v := range drain(q.Shard(i)) {
+			if q.ShardOf(v) != i {
+				t.Fatalf("element %+v in shard %d", v, i)
+			}
+			perKey[v.key] = append(perKey[v.key], v.value)
+		}
+	}
+	for key, values := range perKey {
+		if !slices.IsSorted(values) || len(values) != 4 {
+			t.Fatalf("key %d lost its order: %v", key, values)
+		}
+	}
+}
+
+func TestShardedQueueAbortsAllShards(t *testing.T) {
+	failure := errors.New("rejected")
+	q := NewShardedQueue(2, func(v int) uint64 { return uint64(v) },
+		WithCommitFilter(func(v int) error {
+			if v == 3 {
+				return failure
+			}
+			return nil
+		}), WithStrictCommitFilter[int]())
+	q.PushBackPending(2)
+	q.PushBackPending(3)
+
+	if _, _, err := q.PrepareCommit(context.Background()); !errors.Is(err, failure) {
+		t.Fatalf("expected the filter error, got %v", err)
+	}
+	if q.LenVisible() != 0 {
+		t.Fatal("expected nothing to be published")
+	}
+	if got := drain(q.Shard(0)); !slices.Equal(got, []int{2}) {
+		t.Fatalf("expected the aborted shard to keep its element pending, got %v", got)
+	}
+
+	publish, abort, err := NewShardedQueue[int](2, nil).PrepareCommit(context.Background())
+	if publish != nil || abort != nil || err != nil {
+		t.Fatal("expected nothing to prepare for an empty queue")
+	}
+}
+
+func TestShardedQueueConsumerSteals(t *testing.T) {
+	q := NewShardedQueue[int](2, func(v int) uint64 { return uint64(v) })
+	q.PushBackPending(1)
+	q.PushBackPending(3)
+	q.Commit()
+
+	consumer := q.Consumer(0)
+	var got []int
+	for v, ok := consumer.PopFront(); ok; v, ok = consumer.PopFront() {
+		got = append(got, v)
+	}
+	if !slices.Equal(got, []int{1, 3}) || consumer.Stolen() != 2 {
+		t.Fatalf("expected to steal both elements from shard 1, got %v", got)
+	}
+}
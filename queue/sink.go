@@ -0,0 +1,33 @@
+package queue
+
+import "github.com/timzifer/committable_queue/telemetry"
+
+// WithMetricsSink reports overflow drops and changes of the visible depth to
+// sink under the given queue name. The queue's own counters (see Metrics) are
+// always maintained; the sink receives the same events as they happen.
+func WithMetricsSink[T any](name string, sink telemetry.MetricsSink) SegmentedQueueOption[T] {
+	return func(opts *segmentedQueueOptions[T]) {
+		opts.sinkName = name
+		opts.sink = sink
+	}
+}
+
+func (sq *SegmentedQueue[T]) reportDropped(policy DropPolicy, n int) {
+	sq.counters.dropped(policy, n)
+	if sq.opts.sink != nil && n > 0 {
+		sq.opts.sink.Dropped(sq.opts.sinkName, policy.String(), n)
+	}
+}
+
+func (sq *SegmentedQueue[T]) reportDepth() {
+	if sq.opts.sink != nil {
+		sq.opts.sink.DepthChanged(sq.opts.sinkName, sq.visible.length())
+	}
+}
+
+// reportDepthLocked is reportDepth for callers holding sq.visible.mu.
+func (sq *SegmentedQueue[T]) reportDepthLocked() {
+	if sq.opts.sink != nil {
+		sq.opts.sink.DepthChanged(sq.opts.sinkName, sq.visible.len)
+	}
+}
@@ -0,0 +1,51 @@
+package queue
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/timzifer/committable_queue/telemetry"
+)
+
+type depthSink struct {
+	telemetry.NopSink
+	mu      sync.Mutex
+	depths  []int
+	dropped map[string]int
+}
+
+func (s *depthSink) DepthChanged(queue string, visible int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.depths = append(s.depths, visible)
+}
+
+func (s *depthSink) Dropped(queue string, policy string, n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dropped[queue+"/"+policy] += n
+}
+
+func TestMetricsSinkReportsDepthAndDrops(t *testing.T) {
+	sink := &depthSink{dropped: make(map[string]int)}
+	q := NewSegmentedQueue[int](
+		WithMetricsSink[int]("sensors", sink),
+		WithOptions[int](Options{MaxLen: 2}),
+	)
+
+	for i := 0; i < 3; i++ {
+		q.PushBackPending(i)
+	}
+	q.Commit()
+	q.PopFront()
+
+	if got := sink.depths; len(got) != 2 || got[0] != 2 || got[1] != 1 {
+		t.Fatalf("unexpected depth reports %v", got)
+	}
+	if sink.dropped["sensors/oldest"] != 1 {
+		t.Fatalf("unexpected drop reports %v", sink.dropped)
+	}
+	if m := q.Metrics(); m.Dropped != 1 {
+		t.Fatalf("queue counters must still be maintained, got %+v", m)
+	}
+}
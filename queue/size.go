@@ -0,0 +1,20 @@
+package queue
+
+// WithSizer measures elements for Options.MaxBytes. size returns the number of
+// bytes an element accounts for, typically the length of its payload. When a
+// published commit pushes the visible segment over MaxBytes, elements are
+// dropped according to DropPolicy until it fits again.
+func WithSizer[T any](size func(T) int) SegmentedQueueOption[T] {
+	return func(opts *segmentedQueueOptions[T]) {
+		opts.sizer = size
+	}
+}
+
+// SizeVisible returns the total size of the visible elements as measured by
+// the sizer, or 0 without one.
+func (sq *SegmentedQueue[T]) SizeVisible() int {
+	sq.redeliverExpired()
+	sq.visible.mu.Lock()
+	defer sq.visible.mu.Unlock()
+	return sq.visibleBytes
+}
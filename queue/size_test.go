@@ -0,0 +1,50 @@
+package queue
+
+import "testing"
+
+func TestMaxBytesDropsOldestFrames(t *testing.T) {
+	q := NewSegmentedQueue[[]byte](
+		WithSizer(func(b []byte) int { return len(b) }),
+		WithOptions[[]byte](Options{MaxBytes: 10, DropPolicy: DropOldest}),
+	)
+
+	q.PushBackPending(make([]byte, 4))
+	q.PushBackPending(make([]byte, 4))
+	q.Commit()
+	if q.SizeVisible() != 8 || q.LenVisible() != 2 {
+		t.Fatalf("unexpected state: size %d len %d", q.SizeVisible(), q.LenVisible())
+	}
+
+	q.PushBackPending(make([]byte, 6))
+	q.Commit()
+	if q.SizeVisible() != 10 || q.LenVisible() != 2 {
+		t.Fatalf("expected the oldest frame to be dropped, size %d len %d", q.SizeVisible(), q.LenVisible())
+	}
+	if m := q.Metrics(); m.Drops[DropOldest] != 1 {
+		t.Fatalf("expected 1 drop, got %+v", m.Drops)
+	}
+
+	if v, ok := q.PopBack(); !ok || len(v) != 6 {
+		t.Fatalf("expected the 6 byte frame, got %d %v", len(v), ok)
+	}
+	if q.SizeVisible() != 4 {
+		t.Fatalf("pop must release its bytes, size %d", q.SizeVisible())
+	}
+}
+
+func TestMaxBytesDropNewestAndOversizedFrame(t *testing.T) {
+	q := NewSegmentedQueue[string](
+		WithSizer(func(s string) int { return len(s) }),
+		WithOptions[string](Options{MaxBytes: 5, DropPolicy: DropNewest}),
+	)
+
+	q.PushBackPending("abc")
+	q.PushBackPending("toolarge")
+	q.Commit()
+	if v, ok := q.PopFront(); !ok || v != "abc" {
+		t.Fatalf("expected abc, got %q %v", v, ok)
+	}
+	if q.LenVisible() != 0 || q.SizeVisible() != 0 {
+		t.Fatalf("unexpected state: size %d len %d", q.SizeVisible(), q.LenVisible())
+	}
+}
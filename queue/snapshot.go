@@ -0,0 +1,273 @@
+package queue
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/timzifer/committable_queue/codec"
+)
+
+// ErrInvalidSnapshot is returned by ReadSnapshot when the input is not a
+// snapshot written by WriteSnapshot or is truncated.
+var ErrInvalidSnapshot = errors.New("queue: invalid snapshot")
+
+var (
+	snapshotMagic        = [4]byte{'C', 'Q', 'S', '1'}
+	snapshotMagicDelayed = [4]byte{'C', 'Q', 'S', '2'}
+)
+
+const maxSnapshotElement = 1 << 30
+
+// WriteSnapshot writes the visible and pending segments to w, encoding each
+// element with enc (gob when enc is nil). The commit boundary is preserved:
+// ReadSnapshot restores visible elements as visible and pending elements as
+// pending. Elements of a commit that is prepared but not yet published or
+// aborted belong to neither segment and are not written. Delayed elements keep
+// their due time.
+//
+// The layout is the magic "CQS1", the uvarint visible and pending counts, and
+// then every element as uvarint(len) | bytes, visible first. Queues holding
+// delayed elements use the magic "CQS2", add the uvarint delayed count to the
+// header and append each delayed element as varint(due unix nanoseconds) |
+// uvarint(len) | bytes.
+func (sq *SegmentedQueue[T]) WriteSnapshot(w io.Writer, enc codec.Encoder[T]) error {
+	if enc == nil {
+		enc = codec.Gob[T]{}
+	}
+
+	visible, pending, delayed := sq.segmentValues()
+
+	bw := bufio.NewWriter(w)
+	magic := snapshotMagic
+	if len(delayed) > 0 {
+		magic = snapshotMagicDelayed
+	}
+	header := append([]byte(nil), magic[:]...)
+	header = binary.AppendUvarint(header, uint64(len(visible)))
+	header = binary.AppendUvarint(header, uint64(len(pending)))
+	if len(delayed) > 0 {
+		header = binary.AppendUvarint(header, uint64(len(delayed)))
+	}
+	if _, err := bw.Write(header); err != nil {
+		return err
+	}
+
+	var prefix []byte
+	write := func(v T) error {
+		data, err := enc.Marshal(v)
+		if err != nil {
+			return err
+		}
+		prefix = binary.AppendUvarint(prefix, uint64(len(data)))
+		if _, err := bw.Write(prefix); err != nil {
+			return err
+		}
+		prefix = prefix[:0]
+		_, err = bw.Write(data)
+		return err
+	}
+	for _, values := range [][]T{visible, pending} {
+		for _, v := range values {
+			if err := write(v); err != nil {
+				return err
+			}
+		}
+	}
+	for _, d := range delayed {
+		prefix = binary.AppendVarint(prefix[:0], d.at.UnixNano())
+		if err := write(d.value); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// SnapshotInfo describes a snapshot written by WriteSnapshot without decoding
+// its elements. Each element is kept as the bytes its encoder produced.
+type SnapshotInfo struct {
+	Visible [][]byte
+	Pending [][]byte
+	Delayed []DelayedSnapshotElement
+}
+
+// DelayedSnapshotElement is a delayed element of a snapshot with its due time.
+type DelayedSnapshotElement struct {
+	Due  time.Time
+	Data []byte
+}
+
+// InspectSnapshot reads a snapshot written by WriteSnapshot and checks its
+// framing. It fails with ErrInvalidSnapshot when r does not hold a complete
+// snapshot.
+func InspectSnapshot(r io.Reader) (*SnapshotInfo, error) {
+	br := bufio.NewReader(r)
+	var magic [4]byte
+	if _, err := io.ReadFull(br, magic[:]); err != nil || (magic != snapshotMagic && magic != snapshotMagicDelayed) {
+		return nil, ErrInvalidSnapshot
+	}
+	visibleLen, err := binary.ReadUvarint(br)
+	if err != nil {
+		return nil, ErrInvalidSnapshot
+	}
+	pendingLen, err := binary.ReadUvarint(br)
+	if err != nil {
+		return nil, ErrInvalidSnapshot
+	}
+	var delayedLen uint64
+	if magic == snapshotMagicDelayed {
+		if delayedLen, err = binary.ReadUvarint(br); err != nil {
+			return nil, ErrInvalidSnapshot
+		}
+	}
+
+	read := func() ([]byte, error) {
+		size, err := binary.ReadUvarint(br)
+		if err != nil || size > maxSnapshotElement {
+			return nil, ErrInvalidSnapshot
+		}
+		data := make([]byte, size)
+		if _, err := io.ReadFull(br, data); err != nil {
+			return nil, ErrInvalidSnapshot
+		}
+		return data, nil
+	}
+
+	info := &SnapshotInfo{}
+	for i := uint64(0); i < visibleLen+pendingLen; i++ {
+		data, err := read()
+		if err != nil {
+			return nil, err
+		}
+		if i < visibleLen {
+			info.Visible = append(info.Visible, data)
+		} else {
+			info.Pending = append(info.Pending, data)
+		}
+	}
+	for i := uint64(0); i < delayedLen; i++ {
+		at, err := binary.ReadVarint(br)
+		if err != nil {
+			return nil, ErrInvalidSnapshot
+		}
+		data, err := read()
+		if err != nil {
+			return nil, err
+		}
+		info.Delayed = append(info.Delayed, DelayedSnapshotElement{Due: time.Unix(0, at), Data: data})
+	}
+	return info, nil
+}
+
+// ReadSnapshot replaces the contents of both segments with a snapshot written
+// by WriteSnapshot, decoding elements with dec (gob when dec is nil). Pending
+// elements are restored into the first pending shard. The queue is left
+// unchanged when the snapshot cannot be read completely. Metrics counters
+// are not affected.
+func (sq *SegmentedQueue[T]) ReadSnapshot(r io.Reader, dec codec.Decoder[T]) error {
+	if dec == nil {
+		dec = codec.Gob[T]{}
+	}
+
+	info, err := InspectSnapshot(r)
+	if err != nil {
+		return err
+	}
+	var index int
+	decode := func(data []byte) (T, error) {
+		v, err := dec.Unmarshal(data)
+		if err != nil {
+			err = fmt.Errorf("%w: element %d: %v", ErrInvalidSnapshot, index, err)
+		}
+		index++
+		return v, err
+	}
+
+	fill := func(d *deque[T], values [][]byte) error {
+		for _, data := range values {
+			v, err := decode(data)
+			if err != nil {
+				return err
+			}
+			d.pushBack(v)
+		}
+		return nil
+	}
+	visible := sq.newDeque()
+	pending := sq.newDeque()
+	if err := fill(visible, info.Visible); err != nil {
+		return err
+	}
+	if err := fill(pending, info.Pending); err != nil {
+		return err
+	}
+	var delayed delayHeap[T]
+	for i, element := range info.Delayed {
+		v, err := decode(element.Data)
+		if err != nil {
+			return err
+		}
+		delayed = append(delayed, delayedElement[T]{at: element.Due, seq: uint64(i) + 1, value: v})
+	}
+	delayedLen := uint64(len(delayed))
+
+	defer sq.checkWatermarks()
+	sq.mu.Lock()
+	defer sq.mu.Unlock()
+	sq.visible.mu.Lock()
+	defer sq.visible.mu.Unlock()
+	for _, shard := range sq.shards {
+		shard.mu.Lock()
+		defer shard.mu.Unlock()
+		shard.detachLocked()
+	}
+
+	sq.markCommitted(segment[T]{head: visible.head}, sq.now().UnixNano())
+	sq.stampVersion(segment[T]{head: visible.head}, sq.version.Load())
+	sq.visible.replaceLocked(visible.detachLocked())
+	if sq.tracksVisible() {
+		clear(sq.visibleKeys)
+		sq.visibleBytes = 0
+		for c := sq.visible.head; c != nil; c = c.next {
+			for _, v := range c.values[c.lo:c.hi] {
+				sq.rememberLocked(v)
+			}
+		}
+	}
+	sq.pending.replaceLocked(pending.detachLocked())
+
+	sq.delayMu.Lock()
+	sq.delayed, sq.delaySeq = delayed, delayedLen
+	sq.delayMu.Unlock()
+
+	if sq.visible.len > 0 {
+		sq.notifyReady()
+	}
+	return nil
+}
+
+// segmentValues copies both segments under their locks so that the returned
+// slices describe a single consistent state. Pending shards are concatenated
+// in the order PrepareCommit merges them; delayed elements are sorted by due
+// time.
+func (sq *SegmentedQueue[T]) segmentValues() (visible, pending []T, delayed []delayedElement[T]) {
+	sq.mu.Lock()
+	defer sq.mu.Unlock()
+	sq.visible.mu.Lock()
+	defer sq.visible.mu.Unlock()
+	for _, shard := range sq.shards {
+		shard.mu.Lock()
+		defer shard.mu.Unlock()
+		pending = append(pending, shard.valuesLocked()...)
+	}
+	sq.delayMu.Lock()
+	defer sq.delayMu.Unlock()
+	delayed = append(delayed, sq.delayed...)
+	sort.Sort(delayHeap[T](delayed))
+
+	return sq.visible.valuesLocked(), pending, delayed
+}
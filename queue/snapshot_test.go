@@ -0,0 +1,135 @@
+package queue
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/timzifer/committable_queue/codec"
+)
+
+func TestSnapshotPreservesCommitBoundary(t *testing.T) {
+	q := NewSegmentedQueue[string]()
+	q.PushBackPending("a")
+	q.PushBackPending("b")
+	q.Commit()
+	q.PushBackPending("c")
+
+	var buf bytes.Buffer
+	if err := q.WriteSnapshot(&buf, nil); err != nil {
+		t.Fatalf("write snapshot failed: %v", err)
+	}
+
+	restored := NewSegmentedQueue[string](WithInitialVisible("stale"), WithInitialPending("stale"))
+	if err := restored.ReadSnapshot(bytes.NewReader(buf.Bytes()), nil); err != nil {
+		t.Fatalf("read snapshot failed: %v", err)
+	}
+
+	if got := restored.LenVisible(); got != 2 {
+		t.Fatalf("expected two visible elements, got %d", got)
+	}
+	for _, want := range []string{"a", "b"} {
+		if v, ok := restored.PopFront(); !ok || v != want {
+			t.Fatalf("expected %q, got %q (ok=%v)", want, v, ok)
+		}
+	}
+	if _, ok := restored.PopFront(); ok {
+		t.Fatalf("pending element must not be visible before commit")
+	}
+	restored.Commit()
+	if v, ok := restored.PopFront(); !ok || v != "c" {
+		t.Fatalf("expected pending element after commit, got %q (ok=%v)", v, ok)
+	}
+}
+
+func TestSnapshotSkipsStagedCommit(t *testing.T) {
+	q := NewSegmentedQueue[int](WithInitialVisible(1), WithInitialPending(2))
+	_, abort, err := q.PrepareCommit(context.Background())
+	if err != nil {
+		t.Fatalf("prepare failed: %v", err)
+	}
+	defer abort()
+
+	var buf bytes.Buffer
+	if err := q.WriteSnapshot(&buf, nil); err != nil {
+		t.Fatalf("write snapshot failed: %v", err)
+	}
+	restored := NewSegmentedQueue[int]()
+	if err := restored.ReadSnapshot(&buf, nil); err != nil {
+		t.Fatalf("read snapshot failed: %v", err)
+	}
+	restored.Commit()
+	if got := restored.LenVisible(); got != 1 {
+		t.Fatalf("staged elements must not be part of the snapshot, got %d visible", got)
+	}
+}
+
+func TestReadSnapshotRejectsInvalidInput(t *testing.T) {
+	q := NewSegmentedQueue[int](WithInitialVisible(7))
+
+	var buf bytes.Buffer
+	if err := q.WriteSnapshot(&buf, nil); err != nil {
+		t.Fatalf("write snapshot failed: %v", err)
+	}
+	truncated := buf.Bytes()[:buf.Len()-1]
+
+	for name, input := range map[string][]byte{
+		"empty":     nil,
+		"magic":     []byte("nope"),
+		"truncated": truncated,
+	} {
+		if err := q.ReadSnapshot(bytes.NewReader(input), nil); !errors.Is(err, ErrInvalidSnapshot) {
+			t.Fatalf("%s: expected ErrInvalidSnapshot, got %v", name, err)
+		}
+	}
+	if v, ok := q.PopFront(); !ok || v != 7 {
+		t.Fatalf("failed read must leave the queue unchanged, got %v (ok=%v)", v, ok)
+	}
+}
+
+func TestSnapshotWithJSONCodec(t *testing.T) {
+	q := NewSegmentedQueue[int](WithInitialVisible(1, 2), WithInitialPending(3))
+
+	var buf bytes.Buffer
+	if err := q.WriteSnapshot(&buf, codec.JSON[int]{}); err != nil {
+		t.Fatalf("write snapshot failed: %v", err)
+	}
+	if !bytes.HasSuffix(buf.Bytes(), []byte{1, '1', 1, '2', 1, '3'}) {
+		t.Fatalf("expected length-prefixed JSON elements, got %q", buf.Bytes())
+	}
+
+	restored := NewSegmentedQueue[int]()
+	if err := restored.ReadSnapshot(&buf, codec.JSON[int]{}); err != nil {
+		t.Fatalf("read snapshot failed: %v", err)
+	}
+	if restored.LenVisible() != 2 {
+		t.Fatalf("expected two visible elements, got %d", restored.LenVisible())
+	}
+}
+
+func TestInspectSnapshotKeepsEncodedElements(t *testing.T) {
+	q := NewSegmentedQueue[string]()
+	q.PushBackPending("a")
+	q.Commit()
+	q.PushBackPending("bc")
+
+	var buf bytes.Buffer
+	if err := q.WriteSnapshot(&buf, codec.JSON[string]{}); err != nil {
+		t.Fatalf("write snapshot failed: %v", err)
+	}
+	info, err := InspectSnapshot(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("inspect failed: %v", err)
+	}
+	if len(info.Visible) != 1 || string(info.Visible[0]) != `"a"` {
+		t.Fatalf("expected visible [\"a\"], got %q", info.Visible)
+	}
+	if len(info.Pending) != 1 || string(info.Pending[0]) != `"bc"` {
+		t.Fatalf("expected pending [\"bc\"], got %q", info.Pending)
+	}
+
+	if _, err := InspectSnapshot(bytes.NewReader(buf.Bytes()[:buf.Len()-1])); !errors.Is(err, ErrInvalidSnapshot) {
+		t.Fatalf("expected ErrInvalidSnapshot for truncated input, got %v", err)
+	}
+}
@@ -0,0 +1,71 @@
+package queue
+
+import "sync"
+
+// pairMu serialises operations that lock two queues, so that a.SpliceTo(b)
+// and b.SpliceTo(a) cannot deadlock.
+var pairMu sync.Mutex
+
+// lockPair locks sq and dst for an operation on both queues and returns the
+// function that unlocks them again.
+func (sq *SegmentedQueue[T]) lockPair(dst *SegmentedQueue[T]) (unlock func()) {
+	pairMu.Lock()
+	sq.mu.Lock()
+	dst.mu.Lock()
+	sq.visible.mu.Lock()
+	dst.visible.mu.Lock()
+	return func() {
+		dst.visible.mu.Unlock()
+		sq.visible.mu.Unlock()
+		dst.mu.Unlock()
+		sq.mu.Unlock()
+		pairMu.Unlock()
+	}
+}
+
+// SpliceTo moves all visible elements of sq behind the visible elements of
+// dst, in order, and returns how many it moved. The chunks are relinked under
+// the locks of both queues instead of being copied, so no consumer of either
+// queue sees the elements in both queues or in neither.
+//
+// For dst the splice is a commit: the elements are stamped with its next
+// version and commit time, and its dedup, TTL and overflow policies apply.
+// Pending and staged elements of sq, and elements it has in flight through
+// PopFrontAck, stay where they are.
+func (sq *SegmentedQueue[T]) SpliceTo(dst *SegmentedQueue[T]) int {
+	if dst == sq {
+		return 0
+	}
+	var expired, dropped []T
+	defer dst.checkWatermarks()
+	defer func() {
+		dst.expire(expired)
+		dst.deadLetter(dropped)
+	}()
+	defer sq.checkWatermarks()
+	defer sq.notifySpace()
+
+	unlock := sq.lockPair(dst)
+	defer unlock()
+
+	var moved int
+	moved, expired, dropped = sq.spliceLocked(dst)
+	return moved
+}
+
+// spliceLocked moves the visible elements of sq to dst. The caller must hold
+// the locks of lockPair.
+func (sq *SegmentedQueue[T]) spliceLocked(dst *SegmentedQueue[T]) (moved int, expired, dropped []T) {
+	s := sq.visible.detachLocked()
+	if s.len == 0 {
+		return 0, nil, nil
+	}
+	if sq.visibleKeys != nil {
+		clear(sq.visibleKeys)
+	}
+	sq.visibleBytes = 0
+	sq.reportDepthLocked()
+
+	expired, dropped = dst.publishLocked(s, 0)
+	return s.len, expired, dropped
+}
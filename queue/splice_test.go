@@ -0,0 +1,89 @@
+package queue
+
+import (
+	"slices"
+	"sync"
+	"testing"
+)
+
+func TestSpliceToMovesVisibleElements(t *testing.T) {
+	src := NewSegmentedQueue[int]()
+	dst := NewSegmentedQueue[int]()
+	for i := 0; i < 2*chunkSize+3; i++ {
+		src.PushBackPending(i)
+	}
+	src.Commit()
+	src.PushBackPending(-1)
+	dst.PushBackPending(-2)
+	dst.Commit()
+
+	if n := src.SpliceTo(dst); n != 2*chunkSize+3 {
+		t.Fatalf("expected %d moved elements, got %d", 2*chunkSize+3, n)
+	}
+	if src.LenVisible() != 0 {
+		t.Fatalf("expected an empty source, got %d elements", src.LenVisible())
+	}
+	got := drain(dst)
+	if len(got) != 2*chunkSize+4 || got[0] != -2 || got[1] != 0 || got[len(got)-1] != 2*chunkSize+2 {
+		t.Fatalf("unexpected destination %v", got)
+	}
+
+	// Pending elements stay behind.
+	if got := drain(src); !slices.Equal(got, []int{-1}) {
+		t.Fatalf("expected the pending element to stay in the source, got %v", got)
+	}
+	if n := src.SpliceTo(src); n != 0 {
+		t.Fatalf("splicing a queue into itself must not move anything, moved %d", n)
+	}
+}
+
+func TestSpliceToAppliesDestinationPolicies(t *testing.T) {
+	src := NewSegmentedQueue[int](WithDedup(func(v int) int { return v }))
+	dst := NewSegmentedQueue[int](
+		WithOptions[int](Options{MaxLen: 3, DropPolicy: DropOldest}),
+		WithDedup(func(v int) int { return v }),
+	)
+	for _, v := range []int{1, 2, 3, 4} {
+		src.PushBackPending(v)
+	}
+	src.Commit()
+	dst.PushBackPending(2)
+	dst.Commit()
+
+	src.SpliceTo(dst)
+	if got := drain(dst); !slices.Equal(got, []int{1, 3, 4}) {
+		t.Fatalf("expected dedup and overflow in the destination, got %v", got)
+	}
+
+	// The source forgot the moved keys.
+	src.PushBackPending(1)
+	if got := drain(src); !slices.Equal(got, []int{1}) {
+		t.Fatalf("expected the source to accept a moved key again, got %v", got)
+	}
+}
+
+func TestSpliceToBothDirections(t *testing.T) {
+	a := NewSegmentedQueue[int]()
+	b := NewSegmentedQueue[int]()
+	for i := 0; i < 100; i++ {
+		a.PushBackPending(i)
+	}
+	a.Commit()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				a.SpliceTo(b)
+				b.SpliceTo(a)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := a.LenVisible() + b.LenVisible(); n != 100 {
+		t.Fatalf("expected 100 elements across both queues, got %d", n)
+	}
+}
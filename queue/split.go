@@ -0,0 +1,52 @@
+package queue
+
+// SplitFunc moves every visible element for which pred returns true into a new
+// queue with the same options and returns it. The elements keep their order
+// and are removed under the visible lock, so consumers of sq never see only
+// part of them gone. Pending elements and elements in flight through
+// PopFrontAck stay in sq.
+//
+// The new queue starts at the version of sq, and the moved elements are
+// published there as one commit of that version, subject to its overflow
+// policy.
+func (sq *SegmentedQueue[T]) SplitFunc(pred func(T) bool) *SegmentedQueue[T] {
+	sq.redeliverExpired()
+
+	split := &SegmentedQueue[T]{opts: sq.opts, options: sq.options, now: sq.now}
+	split.initSegments()
+
+	sq.visible.mu.Lock()
+	removed := sq.visible.removeLocked(func(_ int, v T) bool { return pred(v) })
+	for _, v := range removed {
+		sq.forgetLocked(v)
+	}
+	if len(removed) > 0 {
+		sq.reportDepthLocked()
+	}
+	version := sq.version.Load()
+	sq.visible.mu.Unlock()
+
+	if len(removed) > 0 {
+		sq.notifySpace()
+		sq.checkWatermarks()
+	}
+
+	if len(removed) == 0 {
+		split.version.Store(version)
+		return split
+	}
+	d := split.newDeque()
+	for _, v := range removed {
+		d.pushBack(v)
+	}
+	split.mu.Lock()
+	split.visible.mu.Lock()
+	expired, dropped := split.publishLocked(d.detachLocked(), version)
+	split.visible.mu.Unlock()
+	split.mu.Unlock()
+
+	split.expire(expired)
+	split.deadLetter(dropped)
+	split.checkWatermarks()
+	return split
+}
@@ -0,0 +1,36 @@
+package queue
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSplitFuncMovesMatchingElements(t *testing.T) {
+	q := NewSegmentedQueue[int](WithVersionStamps[int]())
+	for i := 0; i < 10; i++ {
+		q.PushBackPending(i)
+	}
+	q.Commit()
+	q.PushBackPending(100)
+
+	high := q.SplitFunc(func(v int) bool { return v%3 == 0 })
+
+	if high.LenVisible() != 4 || q.LenVisible() != 6 {
+		t.Fatalf("expected 4 split and 6 remaining elements, got %d and %d", high.LenVisible(), q.LenVisible())
+	}
+	v, version, _ := high.PopFrontVersioned()
+	if v != 0 || version != 1 {
+		t.Fatalf("expected element 0 at version 1, got %d at %d", v, version)
+	}
+	if got := drain(high); !slices.Equal(got, []int{3, 6, 9}) {
+		t.Fatalf("unexpected split elements %v", got)
+	}
+	if got := drain(q); !slices.Equal(got, []int{1, 2, 4, 5, 7, 8, 100}) {
+		t.Fatalf("unexpected remaining elements %v", got)
+	}
+
+	empty := q.SplitFunc(func(int) bool { return true })
+	if empty.LenVisible() != 0 {
+		t.Fatalf("expected an empty split, got %d elements", empty.LenVisible())
+	}
+}
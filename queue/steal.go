@@ -0,0 +1,89 @@
+package queue
+
+import (
+	"context"
+	"reflect"
+	"sync/atomic"
+	"time"
+)
+
+// StealingConsumer consumes a set of queues that share the load of one
+// stream, one consumer per queue. It pops from its home queue and, when that
+// has nothing visible, steals the oldest visible element of another queue of
+// the set, so idle consumers help out busy ones. Stealing only takes elements
+// that a commit of their queue has published; every queue keeps its own
+// commits, and the elements of one queue are still popped in order.
+//
+// A StealingConsumer is meant for a single goroutine.
+type StealingConsumer[T any] struct {
+	queues []*SegmentedQueue[T]
+	home   int
+	victim int
+	stolen atomic.Uint64
+}
+
+// NewStealingConsumer returns a consumer of queues[home]. home is clamped to
+// the valid indexes; queues must not be empty.
+func NewStealingConsumer[T any](home int, queues ...*SegmentedQueue[T]) *StealingConsumer[T] {
+	home = min(max(home, 0), len(queues)-1)
+	return &StealingConsumer[T]{queues: queues, home: home, victim: home}
+}
+
+// Home returns the index of the consumer's home queue.
+func (c *StealingConsumer[T]) Home() int {
+	return c.home
+}
+
+// Stolen returns how many elements the consumer took from other queues.
+func (c *StealingConsumer[T]) Stolen() uint64 {
+	return c.stolen.Load()
+}
+
+// PopFront removes the oldest visible element of the home queue or, if it is
+// empty, of the next other queue that has one. Victims are visited
+// round-robin, so repeated steals spread over the set.
+func (c *StealingConsumer[T]) PopFront() (zero T, _ bool) {
+	if v, ok := c.queues[c.home].PopFront(); ok {
+		return v, true
+	}
+	for range len(c.queues) - 1 {
+		c.victim = (c.victim + 1) % len(c.queues)
+		if c.victim == c.home {
+			c.victim = (c.victim + 1) % len(c.queues)
+		}
+		if v, ok := c.queues[c.victim].PopFront(); ok {
+			c.stolen.Add(1)
+			return v, true
+		}
+	}
+	return zero, false
+}
+
+// PopFrontWait is PopFront that waits for a commit of any queue of the set to
+// publish an element. It returns ctx.Err() when ctx is done first.
+func (c *StealingConsumer[T]) PopFrontWait(ctx context.Context) (zero T, _ error) {
+	cases := make([]reflect.SelectCase, len(c.queues)+2)
+	for {
+		var poll time.Duration = -1
+		for i, q := range c.queues {
+			cases[i] = reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(q.Ready())}
+			if wait, ok := q.nextRedelivery(); ok && (poll < 0 || wait < poll) {
+				poll = wait
+			}
+		}
+		if v, ok := c.PopFront(); ok {
+			return v, nil
+		}
+		cases[len(c.queues)] = reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ctx.Done())}
+		// Redeliveries are only noticed by pops, so poll until the earliest
+		// lease can expire; a nil channel never fires.
+		var timer <-chan time.Time
+		if poll >= 0 {
+			timer = time.After(poll)
+		}
+		cases[len(c.queues)+1] = reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(timer)}
+		if chosen, _, _ := reflect.Select(cases); chosen == len(c.queues) {
+			return zero, ctx.Err()
+		}
+	}
+}
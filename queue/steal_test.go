@@ -0,0 +1,59 @@
+package queue
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestStealingConsumerStealsWhenHomeIsEmpty(t *testing.T) {
+	a, b, c := NewSegmentedQueue[int](), NewSegmentedQueue[int](), NewSegmentedQueue[int]()
+	a.PushBackPending(1)
+	a.Commit()
+	b.PushBackPending(10)
+	b.PushBackPending(11)
+	b.Commit()
+	c.PushBackPending(20)
+
+	consumer := NewStealingConsumer(0, a, b, c)
+	var got []int
+	for {
+		v, ok := consumer.PopFront()
+		if !ok {
+			break
+		}
+		got = append(got, v)
+	}
+	if !slices.Equal(got, []int{1, 10, 11}) {
+		t.Fatalf("expected the home element first and only committed elements stolen, got %v", got)
+	}
+	if consumer.Stolen() != 2 {
+		t.Fatalf("expected 2 stolen elements, got %d", consumer.Stolen())
+	}
+	if c.LenVisible() != 0 || drain(c)[0] != 20 {
+		t.Fatal("expected the pending element to stay with its queue")
+	}
+}
+
+func TestStealingConsumerWaitsForAnyQueue(t *testing.T) {
+	a, b := NewSegmentedQueue[int](), NewSegmentedQueue[int]()
+	consumer := NewStealingConsumer(0, a, b)
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		b.PushBackPending(7)
+		b.Commit()
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if v, err := consumer.PopFrontWait(ctx); err != nil || v != 7 {
+		t.Fatalf("expected to steal 7, got %v, %v", v, err)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := consumer.PopFrontWait(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("expected the deadline, got %v", err)
+	}
+}
@@ -0,0 +1,69 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrDiscard marks a handler error after which Subscribe must not retry the
+// element. Wrap it, for example with fmt.Errorf("%w: %v", ErrDiscard, err),
+// for elements that can never succeed.
+var ErrDiscard = errors.New("queue: discard element")
+
+// Subscribe runs workers goroutines that pop committed elements and pass
+// each to fn until ctx is done, then waits for the running calls to return
+// and returns ctx.Err().
+//
+// Elements are popped with PopFrontAck and settled by the result of fn: nil
+// acknowledges the element, an error wrapping ErrDiscard moves it to the dead
+// letter queue, and any other error, or a panic, returns it to the front of
+// the visible segment for another attempt. WithMaxRedeliveries bounds these
+// attempts; without it a permanently failing element is retried forever.
+// Values of workers below 1 start a single worker.
+func (sq *SegmentedQueue[T]) Subscribe(ctx context.Context, workers int, fn func(T) error) error {
+	var wg sync.WaitGroup
+	for range max(workers, 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				var v T
+				var handle AckHandle
+				err := sq.wait(ctx, func() (ok bool) {
+					v, handle, ok = sq.PopFrontAck()
+					return ok
+				})
+				if err != nil {
+					return
+				}
+				sq.dispatch(v, handle, fn)
+			}
+		}()
+	}
+	wg.Wait()
+	return ctx.Err()
+}
+
+// dispatch calls fn with v and settles handle by its result.
+func (sq *SegmentedQueue[T]) dispatch(v T, handle AckHandle, fn func(T) error) {
+	err := func() (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("queue: subscriber panicked: %v", r)
+			}
+		}()
+		return fn(v)
+	}()
+	switch {
+	case err == nil:
+		handle.Ack()
+	case errors.Is(err, ErrDiscard):
+		if handle.Ack() {
+			sq.deadLetter([]T{v})
+		}
+	default:
+		handle.Nack()
+	}
+}
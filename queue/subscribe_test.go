@@ -0,0 +1,72 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSubscribeDispatchesCommittedElements(t *testing.T) {
+	dead := NewSegmentedQueue[int]()
+	q := NewSegmentedQueue[int](WithDeadLetter(dead))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var mu sync.Mutex
+	var handled []int
+	attempts := map[int]int{}
+	done := make(chan struct{})
+	fn := func(v int) error {
+		mu.Lock()
+		defer mu.Unlock()
+		attempts[v]++
+		switch {
+		case v == 3 && attempts[v] == 1:
+			return errors.New("transient")
+		case v == 4 && attempts[v] == 1:
+			panic("boom")
+		case v == 5:
+			return fmt.Errorf("%w: malformed", ErrDiscard)
+		}
+		handled = append(handled, v)
+		if len(handled) == 5 {
+			close(done)
+		}
+		return nil
+	}
+
+	result := make(chan error)
+	go func() { result <- q.Subscribe(ctx, 3, fn) }()
+	for v := 1; v <= 6; v++ {
+		q.PushBackPending(v)
+	}
+	q.Commit()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the subscribers")
+	}
+	cancel()
+	if err := <-result; !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected Subscribe to return the context error, got %v", err)
+	}
+
+	slices.Sort(handled)
+	if !slices.Equal(handled, []int{1, 2, 3, 4, 6}) {
+		t.Fatalf("unexpected handled elements %v", handled)
+	}
+	if attempts[3] != 2 || attempts[4] != 2 || attempts[5] != 1 {
+		t.Fatalf("unexpected attempts %v", attempts)
+	}
+	if got := drain(dead); !slices.Equal(got, []int{5}) {
+		t.Fatalf("expected the discarded element in the dead letter queue, got %v", got)
+	}
+	if q.LenVisible() != 0 || q.LenInFlight() != 0 {
+		t.Fatal("expected every element to be settled")
+	}
+}
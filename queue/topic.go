@@ -0,0 +1,166 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"slices"
+	"sync"
+)
+
+// ErrSubscriptionExists is returned by Topic.Subscribe for a name that
+// already has a subscription.
+var ErrSubscriptionExists = errors.New("queue: subscription already exists")
+
+// Topic is a publish/subscribe layer on top of SegmentedQueues. Publishers
+// push into the topic's pending buffer; every subscriber has a visible queue
+// of its own. Topic is a single orchestrator bank: a commit stages the pending
+// elements on every subscriber, and publish makes them visible to all of them,
+// while an abort returns them to the pending buffer. Subscribers only receive
+// elements of commits prepared after they subscribed.
+type Topic[T any] struct {
+	options []SegmentedQueueOption[T]
+
+	mu            sync.Mutex
+	pending       []T
+	subscriptions map[string]*SegmentedQueue[T]
+}
+
+// NewTopic creates a topic without subscribers. The queues of its
+// subscribers are configured with options.
+func NewTopic[T any](options ...SegmentedQueueOption[T]) *Topic[T] {
+	return &Topic[T]{options: options, subscriptions: make(map[string]*SegmentedQueue[T])}
+}
+
+// Subscribe adds a subscriber and returns its queue, from which it pops the
+// committed elements.
+func (t *Topic[T]) Subscribe(name string) (*SegmentedQueue[T], error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if _, ok := t.subscriptions[name]; ok {
+		return nil, ErrSubscriptionExists
+	}
+	q := NewSegmentedQueue(t.options...)
+	t.subscriptions[name] = q
+	return q, nil
+}
+
+// Unsubscribe removes a subscriber. Its queue keeps the elements already
+// committed to it but receives no further ones.
+func (t *Topic[T]) Unsubscribe(name string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if _, ok := t.subscriptions[name]; !ok {
+		return false
+	}
+	delete(t.subscriptions, name)
+	return true
+}
+
+// Subscription returns the queue of a subscriber.
+func (t *Topic[T]) Subscription(name string) (*SegmentedQueue[T], bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	q, ok := t.subscriptions[name]
+	return q, ok
+}
+
+// Subscribers returns the names of all subscribers in ascending order.
+func (t *Topic[T]) Subscribers() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return slices.Sorted(maps.Keys(t.subscriptions))
+}
+
+// PushBackPending appends value to the topic's pending buffer.
+func (t *Topic[T]) PushBackPending(value T) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.pending = append(t.pending, value)
+}
+
+// LenPending returns the number of elements no commit has taken yet.
+func (t *Topic[T]) LenPending() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.pending)
+}
+
+// PrepareCommit takes the pending elements and stages them on every
+// subscriber with PrepareAppend. If a subscriber fails to prepare, the
+// subscribers prepared so far are aborted and the elements return to the
+// pending buffer. Without subscribers the elements are discarded.
+func (t *Topic[T]) PrepareCommit(ctx context.Context) (publish func(), abort func(), err error) {
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+	t.mu.Lock()
+	values := t.pending
+	t.pending = nil
+	subscriptions := slices.Collect(maps.Values(t.subscriptions))
+	t.mu.Unlock()
+
+	if len(values) == 0 {
+		return nil, nil, nil
+	}
+
+	publishes := make([]func(), 0, len(subscriptions))
+	aborts := make([]func(), 0, len(subscriptions))
+	abortAll := func() {
+		for i := len(aborts) - 1; i >= 0; i-- {
+			aborts[i]()
+		}
+	}
+	for _, q := range subscriptions {
+		p, a, err := q.PrepareAppend(ctx, values)
+		if err != nil {
+			abortAll()
+			t.restore(values)
+			return nil, nil, err
+		}
+		if p != nil {
+			publishes = append(publishes, p)
+			aborts = append(aborts, a)
+		}
+	}
+
+	var once sync.Once
+	publish = func() {
+		once.Do(func() {
+			for _, p := range publishes {
+				p()
+			}
+		})
+	}
+	abort = func() {
+		once.Do(func() {
+			abortAll()
+			t.restore(values)
+		})
+	}
+	return publish, abort, nil
+}
+
+// restore puts the elements of an aborted commit back in front of the
+// pending buffer.
+func (t *Topic[T]) restore(values []T) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.pending = slices.Concat(values, t.pending)
+}
+
+// Commit publishes the pending elements to all subscribers.
+func (t *Topic[T]) Commit() {
+	publish, _, err := t.PrepareCommit(context.Background())
+	if errors.Is(err, ErrRejected) {
+		return
+	}
+	if err != nil {
+		panic(err)
+	}
+	if publish != nil {
+		publish()
+	}
+}
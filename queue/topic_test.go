@@ -0,0 +1,67 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/timzifer/committable_queue/orchestrator"
+)
+
+func TestTopicPublishesToAllSubscribers(t *testing.T) {
+	topic := NewTopic[int]()
+	a, _ := topic.Subscribe("a")
+	b, _ := topic.Subscribe("b")
+	if _, err := topic.Subscribe("a"); !errors.Is(err, ErrSubscriptionExists) {
+		t.Fatalf("expected ErrSubscriptionExists, got %v", err)
+	}
+
+	topic.PushBackPending(1)
+	topic.PushBackPending(2)
+	if a.LenVisible() != 0 || b.LenVisible() != 0 {
+		t.Fatal("pending elements must not be visible")
+	}
+	o := orchestrator.NewCommitOrchestrator(orchestrator.WithBanks(topic))
+	if err := o.CommitAll(context.Background()); err != nil {
+		t.Fatalf("commit failed: %v", err)
+	}
+	for name, q := range map[string]*SegmentedQueue[int]{"a": a, "b": b} {
+		if got := drain(q); !slices.Equal(got, []int{1, 2}) {
+			t.Fatalf("subscriber %s got %v", name, got)
+		}
+	}
+
+	topic.Unsubscribe("b")
+	topic.PushBackPending(3)
+	topic.Commit()
+	if got := drain(a); !slices.Equal(got, []int{3}) {
+		t.Fatalf("subscriber a got %v", got)
+	}
+	if b.LenVisible() != 0 {
+		t.Fatal("an unsubscribed queue must not receive elements")
+	}
+}
+
+func TestTopicAbortRestoresPending(t *testing.T) {
+	topic := NewTopic[int]()
+	a, _ := topic.Subscribe("a")
+	topic.PushBackPending(1)
+	topic.PushBackPending(2)
+
+	strict := NewSegmentedQueue(WithCommitFilter(rejectNegative), WithStrictCommitFilter[int]())
+	strict.PushBackPending(-1)
+	o := orchestrator.NewCommitOrchestrator(orchestrator.WithBanks(topic, strict))
+	if err := o.CommitAll(context.Background()); !errors.Is(err, errNegative) {
+		t.Fatalf("expected the strict queue to fail the commit, got %v", err)
+	}
+	topic.PushBackPending(3)
+	if a.LenVisible() != 0 || topic.LenPending() != 3 {
+		t.Fatal("an aborted commit must leave its elements pending")
+	}
+
+	topic.Commit()
+	if got := drain(a); !slices.Equal(got, []int{1, 2, 3}) {
+		t.Fatalf("expected the original order, got %v", got)
+	}
+}
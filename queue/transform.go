@@ -0,0 +1,28 @@
+package queue
+
+// WithCommitTransform replaces every element by transform(element) when its
+// commit is published, before dedup, TTL and overflow handling see it, so
+// consumers never observe the raw value. Typical uses are unit conversion and
+// enrichment with a timestamp. transform runs without holding the queue's
+// locks, once per element; aborted commits return their elements untouched.
+// Elements moved in by SpliceTo or Merge were published by their source queue
+// and are not transformed again.
+func WithCommitTransform[T any](transform func(T) T) SegmentedQueueOption[T] {
+	return func(opts *segmentedQueueOptions[T]) {
+		opts.commitTransform = transform
+	}
+}
+
+// transform applies the commit transform to the elements of s in place. The
+// caller must own s exclusively.
+func (sq *SegmentedQueue[T]) transform(s segment[T]) {
+	transform := sq.opts.commitTransform
+	if transform == nil {
+		return
+	}
+	for c := s.head; c != nil; c = c.next {
+		for i := c.lo; i < c.hi; i++ {
+			c.values[i] = transform(c.values[i])
+		}
+	}
+}
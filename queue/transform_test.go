@@ -0,0 +1,37 @@
+package queue
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+func TestCommitTransformAppliesOnPublish(t *testing.T) {
+	q := NewSegmentedQueue[int](
+		WithCommitTransform(func(v int) int { return v * 10 }),
+		WithDedup(func(v int) int { return v }),
+	)
+	q.PushBackPending(1)
+	q.PushBackPending(2)
+
+	_, abort, err := q.PrepareCommit(context.Background())
+	if err != nil {
+		t.Fatalf("prepare failed: %v", err)
+	}
+	abort()
+	q.PushBackPending(3)
+
+	// Aborted elements are transformed once, when they are finally published.
+	if got := drain(q); !slices.Equal(got, []int{10, 20, 30}) {
+		t.Fatalf("expected transformed elements, got %v", got)
+	}
+
+	publish, _, err := q.PrepareAppend(context.Background(), []int{4, 4})
+	if err != nil {
+		t.Fatalf("prepare append failed: %v", err)
+	}
+	publish()
+	if got := drain(q); !slices.Equal(got, []int{40}) {
+		t.Fatalf("expected dedup on transformed elements, got %v", got)
+	}
+}
@@ -0,0 +1,122 @@
+package queue
+
+import "time"
+
+// WithTTL expires elements older than ttl. Expired elements are evicted from
+// the front of the visible segment whenever a commit is published and are
+// skipped by PopFront and PopBack, so they are never returned. By default an
+// element's age is measured from its push; delayed elements age from the
+// moment they become due.
+func WithTTL[T any](ttl time.Duration) SegmentedQueueOption[T] {
+	return func(opts *segmentedQueueOptions[T]) {
+		opts.ttl = ttl
+	}
+}
+
+// WithTTLFromCommit measures the TTL from the publish of the commit that made
+// an element visible instead of from its push.
+func WithTTLFromCommit[T any]() SegmentedQueueOption[T] {
+	return func(opts *segmentedQueueOptions[T]) {
+		opts.ttlFromCommit = true
+	}
+}
+
+// WithExpiryCallback registers fn to be called with every expired element.
+// It runs after the queue's locks have been released.
+func WithExpiryCallback[T any](fn func(T)) SegmentedQueueOption[T] {
+	return func(opts *segmentedQueueOptions[T]) {
+		opts.onExpire = fn
+	}
+}
+
+// newDeque creates a deque that stamps pushes when the TTL or the maximum
+// age is measured from the push or element metadata is recorded.
+func (sq *SegmentedQueue[T]) newDeque() *deque[T] {
+	d := newDeque[T]()
+	if sq.opts.ttl > 0 && !sq.opts.ttlFromCommit || sq.opts.maxAge > 0 || sq.opts.elementMeta {
+		d.now = func() int64 { return sq.now().UnixNano() }
+	}
+	return d
+}
+
+// markCommitted records the publish time on every chunk of s.
+func (sq *SegmentedQueue[T]) markCommitted(s segment[T], now int64) {
+	if sq.opts.ttl <= 0 && !sq.opts.elementMeta {
+		return
+	}
+	for c := s.head; c != nil; c = c.next {
+		c.committed = now
+	}
+}
+
+func (sq *SegmentedQueue[T]) expiredAt(c *chunk[T], i int, now int64) bool {
+	if sq.opts.ttl <= 0 {
+		return false
+	}
+	stamp := c.committed
+	if !sq.opts.ttlFromCommit && c.stamps != nil {
+		stamp = c.stamps[i]
+	}
+	return now-stamp >= int64(sq.opts.ttl)
+}
+
+// evictExpiredLocked removes expired elements from the front of the visible
+// segment and returns them. The caller must hold sq.visible.mu.
+func (sq *SegmentedQueue[T]) evictExpiredLocked(now int64) []T {
+	var expired []T
+	for c := sq.visible.head; c != nil && sq.expiredAt(c, c.lo, now); c = sq.visible.head {
+		v, _ := sq.visible.popFrontLocked()
+		sq.forgetLocked(v)
+		expired = append(expired, v)
+	}
+	return expired
+}
+
+// expire counts expired elements and passes them to the expiry callback.
+func (sq *SegmentedQueue[T]) expire(expired []T) {
+	if len(expired) == 0 {
+		return
+	}
+	sq.counters.expired.Add(uint64(len(expired)))
+	sq.notifySpace()
+	sq.deadLetter(expired)
+	if sq.opts.onExpire != nil {
+		for _, v := range expired {
+			sq.opts.onExpire(v)
+		}
+	}
+}
+
+// popLive pops from the front or back of the visible segment, skipping and
+// expiring elements whose TTL has passed, and keeps the dedup and size
+// bookkeeping of the removed elements up to date. It also returns the
+// metadata of the popped element.
+func (sq *SegmentedQueue[T]) popLive(front bool) (zero T, _ ElementMeta, _ bool) {
+	var expired []T
+	defer func() { sq.expire(expired) }()
+
+	sq.visible.mu.Lock()
+	defer sq.visible.mu.Unlock()
+
+	now := sq.now().UnixNano()
+	for sq.visible.len > 0 {
+		var v T
+		var live bool
+		var meta ElementMeta
+		if front {
+			c := sq.visible.head
+			live, meta = !sq.expiredAt(c, c.lo, now), sq.metaAt(c, c.lo)
+			v, _ = sq.visible.popFrontLocked()
+		} else {
+			c := sq.visible.tail
+			live, meta = !sq.expiredAt(c, c.hi-1, now), sq.metaAt(c, c.hi-1)
+			v, _ = sq.visible.popBackLocked()
+		}
+		sq.forgetLocked(v)
+		if live {
+			return v, meta, true
+		}
+		expired = append(expired, v)
+	}
+	return zero, ElementMeta{}, false
+}
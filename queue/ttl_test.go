@@ -0,0 +1,84 @@
+package queue
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTTLFromPushEvictsOnCommitAndPop(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	var expired []int
+	q := NewSegmentedQueue[int](
+		WithClock[int](clock.Now),
+		WithTTL[int](time.Second),
+		WithExpiryCallback(func(v int) { expired = append(expired, v) }),
+	)
+
+	q.PushBackPending(1)
+	clock.now = clock.now.Add(600 * time.Millisecond)
+	q.PushBackPending(2)
+	clock.now = clock.now.Add(600 * time.Millisecond)
+
+	// Element 1 is already too old when its commit is published.
+	q.Commit()
+	if q.LenVisible() != 1 || len(expired) != 1 || expired[0] != 1 {
+		t.Fatalf("expected element 1 to expire on commit, visible=%d expired=%v", q.LenVisible(), expired)
+	}
+
+	clock.now = clock.now.Add(500 * time.Millisecond)
+	if _, ok := q.PopFront(); ok {
+		t.Fatalf("stale element must not be delivered")
+	}
+	if len(expired) != 2 || expired[1] != 2 {
+		t.Fatalf("expected element 2 to expire on pop, got %v", expired)
+	}
+	if m := q.Metrics(); m.Expired != 2 || m.Pops != 0 {
+		t.Fatalf("unexpected counters: %+v", m)
+	}
+}
+
+func TestTTLFromCommitStartsAtPublish(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	q := NewSegmentedQueue[int](
+		WithClock[int](clock.Now),
+		WithTTL[int](time.Second),
+		WithTTLFromCommit[int](),
+		WithInitialVisible(0),
+	)
+
+	q.PushBackPending(1)
+	clock.now = clock.now.Add(5 * time.Second)
+	q.PushBackPending(2)
+	q.Commit()
+	if q.LenVisible() != 2 {
+		t.Fatalf("expected initial element to expire and both new ones to stay, got %d", q.LenVisible())
+	}
+
+	clock.now = clock.now.Add(999 * time.Millisecond)
+	if v, ok := q.PopBack(); !ok || v != 2 {
+		t.Fatalf("expected 2 before TTL passed, got %d (ok=%v)", v, ok)
+	}
+	clock.now = clock.now.Add(time.Millisecond)
+	if _, ok := q.PopFront(); ok {
+		t.Fatalf("element must expire exactly at TTL")
+	}
+}
+
+func TestTTLSurvivesChunkSplits(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	q := NewSegmentedQueue[int](WithClock[int](clock.Now), WithTTL[int](time.Second))
+
+	for i := 0; i < chunkSize+10; i++ {
+		q.PushBackPending(i)
+		if i == chunkSize/2 {
+			clock.now = clock.now.Add(time.Second / 2)
+		}
+	}
+	q.CommitUpTo(chunkSize / 4)
+	q.Commit()
+
+	clock.now = clock.now.Add(time.Second / 2)
+	if v, ok := q.PopFront(); !ok || v != chunkSize/2+1 {
+		t.Fatalf("expected the first element pushed after the clock advanced, got %d (ok=%v)", v, ok)
+	}
+}
@@ -0,0 +1,62 @@
+package queue
+
+import (
+	"context"
+
+	"github.com/timzifer/committable_queue/orchestrator"
+)
+
+// WithVersionStamps records on every element the commit version that
+// published it, for PopFrontVersioned. Commits run by a CommitOrchestrator
+// use the orchestrator's version; Commit, CommitUpTo and other commits
+// outside an orchestrator count on from the latest version the queue
+// published. Versions never go backwards: when a commit carries a version the
+// queue has already reached, for example because the queue takes part in
+// several orchestrators, it counts on from the latest version instead.
+func WithVersionStamps[T any]() SegmentedQueueOption[T] {
+	return func(opts *segmentedQueueOptions[T]) {
+		opts.versionStamps = true
+	}
+}
+
+// PopFrontVersioned is PopFront that also returns the commit version which
+// made the element visible. Redelivered elements report the version that was
+// current when they returned to the queue. Without WithVersionStamps the
+// version is always 0.
+func (sq *SegmentedQueue[T]) PopFrontVersioned() (T, uint64, bool) {
+	v, meta, ok := sq.pop(true)
+	return v, meta.Version, ok
+}
+
+// commitVersion returns the orchestrator version carried by ctx, or 0 for a
+// commit outside an orchestrator.
+func commitVersion(ctx context.Context) uint64 {
+	version, _ := orchestrator.VersionFromContext(ctx)
+	return version
+}
+
+// markVersion advances the queue's version, stamps it on every chunk of s and
+// returns it. The new version is the given one, but at least one past the
+// latest publish, so a zero version counts on from there. The caller must
+// hold sq.mu.
+func (sq *SegmentedQueue[T]) markVersion(s segment[T], version uint64) uint64 {
+	for {
+		current := sq.version.Load()
+		next := max(version, current+1)
+		if sq.version.CompareAndSwap(current, next) {
+			sq.stampVersion(s, next)
+			return next
+		}
+	}
+}
+
+// stampVersion sets the version of every chunk of s when version stamps are
+// enabled.
+func (sq *SegmentedQueue[T]) stampVersion(s segment[T], version uint64) {
+	if !sq.opts.versionStamps {
+		return
+	}
+	for c := s.head; c != nil; c = c.next {
+		c.version = version
+	}
+}
@@ -0,0 +1,106 @@
+package queue
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/timzifer/committable_queue/orchestrator"
+)
+
+func TestPopFrontVersionedUsesOrchestratorVersion(t *testing.T) {
+	q := NewSegmentedQueue[int](WithVersionStamps[int]())
+	o := orchestrator.NewCommitOrchestrator(orchestrator.WithBanks(q))
+
+	// Commits without pending elements still advance the orchestrator.
+	for i := 0; i < 3; i++ {
+		if err := o.CommitAll(context.Background()); err != nil {
+			t.Fatalf("commit: %v", err)
+		}
+	}
+	q.PushBackPending(1)
+	if err := o.CommitAll(context.Background()); err != nil {
+		t.Fatalf("commit: %v", err)
+	}
+	q.PushBackPending(2)
+	q.Commit()
+
+	want := []struct{ value, version int }{{1, 4}, {2, 5}}
+	for _, w := range want {
+		v, version, ok := q.PopFrontVersioned()
+		if !ok || v != w.value || version != uint64(w.version) {
+			t.Fatalf("expected %d at version %d, got %d at %d (%v)", w.value, w.version, v, version, ok)
+		}
+	}
+}
+
+func TestPopFrontVersionedRedelivery(t *testing.T) {
+	q := NewSegmentedQueue[int](WithVersionStamps[int]())
+	q.PushBackPending(1)
+	q.Commit()
+
+	_, h, _ := q.PopFrontAck()
+	q.PushBackPending(2)
+	q.Commit()
+	h.Nack()
+
+	if v, version, ok := q.PopFrontVersioned(); !ok || v != 1 || version != 2 {
+		t.Fatalf("expected redelivered 1 at version 2, got %d at %d (%v)", v, version, ok)
+	}
+	if v, version, ok := q.PopFrontVersioned(); !ok || v != 2 || version != 2 {
+		t.Fatalf("expected 2 at version 2, got %d at %d (%v)", v, version, ok)
+	}
+}
+
+func TestPopFrontVersionedWithoutStamps(t *testing.T) {
+	q := NewSegmentedQueue[int]()
+	q.PushBackPending(1)
+	q.Commit()
+
+	if v, version, ok := q.PopFrontVersioned(); !ok || v != 1 || version != 0 {
+		t.Fatalf("expected 1 without version, got %d at %d (%v)", v, version, ok)
+	}
+}
+
+func TestVersionNeverGoesBackwards(t *testing.T) {
+	q := NewSegmentedQueue(WithVersionStamps[int](), WithHistory[int](10))
+	app := orchestrator.NewCommitOrchestrator(orchestrator.WithBanks(q))
+	other := orchestrator.NewCommitOrchestrator(orchestrator.WithBanks(q))
+
+	for i := range 3 {
+		q.PushBackPending(i)
+		if err := app.CommitAll(context.Background()); err != nil {
+			t.Fatalf("commit: %v", err)
+		}
+	}
+	q.PushBackPending(3)
+	if err := other.CommitAll(context.Background()); err != nil {
+		t.Fatalf("commit: %v", err)
+	}
+	q.PushBackPending(4)
+	if err := other.CommitAll(context.Background()); err != nil {
+		t.Fatalf("commit: %v", err)
+	}
+	if got := q.Version(); got != 5 {
+		t.Fatalf("expected version 5, got %d", got)
+	}
+
+	for want := range 5 {
+		v, version, ok := q.PopFrontVersioned()
+		if !ok || v != want || version != uint64(want+1) {
+			t.Fatalf("expected %d at version %d, got %d at %d (%v)", want, want+1, v, version, ok)
+		}
+	}
+
+	replay, err := q.ReplayFrom(3)
+	if err != nil {
+		t.Fatalf("replay: %v", err)
+	}
+	var replayed []int
+	for _, v := range replay {
+		replayed = append(replayed, v)
+	}
+	if !slices.Equal(replayed, []int{2, 3, 4}) {
+		t.Fatalf("expected the commits from version 3 on, got %v", replayed)
+	}
+}
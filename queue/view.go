@@ -0,0 +1,68 @@
+package queue
+
+import "iter"
+
+type viewSpan[T any] struct {
+	chunk  *chunk[T]
+	lo, hi int
+}
+
+// View is an immutable snapshot of the visible segment. It stays valid and
+// unchanged while the queue keeps committing and popping, and may be read
+// from any goroutine.
+type View[T any] struct {
+	spans []viewSpan[T]
+	len   int
+}
+
+// SnapshotView returns a View of the visible segment. Instead of copying the
+// elements it marks the current chunks as shared, so creating a view costs one
+// small record per chunk of 64 elements. The queue copies a shared chunk only
+// when it has to write into it, which happens at most at the two ends of the
+// visible segment. Elements popped with PopFrontAck and elements awaiting TTL
+// eviction are included as they were at the time of the call.
+func (sq *SegmentedQueue[T]) SnapshotView() View[T] {
+	sq.redeliverExpired()
+
+	sq.visible.mu.Lock()
+	defer sq.visible.mu.Unlock()
+	return sq.viewLocked()
+}
+
+// viewLocked shares the visible chunks with a new View. The caller must hold
+// visible.mu.
+func (sq *SegmentedQueue[T]) viewLocked() View[T] {
+	v := View[T]{len: sq.visible.len}
+	for c := sq.visible.head; c != nil; c = c.next {
+		c.shared = true
+		v.spans = append(v.spans, viewSpan[T]{chunk: c, lo: c.lo, hi: c.hi})
+	}
+	return v
+}
+
+// Len returns the number of elements in the view.
+func (v View[T]) Len() int {
+	return v.len
+}
+
+// All iterates over the elements from the oldest to the newest.
+func (v View[T]) All() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for _, s := range v.spans {
+			for _, value := range s.chunk.values[s.lo:s.hi] {
+				if !yield(value) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// Values copies the elements of the view into a new slice.
+func (v View[T]) Values() []T {
+	values := make([]T, 0, v.len)
+	for _, s := range v.spans {
+		values = append(values, s.chunk.values[s.lo:s.hi]...)
+	}
+	return values
+}
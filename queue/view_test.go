@@ -0,0 +1,83 @@
+package queue
+
+import (
+	"slices"
+	"sync"
+	"testing"
+)
+
+func TestSnapshotViewIsStable(t *testing.T) {
+	q := NewSegmentedQueue[int]()
+	for i := 0; i < 3*chunkSize; i++ {
+		q.PushBackPending(i)
+	}
+	q.Commit()
+
+	view := q.SnapshotView()
+
+	// Pop across a chunk boundary, push back and front into the shared end
+	// chunks, and publish more elements.
+	for i := 0; i < chunkSize+1; i++ {
+		q.PopFront()
+	}
+	q.PopBack()
+	q.visible.pushFront(-1)
+	q.visible.pushBack(-2)
+	q.PushBackPending(1000)
+	q.Commit()
+
+	if view.Len() != 3*chunkSize {
+		t.Fatalf("expected %d elements, got %d", 3*chunkSize, view.Len())
+	}
+	i := 0
+	for v := range view.All() {
+		if v != i {
+			t.Fatalf("element %d changed to %d", i, v)
+		}
+		i++
+	}
+	if i != view.Len() {
+		t.Fatalf("iterated %d elements, want %d", i, view.Len())
+	}
+
+	want := []int{-1}
+	for i := chunkSize + 1; i < 3*chunkSize-1; i++ {
+		want = append(want, i)
+	}
+	want = append(want, -2, 1000)
+	if got := q.SnapshotView().Values(); !slices.Equal(got, want) {
+		t.Fatalf("queue contents diverged: got %d elements, want %d", len(got), len(want))
+	}
+}
+
+func TestSnapshotViewConcurrentWithConsumers(t *testing.T) {
+	q := NewSegmentedQueue[int]()
+	for i := 0; i < 10*chunkSize; i++ {
+		q.PushBackPending(i)
+	}
+	q.Commit()
+	view := q.SnapshotView()
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 5*chunkSize; i++ {
+			q.PopFront()
+			q.PushBackPending(i)
+			q.Commit()
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		sum := 0
+		for v := range view.All() {
+			sum += v
+		}
+		n := 10 * chunkSize
+		if sum != n*(n-1)/2 {
+			t.Errorf("unexpected sum %d", sum)
+		}
+	}()
+	wg.Wait()
+}
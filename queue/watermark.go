@@ -0,0 +1,80 @@
+package queue
+
+import "sync"
+
+// Level is the side of the watermarks a queue's visible depth is on.
+type Level int
+
+const (
+	// LevelLow is reported when the depth has fallen to the low watermark or
+	// below after having reached the high one.
+	LevelLow Level = iota
+	// LevelHigh is reported when the depth reaches the high watermark.
+	LevelHigh
+)
+
+func (l Level) String() string {
+	switch l {
+	case LevelLow:
+		return "low"
+	case LevelHigh:
+		return "high"
+	default:
+		return "unknown"
+	}
+}
+
+// Watermarks reports the visible depth crossing thresholds, for example to
+// throttle producers without polling. Notify receives LevelHigh once the
+// depth reaches High and LevelLow once it has fallen back to Low or below, so
+// the gap between the two keeps a depth hovering around one threshold from
+// flapping. Notify runs after the queue's locks have been released and may be
+// called from any goroutine that changes the depth. A High of zero disables
+// the watermarks.
+type Watermarks struct {
+	High   int
+	Low    int
+	Notify func(Level)
+}
+
+// watermarkState remembers on which side of the watermarks a queue is.
+type watermarkState struct {
+	mu   sync.Mutex
+	high bool
+}
+
+// observe compares the current depth with w and notifies a crossing. depth is
+// read under the state's lock so that crossings are detected in order; the
+// caller must not hold the lock depth acquires.
+func (s *watermarkState) observe(w Watermarks, depth func() int) {
+	if w.Notify == nil || w.High <= 0 {
+		return
+	}
+
+	s.mu.Lock()
+	n := depth()
+	crossed := false
+	switch {
+	case !s.high && n >= w.High:
+		s.high, crossed = true, true
+	case s.high && n <= w.Low:
+		s.high, crossed = false, true
+	}
+	level := LevelLow
+	if s.high {
+		level = LevelHigh
+	}
+	s.mu.Unlock()
+
+	if crossed {
+		w.Notify(level)
+	}
+}
+
+func (sq *SegmentedQueue[T]) checkWatermarks() {
+	sq.watermarks.observe(sq.options.Watermarks, sq.visible.length)
+}
+
+func (pq *PriorityQueue[T]) checkWatermarks() {
+	pq.watermarks.observe(pq.options.Watermarks, pq.LenVisible)
+}
@@ -0,0 +1,44 @@
+package queue
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestSegmentedQueueWatermarks(t *testing.T) {
+	var levels []Level
+	q := NewSegmentedQueue[int](WithOptions[int](Options{
+		Watermarks: Watermarks{High: 3, Low: 1, Notify: func(l Level) { levels = append(levels, l) }},
+	}))
+
+	for i := 0; i < 4; i++ {
+		q.PushBackPending(i)
+	}
+	q.Commit()
+	q.PopFront() // 3 left: still above the low watermark
+	q.PopFront()
+	q.PopFront() // 1 left: low
+	q.PushBackPending(4)
+	q.Commit() // 2: between the watermarks, no change
+
+	if fmt.Sprint(levels) != "[high low]" {
+		t.Fatalf("expected [high low], got %v", levels)
+	}
+}
+
+func TestPriorityQueueWatermarks(t *testing.T) {
+	var levels []Level
+	pq := NewPriorityQueue[int](Options{
+		Watermarks: Watermarks{High: 2, Low: 0, Notify: func(l Level) { levels = append(levels, l) }},
+	})
+
+	pq.PushPending(1, 1)
+	pq.PushPending(2, 2)
+	pq.Commit()
+	pq.PopFront()
+	pq.PopFront()
+
+	if fmt.Sprint(levels) != "[high low]" {
+		t.Fatalf("expected [high low], got %v", levels)
+	}
+}
@@ -0,0 +1,52 @@
+package queuebench
+
+import (
+	"github.com/timzifer/committable_queue/persist"
+	"github.com/timzifer/committable_queue/queue"
+)
+
+// Segmented adapts a SegmentedQueue. Every harness producer gets its own
+// queue.Producer, so pending shards configured on q are exercised.
+func Segmented(q *queue.SegmentedQueue[int64]) Queue {
+	return segmented{q}
+}
+
+type segmented struct {
+	q *queue.SegmentedQueue[int64]
+}
+
+func (s segmented) NewProducer() Producer {
+	return segmentedProducer{s.q.NewProducer()}
+}
+
+func (s segmented) Commit() error {
+	s.q.Commit()
+	return nil
+}
+
+func (s segmented) PopFront() (int64, bool, error) {
+	v, ok := s.q.PopFront()
+	return v, ok, nil
+}
+
+type segmentedProducer struct {
+	p *queue.Producer[int64]
+}
+
+func (p segmentedProducer) PushBackPending(value int64) error {
+	p.p.PushBackPending(value)
+	return nil
+}
+
+// Durable adapts a WAL-backed DurableQueue.
+func Durable(q *persist.DurableQueue[int64]) Queue {
+	return durable{q}
+}
+
+type durable struct {
+	*persist.DurableQueue[int64]
+}
+
+func (d durable) NewProducer() Producer {
+	return d.DurableQueue
+}
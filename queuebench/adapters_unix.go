@@ -0,0 +1,18 @@
+//go:build unix
+
+package queuebench
+
+import "github.com/timzifer/committable_queue/persist"
+
+// Mapped adapts a memory-mapped MappedQueue.
+func Mapped(q *persist.MappedQueue[int64]) Queue {
+	return mapped{q}
+}
+
+type mapped struct {
+	*persist.MappedQueue[int64]
+}
+
+func (m mapped) NewProducer() Producer {
+	return m.MappedQueue
+}
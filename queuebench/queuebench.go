@@ -0,0 +1,308 @@
+// Package queuebench drives configurable producer, consumer and commit
+// workloads against a queue and reports throughput and latency.
+//
+// A run starts Workload.Producers producers that push batches at a fixed rate,
+// a committer that commits at a fixed interval and Workload.Consumers
+// consumers that pop visible elements. Every element carries its push time, so
+// the report includes the end-to-end latency from push to pop, which covers
+// the time an element waits for the next commit. Different configurations
+// (pending shards, drop policies, backends) are compared by running the same
+// Workload against differently constructed queues.
+package queuebench
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/timzifer/committable_queue/telemetry"
+)
+
+// Queue is the part of a queue backend the harness drives. Elements are push
+// timestamps in nanoseconds relative to the start of the run.
+type Queue interface {
+	NewProducer() Producer
+	Commit() error
+	PopFront() (int64, bool, error)
+}
+
+// Producer pushes pending elements. Each harness producer gets its own.
+type Producer interface {
+	PushBackPending(value int64) error
+}
+
+// Workload describes one benchmark run.
+type Workload struct {
+	// Duration is how long producers push. Zero means one second.
+	Duration time.Duration
+	// Producers is the number of concurrent producers. Zero means one.
+	Producers int
+	// Rate is the number of pushes per second and producer. Zero pushes as
+	// fast as possible.
+	Rate float64
+	// BatchSize is the number of elements a producer pushes at once. Zero
+	// means one.
+	BatchSize int
+	// Consumers is the number of concurrent consumers. Zero means one.
+	Consumers int
+	// CommitInterval is the time between commits. Zero commits continuously.
+	CommitInterval time.Duration
+	// Drain keeps committing and consuming after Duration until every pushed
+	// element has been popped or dropped, or ctx is done.
+	Drain bool
+}
+
+func (w Workload) withDefaults() Workload {
+	if w.Duration <= 0 {
+		w.Duration = time.Second
+	}
+	if w.Producers <= 0 {
+		w.Producers = 1
+	}
+	if w.BatchSize <= 0 {
+		w.BatchSize = 1
+	}
+	if w.Consumers <= 0 {
+		w.Consumers = 1
+	}
+	return w
+}
+
+// Latency summarises a latency distribution. Percentiles are bucket upper
+// bounds of telemetry.DefaultDurationBuckets.
+type Latency struct {
+	Count uint64
+	P50   time.Duration
+	P95   time.Duration
+	P99   time.Duration
+	Max   time.Duration
+}
+
+func (l Latency) String() string {
+	return fmt.Sprintf("p50=%v p95=%v p99=%v max=%v", l.P50, l.P95, l.P99, l.Max)
+}
+
+type latencyRecorder struct {
+	histogram *telemetry.Histogram
+	max       atomic.Int64
+}
+
+func newLatencyRecorder() *latencyRecorder {
+	return &latencyRecorder{histogram: telemetry.NewDurationHistogram(telemetry.DefaultDurationBuckets)}
+}
+
+func (r *latencyRecorder) observe(d time.Duration) {
+	r.histogram.Observe(int64(d))
+	for {
+		current := r.max.Load()
+		if int64(d) <= current || r.max.CompareAndSwap(current, int64(d)) {
+			return
+		}
+	}
+}
+
+func (r *latencyRecorder) summary() Latency {
+	return Latency{
+		Count: r.histogram.Count(),
+		P50:   time.Duration(r.histogram.Quantile(0.50)),
+		P95:   time.Duration(r.histogram.Quantile(0.95)),
+		P99:   time.Duration(r.histogram.Quantile(0.99)),
+		Max:   time.Duration(r.max.Load()),
+	}
+}
+
+// Report is the result of a run.
+type Report struct {
+	Elapsed time.Duration
+	Pushed  uint64
+	Popped  uint64
+	Commits uint64
+
+	// PushRate and PopRate are elements per second over Elapsed.
+	PushRate float64
+	PopRate  float64
+
+	// EndToEnd is the time from push to pop.
+	EndToEnd Latency
+	// Commit is the duration of Queue.Commit calls.
+	Commit Latency
+}
+
+func (r Report) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "elapsed=%v pushed=%d popped=%d commits=%d\n", r.Elapsed.Round(time.Millisecond), r.Pushed, r.Popped, r.Commits)
+	fmt.Fprintf(&b, "push=%.0f/s pop=%.0f/s\n", r.PushRate, r.PopRate)
+	fmt.Fprintf(&b, "end-to-end %v\n", r.EndToEnd)
+	fmt.Fprintf(&b, "commit     %v", r.Commit)
+	return b.String()
+}
+
+// Run executes w against q and returns the report. It stops early with the
+// first error of a queue operation or when ctx is done; the report then covers
+// the work done so far.
+func Run(ctx context.Context, q Queue, w Workload) (Report, error) {
+	w = w.withDefaults()
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	var (
+		pushed, popped, commits atomic.Uint64
+		endToEnd                = newLatencyRecorder()
+		commitLatency           = newLatencyRecorder()
+		producers, workers      sync.WaitGroup
+		producersDone           = make(chan struct{})
+		finalCommit             = make(chan struct{})
+	)
+	start := time.Now()
+	since := func() int64 { return int64(time.Since(start)) }
+
+	produceCtx, stopProducers := context.WithTimeout(ctx, w.Duration)
+	defer stopProducers()
+
+	for i := 0; i < w.Producers; i++ {
+		producer := q.NewProducer()
+		producers.Add(1)
+		go func() {
+			defer producers.Done()
+			produce(produceCtx, producer, w, since, &pushed, cancel)
+		}()
+	}
+	go func() {
+		producers.Wait()
+		close(producersDone)
+	}()
+
+	commit := func() bool {
+		began := time.Now()
+		if err := q.Commit(); err != nil {
+			cancel(err)
+			return false
+		}
+		commitLatency.observe(time.Since(began))
+		commits.Add(1)
+		return true
+	}
+
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		var tick <-chan time.Time
+		if w.CommitInterval > 0 {
+			ticker := time.NewTicker(w.CommitInterval)
+			defer ticker.Stop()
+			tick = ticker.C
+		}
+		for {
+			stop := false
+			if tick != nil {
+				select {
+				case <-ctx.Done():
+					return
+				case <-producersDone:
+					stop = true
+				case <-tick:
+				}
+			} else {
+				select {
+				case <-ctx.Done():
+					return
+				case <-producersDone:
+					stop = true
+				default:
+					runtime.Gosched()
+				}
+			}
+			if stop {
+				// One last commit publishes everything pushed.
+				if w.Drain && commit() {
+					close(finalCommit)
+				}
+				return
+			}
+			if !commit() {
+				return
+			}
+		}
+	}()
+
+	for i := 0; i < w.Consumers; i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for ctx.Err() == nil {
+				if !w.Drain && isClosed(producersDone) {
+					return
+				}
+				value, ok, err := q.PopFront()
+				if err != nil {
+					cancel(err)
+					return
+				}
+				if ok {
+					endToEnd.observe(time.Duration(since() - value))
+					popped.Add(1)
+					continue
+				}
+				if isClosed(finalCommit) {
+					return
+				}
+				time.Sleep(10 * time.Microsecond)
+			}
+		}()
+	}
+
+	workers.Wait()
+	elapsed := time.Since(start)
+
+	report := Report{
+		Elapsed:  elapsed,
+		Pushed:   pushed.Load(),
+		Popped:   popped.Load(),
+		Commits:  commits.Load(),
+		EndToEnd: endToEnd.summary(),
+		Commit:   commitLatency.summary(),
+	}
+	if seconds := elapsed.Seconds(); seconds > 0 {
+		report.PushRate = float64(report.Pushed) / seconds
+		report.PopRate = float64(report.Popped) / seconds
+	}
+	return report, context.Cause(ctx)
+}
+
+func produce(ctx context.Context, producer Producer, w Workload, since func() int64, pushed *atomic.Uint64, fail context.CancelCauseFunc) {
+	var tick <-chan time.Time
+	if w.Rate > 0 {
+		ticker := time.NewTicker(time.Duration(float64(w.BatchSize) / w.Rate * float64(time.Second)))
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	for ctx.Err() == nil {
+		for j := 0; j < w.BatchSize; j++ {
+			if err := producer.PushBackPending(since()); err != nil {
+				fail(err)
+				return
+			}
+			pushed.Add(1)
+		}
+		if tick != nil {
+			select {
+			case <-tick:
+			case <-ctx.Done():
+			}
+		}
+	}
+}
+
+func isClosed(ch <-chan struct{}) bool {
+	select {
+	case <-ch:
+		return true
+	default:
+		return false
+	}
+}
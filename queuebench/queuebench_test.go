@@ -0,0 +1,75 @@
+package queuebench
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/timzifer/committable_queue/codec"
+	"github.com/timzifer/committable_queue/persist"
+	"github.com/timzifer/committable_queue/queue"
+)
+
+func TestRunDrainsEveryPushedElement(t *testing.T) {
+	q := queue.NewSegmentedQueue[int64](queue.WithPendingShards[int64](2))
+	report, err := Run(context.Background(), Segmented(q), Workload{
+		Duration:       50 * time.Millisecond,
+		Producers:      2,
+		Rate:           2000,
+		BatchSize:      10,
+		Consumers:      2,
+		CommitInterval: 5 * time.Millisecond,
+		Drain:          true,
+	})
+	if err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	if report.Pushed == 0 || report.Popped != report.Pushed {
+		t.Fatalf("expected every pushed element to be popped: %+v", report)
+	}
+	if report.Commits == 0 || report.Commit.Count != report.Commits {
+		t.Fatalf("expected recorded commits: %+v", report)
+	}
+	if report.EndToEnd.Count != report.Popped || report.EndToEnd.Max <= 0 {
+		t.Fatalf("expected end-to-end latencies for every pop: %+v", report.EndToEnd)
+	}
+	// Rate limiting keeps producers well below the unthrottled rate.
+	if limit := 2 * (2000*report.Elapsed.Seconds()*2 + 10); float64(report.Pushed) > limit {
+		t.Fatalf("rate limit ignored: pushed %d", report.Pushed)
+	}
+	if !strings.Contains(report.String(), "commits=") {
+		t.Fatalf("unexpected report text: %s", report)
+	}
+}
+
+func TestRunCountsDropsAsMissingPops(t *testing.T) {
+	q := queue.NewSegmentedQueue[int64](queue.WithOptions[int64](queue.Options{MaxLen: 1, DropPolicy: queue.DropOldest}))
+	report, err := Run(context.Background(), Segmented(q), Workload{
+		Duration:       20 * time.Millisecond,
+		BatchSize:      100,
+		Rate:           10000,
+		CommitInterval: 5 * time.Millisecond,
+		Drain:          true,
+	})
+	if err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	if dropped := q.Metrics().Dropped; report.Popped+dropped != report.Pushed {
+		t.Fatalf("popped %d + dropped %d != pushed %d", report.Popped, dropped, report.Pushed)
+	}
+}
+
+func TestRunReportsQueueErrors(t *testing.T) {
+	dq, err := persist.NewDurableQueue[int64](t.TempDir(), codec.Gob[int64]{}, persist.WithSyncWrites(false))
+	if err != nil {
+		t.Fatalf("open failed: %v", err)
+	}
+	dq.Close()
+
+	_, err = Run(context.Background(), Durable(dq), Workload{Duration: time.Second})
+	if !errors.Is(err, persist.ErrClosed) {
+		t.Fatalf("expected ErrClosed, got %v", err)
+	}
+}
@@ -0,0 +1,187 @@
+// Package queuetest helps testing code built on the commit protocol.
+//
+// FaultyBank wraps a real bank, such as any queue of this module, and injects
+// failures at its prepare, publish and abort steps, failpoint style: fail the
+// third prepare, delay every publish, panic in the next abort. Tests of
+// orchestrator integrations can thereby exercise their error paths without
+// writing their own flaky fakes.
+//
+// RunQueueSuite is a conformance suite for queue implementations: it checks a
+// custom backend against the commit barrier, abort and overflow semantics of
+// the reference queue. Stress goes further and checks random concurrent
+// operation sequences for linearizability against a sequential model; a
+// failure names the seed that reproduces it.
+package queuetest
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/timzifer/committable_queue/orchestrator"
+)
+
+// Point is a step of the commit protocol at which a fault can be injected.
+type Point int
+
+const (
+	Prepare Point = iota
+	Publish
+	Abort
+	pointCount
+)
+
+func (p Point) String() string {
+	switch p {
+	case Prepare:
+		return "prepare"
+	case Publish:
+		return "publish"
+	case Abort:
+		return "abort"
+	}
+	return fmt.Sprintf("Point(%d)", int(p))
+}
+
+// Action is run when an injected fault triggers. A non-nil error fails a
+// prepare before the wrapped bank is called. At Publish and Abort the error
+// skips the wrapped callback instead, as if the bank lost the call.
+type Action func(ctx context.Context) error
+
+// Fail returns an action that fails with err.
+func Fail(err error) Action {
+	return func(context.Context) error { return err }
+}
+
+// Delay returns an action that sleeps for d, or until ctx is done, in which
+// case it fails with the context's error.
+func Delay(d time.Duration) Action {
+	return func(ctx context.Context) error {
+		timer := time.NewTimer(d)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Panic returns an action that panics with v.
+func Panic(v any) Action {
+	return func(context.Context) error { panic(v) }
+}
+
+type fault struct {
+	nth    int
+	action Action
+}
+
+// FaultyBank is an orchestrator.Bank that forwards to a wrapped bank and runs
+// injected faults. It is safe for concurrent use.
+type FaultyBank struct {
+	bank orchestrator.Bank
+
+	mu     sync.Mutex
+	calls  [pointCount]int
+	faults [pointCount][]fault
+}
+
+// WrapBank returns a FaultyBank around bank without any faults.
+func WrapBank(bank orchestrator.Bank) *FaultyBank {
+	return &FaultyBank{bank: bank}
+}
+
+// Inject runs action on the nth call of point, counted from 1 over the life
+// of the bank, or on every call when nth is 0. Several faults at the same call
+// run in the order they were injected until one returns an error.
+func (b *FaultyBank) Inject(point Point, nth int, action Action) *FaultyBank {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.faults[point] = append(b.faults[point], fault{nth: nth, action: action})
+	return b
+}
+
+// FailPrepare fails the nth prepare with err.
+func (b *FaultyBank) FailPrepare(nth int, err error) *FaultyBank {
+	return b.Inject(Prepare, nth, Fail(err))
+}
+
+// DelayPublish delays the nth publish by d.
+func (b *FaultyBank) DelayPublish(nth int, d time.Duration) *FaultyBank {
+	return b.Inject(Publish, nth, Delay(d))
+}
+
+// PanicInAbort panics with v in the nth abort.
+func (b *FaultyBank) PanicInAbort(nth int, v any) *FaultyBank {
+	return b.Inject(Abort, nth, Panic(v))
+}
+
+// Reset removes all faults. Call counts are kept.
+func (b *FaultyBank) Reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.faults = [pointCount][]fault{}
+}
+
+// Calls returns how often point was reached, including calls that faults
+// failed or skipped.
+func (b *FaultyBank) Calls(point Point) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.calls[point]
+}
+
+// Name returns the wrapped bank's name, so wrapping a named bank does not
+// change its telemetry. Unnamed banks are called queuetest.FaultyBank.
+func (b *FaultyBank) Name() string {
+	if named, ok := b.bank.(orchestrator.NamedBank); ok {
+		return named.Name()
+	}
+	return "queuetest.FaultyBank"
+}
+
+// trigger counts a call of point and runs the faults that match it.
+func (b *FaultyBank) trigger(ctx context.Context, point Point) error {
+	b.mu.Lock()
+	b.calls[point]++
+	n := b.calls[point]
+	var actions []Action
+	for _, f := range b.faults[point] {
+		if f.nth == 0 || f.nth == n {
+			actions = append(actions, f.action)
+		}
+	}
+	b.mu.Unlock()
+
+	for _, action := range actions {
+		if err := action(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *FaultyBank) PrepareCommit(ctx context.Context) (publish func(), abort func(), err error) {
+	if err := b.trigger(ctx, Prepare); err != nil {
+		return nil, nil, err
+	}
+	publish, abort, err = b.bank.PrepareCommit(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	return b.wrap(Publish, publish), b.wrap(Abort, abort), nil
+}
+
+// wrap runs the faults of point before fn. A nil fn stays a no-op, but the
+// faults still run.
+func (b *FaultyBank) wrap(point Point, fn func()) func() {
+	return func() {
+		if b.trigger(context.Background(), point) != nil || fn == nil {
+			return
+		}
+		fn()
+	}
+}
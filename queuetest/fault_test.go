@@ -0,0 +1,97 @@
+package queuetest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/timzifer/committable_queue/orchestrator"
+	"github.com/timzifer/committable_queue/queue"
+)
+
+func TestFailPrepareAbortsOtherBanks(t *testing.T) {
+	left := queue.NewSegmentedQueue[int]()
+	right := queue.NewSegmentedQueue[int]()
+	failing := WrapBank(right).FailPrepare(2, errors.New("disk full"))
+	o := orchestrator.NewCommitOrchestrator(orchestrator.WithBanks(left, failing))
+
+	for round := 1; round <= 3; round++ {
+		left.PushBackPending(round)
+		right.PushBackPending(round)
+		err := o.CommitAll(context.Background())
+		if round == 2 {
+			if err == nil {
+				t.Fatal("expected the second commit to fail")
+			}
+			if left.LenVisible() != 1 || right.LenVisible() != 1 {
+				t.Fatalf("failed commit must not publish, got %d and %d visible", left.LenVisible(), right.LenVisible())
+			}
+		} else if err != nil {
+			t.Fatalf("commit %d failed: %v", round, err)
+		}
+	}
+	if left.LenVisible() != 3 || right.LenVisible() != 3 {
+		t.Fatalf("expected all elements visible after retry, got %d and %d", left.LenVisible(), right.LenVisible())
+	}
+	if got := failing.Calls(Prepare); got != 3 {
+		t.Fatalf("expected 3 prepares, got %d", got)
+	}
+}
+
+func TestDelayPublish(t *testing.T) {
+	q := queue.NewSegmentedQueue[int]()
+	bank := WrapBank(q).DelayPublish(0, 20*time.Millisecond)
+	q.PushBackPending(1)
+
+	start := time.Now()
+	if err := orchestrator.NewCommitOrchestrator(orchestrator.WithBanks(bank)).CommitAll(context.Background()); err != nil {
+		t.Fatalf("commit failed: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+		t.Fatalf("expected publish to be delayed, took %v", elapsed)
+	}
+	if q.LenVisible() != 1 {
+		t.Fatalf("expected the element to be published")
+	}
+}
+
+func TestPanicInAbort(t *testing.T) {
+	bank := WrapBank(queue.NewSegmentedQueue[int]()).PanicInAbort(1, "boom")
+	_, abort, err := bank.PrepareCommit(context.Background())
+	if err != nil {
+		t.Fatalf("prepare failed: %v", err)
+	}
+	defer func() {
+		if r := recover(); r != "boom" {
+			t.Fatalf("expected panic boom, got %v", r)
+		}
+		if bank.Calls(Abort) != 1 {
+			t.Fatalf("expected one abort call, got %d", bank.Calls(Abort))
+		}
+	}()
+	abort()
+}
+
+func TestFailPublishSkipsWrappedCallback(t *testing.T) {
+	q := queue.NewSegmentedQueue[int]()
+	bank := WrapBank(q).Inject(Publish, 1, Fail(errors.New("lost")))
+	q.PushBackPending(1)
+
+	publish, abort, err := bank.PrepareCommit(context.Background())
+	if err != nil {
+		t.Fatalf("prepare failed: %v", err)
+	}
+	publish()
+	if q.LenVisible() != 0 {
+		t.Fatal("failed publish must not reach the queue")
+	}
+	abort()
+
+	bank.Reset()
+	publish, _, _ = bank.PrepareCommit(context.Background())
+	publish()
+	if q.LenVisible() != 1 {
+		t.Fatalf("expected the element after reset, got %d visible", q.LenVisible())
+	}
+}
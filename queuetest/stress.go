@@ -0,0 +1,349 @@
+package queuetest
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/timzifer/committable_queue/queue"
+)
+
+// StressConfig configures Stress. Zero fields use the defaults.
+type StressConfig struct {
+	// Seed selects the operation sequence. Zero picks a seed from the clock;
+	// the seed in use is logged and part of every failure message.
+	Seed uint64
+	// Rounds is the number of rounds to run, 200 by default.
+	Rounds int
+	// Workers is the number of operations that run concurrently in each
+	// round, 4 by default and at most 6.
+	Workers int
+}
+
+const maxStressWorkers = 6
+
+// Stress drives random sequences of pushes, pops, prepares, publishes, aborts
+// and length queries against the queue made by factory and checks that they
+// are linearizable against a sequential model of the commit protocol.
+//
+// Operations run in rounds: the operations of one round are started together
+// from Workers goroutines, and a round begins only after the previous one has
+// finished. Each round must be explainable by some sequential order of its
+// operations, applied to a state the earlier rounds can have produced. The
+// operation sequence depends only on Seed, so a failure is reproduced by
+// passing its seed back; the interleaving within a round is up to the
+// scheduler, so a rare race may need several runs.
+func Stress(t *testing.T, factory Factory, config StressConfig) {
+	t.Helper()
+	if config.Seed == 0 {
+		config.Seed = uint64(time.Now().UnixNano())
+	}
+	if config.Rounds <= 0 {
+		config.Rounds = 200
+	}
+	if config.Workers <= 0 {
+		config.Workers = 4
+	}
+	config.Workers = min(config.Workers, maxStressWorkers)
+	t.Logf("stress seed %d", config.Seed)
+
+	q := factory(t, queue.Options{})
+	if q == nil {
+		t.Skip("default options not supported")
+	}
+	s := &stress{
+		t:       t,
+		q:       q,
+		seed:    config.Seed,
+		rng:     rand.New(rand.NewPCG(config.Seed, 0)),
+		handles: map[int]*stressHandle{},
+		states:  []stressModel{{staged: map[int][]int{}}},
+	}
+	for round := 0; round < config.Rounds; round++ {
+		s.run(s.generate(config.Workers))
+		if len(s.states) > maxStressStates {
+			s.settle()
+		}
+	}
+	s.settle()
+}
+
+// maxStressStates bounds the model states Stress tracks. Pending elements
+// cannot be observed, so every round whose pushes, prepares and aborts race
+// may multiply the states until a settle drains the queue.
+const maxStressStates = 32
+
+// settle aborts what is still staged, then publishes and pops everything one
+// operation at a time. The pops reveal the order of all elements, which
+// collapses the model to a single empty state and detects elements lost or
+// duplicated along the way.
+func (s *stress) settle() {
+	s.t.Helper()
+	for _, handle := range slices.Sorted(maps.Keys(s.open)) {
+		s.run([]*stressOp{{kind: opAbort, handle: handle}})
+	}
+	clear(s.open)
+	s.nextHandle++
+	s.run([]*stressOp{{kind: opPrepare, handle: s.nextHandle}})
+	s.run([]*stressOp{{kind: opPublish, handle: s.nextHandle}})
+	for {
+		op := &stressOp{kind: opPop}
+		s.run([]*stressOp{op})
+		if !op.ok {
+			return
+		}
+	}
+}
+
+type stressKind int
+
+const (
+	opPush stressKind = iota
+	opPop
+	opPrepare
+	opPublish
+	opAbort
+	opLen
+)
+
+type stressOp struct {
+	kind   stressKind
+	value  int
+	handle int
+
+	got int
+	ok  bool
+}
+
+func (op *stressOp) String() string {
+	switch op.kind {
+	case opPush:
+		return fmt.Sprintf("push(%d)", op.value)
+	case opPop:
+		return fmt.Sprintf("pop()=%d,%v", op.got, op.ok)
+	case opPrepare:
+		return fmt.Sprintf("prepare()#%d", op.handle)
+	case opPublish:
+		return fmt.Sprintf("publish#%d", op.handle)
+	case opAbort:
+		return fmt.Sprintf("abort#%d", op.handle)
+	default:
+		return fmt.Sprintf("len()=%d", op.got)
+	}
+}
+
+type stressHandle struct {
+	publish, abort func()
+}
+
+type stress struct {
+	t    *testing.T
+	q    Queue
+	seed uint64
+	rng  *rand.Rand
+
+	nextValue  int
+	nextHandle int
+	// open holds the handles that no generated operation has finished yet.
+	open map[int]bool
+
+	mu      sync.Mutex
+	handles map[int]*stressHandle
+
+	states []stressModel
+}
+
+// generate picks the operations of one round.
+func (s *stress) generate(workers int) []*stressOp {
+	if s.open == nil {
+		s.open = map[int]bool{}
+	}
+	ops := make([]*stressOp, workers)
+	for i := range ops {
+		op := &stressOp{}
+		switch roll := s.rng.IntN(100); {
+		case roll < 35:
+			s.nextValue++
+			op.kind, op.value = opPush, s.nextValue
+		case roll < 60:
+			op.kind = opPop
+		case roll < 70:
+			s.nextHandle++
+			op.kind, op.handle = opPrepare, s.nextHandle
+		case roll < 90 && len(s.open) > 0:
+			handles := slices.Sorted(maps.Keys(s.open))
+			op.kind, op.handle = opPublish, handles[s.rng.IntN(len(handles))]
+			if s.rng.IntN(3) == 0 {
+				op.kind = opAbort
+			}
+		default:
+			op.kind = opLen
+		}
+		ops[i] = op
+	}
+	// Handles become usable in the round after their prepare; finishing one
+	// twice in a round is allowed and exercises the at-most-once guarantee.
+	for _, op := range ops {
+		switch op.kind {
+		case opPrepare:
+			s.open[op.handle] = true
+		case opPublish, opAbort:
+			delete(s.open, op.handle)
+		}
+	}
+	return ops
+}
+
+// run executes ops concurrently and checks the round against the model.
+func (s *stress) run(ops []*stressOp) {
+	s.t.Helper()
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for _, op := range ops {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			s.execute(op)
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	var next []stressModel
+	seen := map[string]bool{}
+	for _, state := range s.states {
+		explain(state, ops, make([]bool, len(ops)), func(result stressModel) {
+			if key := result.key(); !seen[key] {
+				seen[key] = true
+				next = append(next, result)
+			}
+		})
+	}
+	if len(next) == 0 {
+		names := make([]string, len(ops))
+		for i, op := range ops {
+			names[i] = op.String()
+		}
+		s.t.Fatalf("seed %d: no sequential order explains [%s] from any of %d model states, e.g. %s",
+			s.seed, strings.Join(names, " "), len(s.states), s.states[0].key())
+	}
+	s.states = next
+}
+
+func (s *stress) execute(op *stressOp) {
+	switch op.kind {
+	case opPush:
+		if err := s.q.PushBackPending(op.value); err != nil {
+			s.t.Errorf("seed %d: push failed: %v", s.seed, err)
+		}
+	case opPop:
+		v, ok, err := s.q.PopFront()
+		if err != nil {
+			s.t.Errorf("seed %d: pop failed: %v", s.seed, err)
+		}
+		op.got, op.ok = v, ok
+	case opPrepare:
+		publish, abort, err := s.q.PrepareCommit(context.Background())
+		if err != nil {
+			s.t.Errorf("seed %d: prepare failed: %v", s.seed, err)
+		}
+		s.mu.Lock()
+		s.handles[op.handle] = &stressHandle{publish: publish, abort: abort}
+		s.mu.Unlock()
+	case opPublish, opAbort:
+		s.mu.Lock()
+		handle := s.handles[op.handle]
+		s.mu.Unlock()
+		fn := handle.publish
+		if op.kind == opAbort {
+			fn = handle.abort
+		}
+		if fn != nil {
+			fn()
+		}
+	case opLen:
+		op.got = s.q.LenVisible()
+	}
+}
+
+// stressModel is the sequential model of a queue: pending and visible
+// elements and the batches of prepared commits by handle. Finished handles
+// are removed from staged.
+type stressModel struct {
+	visible []int
+	pending []int
+	staged  map[int][]int
+}
+
+func (m stressModel) clone() stressModel {
+	staged := make(map[int][]int, len(m.staged))
+	for handle, batch := range m.staged {
+		staged[handle] = batch
+	}
+	return stressModel{visible: slices.Clone(m.visible), pending: slices.Clone(m.pending), staged: staged}
+}
+
+func (m stressModel) key() string {
+	return fmt.Sprintf("visible=%v pending=%v staged=%v", m.visible, m.pending, m.staged)
+}
+
+// apply performs op on m and reports whether the observed result matches.
+func (m *stressModel) apply(op *stressOp) bool {
+	switch op.kind {
+	case opPush:
+		m.pending = append(m.pending, op.value)
+	case opPop:
+		if len(m.visible) == 0 {
+			return !op.ok
+		}
+		if !op.ok || op.got != m.visible[0] {
+			return false
+		}
+		m.visible = m.visible[1:]
+	case opPrepare:
+		m.staged[op.handle] = m.pending
+		m.pending = nil
+	case opPublish:
+		if batch, ok := m.staged[op.handle]; ok {
+			m.visible = append(m.visible, batch...)
+			delete(m.staged, op.handle)
+		}
+	case opAbort:
+		if batch, ok := m.staged[op.handle]; ok {
+			m.pending = append(slices.Clone(batch), m.pending...)
+			delete(m.staged, op.handle)
+		}
+	case opLen:
+		return op.got == len(m.visible)
+	}
+	return true
+}
+
+// explain calls found with every state reached by applying the unused ops to
+// state in an order that matches all observed results.
+func explain(state stressModel, ops []*stressOp, used []bool, found func(stressModel)) {
+	done := true
+	for i, op := range ops {
+		if used[i] {
+			continue
+		}
+		done = false
+		next := state.clone()
+		if !next.apply(op) {
+			continue
+		}
+		used[i] = true
+		explain(next, ops, used, found)
+		used[i] = false
+	}
+	if done {
+		found(state)
+	}
+}
@@ -0,0 +1,26 @@
+package queuetest
+
+import (
+	"testing"
+
+	"github.com/timzifer/committable_queue/codec"
+	"github.com/timzifer/committable_queue/persist"
+	"github.com/timzifer/committable_queue/queue"
+)
+
+func TestSegmentedQueueStress(t *testing.T) {
+	Stress(t, func(t *testing.T, options queue.Options) Queue {
+		return Segmented(queue.NewSegmentedQueue(queue.WithOptions[int](options)))
+	}, StressConfig{})
+}
+
+func TestDurableQueueStress(t *testing.T) {
+	Stress(t, func(t *testing.T, options queue.Options) Queue {
+		q, err := persist.NewDurableQueue[int](t.TempDir(), codec.Gob[int]{}, persist.WithQueueOptions(options), persist.WithSyncWrites(false))
+		if err != nil {
+			t.Fatalf("open failed: %v", err)
+		}
+		t.Cleanup(func() { q.Close() })
+		return q
+	}, StressConfig{Rounds: 100})
+}
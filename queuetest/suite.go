@@ -0,0 +1,226 @@
+package queuetest
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/timzifer/committable_queue/orchestrator"
+	"github.com/timzifer/committable_queue/queue"
+)
+
+// Queue is the behaviour RunQueueSuite checks, for a queue of ints.
+// persist.DurableQueue satisfies it directly; Segmented adapts a
+// queue.SegmentedQueue.
+type Queue interface {
+	orchestrator.Bank
+	PushBackPending(value int) error
+	PopFront() (int, bool, error)
+	LenVisible() int
+}
+
+// Factory creates an empty queue configured with options. It returns nil when
+// the implementation does not support options, which skips the tests that
+// need them.
+type Factory func(t *testing.T, options queue.Options) Queue
+
+// Segmented adapts a SegmentedQueue to Queue.
+func Segmented(q *queue.SegmentedQueue[int]) Queue {
+	return segmented{q}
+}
+
+type segmented struct {
+	*queue.SegmentedQueue[int]
+}
+
+func (s segmented) PushBackPending(value int) error {
+	s.SegmentedQueue.PushBackPending(value)
+	return nil
+}
+
+func (s segmented) PopFront() (int, bool, error) {
+	v, ok := s.SegmentedQueue.PopFront()
+	return v, ok, nil
+}
+
+// RunQueueSuite checks that the queues made by factory behave like the
+// reference queue.SegmentedQueue: pending elements stay invisible until their
+// commit is published, an aborted commit returns its elements in their
+// original order, publish and abort take effect at most once, and the
+// DropOldest and DropNewest overflow policies keep the same elements.
+func RunQueueSuite(t *testing.T, factory Factory) {
+	t.Helper()
+	for _, test := range []struct {
+		name    string
+		options queue.Options
+		run     func(*suite)
+	}{
+		{"CommitBarrier", queue.Options{}, (*suite).commitBarrier},
+		{"PushAfterPrepareStaysPending", queue.Options{}, (*suite).pushAfterPrepare},
+		{"AbortRestoresOrder", queue.Options{}, (*suite).abortRestoresOrder},
+		{"PublishIsIdempotent", queue.Options{}, (*suite).publishIdempotent},
+		{"AbortIsIdempotent", queue.Options{}, (*suite).abortIdempotent},
+		{"EmptyPrepare", queue.Options{}, (*suite).emptyPrepare},
+		{"CancelledPrepare", queue.Options{}, (*suite).cancelledPrepare},
+		{"DropOldest", queue.Options{MaxLen: 3, DropPolicy: queue.DropOldest}, (*suite).dropOldest},
+		{"DropNewest", queue.Options{MaxLen: 3, DropPolicy: queue.DropNewest}, (*suite).dropNewest},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			q := factory(t, test.options)
+			if q == nil {
+				t.Skipf("options %+v not supported", test.options)
+			}
+			test.run(&suite{t: t, q: q})
+		})
+	}
+}
+
+type suite struct {
+	t *testing.T
+	q Queue
+}
+
+func (s *suite) push(values ...int) {
+	s.t.Helper()
+	for _, v := range values {
+		if err := s.q.PushBackPending(v); err != nil {
+			s.t.Fatalf("push %d failed: %v", v, err)
+		}
+	}
+}
+
+func (s *suite) prepare() (publish, abort func()) {
+	s.t.Helper()
+	publish, abort, err := s.q.PrepareCommit(context.Background())
+	if err != nil {
+		s.t.Fatalf("prepare failed: %v", err)
+	}
+	if publish == nil {
+		publish = func() {}
+	}
+	if abort == nil {
+		abort = func() {}
+	}
+	return publish, abort
+}
+
+func (s *suite) commit() {
+	s.t.Helper()
+	publish, _ := s.prepare()
+	publish()
+}
+
+// expectVisible pops every visible element and compares them with want.
+func (s *suite) expectVisible(want ...int) {
+	s.t.Helper()
+	if n := s.q.LenVisible(); n != len(want) {
+		s.t.Fatalf("expected %d visible elements, got %d", len(want), n)
+	}
+	var got []int
+	for {
+		v, ok, err := s.q.PopFront()
+		if err != nil {
+			s.t.Fatalf("pop failed: %v", err)
+		}
+		if !ok {
+			break
+		}
+		got = append(got, v)
+	}
+	if !slices.Equal(got, want) {
+		s.t.Fatalf("expected visible %v, got %v", want, got)
+	}
+}
+
+func (s *suite) commitBarrier() {
+	s.push(1, 2)
+	s.expectVisible()
+	publish, _ := s.prepare()
+	s.expectVisible()
+	publish()
+	s.expectVisible(1, 2)
+}
+
+func (s *suite) pushAfterPrepare() {
+	s.push(1)
+	publish, _ := s.prepare()
+	s.push(2)
+	publish()
+	s.expectVisible(1)
+	s.commit()
+	s.expectVisible(2)
+}
+
+func (s *suite) abortRestoresOrder() {
+	s.push(1, 2)
+	_, abort := s.prepare()
+	s.push(3)
+	abort()
+	s.expectVisible()
+	s.commit()
+	s.expectVisible(1, 2, 3)
+}
+
+func (s *suite) publishIdempotent() {
+	s.push(1)
+	publish, abort := s.prepare()
+	publish()
+	publish()
+	abort()
+	s.expectVisible(1)
+	s.commit()
+	s.expectVisible()
+}
+
+func (s *suite) abortIdempotent() {
+	s.push(1)
+	publish, abort := s.prepare()
+	abort()
+	abort()
+	publish()
+	s.expectVisible()
+	s.commit()
+	s.expectVisible(1)
+}
+
+func (s *suite) emptyPrepare() {
+	publish, abort := s.prepare()
+	publish()
+	abort()
+	s.expectVisible()
+}
+
+func (s *suite) cancelledPrepare() {
+	s.push(1)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, _, err := s.q.PrepareCommit(ctx); err == nil {
+		s.t.Fatal("expected prepare with a cancelled context to fail")
+	}
+	s.commit()
+	s.expectVisible(1)
+}
+
+func (s *suite) dropOldest() {
+	s.push(1, 2, 3, 4, 5)
+	s.commit()
+	s.expectVisible(3, 4, 5)
+
+	s.push(1, 2, 3)
+	s.commit()
+	s.push(4)
+	s.commit()
+	s.expectVisible(2, 3, 4)
+}
+
+func (s *suite) dropNewest() {
+	s.push(1, 2, 3, 4, 5)
+	s.commit()
+	s.expectVisible(1, 2, 3)
+
+	s.push(1, 2, 3)
+	s.commit()
+	s.push(4)
+	s.commit()
+	s.expectVisible(1, 2, 3)
+}
@@ -0,0 +1,112 @@
+package serve
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"sync"
+
+	"github.com/timzifer/committable_queue/codec"
+)
+
+// RemoteError is an error the server reported in a StatusError response.
+type RemoteError struct {
+	Message string
+}
+
+func (e *RemoteError) Error() string {
+	return "serve: remote: " + e.Message
+}
+
+// Client speaks the protocol of a Server. It is the Go counterpart of the
+// clients other languages implement from the package documentation, and is
+// safe for concurrent use; requests are sent one at a time.
+type Client[T any] struct {
+	codec codec.Codec[T]
+
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+// Dial connects to the server listening on the unix socket at path.
+func Dial[T any](path string, c codec.Codec[T]) (*Client[T], error) {
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	return NewClient(conn, c), nil
+}
+
+// NewClient returns a client that talks to a server over conn.
+func NewClient[T any](conn net.Conn, c codec.Codec[T]) *Client[T] {
+	return &Client[T]{codec: c, conn: conn}
+}
+
+// Close closes the connection.
+func (c *Client[T]) Close() error {
+	return c.conn.Close()
+}
+
+// Push appends value to the pending segment of the served queue.
+func (c *Client[T]) Push(value T) error {
+	data, err := c.codec.Marshal(value)
+	if err != nil {
+		return err
+	}
+	_, _, err = c.roundTrip(OpPush, data)
+	return err
+}
+
+// Pop removes the oldest visible element. It reports false when the queue has
+// nothing visible.
+func (c *Client[T]) Pop() (zero T, _ bool, _ error) {
+	status, payload, err := c.roundTrip(OpPop, nil)
+	if err != nil || status == StatusEmpty {
+		return zero, false, err
+	}
+	v, err := c.codec.Unmarshal(payload)
+	if err != nil {
+		return zero, false, err
+	}
+	return v, true, nil
+}
+
+// Commit publishes the pending elements.
+func (c *Client[T]) Commit() error {
+	_, _, err := c.roundTrip(OpCommit, nil)
+	return err
+}
+
+// Stats returns the counters of the served queue.
+func (c *Client[T]) Stats() (Stats, error) {
+	var stats Stats
+	_, payload, err := c.roundTrip(OpStats, nil)
+	if err != nil {
+		return stats, err
+	}
+	err = json.Unmarshal(payload, &stats)
+	return stats, err
+}
+
+// roundTrip sends one request and reads its response. StatusError is turned
+// into a RemoteError.
+func (c *Client[T]) roundTrip(op Op, payload []byte) (Status, []byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := writeFrame(c.conn, byte(op), payload); err != nil {
+		return 0, nil, err
+	}
+	response, err := readFrame(c.conn)
+	if err != nil {
+		return 0, nil, err
+	}
+	if len(response) == 0 {
+		return 0, nil, errors.New("serve: empty response")
+	}
+	status, payload := Status(response[0]), response[1:]
+	if status == StatusError {
+		return status, nil, &RemoteError{Message: string(payload)}
+	}
+	return status, payload, nil
+}
@@ -0,0 +1,288 @@
+// Package serve exposes a queue over a unix domain socket, so sidecar
+// processes on the same host, written in any language, can share its buffer.
+//
+// The protocol is request/response over a stream connection. Every message is
+// a frame of a 4-byte big-endian length followed by that many bytes. The
+// first byte of a request is its op, the first byte of a response its status:
+//
+//	request   op | payload
+//	response  status | payload
+//
+//	op 1 push    payload is an element encoded with the server's codec
+//	op 2 pop     no payload; responds with the element or StatusEmpty
+//	op 3 commit  no payload; publishes the pending elements
+//	op 4 stats   no payload; responds with Stats as JSON
+//
+//	status 0 ok, 1 empty, 2 error with a UTF-8 message as payload
+//
+// A connection may send any number of requests; each is answered before the
+// next one is read.
+package serve
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sync"
+
+	"github.com/timzifer/committable_queue/codec"
+	"github.com/timzifer/committable_queue/orchestrator"
+	"github.com/timzifer/committable_queue/queue"
+)
+
+// ErrServerClosed is returned by Serve after Close.
+var ErrServerClosed = errors.New("serve: server closed")
+
+// Op is the first byte of a request.
+type Op byte
+
+const (
+	OpPush Op = iota + 1
+	OpPop
+	OpCommit
+	OpStats
+)
+
+// Status is the first byte of a response.
+type Status byte
+
+const (
+	StatusOK Status = iota
+	StatusEmpty
+	StatusError
+)
+
+// MaxFrame bounds the length of a frame. Longer frames close the connection.
+const MaxFrame = 1 << 24
+
+// Stats is the response to OpStats.
+type Stats struct {
+	Visible  int    `json:"visible"`
+	InFlight int    `json:"in_flight"`
+	Pushes   uint64 `json:"pushes"`
+	Pops     uint64 `json:"pops"`
+	Commits  uint64 `json:"commits"`
+	Aborts   uint64 `json:"aborts"`
+	Dropped  uint64 `json:"dropped"`
+}
+
+// Server serves one queue. Elements are encoded with a codec on the wire, so
+// clients in other languages must agree on it; codec.JSON is the natural
+// choice for them.
+type Server[T any] struct {
+	queue  *queue.SegmentedQueue[T]
+	codec  codec.Codec[T]
+	commit func(context.Context) error
+
+	mu        sync.Mutex
+	listeners map[net.Listener]struct{}
+	conns     map[net.Conn]struct{}
+	closed    bool
+	wg        sync.WaitGroup
+}
+
+// Option configures a Server.
+type Option[T any] func(*Server[T])
+
+// WithOrchestrator commits through o instead of the queue alone, so a commit
+// request publishes the queue together with the other banks of o.
+func WithOrchestrator[T any](o *orchestrator.CommitOrchestrator) Option[T] {
+	return func(s *Server[T]) {
+		s.commit = o.CommitAll
+	}
+}
+
+// NewServer creates a server for q whose elements are encoded with c.
+func NewServer[T any](q *queue.SegmentedQueue[T], c codec.Codec[T], options ...Option[T]) *Server[T] {
+	s := &Server[T]{
+		queue:     q,
+		codec:     c,
+		listeners: make(map[net.Listener]struct{}),
+		conns:     make(map[net.Conn]struct{}),
+	}
+	s.commit = func(context.Context) error {
+		q.Commit()
+		return nil
+	}
+	for _, option := range options {
+		option(s)
+	}
+	return s
+}
+
+// ListenAndServe listens on the unix socket at path and serves it. A stale
+// socket file left behind by a previous process is removed first.
+func (s *Server[T]) ListenAndServe(path string) error {
+	if info, err := os.Stat(path); err == nil && info.Mode()&os.ModeSocket != 0 {
+		os.Remove(path)
+	}
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return err
+	}
+	return s.Serve(l)
+}
+
+// Serve accepts connections on l until Close is called, and then returns
+// ErrServerClosed. l is closed when Serve returns.
+func (s *Server[T]) Serve(l net.Listener) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		l.Close()
+		return ErrServerClosed
+	}
+	s.listeners[l] = struct{}{}
+	s.mu.Unlock()
+
+	defer l.Close()
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			s.mu.Lock()
+			closed := s.closed
+			delete(s.listeners, l)
+			s.mu.Unlock()
+			if closed {
+				return ErrServerClosed
+			}
+			return err
+		}
+		if !s.track(conn) {
+			conn.Close()
+			return ErrServerClosed
+		}
+		go s.handle(conn)
+	}
+}
+
+// track registers conn, unless the server is closed.
+func (s *Server[T]) track(conn net.Conn) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return false
+	}
+	s.conns[conn] = struct{}{}
+	s.wg.Add(1)
+	return true
+}
+
+// Close stops all listeners, closes every connection and waits for the
+// requests in progress to finish.
+func (s *Server[T]) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	for l := range s.listeners {
+		l.Close()
+	}
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+	return nil
+}
+
+func (s *Server[T]) handle(conn net.Conn) {
+	defer func() {
+		conn.Close()
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+		s.wg.Done()
+	}()
+
+	for {
+		request, err := readFrame(conn)
+		if err != nil {
+			return
+		}
+		status, payload := s.dispatch(request)
+		if err := writeFrame(conn, byte(status), payload); err != nil {
+			return
+		}
+	}
+}
+
+// dispatch executes one request.
+func (s *Server[T]) dispatch(request []byte) (Status, []byte) {
+	if len(request) == 0 {
+		return StatusError, []byte("empty request")
+	}
+	switch Op(request[0]) {
+	case OpPush:
+		v, err := s.codec.Unmarshal(request[1:])
+		if err != nil {
+			return StatusError, []byte(err.Error())
+		}
+		s.queue.PushBackPending(v)
+		return StatusOK, nil
+	case OpPop:
+		v, ok := s.queue.PopFront()
+		if !ok {
+			return StatusEmpty, nil
+		}
+		data, err := s.codec.Marshal(v)
+		if err != nil {
+			return StatusError, []byte(err.Error())
+		}
+		return StatusOK, data
+	case OpCommit:
+		if err := s.commit(context.Background()); err != nil {
+			return StatusError, []byte(err.Error())
+		}
+		return StatusOK, nil
+	case OpStats:
+		m := s.queue.Metrics()
+		data, err := json.Marshal(Stats{
+			Visible:  s.queue.LenVisible(),
+			InFlight: s.queue.LenInFlight(),
+			Pushes:   m.Pushes,
+			Pops:     m.Pops,
+			Commits:  m.Commits,
+			Aborts:   m.Aborts,
+			Dropped:  m.Dropped,
+		})
+		if err != nil {
+			return StatusError, []byte(err.Error())
+		}
+		return StatusOK, data
+	}
+	return StatusError, fmt.Appendf(nil, "unknown op %d", request[0])
+}
+
+// readFrame reads one frame. Every frame gets its own buffer, as codecs may
+// keep referencing the bytes they decode.
+func readFrame(r io.Reader) ([]byte, error) {
+	var header [4]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, err
+	}
+	n := binary.BigEndian.Uint32(header[:])
+	if n > MaxFrame {
+		return nil, fmt.Errorf("serve: frame of %d bytes exceeds MaxFrame", n)
+	}
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return buf, nil
+}
+
+// writeFrame writes head and payload as one frame.
+func writeFrame(w io.Writer, head byte, payload []byte) error {
+	frame := make([]byte, 5, 5+len(payload))
+	binary.BigEndian.PutUint32(frame, uint32(1+len(payload)))
+	frame[4] = head
+	_, err := w.Write(append(frame, payload...))
+	return err
+}
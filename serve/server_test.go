@@ -0,0 +1,114 @@
+package serve
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+	"path/filepath"
+	"testing"
+
+	"github.com/timzifer/committable_queue/codec"
+	"github.com/timzifer/committable_queue/orchestrator"
+	"github.com/timzifer/committable_queue/queue"
+)
+
+// start serves s on a socket in a temporary directory and returns its path.
+func start[T any](t *testing.T, s *Server[T]) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "queue.sock")
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatalf("listen failed: %v", err)
+	}
+	done := make(chan error, 1)
+	go func() { done <- s.Serve(l) }()
+	t.Cleanup(func() {
+		s.Close()
+		if err := <-done; !errors.Is(err, ErrServerClosed) {
+			t.Errorf("expected ErrServerClosed, got %v", err)
+		}
+	})
+	return path
+}
+
+func TestServerPushCommitPop(t *testing.T) {
+	q := queue.NewSegmentedQueue[string]()
+	path := start(t, NewServer(q, codec.JSON[string]{}))
+
+	c, err := Dial(path, codec.JSON[string]{})
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	defer c.Close()
+
+	for _, v := range []string{"a", "b"} {
+		if err := c.Push(v); err != nil {
+			t.Fatalf("push failed: %v", err)
+		}
+	}
+	if _, ok, err := c.Pop(); ok || err != nil {
+		t.Fatalf("pending elements must not be visible, got %v %v", ok, err)
+	}
+	if err := c.Commit(); err != nil {
+		t.Fatalf("commit failed: %v", err)
+	}
+	if v, ok, err := c.Pop(); !ok || err != nil || v != "a" {
+		t.Fatalf("expected a, got %q %v %v", v, ok, err)
+	}
+
+	stats, err := c.Stats()
+	if err != nil {
+		t.Fatalf("stats failed: %v", err)
+	}
+	if stats.Visible != 1 || stats.Pushes != 2 || stats.Pops != 1 || stats.Commits != 1 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+	if q.LenVisible() != 1 {
+		t.Fatal("expected the server to operate on the shared queue")
+	}
+}
+
+func TestServerCommitsThroughOrchestrator(t *testing.T) {
+	q := queue.NewSegmentedQueue[int]()
+	strict := queue.NewSegmentedQueue(queue.WithCommitFilter(func(int) error {
+		return errors.New("rejected")
+	}), queue.WithStrictCommitFilter[int]())
+	strict.PushBackPending(1)
+	o := orchestrator.NewCommitOrchestrator(orchestrator.WithBanks(q, strict))
+	path := start(t, NewServer(q, codec.JSON[int]{}, WithOrchestrator[int](o)))
+
+	c, err := Dial(path, codec.JSON[int]{})
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	defer c.Close()
+	c.Push(1)
+
+	var remote *RemoteError
+	if err := c.Commit(); !errors.As(err, &remote) {
+		t.Fatalf("expected the orchestrator's error, got %v", err)
+	}
+	if q.LenVisible() != 0 {
+		t.Fatal("a failed commit must not publish the queue")
+	}
+}
+
+func TestServerRejectsMalformedRequests(t *testing.T) {
+	path := start(t, NewServer(queue.NewSegmentedQueue[int](), codec.JSON[int]{}))
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	defer conn.Close()
+
+	for _, request := range [][]byte{{}, {99}, {byte(OpPush), '{'}} {
+		frame := binary.BigEndian.AppendUint32(nil, uint32(len(request)))
+		if _, err := conn.Write(append(frame, request...)); err != nil {
+			t.Fatal(err)
+		}
+		response, err := readFrame(conn)
+		if err != nil || Status(response[0]) != StatusError {
+			t.Fatalf("expected an error response for %v, got %v %v", request, response, err)
+		}
+	}
+}
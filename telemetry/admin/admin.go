@@ -0,0 +1,102 @@
+// Package admin stellt die Queues einer queue.Registry über HTTP als JSON
+// bereit, damit sich etwa die Queue mit dem größten Rückstau ohne eigene
+// Verdrahtung finden lässt:
+//
+//	GET /queues               alle Queues, die tiefste zuerst, samt Summen
+//	GET /queues/{name}        eine einzelne Queue
+//	GET /queues/{name}/debug  die Interna einer Queue als Text (siehe queue.DebugInfo)
+//
+// Der Handler wird üblicherweise unter einem Präfix eingehängt:
+//
+//	mux.Handle("/admin/", http.StripPrefix("/admin", admin.Handler(queue.DefaultRegistry)))
+package admin
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/timzifer/committable_queue/queue"
+)
+
+// QueueStats ist die JSON-Darstellung einer Queue.
+type QueueStats struct {
+	Name    string `json:"name"`
+	Visible int    `json:"visible"`
+	Pushes  uint64 `json:"pushes"`
+	Pops    uint64 `json:"pops"`
+	Commits uint64 `json:"commits"`
+	Aborts  uint64 `json:"aborts"`
+	Dropped uint64 `json:"dropped"`
+}
+
+// RegistryStats ist die JSON-Darstellung aller Queues einer Registry.
+type RegistryStats struct {
+	Visible int          `json:"visible"`
+	Pushes  uint64       `json:"pushes"`
+	Pops    uint64       `json:"pops"`
+	Dropped uint64       `json:"dropped"`
+	Queues  []QueueStats `json:"queues"`
+}
+
+func queueStats(name string, visible int, m queue.QueueMetrics) QueueStats {
+	return QueueStats{
+		Name:    name,
+		Visible: visible,
+		Pushes:  m.Pushes,
+		Pops:    m.Pops,
+		Commits: m.Commits,
+		Aborts:  m.Aborts,
+		Dropped: m.Dropped,
+	}
+}
+
+// Handler liefert einen http.Handler über die Queues von r.
+func Handler(r *queue.Registry) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /queues", func(w http.ResponseWriter, _ *http.Request) {
+		s := r.Stats()
+		stats := RegistryStats{
+			Visible: s.Visible,
+			Pushes:  s.Pushes,
+			Pops:    s.Pops,
+			Dropped: s.Dropped,
+			Queues:  make([]QueueStats, len(s.Queues)),
+		}
+		for i, q := range s.Queues {
+			stats.Queues[i] = queueStats(q.Name, q.Visible, q.Metrics)
+		}
+		writeJSON(w, stats)
+	})
+	mux.HandleFunc("GET /queues/{name}", func(w http.ResponseWriter, req *http.Request) {
+		name := req.PathValue("name")
+		q, ok := r.Get(name)
+		if !ok {
+			http.Error(w, "unknown queue", http.StatusNotFound)
+			return
+		}
+		writeJSON(w, queueStats(name, q.LenVisible(), q.Metrics()))
+	})
+	mux.HandleFunc("GET /queues/{name}/debug", func(w http.ResponseWriter, req *http.Request) {
+		q, ok := r.Get(req.PathValue("name"))
+		if !ok {
+			http.Error(w, "unknown queue", http.StatusNotFound)
+			return
+		}
+		// Debug nimmt nur kurz die Locks der einzelnen Segmente und ist daher
+		// auch im laufenden Betrieb unbedenklich.
+		d, ok := q.(queue.Debuggable)
+		if !ok {
+			http.Error(w, "queue has no debug view", http.StatusNotImplemented)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		io.WriteString(w, d.Debug().String())
+	})
+	return mux
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,79 @@
+package admin
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/timzifer/committable_queue/queue"
+)
+
+func TestHandler(t *testing.T) {
+	registry := queue.NewRegistry()
+	orders := queue.NewSegmentedQueue(queue.WithRegistry[int](registry, "orders"))
+	queue.NewSegmentedQueue(queue.WithRegistry[int](registry, "events"))
+	orders.PushBackPending(1)
+	orders.PushBackPending(2)
+	orders.Commit()
+
+	server := httptest.NewServer(http.StripPrefix("/admin", Handler(registry)))
+	defer server.Close()
+
+	var all RegistryStats
+	get(t, server.URL+"/admin/queues", http.StatusOK, &all)
+	if all.Visible != 2 || len(all.Queues) != 2 || all.Queues[0].Name != "orders" {
+		t.Fatalf("unexpected registry stats: %+v", all)
+	}
+
+	var one QueueStats
+	get(t, server.URL+"/admin/queues/orders", http.StatusOK, &one)
+	if one.Visible != 2 || one.Pushes != 2 || one.Commits != 1 {
+		t.Fatalf("unexpected queue stats: %+v", one)
+	}
+
+	get(t, server.URL+"/admin/queues/missing", http.StatusNotFound, nil)
+}
+
+func TestHandlerDebug(t *testing.T) {
+	registry := queue.NewRegistry()
+	q := queue.NewSegmentedQueue(queue.WithRegistry[string](registry, "orders"))
+	q.PushBackPending("first")
+	q.Commit()
+	registry.Register("priority", queue.NewPriorityQueue[int](queue.Options{}))
+
+	server := httptest.NewServer(Handler(registry))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/queues/orders/debug")
+	if err != nil {
+		t.Fatalf("GET failed: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !strings.Contains(string(body), `"first"`) {
+		t.Fatalf("unexpected debug response %d:\n%s", resp.StatusCode, body)
+	}
+
+	get(t, server.URL+"/queues/priority/debug", http.StatusNotImplemented, nil)
+}
+
+func get(t *testing.T, url string, status int, v any) {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("GET %s failed: %v", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != status {
+		t.Fatalf("GET %s: status %d, want %d", url, resp.StatusCode, status)
+	}
+	if v == nil {
+		return
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		t.Fatalf("GET %s: invalid JSON: %v", url, err)
+	}
+}
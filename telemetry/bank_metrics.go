@@ -0,0 +1,122 @@
+package telemetry
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// BankMetrics fasst Messwerte einer einzelnen Bank zusammen.
+type BankMetrics struct {
+	prepareDuration atomic.Int64
+	publishDuration atomic.Int64
+	prepares        atomic.Uint64
+	publishes       atomic.Uint64
+	failures        atomic.Uint64
+	aborts          atomic.Uint64
+	abortFailures   atomic.Uint64
+	trips           atomic.Uint64
+
+	batchSizesOnce sync.Once
+	batchSizes     *Histogram
+}
+
+// BankSnapshot enthält die aggregierten Werte einer Bank.
+type BankSnapshot struct {
+	Name          string
+	Prepares      uint64
+	Publishes     uint64
+	Failures      uint64
+	Aborts        uint64
+	AbortFailures uint64
+	// Trips zählt, wie oft der Circuit Breaker der Bank ausgelöst hat.
+	Trips          uint64
+	PrepareAverage time.Duration
+	PublishAverage time.Duration
+	// Elements ist die Summe der Elemente aller veröffentlichten Commits,
+	// BatchP50 bis BatchP99 sind Quantile der Elemente je Commit (siehe
+	// BatchSizes). Es zählen nur Banken, die ReportElements aufrufen.
+	Elements uint64
+	BatchP50 int64
+	BatchP95 int64
+	BatchP99 int64
+}
+
+// ObservePrepare meldet die Dauer eines PrepareCommit-Aufrufs und zählt Fehler.
+func (m *BankMetrics) ObservePrepare(elapsed time.Duration, err error) {
+	m.prepares.Add(1)
+	m.prepareDuration.Add(elapsed.Nanoseconds())
+	if err != nil {
+		m.failures.Add(1)
+	}
+}
+
+// ObservePublish meldet die Dauer eines Publish-Callbacks.
+func (m *BankMetrics) ObservePublish(elapsed time.Duration) {
+	m.publishes.Add(1)
+	m.publishDuration.Add(elapsed.Nanoseconds())
+}
+
+// ObserveAbort zählt einen Abort-Callback und seinen Fehler.
+func (m *BankMetrics) ObserveAbort(err error) {
+	m.aborts.Add(1)
+	if err != nil {
+		m.abortFailures.Add(1)
+	}
+}
+
+// ObserveTrip zählt ein Auslösen des Circuit Breakers.
+func (m *BankMetrics) ObserveTrip() {
+	m.trips.Add(1)
+}
+
+// ObserveBatch erfasst die Zahl der Elemente, die ein veröffentlichter Commit
+// der Bank bewegt hat.
+func (m *BankMetrics) ObserveBatch(elements int) {
+	m.BatchSizes().Observe(int64(elements))
+}
+
+// BatchSizes liefert das Histogramm über die Zahl der Elemente je
+// veröffentlichtem Commit mit den Grenzen DefaultBatchSizeBuckets.
+func (m *BankMetrics) BatchSizes() *Histogram {
+	m.batchSizesOnce.Do(func() {
+		m.batchSizes = NewHistogram(DefaultBatchSizeBuckets)
+	})
+	return m.batchSizes
+}
+
+// Snapshot gibt die gesammelten Werte zurück.
+func (m *BankMetrics) Snapshot() BankSnapshot {
+	s := BankSnapshot{
+		Prepares:      m.prepares.Load(),
+		Publishes:     m.publishes.Load(),
+		Failures:      m.failures.Load(),
+		Aborts:        m.aborts.Load(),
+		AbortFailures: m.abortFailures.Load(),
+		Trips:         m.trips.Load(),
+	}
+	if h := m.BatchSizes(); h.Count() > 0 {
+		s.Elements = uint64(h.Sum())
+		s.BatchP50, s.BatchP95, s.BatchP99 = h.Quantile(0.50), h.Quantile(0.95), h.Quantile(0.99)
+	}
+	if s.Prepares > 0 {
+		s.PrepareAverage = time.Duration(m.prepareDuration.Load() / int64(s.Prepares))
+	}
+	if s.Publishes > 0 {
+		s.PublishAverage = time.Duration(m.publishDuration.Load() / int64(s.Publishes))
+	}
+	return s
+}
+
+// Reset setzt alle Zähler zurück.
+func (m *BankMetrics) Reset() {
+	m.prepareDuration.Store(0)
+	m.publishDuration.Store(0)
+	m.prepares.Store(0)
+	m.publishes.Store(0)
+	m.failures.Store(0)
+	m.aborts.Store(0)
+	m.abortFailures.Store(0)
+	m.trips.Store(0)
+	m.BatchSizes().Reset()
+}
@@ -0,0 +1,46 @@
+package telemetry
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBankMetricsRecordsPrepareAndPublish(t *testing.T) {
+	var metrics BankMetrics
+
+	metrics.ObservePrepare(2*time.Millisecond, nil)
+	metrics.ObservePrepare(4*time.Millisecond, errors.New("prepare failed"))
+	metrics.ObservePublish(6 * time.Millisecond)
+
+	s := metrics.Snapshot()
+	if s.Prepares != 2 || s.Failures != 1 || s.Publishes != 1 {
+		t.Fatalf("unexpected counters: %+v", s)
+	}
+	if s.PrepareAverage != 3*time.Millisecond {
+		t.Fatalf("expected prepare average 3ms, got %v", s.PrepareAverage)
+	}
+	if s.PublishAverage != 6*time.Millisecond {
+		t.Fatalf("expected publish average 6ms, got %v", s.PublishAverage)
+	}
+
+	metrics.Reset()
+	if s := metrics.Snapshot(); s != (BankSnapshot{}) {
+		t.Fatalf("expected metrics to reset to zero, got %+v", s)
+	}
+}
+
+func TestBankMetricsBatchSizes(t *testing.T) {
+	var metrics BankMetrics
+	for _, n := range []int{10, 10, 10, 4000} {
+		metrics.ObserveBatch(n)
+	}
+
+	s := metrics.Snapshot()
+	if s.Elements != 4030 || s.BatchP50 > 10 || s.BatchP99 <= 1000 {
+		t.Fatalf("unexpected batch sizes: %+v", s)
+	}
+	if got := metrics.BatchSizes().Count(); got != 4 {
+		t.Fatalf("expected 4 batches, got %d", got)
+	}
+}
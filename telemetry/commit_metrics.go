@@ -0,0 +1,108 @@
+package telemetry
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// CommitMetrics fasst Messwerte zu Commit-Versuchen zusammen.
+type CommitMetrics struct {
+	totalDuration atomic.Int64
+	attempts      atomic.Uint64
+	failures      atomic.Uint64
+
+	durationsOnce sync.Once
+	durations     *Histogram
+}
+
+// CommitSnapshot enthält die aggregierten Commit-Messwerte.
+type CommitSnapshot struct {
+	Attempts uint64
+	Failures uint64
+	Average  time.Duration
+	P50      time.Duration
+	P95      time.Duration
+	P99      time.Duration
+	// Buckets enthält die kumulativen Zählerstände des Dauer-Histogramms mit
+	// Grenzen in Nanosekunden (siehe DefaultDurationBuckets).
+	Buckets []Bucket
+}
+
+// NewCommitMetrics erzeugt einen eigenständigen Satz Commit-Metriken. Jeder
+// Orchestrator besitzt eine eigene Instanz, sofern ihm keine gemeinsame
+// übergeben wird.
+func NewCommitMetrics() *CommitMetrics {
+	return &CommitMetrics{}
+}
+
+// TraceCommit startet ein Commit-Span und liefert eine Abschlusstfunktion, die Dauer und Fehlerzustand meldet.
+func (m *CommitMetrics) TraceCommit(ctx context.Context) (context.Context, func(error)) {
+	start := time.Now()
+	m.CommitStarted(0)
+	ctx, endSpan := StartSpan(ctx, "CommitAll")
+	return ctx, func(err error) {
+		m.CommitFinished(0, time.Since(start), err)
+		endSpan(err)
+	}
+}
+
+// CommitStarted zählt einen Commit-Versuch. Zusammen mit CommitFinished ist
+// CommitMetrics die Standard-MetricsSink des Orchestrators.
+func (m *CommitMetrics) CommitStarted(uint64) {
+	m.attempts.Add(1)
+}
+
+// CommitFinished erfasst Dauer und Fehlerzustand eines Commit-Versuchs.
+func (m *CommitMetrics) CommitFinished(_ uint64, elapsed time.Duration, err error) {
+	m.totalDuration.Add(elapsed.Nanoseconds())
+	m.histogram().Observe(elapsed.Nanoseconds())
+	if err != nil {
+		m.failures.Add(1)
+	}
+}
+
+// Dropped wird ignoriert; Verwerfungen zählen die Queues selbst.
+func (m *CommitMetrics) Dropped(string, string, int) {}
+
+// DepthChanged wird ignoriert; Füllstände fragen die Exporter direkt ab.
+func (m *CommitMetrics) DepthChanged(string, int) {}
+
+func (m *CommitMetrics) histogram() *Histogram {
+	m.durationsOnce.Do(func() {
+		m.durations = NewDurationHistogram(DefaultDurationBuckets)
+	})
+	return m.durations
+}
+
+// Snapshot gibt die gesammelten Werte zurück.
+func (m *CommitMetrics) Snapshot() CommitSnapshot {
+	h := m.histogram()
+	s := CommitSnapshot{
+		Attempts: m.attempts.Load(),
+		Failures: m.failures.Load(),
+		Buckets:  h.Buckets(),
+	}
+	if s.Attempts == 0 {
+		return s
+	}
+	s.Average = time.Duration(m.totalDuration.Load() / int64(s.Attempts))
+	s.P50 = time.Duration(h.Quantile(0.50))
+	s.P95 = time.Duration(h.Quantile(0.95))
+	s.P99 = time.Duration(h.Quantile(0.99))
+	return s
+}
+
+// TotalDuration gibt die aufsummierte Dauer aller Commit-Versuche zurück.
+func (m *CommitMetrics) TotalDuration() time.Duration {
+	return time.Duration(m.totalDuration.Load())
+}
+
+// Reset setzt alle Zähler zurück.
+func (m *CommitMetrics) Reset() {
+	m.totalDuration.Store(0)
+	m.attempts.Store(0)
+	m.failures.Store(0)
+	m.histogram().Reset()
+}
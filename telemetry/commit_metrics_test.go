@@ -0,0 +1,79 @@
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCommitMetricsInstancesAreIndependent(t *testing.T) {
+	first := NewCommitMetrics()
+	second := NewCommitMetrics()
+
+	_, finish := first.TraceCommit(context.Background())
+	finish(nil)
+
+	if s := first.Snapshot(); s.Attempts != 1 {
+		t.Fatalf("expected one attempt on first instance, got %d", s.Attempts)
+	}
+	if s := second.Snapshot(); s.Attempts != 0 {
+		t.Fatalf("second instance must not observe commits of the first, got %d", s.Attempts)
+	}
+}
+
+func TestTraceCommitRecordsAttemptsFailuresAndDuration(t *testing.T) {
+	metrics := NewCommitMetrics()
+
+	ctx := context.Background()
+
+	ctx, finish := metrics.TraceCommit(ctx)
+	time.Sleep(time.Millisecond)
+	finish(nil)
+
+	_, finish = metrics.TraceCommit(ctx)
+	finish(errors.New("commit failed"))
+
+	s := metrics.Snapshot()
+	if s.Attempts != 2 {
+		t.Fatalf("expected 2 attempts, got %d", s.Attempts)
+	}
+	if s.Failures != 1 {
+		t.Fatalf("expected 1 failure, got %d", s.Failures)
+	}
+	if s.Average <= 0 {
+		t.Fatalf("expected average duration > 0, got %v", s.Average)
+	}
+
+	metrics.Reset()
+	s = metrics.Snapshot()
+	if s.Attempts != 0 || s.Failures != 0 || s.Average != 0 {
+		t.Fatalf("expected metrics to reset to zero, got attempts=%d failures=%d average=%v", s.Attempts, s.Failures, s.Average)
+	}
+}
+
+func TestTraceCommitRecordsLatencyPercentiles(t *testing.T) {
+	metrics := NewCommitMetrics()
+
+	for i := 0; i < 20; i++ {
+		_, finish := metrics.TraceCommit(context.Background())
+		if i == 19 {
+			time.Sleep(20 * time.Millisecond)
+		}
+		finish(nil)
+	}
+
+	s := metrics.Snapshot()
+	if s.P50 <= 0 || s.P50 > s.P95 || s.P95 > s.P99 {
+		t.Fatalf("percentiles must be positive and ordered: p50=%v p95=%v p99=%v", s.P50, s.P95, s.P99)
+	}
+	if s.P99 < 10*time.Millisecond {
+		t.Fatalf("p99 should reflect the slow commit, got %v", s.P99)
+	}
+	if s.P50 >= 10*time.Millisecond {
+		t.Fatalf("p50 should not be dominated by the slow commit, got %v", s.P50)
+	}
+	if last := s.Buckets[len(s.Buckets)-1]; last.Count != 20 {
+		t.Fatalf("expected all commits within the last bucket, got %d", last.Count)
+	}
+}
@@ -0,0 +1,160 @@
+// Package expvar veröffentlicht Commit-Metriken und Queue-Füllstände über das
+// expvar-Paket der Standardbibliothek.
+package expvar
+
+import (
+	goexpvar "expvar"
+	"fmt"
+	"sync"
+
+	"github.com/timzifer/committable_queue/queue"
+	"github.com/timzifer/committable_queue/telemetry"
+)
+
+// DepthSource liefert den sichtbaren Füllstand einer Queue, etwa
+// *queue.SegmentedQueue.
+type DepthSource = telemetry.DepthSource
+
+// QueueMetricsSource wird von Queues implementiert, die Zähler für Pushes,
+// Pops, Commits und Verwerfungen führen (siehe queue.SegmentedQueue.Metrics).
+type QueueMetricsSource interface {
+	Metrics() queue.QueueMetrics
+}
+
+// ExpvarPublisher veröffentlicht Commit-Metriken und Queue-Füllstände als
+// einzelne expvar-Variable, die unter /debug/vars als JSON-Objekt erscheint:
+//
+//	{"commits": {"<name>": {"attempts": …, "failures": …, "average_ns": …}},
+//	 "queues":  {"<name>": {"visible": …, "pushes": …, "dropped": …}}}
+//
+// Die Zähler einer Queue erscheinen nur, wenn sie QueueMetricsSource implementiert.
+// Queues aus Registries (siehe AddRegistry) erscheinen unter ihrem dort
+// registrierten Namen; mit AddQueue hinzugefügte Queues haben bei gleichem
+// Namen Vorrang.
+type ExpvarPublisher struct {
+	mu         sync.Mutex
+	commits    map[string]*telemetry.CommitMetrics
+	queues     map[string]DepthSource
+	registries []*queue.Registry
+}
+
+// publishMu serialisiert PublishExpvar, damit von zwei gleichzeitigen
+// Aufrufen mit demselben Präfix nur einer die Prüfung besteht.
+var publishMu sync.Mutex
+
+// PublishExpvar registriert einen ExpvarPublisher unter prefix. Da expvar keine
+// Variablen entfernen kann, schlägt ein zweiter Aufruf mit demselben Präfix fehl.
+func PublishExpvar(prefix string) (*ExpvarPublisher, error) {
+	publishMu.Lock()
+	defer publishMu.Unlock()
+
+	if goexpvar.Get(prefix) != nil {
+		return nil, fmt.Errorf("expvar %q already published", prefix)
+	}
+	p := &ExpvarPublisher{
+		commits: make(map[string]*telemetry.CommitMetrics),
+		queues:  make(map[string]DepthSource),
+	}
+	goexpvar.Publish(prefix, goexpvar.Func(p.value))
+	return p, nil
+}
+
+// AddCommitMetrics veröffentlicht die Commit-Metriken eines Orchestrators unter name.
+func (p *ExpvarPublisher) AddCommitMetrics(name string, metrics *telemetry.CommitMetrics) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.commits[name] = metrics
+}
+
+// AddQueue veröffentlicht den Füllstand einer Queue unter name.
+func (p *ExpvarPublisher) AddQueue(name string, q DepthSource) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.queues[name] = q
+}
+
+// AddRegistry veröffentlicht alle Queues, die bei r registriert sind. Die
+// Registry wird bei jedem Abruf neu gelesen, spätere Registrierungen erscheinen
+// also ohne weiteren Aufruf.
+func (p *ExpvarPublisher) AddRegistry(r *queue.Registry) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.registries = append(p.registries, r)
+}
+
+// RemoveQueue entfernt eine zuvor veröffentlichte Queue.
+func (p *ExpvarPublisher) RemoveQueue(name string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.queues, name)
+}
+
+type expvarCommitStats struct {
+	Attempts  uint64 `json:"attempts"`
+	Failures  uint64 `json:"failures"`
+	AverageNS int64  `json:"average_ns"`
+	P50NS     int64  `json:"p50_ns"`
+	P95NS     int64  `json:"p95_ns"`
+	P99NS     int64  `json:"p99_ns"`
+}
+
+type expvarQueueStats struct {
+	Visible int    `json:"visible"`
+	Pushes  uint64 `json:"pushes,omitempty"`
+	Pops    uint64 `json:"pops,omitempty"`
+	Commits uint64 `json:"commits,omitempty"`
+	Aborts  uint64 `json:"aborts,omitempty"`
+	Dropped uint64 `json:"dropped,omitempty"`
+	// Committed ist die Summe der Elemente aller veröffentlichten Commits;
+	// zusammen mit commits ergibt sich die mittlere Commit-Größe.
+	Committed uint64 `json:"committed,omitempty"`
+}
+
+func (p *ExpvarPublisher) value() any {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	commits := make(map[string]expvarCommitStats, len(p.commits))
+	for name, metrics := range p.commits {
+		s := metrics.Snapshot()
+		commits[name] = expvarCommitStats{
+			Attempts:  s.Attempts,
+			Failures:  s.Failures,
+			AverageNS: s.Average.Nanoseconds(),
+			P50NS:     s.P50.Nanoseconds(),
+			P95NS:     s.P95.Nanoseconds(),
+			P99NS:     s.P99.Nanoseconds(),
+		}
+	}
+
+	queues := make(map[string]expvarQueueStats, len(p.queues))
+	for _, r := range p.registries {
+		for _, s := range r.Stats().Queues {
+			queues[s.Name] = queueStats(s.Visible, s.Metrics)
+		}
+	}
+	for name, q := range p.queues {
+		stats := expvarQueueStats{Visible: q.LenVisible()}
+		if source, ok := q.(QueueMetricsSource); ok {
+			stats = queueStats(stats.Visible, source.Metrics())
+		}
+		queues[name] = stats
+	}
+
+	return map[string]any{
+		"commits": commits,
+		"queues":  queues,
+	}
+}
+
+func queueStats(visible int, m queue.QueueMetrics) expvarQueueStats {
+	return expvarQueueStats{
+		Visible:   visible,
+		Pushes:    m.Pushes,
+		Pops:      m.Pops,
+		Commits:   m.Commits,
+		Aborts:    m.Aborts,
+		Dropped:   m.Dropped,
+		Committed: m.CommittedElements,
+	}
+}
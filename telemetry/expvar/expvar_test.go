@@ -0,0 +1,124 @@
+package expvar
+
+import (
+	"context"
+	"encoding/json"
+	goexpvar "expvar"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/timzifer/committable_queue/queue"
+	"github.com/timzifer/committable_queue/telemetry"
+)
+
+type fixedDepth int
+
+func (d fixedDepth) LenVisible() int {
+	return int(d)
+}
+
+func TestPublishExpvar(t *testing.T) {
+	publisher, err := PublishExpvar("committable_queue_test")
+	if err != nil {
+		t.Fatalf("publish failed: %v", err)
+	}
+	if _, err := PublishExpvar("committable_queue_test"); err == nil {
+		t.Fatalf("expected error when publishing the same prefix twice")
+	}
+
+	metrics := telemetry.NewCommitMetrics()
+	_, finish := metrics.TraceCommit(context.Background())
+	finish(nil)
+
+	publisher.AddCommitMetrics("main", metrics)
+	publisher.AddQueue("sensors", fixedDepth(7))
+	publisher.AddQueue("gone", fixedDepth(1))
+
+	q := queue.NewSegmentedQueue[int]()
+	q.PushBackPending(1)
+	q.Commit()
+	publisher.AddQueue("segmented", q)
+	publisher.RemoveQueue("gone")
+
+	var decoded struct {
+		Commits map[string]struct {
+			Attempts uint64 `json:"attempts"`
+			Failures uint64 `json:"failures"`
+		} `json:"commits"`
+		Queues map[string]struct {
+			Visible int    `json:"visible"`
+			Pushes  uint64 `json:"pushes"`
+			Commits uint64 `json:"commits"`
+		} `json:"queues"`
+	}
+	if err := json.Unmarshal([]byte(goexpvar.Get("committable_queue_test").String()), &decoded); err != nil {
+		t.Fatalf("expvar output is not valid JSON: %v", err)
+	}
+
+	if got := decoded.Commits["main"]; got.Attempts != 1 || got.Failures != 0 {
+		t.Fatalf("unexpected commit stats: %+v", got)
+	}
+	if len(decoded.Queues) != 2 || decoded.Queues["sensors"].Visible != 7 {
+		t.Fatalf("unexpected queue depths: %+v", decoded.Queues)
+	}
+	if got := decoded.Queues["segmented"]; got.Visible != 1 || got.Pushes != 1 || got.Commits != 1 {
+		t.Fatalf("unexpected queue counters: %+v", got)
+	}
+}
+
+func TestPublishExpvarConcurrently(t *testing.T) {
+	var wg sync.WaitGroup
+	var published atomic.Int32
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := PublishExpvar("committable_queue_concurrent_test"); err == nil {
+				published.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := published.Load(); n != 1 {
+		t.Fatalf("expected exactly one successful PublishExpvar, got %d", n)
+	}
+}
+
+func TestPublishExpvarTakenByStdlib(t *testing.T) {
+	goexpvar.NewInt("committable_queue_taken_test")
+	if _, err := PublishExpvar("committable_queue_taken_test"); err == nil {
+		t.Fatal("expected error for a name published through the standard library")
+	}
+}
+
+func TestPublishExpvarRegistry(t *testing.T) {
+	publisher, err := PublishExpvar("committable_queue_registry_test")
+	if err != nil {
+		t.Fatalf("publish failed: %v", err)
+	}
+
+	registry := queue.NewRegistry()
+	publisher.AddRegistry(registry)
+	q := queue.NewSegmentedQueue(queue.WithRegistry[int](registry, "orders"))
+	q.PushBackPending(1)
+	q.PushBackPending(2)
+	q.Commit()
+	publisher.AddQueue("explicit", fixedDepth(3))
+
+	var decoded struct {
+		Queues map[string]struct {
+			Visible int    `json:"visible"`
+			Pushes  uint64 `json:"pushes"`
+		} `json:"queues"`
+	}
+	if err := json.Unmarshal([]byte(goexpvar.Get("committable_queue_registry_test").String()), &decoded); err != nil {
+		t.Fatalf("expvar output is not valid JSON: %v", err)
+	}
+	if got := decoded.Queues["orders"]; got.Visible != 2 || got.Pushes != 2 {
+		t.Fatalf("unexpected registry queue: %+v", got)
+	}
+	if got := decoded.Queues["explicit"]; got.Visible != 3 {
+		t.Fatalf("unexpected explicit queue: %+v", got)
+	}
+}
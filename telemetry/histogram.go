@@ -0,0 +1,162 @@
+package telemetry
+
+import (
+	"math"
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultDurationBuckets sind die oberen Grenzen der Commit-Dauer-Histogramme.
+var DefaultDurationBuckets = []time.Duration{
+	10 * time.Microsecond,
+	25 * time.Microsecond,
+	50 * time.Microsecond,
+	100 * time.Microsecond,
+	250 * time.Microsecond,
+	500 * time.Microsecond,
+	time.Millisecond,
+	2500 * time.Microsecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+	10 * time.Second,
+}
+
+// DefaultBatchSizeBuckets sind die oberen Grenzen der Histogramme über die
+// Zahl der Elemente je Commit.
+var DefaultBatchSizeBuckets = []int64{1, 5, 10, 50, 100, 500, 1000, 5000, 10000, 50000}
+
+// Bucket ist ein kumulativer Histogramm-Eintrag: Count zählt alle Werte, die
+// kleiner oder gleich UpperBound sind.
+type Bucket struct {
+	UpperBound int64
+	Count      uint64
+}
+
+// Histogram zählt Werte in festen Buckets. Werte oberhalb der letzten Grenze
+// landen in einem Überlauf-Bucket; für Quantile in diesem Bereich wird der
+// größte beobachtete Wert verwendet.
+type Histogram struct {
+	bounds []int64
+	counts []atomic.Uint64
+	max    atomic.Int64
+	sum    atomic.Int64
+}
+
+// NewHistogram erzeugt ein Histogramm mit den aufsteigend sortierten Grenzen bounds.
+func NewHistogram(bounds []int64) *Histogram {
+	sorted := append([]int64(nil), bounds...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return &Histogram{
+		bounds: sorted,
+		counts: make([]atomic.Uint64, len(sorted)+1),
+	}
+}
+
+// NewDurationHistogram erzeugt ein Histogramm über Dauern in Nanosekunden.
+func NewDurationHistogram(bounds []time.Duration) *Histogram {
+	converted := make([]int64, len(bounds))
+	for i, bound := range bounds {
+		converted[i] = bound.Nanoseconds()
+	}
+	return NewHistogram(converted)
+}
+
+// Observe zählt einen Wert.
+func (h *Histogram) Observe(value int64) {
+	idx := sort.Search(len(h.bounds), func(i int) bool { return h.bounds[i] >= value })
+	h.counts[idx].Add(1)
+	h.sum.Add(value)
+	for {
+		current := h.max.Load()
+		if value <= current || h.max.CompareAndSwap(current, value) {
+			return
+		}
+	}
+}
+
+// Buckets liefert die kumulativen Zählerstände ohne den Überlauf-Bucket.
+func (h *Histogram) Buckets() []Bucket {
+	buckets := make([]Bucket, len(h.bounds))
+	var cumulative uint64
+	for i, bound := range h.bounds {
+		cumulative += h.counts[i].Load()
+		buckets[i] = Bucket{UpperBound: bound, Count: cumulative}
+	}
+	return buckets
+}
+
+// Count liefert die Anzahl aller beobachteten Werte.
+func (h *Histogram) Count() uint64 {
+	var total uint64
+	for i := range h.counts {
+		total += h.counts[i].Load()
+	}
+	return total
+}
+
+// Sum liefert die Summe aller beobachteten Werte.
+func (h *Histogram) Sum() int64 {
+	return h.sum.Load()
+}
+
+// Quantile schätzt das q-Quantil (0 < q <= 1) durch lineare Interpolation
+// innerhalb des betroffenen Buckets.
+func (h *Histogram) Quantile(q float64) int64 {
+	counts := make([]uint64, len(h.counts))
+	var total uint64
+	for i := range h.counts {
+		counts[i] = h.counts[i].Load()
+		total += counts[i]
+	}
+	if total == 0 {
+		return 0
+	}
+
+	rank := uint64(math.Ceil(q * float64(total)))
+	if rank == 0 {
+		rank = 1
+	}
+
+	var cumulative uint64
+	for i, count := range counts {
+		if cumulative+count < rank {
+			cumulative += count
+			continue
+		}
+		if i == len(h.bounds) {
+			return h.max.Load()
+		}
+		var lower int64
+		if i > 0 {
+			lower = h.bounds[i-1]
+		}
+		upper := h.bounds[i]
+		if observedMax := h.max.Load(); observedMax < upper {
+			upper = observedMax
+		}
+		if upper <= lower {
+			return upper
+		}
+		fraction := float64(rank-cumulative) / float64(count)
+		return lower + int64(fraction*float64(upper-lower))
+	}
+	return h.max.Load()
+}
+
+// Reset setzt alle Zähler zurück.
+func (h *Histogram) Reset() {
+	for i := range h.counts {
+		h.counts[i].Store(0)
+	}
+	h.max.Store(0)
+	h.sum.Store(0)
+}
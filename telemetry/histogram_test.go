@@ -0,0 +1,59 @@
+package telemetry
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHistogramBucketsAndQuantiles(t *testing.T) {
+	h := NewHistogram([]int64{100, 10, 1000})
+
+	for i := 0; i < 90; i++ {
+		h.Observe(5)
+	}
+	for i := 0; i < 9; i++ {
+		h.Observe(50)
+	}
+	h.Observe(5000)
+
+	if got := h.Count(); got != 100 {
+		t.Fatalf("expected 100 observations, got %d", got)
+	}
+	if got := h.Sum(); got != 90*5+9*50+5000 {
+		t.Fatalf("unexpected sum %d", got)
+	}
+
+	buckets := h.Buckets()
+	expected := []Bucket{{UpperBound: 10, Count: 90}, {UpperBound: 100, Count: 99}, {UpperBound: 1000, Count: 99}}
+	if len(buckets) != len(expected) {
+		t.Fatalf("unexpected buckets: %v", buckets)
+	}
+	for i := range expected {
+		if buckets[i] != expected[i] {
+			t.Fatalf("unexpected buckets: %v", buckets)
+		}
+	}
+
+	if p50 := h.Quantile(0.5); p50 <= 0 || p50 > 10 {
+		t.Fatalf("p50 should fall into the first bucket, got %d", p50)
+	}
+	if p95 := h.Quantile(0.95); p95 <= 10 || p95 > 100 {
+		t.Fatalf("p95 should fall into the second bucket, got %d", p95)
+	}
+	if p100 := h.Quantile(1); p100 != 5000 {
+		t.Fatalf("overflow quantile should report the maximum, got %d", p100)
+	}
+
+	h.Reset()
+	if h.Count() != 0 || h.Sum() != 0 || h.Quantile(0.5) != 0 {
+		t.Fatalf("expected histogram to reset")
+	}
+}
+
+func TestDurationHistogramUsesNanoseconds(t *testing.T) {
+	h := NewDurationHistogram([]time.Duration{time.Millisecond})
+	h.Observe((500 * time.Microsecond).Nanoseconds())
+	if buckets := h.Buckets(); buckets[0].UpperBound != time.Millisecond.Nanoseconds() || buckets[0].Count != 1 {
+		t.Fatalf("unexpected buckets: %v", buckets)
+	}
+}
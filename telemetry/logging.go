@@ -0,0 +1,88 @@
+package telemetry
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// EventKind bezeichnet die Art eines Commit-Ereignisses.
+type EventKind string
+
+const (
+	EventCommitStarted   EventKind = "commit_started"
+	EventCommitSucceeded EventKind = "commit_succeeded"
+	EventCommitFailed    EventKind = "commit_failed"
+	EventBankAborted     EventKind = "bank_aborted"
+	EventBankSkipped     EventKind = "bank_skipped"
+	EventBankCompensated EventKind = "bank_compensated"
+	EventBankTripped     EventKind = "bank_tripped"
+)
+
+// Event beschreibt ein einzelnes Commit-Ereignis. Version ist die Version, die
+// der Commit-Versuch veröffentlicht bzw. veröffentlichen wollte; Bank ist nur
+// bei bankbezogenen Ereignissen gesetzt. CommitID identifiziert den
+// Commit-Versuch.
+type Event struct {
+	Kind     EventKind
+	Version  uint64
+	CommitID string
+	Duration time.Duration
+	Bank     string
+	Err      error
+}
+
+// Logger nimmt strukturierte Commit-Ereignisse entgegen.
+type Logger interface {
+	LogEvent(ctx context.Context, event Event)
+}
+
+// NopLogger verwirft alle Ereignisse.
+type NopLogger struct{}
+
+// LogEvent implementiert Logger.
+func (NopLogger) LogEvent(context.Context, Event) {}
+
+type slogLogger struct {
+	logger *slog.Logger
+}
+
+// NewSlogLogger leitet Commit-Ereignisse an logger weiter. Start-Ereignisse
+// werden auf Debug-, Erfolge auf Info-, Abbrüche, Kompensationen,
+// ausgelöste Circuit Breaker und übersprungene Banken auf Warn- und Fehler auf Error-Ebene protokolliert.
+func NewSlogLogger(logger *slog.Logger) Logger {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &slogLogger{logger: logger}
+}
+
+func (l *slogLogger) LogEvent(ctx context.Context, event Event) {
+	level := slog.LevelInfo
+	switch event.Kind {
+	case EventCommitStarted:
+		level = slog.LevelDebug
+	case EventBankAborted, EventBankSkipped, EventBankCompensated, EventBankTripped:
+		level = slog.LevelWarn
+	case EventCommitFailed:
+		level = slog.LevelError
+	}
+
+	attrs := []slog.Attr{
+		slog.String("event", string(event.Kind)),
+		slog.Uint64("version", event.Version),
+	}
+	if event.CommitID != "" {
+		attrs = append(attrs, slog.String("commit_id", event.CommitID))
+	}
+	if event.Duration > 0 {
+		attrs = append(attrs, slog.Duration("duration", event.Duration))
+	}
+	if event.Bank != "" {
+		attrs = append(attrs, slog.String("bank", event.Bank))
+	}
+	if event.Err != nil {
+		attrs = append(attrs, slog.String("error", event.Err.Error()))
+	}
+	l.logger.LogAttrs(ctx, level, "commit", attrs...)
+}
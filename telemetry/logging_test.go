@@ -0,0 +1,55 @@
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestNopLoggerDiscardsEvents(t *testing.T) {
+	var logger Logger = NopLogger{}
+	logger.LogEvent(context.Background(), Event{Kind: EventCommitStarted})
+}
+
+func TestSlogLoggerEmitsStructuredEvents(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewSlogLogger(slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})))
+
+	logger.LogEvent(context.Background(), Event{Kind: EventCommitStarted, Version: 4, CommitID: "c0ffee"})
+	logger.LogEvent(context.Background(), Event{Kind: EventBankAborted, Version: 4, Bank: "holding"})
+	logger.LogEvent(context.Background(), Event{Kind: EventCommitFailed, Version: 4, Duration: time.Millisecond, Err: errors.New("prepare failed")})
+
+	decoder := json.NewDecoder(&buf)
+	expected := []struct {
+		level string
+		event string
+		bank  string
+		err   string
+	}{
+		{"DEBUG", "commit_started", "", ""},
+		{"WARN", "bank_aborted", "holding", ""},
+		{"ERROR", "commit_failed", "", "prepare failed"},
+	}
+	for i, want := range expected {
+		var record map[string]any
+		if err := decoder.Decode(&record); err != nil {
+			t.Fatalf("record %d: %v", i, err)
+		}
+		if record["level"] != want.level || record["event"] != want.event || record["version"] != float64(4) {
+			t.Fatalf("record %d: unexpected fields %v", i, record)
+		}
+		if i == 0 && record["commit_id"] != "c0ffee" {
+			t.Fatalf("record %d: expected commit_id, got %v", i, record)
+		}
+		if bank, _ := record["bank"].(string); bank != want.bank {
+			t.Fatalf("record %d: expected bank %q, got %v", i, want.bank, record["bank"])
+		}
+		if errText, _ := record["error"].(string); errText != want.err {
+			t.Fatalf("record %d: expected error %q, got %v", i, want.err, record["error"])
+		}
+	}
+}
@@ -0,0 +1,69 @@
+// Package otel verbindet die Commit-Spans des Orchestrators mit OpenTelemetry.
+//
+// Install registriert einen trace.Tracer global; danach erzeugt jeder
+// CommitAll-Aufruf einen Span "CommitAll" mit Version und Banknamen als
+// Attributen sowie Kind-Spans "PrepareCommit" und "Publish" je Bank.
+package otel
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/timzifer/committable_queue/telemetry"
+)
+
+type tracer struct {
+	tracer trace.Tracer
+}
+
+// Install leitet alle Commit-Spans an t weiter. Die zurückgegebene Funktion
+// stellt den No-op-Tracer wieder her.
+func Install(t trace.Tracer) (uninstall func()) {
+	telemetry.SetTracer(&tracer{tracer: t})
+	return func() { telemetry.SetTracer(nil) }
+}
+
+func (t *tracer) Start(ctx context.Context, name string, attrs ...telemetry.Attribute) (context.Context, telemetry.Span) {
+	ctx, s := t.tracer.Start(ctx, name, trace.WithAttributes(convert(attrs)...))
+	return ctx, &span{span: s}
+}
+
+type span struct {
+	span trace.Span
+}
+
+func (s *span) SetAttributes(attrs ...telemetry.Attribute) {
+	s.span.SetAttributes(convert(attrs)...)
+}
+
+func (s *span) End(err error) {
+	if err != nil {
+		s.span.RecordError(err)
+		s.span.SetStatus(codes.Error, err.Error())
+	}
+	s.span.End()
+}
+
+func convert(attrs []telemetry.Attribute) []attribute.KeyValue {
+	converted := make([]attribute.KeyValue, 0, len(attrs))
+	for _, attr := range attrs {
+		switch v := attr.Value.(type) {
+		case string:
+			converted = append(converted, attribute.String(attr.Key, v))
+		case int64:
+			converted = append(converted, attribute.Int64(attr.Key, v))
+		case int:
+			converted = append(converted, attribute.Int(attr.Key, v))
+		case uint64:
+			converted = append(converted, attribute.Int64(attr.Key, int64(v)))
+		case bool:
+			converted = append(converted, attribute.Bool(attr.Key, v))
+		case []string:
+			converted = append(converted, attribute.StringSlice(attr.Key, v))
+		}
+	}
+	return converted
+}
@@ -0,0 +1,63 @@
+package otel
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/timzifer/committable_queue/orchestrator"
+)
+
+type namedBank struct {
+	name string
+}
+
+func (b namedBank) Name() string {
+	return b.name
+}
+
+func (b namedBank) PrepareCommit(context.Context) (func(), func(), error) {
+	return nil, nil, nil
+}
+
+func TestInstallExportsCommitSpans(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	uninstall := Install(provider.Tracer("committable_queue"))
+	defer uninstall()
+
+	o := orchestrator.NewCommitOrchestrator(orchestrator.WithBanks(namedBank{name: "holding"}, namedBank{name: "input"}))
+	if err := o.CommitAll(context.Background()); err != nil {
+		t.Fatalf("commit failed: %v", err)
+	}
+
+	spans := recorder.Ended()
+	names := map[string]int{}
+	var root sdktrace.ReadOnlySpan
+	for _, span := range spans {
+		names[span.Name()]++
+		if span.Name() == "CommitAll" {
+			root = span
+		}
+	}
+	if names["CommitAll"] != 1 || names["PrepareCommit"] != 2 || names["Publish"] != 2 {
+		t.Fatalf("unexpected spans: %v", names)
+	}
+
+	attrs := attribute.NewSet(root.Attributes()...)
+	if v, ok := attrs.Value("commit.version"); !ok || v.AsInt64() != 1 {
+		t.Fatalf("commit span missing version: %v", root.Attributes())
+	}
+	if v, ok := attrs.Value("commit.banks"); !ok || len(v.AsStringSlice()) != 2 {
+		t.Fatalf("commit span missing bank names: %v", root.Attributes())
+	}
+
+	for _, span := range spans {
+		if span.Name() != "CommitAll" && span.Parent().SpanID() != root.SpanContext().SpanID() {
+			t.Fatalf("span %s is not a child of the commit span", span.Name())
+		}
+	}
+}
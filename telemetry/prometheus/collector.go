@@ -0,0 +1,187 @@
+// Package prometheus stellt die Commit-Telemetrie und Queue-Füllstände als
+// prometheus.Collector bereit.
+package prometheus
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	prom "github.com/prometheus/client_golang/prometheus"
+
+	"github.com/timzifer/committable_queue/queue"
+	"github.com/timzifer/committable_queue/telemetry"
+)
+
+const namespace = "committable_queue"
+
+// DepthSource liefert den sichtbaren Füllstand einer Queue, etwa
+// *queue.SegmentedQueue.
+type DepthSource = telemetry.DepthSource
+
+// QueueMetricsSource wird von Queues implementiert, die Zähler für Pushes,
+// Pops, Commits und Verwerfungen führen (siehe queue.SegmentedQueue.Metrics).
+type QueueMetricsSource interface {
+	Metrics() queue.QueueMetrics
+}
+
+// ResidencySource wird von Queues implementiert, die Verweildauern messen
+// (siehe queue.SegmentedQueue.Residency und queue.WithElementMeta).
+type ResidencySource interface {
+	Residency() telemetry.ResidencySnapshot
+}
+
+// Collector exportiert Commit-Versuche, Fehler, die Commit-Dauer sowie
+// Füllstände und Zähler registrierter Queues.
+type Collector struct {
+	metrics *telemetry.CommitMetrics
+
+	mu     sync.Mutex
+	queues map[string]DepthSource
+
+	attempts  *prom.Desc
+	failures  *prom.Desc
+	duration  *prom.Desc
+	depth     *prom.Desc
+	pushes    *prom.Desc
+	pops      *prom.Desc
+	commits   *prom.Desc
+	dropped   *prom.Desc
+	batches   *prom.Desc
+	residency *prom.Desc
+}
+
+// NewCollector erzeugt einen Collector über die Commit-Metriken eines
+// Orchestrators (siehe CommitOrchestrator.Metrics bzw. WithMetrics).
+func NewCollector(metrics *telemetry.CommitMetrics) *Collector {
+	return &Collector{
+		metrics: metrics,
+		queues:  make(map[string]DepthSource),
+		attempts: prom.NewDesc(
+			prom.BuildFQName(namespace, "commit", "attempts_total"),
+			"Number of CommitAll attempts.", nil, nil),
+		failures: prom.NewDesc(
+			prom.BuildFQName(namespace, "commit", "failures_total"),
+			"Number of failed CommitAll attempts.", nil, nil),
+		duration: prom.NewDesc(
+			prom.BuildFQName(namespace, "commit", "duration_seconds"),
+			"Duration of CommitAll attempts.", nil, nil),
+		depth: prom.NewDesc(
+			prom.BuildFQName(namespace, "queue", "visible_elements"),
+			"Number of committed elements visible to consumers.", []string{"queue"}, nil),
+		pushes: prom.NewDesc(
+			prom.BuildFQName(namespace, "queue", "pushes_total"),
+			"Number of elements pushed into the pending segment.", []string{"queue"}, nil),
+		pops: prom.NewDesc(
+			prom.BuildFQName(namespace, "queue", "pops_total"),
+			"Number of elements popped from the visible segment.", []string{"queue"}, nil),
+		commits: prom.NewDesc(
+			prom.BuildFQName(namespace, "queue", "commits_total"),
+			"Number of published commits.", []string{"queue"}, nil),
+		dropped: prom.NewDesc(
+			prom.BuildFQName(namespace, "queue", "dropped_total"),
+			"Number of elements discarded by overflow handling.", []string{"queue", "policy"}, nil),
+		batches: prom.NewDesc(
+			prom.BuildFQName(namespace, "queue", "commit_batch_size"),
+			"Number of elements moved by each published commit.", []string{"queue"}, nil),
+		residency: prom.NewDesc(
+			prom.BuildFQName(namespace, "queue", "residency_seconds"),
+			"Time elements spent pending, visible and in total before they were popped.", []string{"queue", "stage"}, nil),
+	}
+}
+
+// AddQueue meldet den Füllstand von q unter dem Label queue=name. Ein erneuter
+// Aufruf mit demselben Namen ersetzt die Quelle.
+func (c *Collector) AddQueue(name string, q DepthSource) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.queues[name] = q
+}
+
+// RemoveQueue entfernt eine zuvor registrierte Queue.
+func (c *Collector) RemoveQueue(name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.queues, name)
+}
+
+// Describe implementiert prometheus.Collector.
+func (c *Collector) Describe(ch chan<- *prom.Desc) {
+	ch <- c.attempts
+	ch <- c.failures
+	ch <- c.duration
+	ch <- c.depth
+	ch <- c.pushes
+	ch <- c.pops
+	ch <- c.commits
+	ch <- c.dropped
+	ch <- c.batches
+	ch <- c.residency
+}
+
+// Collect implementiert prometheus.Collector.
+func (c *Collector) Collect(ch chan<- prom.Metric) {
+	snapshot := c.metrics.Snapshot()
+	ch <- prom.MustNewConstMetric(c.attempts, prom.CounterValue, float64(snapshot.Attempts))
+	ch <- prom.MustNewConstMetric(c.failures, prom.CounterValue, float64(snapshot.Failures))
+	buckets := make(map[float64]uint64, len(snapshot.Buckets))
+	for _, bucket := range snapshot.Buckets {
+		buckets[time.Duration(bucket.UpperBound).Seconds()] = bucket.Count
+	}
+	ch <- prom.MustNewConstHistogram(c.duration, snapshot.Attempts, c.metrics.TotalDuration().Seconds(), buckets)
+
+	c.mu.Lock()
+	names := make([]string, 0, len(c.queues))
+	for name := range c.queues {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	sources := make([]DepthSource, len(names))
+	for i, name := range names {
+		sources[i] = c.queues[name]
+	}
+	c.mu.Unlock()
+
+	for i, name := range names {
+		ch <- prom.MustNewConstMetric(c.depth, prom.GaugeValue, float64(sources[i].LenVisible()), name)
+
+		source, ok := sources[i].(QueueMetricsSource)
+		if !ok {
+			continue
+		}
+		m := source.Metrics()
+		ch <- prom.MustNewConstMetric(c.pushes, prom.CounterValue, float64(m.Pushes), name)
+		ch <- prom.MustNewConstMetric(c.pops, prom.CounterValue, float64(m.Pops), name)
+		ch <- prom.MustNewConstMetric(c.commits, prom.CounterValue, float64(m.Commits), name)
+		for policy, n := range m.Drops {
+			ch <- prom.MustNewConstMetric(c.dropped, prom.CounterValue, float64(n), name, policy.String())
+		}
+		batches := make(map[float64]uint64, len(m.BatchSizes))
+		for _, bucket := range m.BatchSizes {
+			batches[float64(bucket.UpperBound)] = bucket.Count
+		}
+		ch <- prom.MustNewConstHistogram(c.batches, m.Commits, float64(m.CommittedElements), batches, name)
+
+		if source, ok := sources[i].(ResidencySource); ok {
+			c.collectResidency(ch, name, source.Residency())
+		}
+	}
+}
+
+// collectResidency exportiert die Verweildauern einer Queue, sofern sie welche
+// gemessen hat.
+func (c *Collector) collectResidency(ch chan<- prom.Metric, name string, r telemetry.ResidencySnapshot) {
+	if r.Pending.Count == 0 && r.Total.Count == 0 {
+		return
+	}
+	for _, stage := range []struct {
+		name string
+		s    telemetry.LatencySnapshot
+	}{{"pending", r.Pending}, {"visible", r.Visible}, {"total", r.Total}} {
+		buckets := make(map[float64]uint64, len(stage.s.Buckets))
+		for _, bucket := range stage.s.Buckets {
+			buckets[time.Duration(bucket.UpperBound).Seconds()] = bucket.Count
+		}
+		ch <- prom.MustNewConstHistogram(c.residency, stage.s.Count, stage.s.Sum.Seconds(), buckets, name, stage.name)
+	}
+}
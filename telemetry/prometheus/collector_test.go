@@ -0,0 +1,133 @@
+package prometheus
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	prom "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/timzifer/committable_queue/queue"
+	"github.com/timzifer/committable_queue/telemetry"
+)
+
+func TestCollectorExportsCommitMetricsAndDepths(t *testing.T) {
+	metrics := telemetry.NewCommitMetrics()
+	_, finish := metrics.TraceCommit(context.Background())
+	finish(nil)
+	_, finish = metrics.TraceCommit(context.Background())
+	finish(errors.New("commit failed"))
+
+	q := queue.NewSegmentedQueue[int](
+		queue.WithInitialVisible(1, 2, 3),
+		queue.WithOptions[int](queue.Options{MaxLen: 3}),
+	)
+	q.PushBackPending(4)
+	q.Commit()
+
+	collector := NewCollector(metrics)
+	collector.AddQueue("sensors", q)
+	collector.AddQueue("removed", q)
+	collector.RemoveQueue("removed")
+
+	registry := prom.NewPedanticRegistry()
+	if err := registry.Register(collector); err != nil {
+		t.Fatalf("register failed: %v", err)
+	}
+
+	expected := `
+# HELP committable_queue_commit_attempts_total Number of CommitAll attempts.
+# TYPE committable_queue_commit_attempts_total counter
+committable_queue_commit_attempts_total 2
+# HELP committable_queue_commit_failures_total Number of failed CommitAll attempts.
+# TYPE committable_queue_commit_failures_total counter
+committable_queue_commit_failures_total 1
+# HELP committable_queue_queue_dropped_total Number of elements discarded by overflow handling.
+# TYPE committable_queue_queue_dropped_total counter
+committable_queue_queue_dropped_total{policy="oldest",queue="sensors"} 1
+# HELP committable_queue_queue_pushes_total Number of elements pushed into the pending segment.
+# TYPE committable_queue_queue_pushes_total counter
+committable_queue_queue_pushes_total{queue="sensors"} 1
+# HELP committable_queue_queue_visible_elements Number of committed elements visible to consumers.
+# TYPE committable_queue_queue_visible_elements gauge
+committable_queue_queue_visible_elements{queue="sensors"} 3
+`
+	err := testutil.GatherAndCompare(registry, strings.NewReader(expected),
+		"committable_queue_commit_attempts_total",
+		"committable_queue_commit_failures_total",
+		"committable_queue_queue_visible_elements",
+		"committable_queue_queue_pushes_total",
+		"committable_queue_queue_dropped_total",
+	)
+	if err != nil {
+		t.Fatalf("unexpected metrics: %v", err)
+	}
+
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatalf("gather failed: %v", err)
+	}
+	batches := false
+	for _, family := range families {
+		if family.GetName() != "committable_queue_queue_commit_batch_size" {
+			continue
+		}
+		histogram := family.GetMetric()[0].GetHistogram()
+		if histogram.GetSampleCount() != 1 || histogram.GetSampleSum() != 1 {
+			t.Fatalf("expected one commit of one element, got %v", histogram)
+		}
+		batches = true
+	}
+	if !batches {
+		t.Fatalf("batch size histogram not exported")
+	}
+	for _, family := range families {
+		if family.GetName() != "committable_queue_commit_duration_seconds" {
+			continue
+		}
+		histogram := family.GetMetric()[0].GetHistogram()
+		if got := histogram.GetSampleCount(); got != 2 {
+			t.Fatalf("expected duration sample count 2, got %d", got)
+		}
+		if got := len(histogram.GetBucket()); got != len(telemetry.DefaultDurationBuckets) {
+			t.Fatalf("expected %d duration buckets, got %d", len(telemetry.DefaultDurationBuckets), got)
+		}
+		return
+	}
+	t.Fatalf("duration histogram not exported")
+}
+
+func TestCollectorExportsResidency(t *testing.T) {
+	q := queue.NewSegmentedQueue[int](queue.WithElementMeta[int]())
+	q.PushBackPending(1)
+	q.Commit()
+	q.PopFront()
+
+	collector := NewCollector(telemetry.NewCommitMetrics())
+	collector.AddQueue("sensors", q)
+	registry := prom.NewPedanticRegistry()
+	if err := registry.Register(collector); err != nil {
+		t.Fatalf("register failed: %v", err)
+	}
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatalf("gather failed: %v", err)
+	}
+	for _, family := range families {
+		if family.GetName() != "committable_queue_queue_residency_seconds" {
+			continue
+		}
+		if got := len(family.GetMetric()); got != 3 {
+			t.Fatalf("expected pending, visible and total residency, got %d series", got)
+		}
+		for _, metric := range family.GetMetric() {
+			if metric.GetHistogram().GetSampleCount() != 1 {
+				t.Fatalf("expected one sample per stage, got %v", metric)
+			}
+		}
+		return
+	}
+	t.Fatalf("residency histogram not exported")
+}
@@ -0,0 +1,107 @@
+package telemetry
+
+import (
+	"sync"
+	"time"
+)
+
+// DefaultResidencyBuckets sind die oberen Grenzen der Verweildauer-Histogramme.
+// Sie reichen weiter als DefaultDurationBuckets, da Elemente Minuten bis
+// Stunden in einer Queue liegen können.
+var DefaultResidencyBuckets = []time.Duration{
+	time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	5 * time.Second,
+	10 * time.Second,
+	30 * time.Second,
+	time.Minute,
+	5 * time.Minute,
+	15 * time.Minute,
+	time.Hour,
+}
+
+// ResidencyMetrics misst, wie lange Elemente in einer Queue verweilen: Pending
+// vom Einreihen bis zur Veröffentlichung ihres Commits, Visible von der
+// Veröffentlichung bis zur Entnahme und Total vom Einreihen bis zur Entnahme.
+// Der Nullwert ist einsatzbereit.
+type ResidencyMetrics struct {
+	once                    sync.Once
+	pending, visible, total *Histogram
+}
+
+// LatencySnapshot fasst ein Verweildauer-Histogramm zusammen. Buckets hat
+// Grenzen in Nanosekunden (siehe DefaultResidencyBuckets).
+type LatencySnapshot struct {
+	Count   uint64
+	Sum     time.Duration
+	Average time.Duration
+	P50     time.Duration
+	P95     time.Duration
+	P99     time.Duration
+	Buckets []Bucket
+}
+
+// ResidencySnapshot enthält die Verweildauern einer Queue.
+type ResidencySnapshot struct {
+	Pending LatencySnapshot
+	Visible LatencySnapshot
+	Total   LatencySnapshot
+}
+
+func (m *ResidencyMetrics) histograms() {
+	m.once.Do(func() {
+		m.pending = NewDurationHistogram(DefaultResidencyBuckets)
+		m.visible = NewDurationHistogram(DefaultResidencyBuckets)
+		m.total = NewDurationHistogram(DefaultResidencyBuckets)
+	})
+}
+
+// ObserveCommitted erfasst, wie lange ein Element bis zur Veröffentlichung
+// seines Commits ausstand.
+func (m *ResidencyMetrics) ObserveCommitted(pending time.Duration) {
+	m.histograms()
+	m.pending.Observe(pending.Nanoseconds())
+}
+
+// ObservePopped erfasst, wie lange ein entnommenes Element sichtbar war und
+// wie lange es insgesamt in der Queue lag.
+func (m *ResidencyMetrics) ObservePopped(visible, total time.Duration) {
+	m.histograms()
+	m.visible.Observe(visible.Nanoseconds())
+	m.total.Observe(total.Nanoseconds())
+}
+
+// Snapshot gibt die gesammelten Werte zurück.
+func (m *ResidencyMetrics) Snapshot() ResidencySnapshot {
+	m.histograms()
+	return ResidencySnapshot{
+		Pending: latencySnapshot(m.pending),
+		Visible: latencySnapshot(m.visible),
+		Total:   latencySnapshot(m.total),
+	}
+}
+
+// Reset setzt alle Histogramme zurück.
+func (m *ResidencyMetrics) Reset() {
+	m.histograms()
+	m.pending.Reset()
+	m.visible.Reset()
+	m.total.Reset()
+}
+
+func latencySnapshot(h *Histogram) LatencySnapshot {
+	s := LatencySnapshot{Count: h.Count(), Sum: time.Duration(h.Sum()), Buckets: h.Buckets()}
+	if s.Count == 0 {
+		return s
+	}
+	s.Average = s.Sum / time.Duration(s.Count)
+	s.P50 = time.Duration(h.Quantile(0.50))
+	s.P95 = time.Duration(h.Quantile(0.95))
+	s.P99 = time.Duration(h.Quantile(0.99))
+	return s
+}
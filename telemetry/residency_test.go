@@ -0,0 +1,31 @@
+package telemetry
+
+import (
+	"testing"
+	"time"
+)
+
+func TestResidencyMetrics(t *testing.T) {
+	var m ResidencyMetrics
+	for range 9 {
+		m.ObserveCommitted(20 * time.Millisecond)
+	}
+	m.ObserveCommitted(10 * time.Minute)
+	m.ObservePopped(time.Second, 3*time.Second)
+
+	s := m.Snapshot()
+	if s.Pending.Count != 10 || s.Pending.P50 > 50*time.Millisecond || s.Pending.P99 < 5*time.Minute {
+		t.Fatalf("unexpected pending residency: %+v", s.Pending)
+	}
+	if s.Visible.Average != time.Second || s.Total.Average != 3*time.Second {
+		t.Fatalf("unexpected pop residency: %+v %+v", s.Visible, s.Total)
+	}
+	if len(s.Total.Buckets) != len(DefaultResidencyBuckets) {
+		t.Fatalf("expected %d buckets, got %d", len(DefaultResidencyBuckets), len(s.Total.Buckets))
+	}
+
+	m.Reset()
+	if s := m.Snapshot(); s.Pending.Count != 0 || s.Total.Sum != 0 {
+		t.Fatalf("expected metrics to reset, got %+v", s)
+	}
+}
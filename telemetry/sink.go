@@ -0,0 +1,82 @@
+// Package telemetry enthält die Messwerte, Spans und Ereignisse des
+// Orchestrators und der Queues. Exporter für konkrete Systeme liegen in den
+// Unterpaketen expvar, prometheus und otel.
+package telemetry
+
+import "time"
+
+// DepthSource liefert den sichtbaren Füllstand einer Queue, etwa
+// *queue.SegmentedQueue.
+type DepthSource interface {
+	LenVisible() int
+}
+
+// MetricsSink empfängt Telemetrie-Ereignisse und kann von Anwendungen
+// implementiert werden, um Commits und Queues an ein bestehendes
+// Monitoring anzubinden. Die Methoden werden synchron aufgerufen und müssen
+// daher schnell zurückkehren und nebenläufig aufrufbar sein.
+//
+// CommitStarted und CommitFinished meldet der Orchestrator für jeden
+// CommitAll-Versuch mit der angestrebten Version. Dropped und DepthChanged
+// melden Queues, denen eine Senke übergeben wurde (siehe
+// queue.WithMetricsSink), unter ihrem Namen.
+type MetricsSink interface {
+	CommitStarted(version uint64)
+	CommitFinished(version uint64, elapsed time.Duration, err error)
+	Dropped(queue string, policy string, n int)
+	DepthChanged(queue string, visible int)
+}
+
+// CommitIDSink kann von einer MetricsSink zusätzlich implementiert werden, um
+// die ID jedes Commit-Versuchs zu erhalten, etwa für Exemplare oder zur
+// Korrelation mit Logs. Der Orchestrator ruft CommitIdentified unmittelbar
+// nach CommitStarted mit derselben Version auf.
+type CommitIDSink interface {
+	CommitIdentified(version uint64, id string)
+}
+
+// NopSink verwirft alle Ereignisse. Eigene Senken können sie einbetten, um nur
+// einen Teil der Methoden zu implementieren.
+type NopSink struct{}
+
+func (NopSink) CommitStarted(uint64)                        {}
+func (NopSink) CommitFinished(uint64, time.Duration, error) {}
+func (NopSink) Dropped(string, string, int)                 {}
+func (NopSink) DepthChanged(string, int)                    {}
+
+// MultiSink verteilt jedes Ereignis an alle enthaltenen Senken.
+type MultiSink []MetricsSink
+
+func (m MultiSink) CommitStarted(version uint64) {
+	for _, s := range m {
+		s.CommitStarted(version)
+	}
+}
+
+// CommitIdentified reicht die ID an die Senken weiter, die CommitIDSink
+// implementieren.
+func (m MultiSink) CommitIdentified(version uint64, id string) {
+	for _, s := range m {
+		if ids, ok := s.(CommitIDSink); ok {
+			ids.CommitIdentified(version, id)
+		}
+	}
+}
+
+func (m MultiSink) CommitFinished(version uint64, elapsed time.Duration, err error) {
+	for _, s := range m {
+		s.CommitFinished(version, elapsed, err)
+	}
+}
+
+func (m MultiSink) Dropped(queue string, policy string, n int) {
+	for _, s := range m {
+		s.Dropped(queue, policy, n)
+	}
+}
+
+func (m MultiSink) DepthChanged(queue string, visible int) {
+	for _, s := range m {
+		s.DepthChanged(queue, visible)
+	}
+}
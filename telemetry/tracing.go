@@ -0,0 +1,71 @@
+package telemetry
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// Attribute ist ein Schlüssel-Wert-Paar an einem Span. Unterstützte Werte sind
+// string, int64, uint64, bool und []string.
+type Attribute struct {
+	Key   string
+	Value any
+}
+
+// Span ist ein laufender Trace-Abschnitt.
+type Span interface {
+	SetAttributes(attrs ...Attribute)
+	End(err error)
+}
+
+// Tracer erzeugt Spans. Der Kern bleibt damit frei von Abhängigkeiten; eine
+// OpenTelemetry-Anbindung liefert das Paket telemetry/otel.
+type Tracer interface {
+	Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span)
+}
+
+type tracerHolder struct {
+	tracer Tracer
+}
+
+var defaultTracer atomic.Pointer[tracerHolder]
+
+// SetTracer setzt den globalen Tracer. nil stellt den No-op-Tracer wieder her.
+func SetTracer(tracer Tracer) {
+	if tracer == nil {
+		defaultTracer.Store(nil)
+		return
+	}
+	defaultTracer.Store(&tracerHolder{tracer: tracer})
+}
+
+type noopSpan struct{}
+
+func (noopSpan) SetAttributes(...Attribute) {}
+func (noopSpan) End(error)                  {}
+
+type spanKey struct{}
+
+// StartSpan startet einen Span unterhalb des Spans in ctx und liefert eine
+// Abschlussfunktion, die den Span mit dem Fehlerzustand beendet.
+func StartSpan(ctx context.Context, name string, attrs ...Attribute) (context.Context, func(error)) {
+	holder := defaultTracer.Load()
+	if holder == nil {
+		return ctx, func(error) {}
+	}
+	ctx, span := holder.tracer.Start(ctx, name, attrs...)
+	ctx = context.WithValue(ctx, spanKey{}, span)
+	return ctx, span.End
+}
+
+// AnnotateSpan ergänzt den aktuellen Span in ctx um Attribute.
+func AnnotateSpan(ctx context.Context, attrs ...Attribute) {
+	spanFromContext(ctx).SetAttributes(attrs...)
+}
+
+func spanFromContext(ctx context.Context) Span {
+	if span, ok := ctx.Value(spanKey{}).(Span); ok {
+		return span
+	}
+	return noopSpan{}
+}
@@ -0,0 +1,78 @@
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+type recordedSpan struct {
+	name   string
+	parent *recordedSpan
+	attrs  map[string]any
+	ended  bool
+	err    error
+}
+
+func (s *recordedSpan) SetAttributes(attrs ...Attribute) {
+	for _, attr := range attrs {
+		s.attrs[attr.Key] = attr.Value
+	}
+}
+
+func (s *recordedSpan) End(err error) {
+	s.ended = true
+	s.err = err
+}
+
+type recordingTracer struct {
+	spans []*recordedSpan
+}
+
+type recordedSpanKey struct{}
+
+func (tr *recordingTracer) Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span) {
+	parent, _ := ctx.Value(recordedSpanKey{}).(*recordedSpan)
+	span := &recordedSpan{name: name, parent: parent, attrs: map[string]any{}}
+	span.SetAttributes(attrs...)
+	tr.spans = append(tr.spans, span)
+	return context.WithValue(ctx, recordedSpanKey{}, span), span
+}
+
+func TestStartSpanWithoutTracerIsNoop(t *testing.T) {
+	SetTracer(nil)
+	base := context.Background()
+	ctx, end := StartSpan(base, "noop")
+	if ctx != base {
+		t.Fatalf("no-op tracer must return the original context")
+	}
+	AnnotateSpan(ctx, Attribute{Key: "ignored", Value: true})
+	end(nil)
+}
+
+func TestTraceCommitCreatesSpans(t *testing.T) {
+	tracer := &recordingTracer{}
+	SetTracer(tracer)
+	defer SetTracer(nil)
+
+	ctx, finish := NewCommitMetrics().TraceCommit(context.Background())
+	AnnotateSpan(ctx, Attribute{Key: "commit.version", Value: uint64(3)})
+	_, endChild := StartSpan(ctx, "PrepareCommit", Attribute{Key: "commit.bank", Value: "holding"})
+	endChild(nil)
+	commitErr := errors.New("commit failed")
+	finish(commitErr)
+
+	if len(tracer.spans) != 2 {
+		t.Fatalf("expected two spans, got %d", len(tracer.spans))
+	}
+	root, child := tracer.spans[0], tracer.spans[1]
+	if root.name != "CommitAll" || !root.ended || !errors.Is(root.err, commitErr) {
+		t.Fatalf("unexpected commit span: %+v", root)
+	}
+	if root.attrs["commit.version"] != uint64(3) {
+		t.Fatalf("commit span missing version attribute: %v", root.attrs)
+	}
+	if child.parent != root || child.attrs["commit.bank"] != "holding" || !child.ended {
+		t.Fatalf("unexpected child span: %+v", child)
+	}
+}
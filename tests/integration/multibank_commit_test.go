@@ -7,7 +7,7 @@ import (
 	"testing"
 	"time"
 
-	"github.com/timzifer/committable_queue/internal/core"
+	"github.com/timzifer/committable_queue/orchestrator"
 )
 
 type registerState struct {
@@ -87,7 +87,7 @@ func TestMultiBankCommitProducesConsistentSnapshot(t *testing.T) {
 	leftBank := newRegisterBank("holding", initialStateLeft)
 	rightBank := newRegisterBank("input", initialStateRight)
 
-	orchestrator := core.NewCommitOrchestrator(leftBank, rightBank)
+	o := orchestrator.NewCommitOrchestrator(orchestrator.WithBanks(leftBank, rightBank))
 
 	// Reader should observe the initial, consistent snapshot.
 	initialPair := performModbusRead(leftBank, rightBank)
@@ -153,11 +153,11 @@ func TestMultiBankCommitProducesConsistentSnapshot(t *testing.T) {
 	}()
 
 	errCh := make(chan error, 1)
-	ctx := core.WithCommitObserver(context.Background(), func(err error) {
+	ctx := orchestrator.WithCommitObserver(context.Background(), orchestrator.ObserverFunc(func(err error) {
 		close(commitDone)
-	})
+	}))
 	go func() {
-		err := orchestrator.CommitAll(ctx)
+		err := o.CommitAll(ctx)
 		errCh <- err
 	}()
 
@@ -0,0 +1,146 @@
+// Package tx stages pushes across several queues and applies them atomically.
+//
+// A Tx collects elements per queue and, on Commit, runs a two-phase commit
+// over all of them through the commit orchestrator: either every queue makes
+// its elements visible or none does. WithOrchestrator commits through the
+// application's orchestrator, so transactions are serialised with its other
+// commits and share its versions, middleware, hooks and commit log:
+//
+//	t := tx.New(tx.WithOrchestrator(o))
+//	tx.Push(t, orders, order)
+//	tx.Push(t, audit, entry)
+//	if err := t.Commit(ctx); err != nil {
+//		// no queue received anything
+//	}
+package tx
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+
+	"github.com/timzifer/committable_queue/orchestrator"
+)
+
+// ErrDone is returned when a Tx is used after Commit or Discard.
+var ErrDone = errors.New("tx: transaction already finished")
+
+// Appender is a queue that can stage a batch of elements as a commit of its
+// own. queue.SegmentedQueue implements it.
+type Appender[T any] interface {
+	PrepareAppend(ctx context.Context, values []T) (publish func(), abort func(), err error)
+}
+
+// participant is one queue of a Tx, staged as a bank of the orchestrator.
+type participant interface {
+	orchestrator.Bank
+	target() any
+}
+
+type batch[T any] struct {
+	queue  Appender[T]
+	values []T
+}
+
+func (b *batch[T]) target() any {
+	return b.queue
+}
+
+func (b *batch[T]) PrepareCommit(ctx context.Context) (publish func(), abort func(), err error) {
+	return b.queue.PrepareAppend(ctx, b.values)
+}
+
+// Tx collects pushes for several queues. It is safe for concurrent use, but
+// each Tx commits at most once.
+type Tx struct {
+	orchestrator *orchestrator.CommitOrchestrator
+
+	mu    sync.Mutex
+	parts []participant
+	done  bool
+}
+
+// Option configures a Tx.
+type Option func(*Tx)
+
+// WithOrchestrator commits the transaction through o with CommitBanks. Without
+// it every Commit runs a private orchestrator, which is not serialised with
+// any other commit of the queues involved.
+func WithOrchestrator(o *orchestrator.CommitOrchestrator) Option {
+	return func(t *Tx) {
+		t.orchestrator = o
+	}
+}
+
+// New returns an empty transaction.
+func New(options ...Option) *Tx {
+	t := &Tx{}
+	for _, option := range options {
+		option(t)
+	}
+	return t
+}
+
+// sameQueue reports whether a and b are the same queue. Queues whose dynamic
+// type is not comparable are never merged, so each Push to them stages a
+// batch of its own.
+func sameQueue(a, b any) bool {
+	if !reflect.ValueOf(a).Comparable() || !reflect.ValueOf(b).Comparable() {
+		return false
+	}
+	return a == b
+}
+
+// Push stages values for q. Pushes to the same queue keep their order and
+// are appended as one batch. It returns ErrDone once the transaction has
+// finished.
+func Push[T any](t *Tx, q Appender[T], values ...T) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.done {
+		return ErrDone
+	}
+	for _, p := range t.parts {
+		if sameQueue(p.target(), q) {
+			b := p.(*batch[T])
+			b.values = append(b.values, values...)
+			return nil
+		}
+	}
+	t.parts = append(t.parts, &batch[T]{queue: q, values: append([]T(nil), values...)})
+	return nil
+}
+
+// Commit prepares every queue in push order and publishes them only when all
+// prepares succeed. On error, queues that were already prepared are aborted
+// and no queue receives any element. The transaction is finished either way.
+func (t *Tx) Commit(ctx context.Context) error {
+	t.mu.Lock()
+	if t.done {
+		t.mu.Unlock()
+		return ErrDone
+	}
+	t.done = true
+	parts := t.parts
+	t.parts = nil
+	t.mu.Unlock()
+
+	banks := make([]orchestrator.Bank, len(parts))
+	for i, p := range parts {
+		banks[i] = p
+	}
+	if t.orchestrator != nil {
+		return t.orchestrator.CommitBanks(ctx, banks...)
+	}
+	return orchestrator.NewCommitOrchestrator(orchestrator.WithBanks(banks...)).CommitAll(ctx)
+}
+
+// Discard drops the staged elements without touching any queue.
+func (t *Tx) Discard() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.done = true
+	t.parts = nil
+}
@@ -0,0 +1,138 @@
+package tx
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/timzifer/committable_queue/orchestrator"
+	"github.com/timzifer/committable_queue/queue"
+)
+
+type failingQueue struct{}
+
+var errPrepare = errors.New("prepare failed")
+
+func (f *failingQueue) PrepareAppend(context.Context, []string) (func(), func(), error) {
+	return nil, nil, errPrepare
+}
+
+func TestCommitPublishesAllQueues(t *testing.T) {
+	orders := queue.NewSegmentedQueue[int]()
+	audit := queue.NewSegmentedQueue[string]()
+	orders.PushBackPending(99)
+
+	tr := New()
+	Push(tr, orders, 1, 2)
+	Push(tr, audit, "created")
+	Push(tr, orders, 3)
+
+	if orders.LenVisible() != 0 || audit.LenVisible() != 0 {
+		t.Fatalf("staged elements must not be visible before commit")
+	}
+	if err := tr.Commit(context.Background()); err != nil {
+		t.Fatalf("commit: %v", err)
+	}
+
+	for _, want := range []int{1, 2, 3} {
+		if v, ok := orders.PopFront(); !ok || v != want {
+			t.Fatalf("expected %d, got %d %v", want, v, ok)
+		}
+	}
+	if v, ok := audit.PopFront(); !ok || v != "created" {
+		t.Fatalf("expected audit entry, got %q %v", v, ok)
+	}
+	if orders.LenVisible() != 0 {
+		t.Fatalf("pending elements of other producers must stay pending")
+	}
+	orders.Commit()
+	if v, ok := orders.PopFront(); !ok || v != 99 {
+		t.Fatalf("expected 99 after the queue's own commit, got %d %v", v, ok)
+	}
+}
+
+func TestCommitFailureLeavesQueuesUntouched(t *testing.T) {
+	audit := queue.NewSegmentedQueue[string]()
+	broken := &failingQueue{}
+
+	tr := New()
+	Push(tr, audit, "created")
+	Push[string](tr, broken, "lost")
+
+	if err := tr.Commit(context.Background()); !errors.Is(err, errPrepare) {
+		t.Fatalf("expected prepare error, got %v", err)
+	}
+	if audit.LenVisible() != 0 {
+		t.Fatalf("a failed transaction must not publish anything")
+	}
+	if err := tr.Commit(context.Background()); !errors.Is(err, ErrDone) {
+		t.Fatalf("expected ErrDone, got %v", err)
+	}
+	if err := Push(tr, audit, "late"); !errors.Is(err, ErrDone) {
+		t.Fatalf("expected ErrDone, got %v", err)
+	}
+}
+
+// versionHook records the versions the orchestrator published.
+type versionHook struct {
+	published []uint64
+}
+
+func (h *versionHook) BeforePublish(uint64) {}
+
+func (h *versionHook) AfterPublish(version uint64, err error) {
+	if err == nil {
+		h.published = append(h.published, version)
+	}
+}
+
+func TestCommitThroughOrchestrator(t *testing.T) {
+	orders := queue.NewSegmentedQueue(queue.WithVersionStamps[int]())
+	o := orchestrator.NewCommitOrchestrator(orchestrator.WithBanks(orders))
+	hook := &versionHook{}
+	o.AddHook(hook)
+
+	orders.PushBackPending(1)
+	if err := o.CommitAll(context.Background()); err != nil {
+		t.Fatalf("commit: %v", err)
+	}
+	tr := New(WithOrchestrator(o))
+	Push(tr, orders, 2)
+	if err := tr.Commit(context.Background()); err != nil {
+		t.Fatalf("commit: %v", err)
+	}
+
+	if o.Version() != 2 || !slices.Equal(hook.published, []uint64{1, 2}) {
+		t.Fatalf("expected the transaction as version 2 of the orchestrator, got %d and hooks %v", o.Version(), hook.published)
+	}
+	for want := range 2 {
+		if v, version, ok := orders.PopFrontVersioned(); !ok || v != want+1 || version != uint64(want+1) {
+			t.Fatalf("expected %d at version %d, got %d at %d (%v)", want+1, want+1, v, version, ok)
+		}
+	}
+}
+
+// sliceQueue is an Appender whose dynamic type is not comparable.
+type sliceQueue []*queue.SegmentedQueue[int]
+
+func (s sliceQueue) PrepareAppend(ctx context.Context, values []int) (func(), func(), error) {
+	return s[0].PrepareAppend(ctx, values)
+}
+
+func TestPushToNonComparableQueue(t *testing.T) {
+	q := queue.NewSegmentedQueue[int]()
+	target := sliceQueue{q}
+
+	tr := New()
+	Push[int](tr, target, 1)
+	Push[int](tr, target, 2)
+	if err := tr.Commit(context.Background()); err != nil {
+		t.Fatalf("commit: %v", err)
+	}
+	for _, want := range []int{1, 2} {
+		if v, ok := q.PopFront(); !ok || v != want {
+			t.Fatalf("expected %d, got %d %v", want, v, ok)
+		}
+	}
+}